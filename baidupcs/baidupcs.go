@@ -2,7 +2,9 @@
 package baidupcs
 
 import (
+	"context"
 	"errors"
+	"github.com/Erope/BaiduPCS-Go/baidupcs/diskcache"
 	"github.com/Erope/BaiduPCS-Go/baidupcs/expires/cachemap"
 	"github.com/Erope/BaiduPCS-Go/baidupcs/internal/panhome"
 	"github.com/Erope/BaiduPCS-Go/baidupcs/pcserror"
@@ -67,6 +69,12 @@ const (
 	OperationCloudDlDeleteTask = "删除离线下载任务"
 	// OperationCloudDlClearTask 清空离线下载任务记录
 	OperationCloudDlClearTask = "清空离线下载任务记录"
+	// OperationCloudDlQueryTorrentInfo 查询种子信息
+	OperationCloudDlQueryTorrentInfo = "查询种子信息"
+	// OperationCloudDlQueryMagnetInfo 查询磁力链接信息
+	OperationCloudDlQueryMagnetInfo = "查询磁力链接信息"
+	// OperationCloudDlAddBtTask 添加BT离线下载任务
+	OperationCloudDlAddBtTask = "添加BT离线下载任务"
 	// OperationShareSet 创建分享链接
 	OperationShareSet = "创建分享链接"
 	// OperationShareCancel 取消分享
@@ -132,7 +140,14 @@ type (
 		panUA      string
 		isSetPanUA bool
 		ph         *panhome.PanHome
-		cacheOpMap cachemap.CacheOpMap
+		cacheOpMap *cachemap.CacheOpMap
+		ctx        context.Context  // 请求绑定的 context, 为空时使用 context.Background()
+		pcsAddr    *url.URL         // 自定义 PCS api 地址
+		panAddr    *url.URL         // 自定义网盘首页 api 地址
+		retry      *RetryPolicy     // 请求重试策略, 为空时使用默认策略
+		limiter    *rateLimiter     // 请求限流器, 为空时不限制
+		diskCache  *diskcache.Cache // 本地持久化缓存, 为空时不使用
+		batchSize  int              // 批量操作时, 每个请求最多包含的项数
 	}
 
 	userInfoJSON struct {
@@ -195,12 +210,41 @@ func (pcs *BaiduPCS) lazyInit() {
 	}
 	if pcs.ph == nil {
 		pcs.ph = panhome.NewPanHome(pcs.client)
+		pcs.ph.SetPanURL(pcs.panAddr)
+		// 获取签名时请求网盘首页, 同样需要限流
+		pcs.ph.SetBeforeRequest(func(ctx context.Context) error {
+			return pcs.limiter.wait(ctx, reqTypePan, OperationLocatePanAPIDownload)
+		})
 	}
-	if !pcs.isSetPanUA {
+	if pcs.cacheOpMap == nil {
+		pcs.cacheOpMap = &cachemap.CacheOpMap{}
+	}
+	if !pcs.isSetPanUA && pcs.panUA != NetdiskUA {
 		pcs.panUA = NetdiskUA
 	}
 }
 
+// WithContext 返回绑定 ctx 的 BaiduPCS 浅拷贝,
+// 拷贝与原对象共用 http 客户端, cookie 及缓存,
+// 通过拷贝发起的所有请求在 ctx 取消或超时后立即中断
+func (pcs *BaiduPCS) WithContext(ctx context.Context) *BaiduPCS {
+	if ctx == nil {
+		panic("baidupcs: nil context")
+	}
+	pcs.lazyInit()
+	pcs2 := *pcs
+	pcs2.ctx = ctx
+	return &pcs2
+}
+
+// Context 返回请求绑定的 context
+func (pcs *BaiduPCS) Context() context.Context {
+	if pcs.ctx != nil {
+		return pcs.ctx
+	}
+	return context.Background()
+}
+
 // GetClient 获取当前的http client
 func (pcs *BaiduPCS) GetClient() *requester.HTTPClient {
 	pcs.lazyInit()
@@ -264,14 +308,83 @@ func (pcs *BaiduPCS) SetHTTPS(https bool) {
 	pcs.isHTTPS = https
 }
 
-// URL 返回 url
+// SetRetryPolicy 设置请求重试策略, rp 为空则恢复默认策略
+func (pcs *BaiduPCS) SetRetryPolicy(rp *RetryPolicy) {
+	pcs.retry = rp
+}
+
+// RetryPolicy 返回请求重试策略
+func (pcs *BaiduPCS) RetryPolicy() *RetryPolicy {
+	if pcs.retry == nil {
+		return NewDefaultRetryPolicy()
+	}
+	return pcs.retry
+}
+
+// SetRateLimit 设置请求频率限制, rl 为空则不限制
+func (pcs *BaiduPCS) SetRateLimit(rl *RateLimit) {
+	pcs.limiter = newRateLimiter(rl)
+}
+
+// SetDiskCache 设置本地持久化缓存, 用于缓存文件列表和元信息, c 为空则不使用
+func (pcs *BaiduPCS) SetDiskCache(c *diskcache.Cache) {
+	pcs.diskCache = c
+}
+
+// DiskCache 返回本地持久化缓存
+func (pcs *BaiduPCS) DiskCache() *diskcache.Cache {
+	return pcs.diskCache
+}
+
+// SetPCSAddr 设置 PCS api 地址, 用于替代 pcs.baidu.com, 如 http://127.0.0.1:8080,
+// addr 为空则恢复默认地址
+func (pcs *BaiduPCS) SetPCSAddr(addr string) error {
+	u, err := parseAddr(addr)
+	if err != nil {
+		return err
+	}
+	pcs.pcsAddr = u
+	return nil
+}
+
+// SetPanAddr 设置网盘首页 api 地址, 用于替代 pan.baidu.com, 如 http://127.0.0.1:8080,
+// addr 为空则恢复默认地址
+func (pcs *BaiduPCS) SetPanAddr(addr string) error {
+	u, err := parseAddr(addr)
+	if err != nil {
+		return err
+	}
+	pcs.panAddr = u
+	if pcs.ph != nil {
+		pcs.ph.SetPanURL(u)
+	}
+	return nil
+}
+
+// URL 返回 PCS api 的 url
 func (pcs *BaiduPCS) URL() *url.URL {
+	if pcs.pcsAddr != nil {
+		u := *pcs.pcsAddr
+		return &u
+	}
 	return &url.URL{
 		Scheme: GetHTTPScheme(pcs.isHTTPS),
 		Host:   PCSBaiduCom,
 	}
 }
 
+// PanURL 返回网盘首页 api 的 url
+func (pcs *BaiduPCS) PanURL() *url.URL {
+	if pcs.panAddr != nil {
+		u := *pcs.panAddr
+		return &u
+	}
+	return &url.URL{
+		Scheme: GetHTTPScheme(pcs.isHTTPS),
+		Host:   PanBaiduCom,
+	}
+}
+
 func (pcs *BaiduPCS) getPanUAHeader() (header map[string]string) {
 	return map[string]string{
 		"User-Agent": pcs.panUA,
@@ -280,7 +393,7 @@ func (pcs *BaiduPCS) getPanUAHeader() (header map[string]string) {
 
 func (pcs *BaiduPCS) generatePCSURL(subPath, method string, param ...map[string]string) *url.URL {
 	pcsURL := pcs.URL()
-	pcsURL.Path = "/rest/2.0/pcs/" + subPath
+	pcsURL.Path += "/rest/2.0/pcs/" + subPath
 
 	uv := pcsURL.Query()
 	uv.Set("app_id", strconv.Itoa(pcs.appID))
@@ -296,11 +409,8 @@ func (pcs *BaiduPCS) generatePCSURL(subPath, method string, param ...map[string]
 }
 
 func (pcs *BaiduPCS) generatePCSURL2(subPath, method string, param ...map[string]string) *url.URL {
-	pcsURL2 := &url.URL{
-		Scheme: GetHTTPScheme(pcs.isHTTPS),
-		Host:   PanBaiduCom,
-		Path:   "/rest/2.0/" + subPath,
-	}
+	pcsURL2 := pcs.PanURL()
+	pcsURL2.Path += "/rest/2.0/" + subPath
 
 	uv := pcsURL2.Query()
 	uv.Set("app_id", PanAppID)
@@ -316,11 +426,12 @@ func (pcs *BaiduPCS) generatePCSURL2(subPath, method string, param ...map[string
 }
 
 func (pcs *BaiduPCS) generatePanURL(subPath string, param map[string]string) *url.URL {
-	panURL := url.URL{
-		Scheme: GetHTTPScheme(pcs.isHTTPS),
-		Host:   PanBaiduCom,
-		Path:   "/api/" + subPath,
-	}
+	return pcs.generatePanHomeURL("/api/"+subPath, param)
+}
+
+func (pcs *BaiduPCS) generatePanHomeURL(p string, param map[string]string) *url.URL {
+	panURL := pcs.PanURL()
+	panURL.Path += p
 
 	if param != nil {
 		uv := url.Values{}
@@ -329,7 +440,7 @@ func (pcs *BaiduPCS) generatePanURL(subPath string, param map[string]string) *ur
 		}
 		panURL.RawQuery = uv.Encode()
 	}
-	return &panURL
+	return panURL
 }
 
 // UK 获取用户 UK
@@ -0,0 +1,387 @@
+package baidupcs
+
+import (
+	"errors"
+	"github.com/Erope/BaiduPCS-Go/baidupcs/pcserror"
+)
+
+const (
+	// DefaultBatchSize 批量操作时, 每个请求最多包含的项数
+	DefaultBatchSize = 100
+
+	// ErrCodeFileNotExists 文件或目录不存在
+	ErrCodeFileNotExists = 31066
+	// ErrCodeFileExists 文件或目录已存在
+	ErrCodeFileExists = 31061
+	// ErrCodeParam 参数错误
+	ErrCodeParam = 31023
+	// ErrCodeFileNameInvalid 文件名非法
+	ErrCodeFileNameInvalid = 31062
+)
+
+const (
+	// BatchStatusSuccess 成功
+	BatchStatusSuccess BatchStatus = iota
+	// BatchStatusSkipped 目标已存在, 按 OnDupSkip 跳过
+	BatchStatusSkipped
+	// BatchStatusNotFound 文件或目录不存在
+	BatchStatusNotFound
+	// BatchStatusConflict 目标文件或目录已存在
+	BatchStatusConflict
+	// BatchStatusInvalid 参数或文件名非法
+	BatchStatusInvalid
+	// BatchStatusFailed 其他错误
+	BatchStatusFailed
+)
+
+type (
+	// BatchStatus 批量操作中单项的结果状态
+	BatchStatus int
+
+	// BatchResult 批量操作中单项的结果
+	BatchResult struct {
+		Path     string         // 删除操作的路径
+		From     string         // 拷贝/移动操作的源路径
+		To       string         // 拷贝/移动操作的目标路径
+		Status   BatchStatus    // 结果状态
+		PCSError pcserror.Error // 错误信息, 成功或跳过时为空
+
+		cpmv *CpMvJSON
+	}
+
+	// BatchResultList 批量操作的结果列表, 与请求的项一一对应
+	BatchResultList []*BatchResult
+)
+
+func (bs BatchStatus) String() string {
+	switch bs {
+	case BatchStatusSuccess:
+		return "成功"
+	case BatchStatusSkipped:
+		return "目标已存在, 跳过"
+	case BatchStatusNotFound:
+		return "文件或目录不存在"
+	case BatchStatusConflict:
+		return "目标已存在"
+	case BatchStatusInvalid:
+		return "参数或文件名非法"
+	default:
+		return "失败"
+	}
+}
+
+// batchStatusOf 根据错误判断单项的结果状态
+func batchStatusOf(pcsError pcserror.Error) BatchStatus {
+	if pcsError == nil {
+		return BatchStatusSuccess
+	}
+	switch {
+	case errors.Is(pcsError, pcserror.ErrNotFound):
+		return BatchStatusNotFound
+	case errors.Is(pcsError, pcserror.ErrAlreadyExists):
+		return BatchStatusConflict
+	case errors.Is(pcsError, pcserror.ErrParamInvalid):
+		return BatchStatusInvalid
+	}
+	return BatchStatusFailed
+}
+
+// isItemError 错误是否由批量操作中的个别项引起, 是则可以拆分请求找出出错的项
+func isItemError(pcsError pcserror.Error) bool {
+	return batchStatusOf(pcsError) != BatchStatusFailed
+}
+
+// IsFailed 是否失败, 跳过的项不算作失败
+func (bs BatchStatus) IsFailed() bool {
+	return bs != BatchStatusSuccess && bs != BatchStatusSkipped
+}
+
+// Failed 返回失败的项
+func (brl BatchResultList) Failed() (failed BatchResultList) {
+	for _, br := range brl {
+		if br.Status.IsFailed() {
+			failed = append(failed, br)
+		}
+	}
+	return
+}
+
+// Skipped 返回跳过的项
+func (brl BatchResultList) Skipped() (skipped BatchResultList) {
+	for _, br := range brl {
+		if br.Status == BatchStatusSkipped {
+			skipped = append(skipped, br)
+		}
+	}
+	return
+}
+
+// Succeeded 返回成功的项
+func (brl BatchResultList) Succeeded() (succeeded BatchResultList) {
+	for _, br := range brl {
+		if br.Status == BatchStatusSuccess {
+			succeeded = append(succeeded, br)
+		}
+	}
+	return
+}
+
+// Err 返回第一个失败的项的错误, 全部成功则返回空
+func (brl BatchResultList) Err() pcserror.Error {
+	for _, br := range brl {
+		if br.PCSError != nil {
+			return br.PCSError
+		}
+	}
+	return nil
+}
+
+// SetBatchSize 设置批量操作时, 每个请求最多包含的项数, 小于等于0则使用 DefaultBatchSize
+func (pcs *BaiduPCS) SetBatchSize(size int) {
+	pcs.batchSize = size
+}
+
+func (pcs *BaiduPCS) getBatchSize() int {
+	if pcs.batchSize <= 0 {
+		return DefaultBatchSize
+	}
+	return pcs.batchSize
+}
+
+// runBatch 将 results 分块执行 do, 某块因个别项出错时, 找出出错的项, 只重新发送未处理的项.
+// 服务器在出错时可能已处理了部分项, 重新发送会重复执行这些项, 所以先由 check 检查该块中各项的状态:
+// check 返回 true 表示该项未处理, 可以重新发送; 否则由 check 设置该项的结果.
+// 各项均未处理时, 将该块二分后重新发送, 直到找出出错的项.
+func (pcs *BaiduPCS) runBatch(results BatchResultList, do func(part BatchResultList) pcserror.Error, check func(br *BatchResult, pcsError pcserror.Error) (pending bool)) {
+	var run func(part BatchResultList)
+	run = func(part BatchResultList) {
+		pcsError := do(part)
+		if pcsError == nil || len(part) <= 1 || !isItemError(pcsError) {
+			setBatchResult(part, pcsError)
+			return
+		}
+
+		pending := make(BatchResultList, 0, len(part))
+		for _, br := range part {
+			if check(br, pcsError) {
+				pending = append(pending, br)
+			}
+		}
+
+		switch {
+		case len(pending) == len(part):
+			baiduPCSVerbose.Infof("%s, 拆分 %d 项重试\n", pcsError, len(part))
+			mid := len(part) / 2
+			run(part[:mid])
+			run(part[mid:])
+		case len(pending) > 0:
+			baiduPCSVerbose.Infof("%s, 重试未处理的 %d 项\n", pcsError, len(pending))
+			run(pending)
+		}
+	}
+
+	size := pcs.getBatchSize()
+	for start := 0; start < len(results); start += size {
+		end := start + size
+		if end > len(results) {
+			end = len(results)
+		}
+		run(results[start:end])
+	}
+}
+
+func setBatchResult(part BatchResultList, pcsError pcserror.Error) {
+	status := batchStatusOf(pcsError)
+	for _, br := range part {
+		br.Status = status
+		br.PCSError = pcsError
+	}
+}
+
+// newBatchItemError 返回单项的远端服务器错误, 用于检查后确定的出错项
+func newBatchItemError(op string, errCode int) pcserror.Error {
+	errInfo := pcserror.NewPCSErrorInfo(op)
+	errInfo.SetRemoteError()
+	errInfo.ErrCode = errCode
+	return errInfo
+}
+
+// batchMeta 获取 p 的元信息, 用于检查批量操作中单项的状态, p 不存在时 fd 和 pcsError 均为空
+func (pcs *BaiduPCS) batchMeta(p string) (fd *FileDirectory, pcsError pcserror.Error) {
+	fd, pcsError = pcs.FilesDirectoriesMeta(p)
+	if pcsError != nil && errors.Is(pcsError, pcserror.ErrNotFound) {
+		return nil, nil
+	}
+	return
+}
+
+// checkRemoveItem 删除出错后, 检查单项的状态: 路径仍存在则未处理;
+// 不存在时, 若请求因路径不存在而出错, 无法区分是否为该项引起, 按不存在处理, 否则为已删除.
+func (pcs *BaiduPCS) checkRemoveItem(br *BatchResult, pcsError pcserror.Error) bool {
+	fd, metaError := pcs.batchMeta(br.Path)
+	switch {
+	case metaError != nil:
+		// 无法检查, 重新发送不会重复删除
+		return true
+	case fd != nil:
+		return true
+	case errors.Is(pcsError, pcserror.ErrNotFound):
+		br.Status, br.PCSError = BatchStatusNotFound, newBatchItemError(OperationRemove, ErrCodeFileNotExists)
+	default:
+		br.Status, br.PCSError = BatchStatusSuccess, nil
+	}
+	return false
+}
+
+// checkCpMvItem 拷贝/移动出错后, 检查单项的状态, ts 为操作前目标是否已存在.
+// 无法确定是否已处理的项不重新发送, 按出错处理
+func (pcs *BaiduPCS) checkCpMvItem(op string, br *BatchResult, pcsError pcserror.Error, ts *targetState) bool {
+	from, metaError := pcs.batchMeta(br.From)
+	if metaError != nil {
+		br.Status, br.PCSError = BatchStatusFailed, metaError
+		return false
+	}
+	to, metaError := pcs.batchMeta(br.To)
+	if metaError != nil {
+		br.Status, br.PCSError = BatchStatusFailed, metaError
+		return false
+	}
+
+	ondup := OnDup(br.cpmv.OnDup.pcsParam(OnDupDefault))
+	switch {
+	case from == nil && to != nil && op == OperationMove:
+		// 已移动
+		br.Status, br.PCSError = BatchStatusSuccess, nil
+		return false
+	case from == nil:
+		br.Status, br.PCSError = BatchStatusNotFound, newBatchItemError(op, ErrCodeFileNotExists)
+		return false
+	case to == nil:
+		return true
+	case op == OperationMove:
+		// 源路径仍存在, 未移动
+		if ondup == OnDupOverwrite || ondup == OnDupNewCopy {
+			return true
+		}
+		br.Status, br.PCSError = BatchStatusConflict, newBatchItemError(op, ErrCodeFileExists)
+		return false
+	}
+
+	// 拷贝, 目标存在
+	existed, known := ts.exists(br.To)
+	switch {
+	case known && !existed:
+		// 由该请求拷贝
+		br.Status, br.PCSError = BatchStatusSuccess, nil
+	case ondup == OnDupOverwrite:
+		return true
+	case ondup == OnDupNewCopy:
+		// 可能已生成副本, 重新发送会生成多个副本
+		br.Status, br.PCSError = batchStatusOf(pcsError), pcsError
+	default:
+		br.Status, br.PCSError = BatchStatusConflict, newBatchItemError(op, ErrCodeFileExists)
+	}
+	return false
+}
+
+// BatchRemove 批量删除文件/目录, 自动分块, 返回每一项的结果
+func (pcs *BaiduPCS) BatchRemove(paths ...string) (results BatchResultList) {
+	results = make(BatchResultList, len(paths))
+	for k := range paths {
+		results[k] = &BatchResult{
+			Path: paths[k],
+		}
+	}
+
+	pcs.runBatch(results, func(part BatchResultList) pcserror.Error {
+		partPaths := make([]string, len(part))
+		for k := range part {
+			partPaths[k] = part[k].Path
+		}
+		return pcs.remove(partPaths...)
+	}, pcs.checkRemoveItem)
+
+	// 更新缓存
+	pcs.deleteCache(allRelatedDir(paths))
+	return
+}
+
+// BatchCopy 批量拷贝文件/目录, 自动分块, 返回每一项的结果
+func (pcs *BaiduPCS) BatchCopy(cpmvJSON ...*CpMvJSON) (results BatchResultList) {
+	return pcs.batchCpMvOp(OperationCopy, cpmvJSON...)
+}
+
+// BatchMove 批量移动文件/目录, 自动分块, 返回每一项的结果
+func (pcs *BaiduPCS) BatchMove(cpmvJSON ...*CpMvJSON) (results BatchResultList) {
+	return pcs.batchCpMvOp(OperationMove, cpmvJSON...)
+}
+
+func (pcs *BaiduPCS) batchCpMvOp(op string, cpmvJSON ...*CpMvJSON) (results BatchResultList) {
+	results = make(BatchResultList, len(cpmvJSON))
+	for k := range cpmvJSON {
+		results[k] = &BatchResult{
+			From: cpmvJSON[k].From,
+			To:   cpmvJSON[k].To,
+			cpmv: cpmvJSON[k],
+		}
+	}
+
+	// 操作前目标是否已存在, 用于跳过已存在的目标 (服务器不支持跳过),
+	// 以及拷贝出错时区分目标是否由该请求拷贝
+	targets := make([]string, 0, len(cpmvJSON))
+	for _, cj := range cpmvJSON {
+		if cj.OnDup == OnDupSkip || (op == OperationCopy && len(cpmvJSON) > 1) {
+			targets = append(targets, cj.To)
+		}
+	}
+	ts := pcs.listTargets(targets)
+	skipExistingCpMv(results, ts)
+	pending := make(BatchResultList, 0, len(results))
+	for _, br := range results {
+		if br.Status != BatchStatusSkipped {
+			pending = append(pending, br)
+		}
+	}
+
+	// 服务器只支持整个请求的处理方式, 按处理方式分组
+	var (
+		params []string
+		groups = map[string]BatchResultList{}
+	)
+	for _, br := range pending {
+		param := br.cpmv.OnDup.pcsParam(OnDupDefault)
+		if _, ok := groups[param]; !ok {
+			params = append(params, param)
+		}
+		groups[param] = append(groups[param], br)
+	}
+	for _, param := range params {
+		ondup := OnDup(param)
+		pcs.runBatch(groups[param], func(part BatchResultList) pcserror.Error {
+			partJSON := make([]*CpMvJSON, len(part))
+			for k := range part {
+				partJSON[k] = &CpMvJSON{
+					From:  part[k].From,
+					To:    part[k].To,
+					OnDup: ondup,
+				}
+			}
+			return pcs.cpmvOp(op, partJSON...)
+		}, func(br *BatchResult, pcsError pcserror.Error) bool {
+			return pcs.checkCpMvItem(op, br, pcsError, ts)
+		})
+	}
+
+	// 检查之后才出现的同名文件, 同样跳过
+	for _, br := range pending {
+		if br.Status == BatchStatusConflict && br.cpmv.OnDup == OnDupSkip {
+			br.Status = BatchStatusSkipped
+			br.PCSError = nil
+		}
+	}
+
+	// 更新缓存
+	cjl := CpMvJSONList(cpmvJSON)
+	pcs.deleteCache(cjl.AllRelatedDir())
+	return
+}
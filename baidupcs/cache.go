@@ -4,11 +4,30 @@ import (
 	"fmt"
 	"github.com/Erope/BaiduPCS-Go/baidupcs/expires"
 	"github.com/Erope/BaiduPCS-Go/baidupcs/pcserror"
+	"github.com/json-iterator/go"
+	"path"
 	"time"
 )
 
+const (
+	// listCacheTTL 文件列表的内存缓存有效期
+	listCacheTTL = 1 * time.Minute
+
+	// diskCacheMetaName 本地持久化缓存中, 元信息的缓存项名称
+	diskCacheMetaName = "meta"
+)
+
+// diskCacheListName 本地持久化缓存中, 文件列表的缓存项名称
+func diskCacheListName(options *OrderOptions) string {
+	if options == nil {
+		options = DefaultOrderOptions
+	}
+	return "list_" + string(options.By) + "_" + string(options.Order)
+}
+
 // deleteCache 删除含有 dirs 的缓存
 func (pcs *BaiduPCS) deleteCache(dirs []string) {
+	pcs.lazyInit()
 	cache := pcs.cacheOpMap.LazyInitCachePoolOp(OperationFilesDirectoriesList)
 	for _, v := range dirs {
 		key := v + "_" + defaultOrderOptionsStr
@@ -16,20 +35,127 @@ func (pcs *BaiduPCS) deleteCache(dirs []string) {
 		if ok {
 			cache.Delete(key)
 		}
+
+		err := pcs.diskCache.Invalidate(v)
+		if err != nil {
+			baiduPCSVerbose.Warnf("invalidate disk cache error: %s\n", err)
+		}
+	}
+}
+
+// deleteAllCache 删除所有目录的缓存, 用于无法确定受影响目录的操作
+func (pcs *BaiduPCS) deleteAllCache() {
+	pcs.lazyInit()
+	cache := pcs.cacheOpMap.LazyInitCachePoolOp(OperationFilesDirectoriesList)
+	cache.Range(func(key interface{}, _ expires.DataExpires) bool {
+		cache.Delete(key)
+		return true
+	})
+
+	err := pcs.diskCache.Clear()
+	if err != nil {
+		baiduPCSVerbose.Warnf("clear disk cache error: %s\n", err)
+	}
+}
+
+// deleteCloudDlCache 删除最近下载成功的离线下载任务的保存路径的缓存.
+// 完成时间早于缓存有效期的任务, 其保存路径在完成前的缓存均已过期, 无需删除
+func (pcs *BaiduPCS) deleteCloudDlCache(cl CloudDlTaskList) {
+	ttl := listCacheTTL
+	if pcs.diskCache != nil && pcs.diskCache.TTL() > ttl {
+		ttl = pcs.diskCache.TTL()
+	}
+
+	var dirs []string
+	for _, task := range cl {
+		if task.Status != 0 || task.SavePath == "" || time.Since(time.Unix(task.FinishTime, 0)) > ttl {
+			continue
+		}
+		dirs = append(dirs, task.SavePath, path.Dir(task.SavePath))
+	}
+	if len(dirs) > 0 {
+		pcs.deleteCache(dirs)
 	}
 }
 
 // CacheFilesDirectoriesList 缓存获取
 func (pcs *BaiduPCS) CacheFilesDirectoriesList(path string, options *OrderOptions) (fdl FileDirectoryList, pcsError pcserror.Error) {
+	pcs.lazyInit()
 	data := pcs.cacheOpMap.CacheOperation(OperationFilesDirectoriesList, path+"_"+fmt.Sprint(options), func() expires.DataExpires {
-		fdl, pcsError = pcs.FilesDirectoriesList(path, options)
+		fdl, pcsError = pcs.diskCacheFilesDirectoriesList(path, options)
 		if pcsError != nil {
 			return nil
 		}
-		return expires.NewDataExpires(fdl, 1*time.Minute)
+		return expires.NewDataExpires(fdl, listCacheTTL)
 	})
 	if pcsError != nil {
 		return
 	}
 	return data.Data().(FileDirectoryList), nil
 }
+
+// diskCacheFilesDirectoriesList 从本地持久化缓存获取文件列表, 未命中则从服务器获取并写入缓存
+func (pcs *BaiduPCS) diskCacheFilesDirectoriesList(path string, options *OrderOptions) (fdl FileDirectoryList, pcsError pcserror.Error) {
+	name := diskCacheListName(options)
+	data, ok := pcs.diskCache.Get(path, name)
+	if ok && jsoniter.Unmarshal(data, &fdl) == nil {
+		return fdl, nil
+	}
+
+	fdl, pcsError = pcs.FilesDirectoriesList(path, options)
+	if pcsError != nil {
+		return nil, pcsError
+	}
+	pcs.setDiskCache(path, name, fdl.withoutRelations())
+	return fdl, nil
+}
+
+// CacheFilesDirectoriesMeta 获取单个文件/目录的元信息, 优先从本地持久化缓存获取
+func (pcs *BaiduPCS) CacheFilesDirectoriesMeta(path string) (fd *FileDirectory, pcsError pcserror.Error) {
+	if path == "" {
+		path = PathSeparator
+	}
+
+	data, ok := pcs.diskCache.Get(path, diskCacheMetaName)
+	if ok && jsoniter.Unmarshal(data, &fd) == nil && fd != nil {
+		return fd, nil
+	}
+
+	fd, pcsError = pcs.FilesDirectoriesMeta(path)
+	if pcsError != nil {
+		return nil, pcsError
+	}
+	pcs.setDiskCache(path, diskCacheMetaName, fd.withoutRelations())
+	return fd, nil
+}
+
+func (pcs *BaiduPCS) setDiskCache(path, name string, v interface{}) {
+	if pcs.diskCache == nil {
+		return
+	}
+
+	data, err := jsoniter.Marshal(v)
+	if err != nil {
+		baiduPCSVerbose.Warnf("marshal disk cache error: %s\n", err)
+		return
+	}
+	err = pcs.diskCache.Set(path, name, data)
+	if err != nil {
+		baiduPCSVerbose.Warnf("set disk cache error: %s\n", err)
+	}
+}
+
+// withoutRelations 返回不含父目录和子目录信息的拷贝, 用于序列化
+func (f *FileDirectory) withoutRelations() *FileDirectory {
+	f2 := *f
+	f2.Parent, f2.Children = nil, nil
+	return &f2
+}
+
+func (fl FileDirectoryList) withoutRelations() FileDirectoryList {
+	fl2 := make(FileDirectoryList, 0, len(fl))
+	for _, f := range fl {
+		fl2 = append(fl2, f.withoutRelations())
+	}
+	return fl2
+}
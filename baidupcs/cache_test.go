@@ -0,0 +1,64 @@
+package baidupcs_test
+
+import (
+	"fmt"
+	"github.com/Erope/BaiduPCS-Go/baidupcs"
+	"github.com/Erope/BaiduPCS-Go/baidupcs/diskcache"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCacheInvalidate(t *testing.T) {
+	now := time.Now().Unix()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case strings.Contains(r.URL.Path, "/cloud_dl"):
+			// 任务 1 刚刚下载成功, 任务 2 很久之前下载成功
+			fmt.Fprintf(w, `{"task_info":{"1":{"status":"0","finish_time":"%d","save_path":"/dl/new"},"2":{"status":"0","finish_time":"%d","save_path":"/old/file"}}}`, now, now-86400)
+		case strings.Contains(r.URL.Path, "/file"):
+			w.Write([]byte(`{"errno":0,"extra":{"list":[{"fs_id":1}]}}`))
+		}
+	}))
+	defer server.Close()
+
+	pcs := baidupcs.NewPCS(266719, "")
+	if err := pcs.SetPCSAddr(server.URL); err != nil {
+		t.Fatal(err)
+	}
+	if err := pcs.SetPanAddr(server.URL); err != nil {
+		t.Fatal(err)
+	}
+	cache := diskcache.New(t.TempDir(), time.Hour)
+	pcs.SetDiskCache(cache)
+
+	set := func(dirs ...string) {
+		for _, dir := range dirs {
+			if err := cache.Set(dir, "list", []byte("[]")); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	cached := func(dir string) bool {
+		_, ok := cache.Get(dir, "list")
+		return ok
+	}
+
+	set("/dl", "/old", "/other")
+	if _, err := pcs.CloudDlQueryTask([]int64{1, 2}); err != nil {
+		t.Fatal(err)
+	}
+	if cached("/dl") || !cached("/old") || !cached("/other") {
+		t.Fatalf("unexpected cache after cloud dl query: /dl %t, /old %t, /other %t", cached("/dl"), cached("/old"), cached("/other"))
+	}
+
+	if _, err := pcs.RecycleRestore(1); err != nil {
+		t.Fatal(err)
+	}
+	if cached("/old") || cached("/other") {
+		t.Fatal("cache not deleted after recycle restore")
+	}
+}
@@ -0,0 +1,97 @@
+package baidupcs
+
+import (
+	"context"
+	"github.com/Erope/BaiduPCS-Go/baidupcs/diskcache"
+	"github.com/Erope/BaiduPCS-Go/baidupcs/pcserror"
+	"io"
+)
+
+type (
+	// Client 网盘客户端, 包括命令层用到的所有网盘操作, *BaiduPCS 为其默认实现.
+	// 其他实现 (如测试用的内存实现) 可使用 NewListIter 和 WalkWith 实现 ListIter 和 Walk
+	Client interface {
+		// Context 返回请求绑定的 context, 上传和下载的处理函数应使用它发起请求
+		Context() context.Context
+
+		// 文件和目录
+		FilesDirectoriesMeta(path string) (data *FileDirectory, pcsError pcserror.Error)
+		FilesDirectoriesBatchMeta(paths ...string) (data FileDirectoryList, pcsError pcserror.Error)
+		FilesDirectoriesList(path string, options *OrderOptions) (data FileDirectoryList, pcsError pcserror.Error)
+		FilesDirectoriesListPage(path string, options *OrderOptions, start, limit int) (data FileDirectoryList, pcsError pcserror.Error)
+		PrepareFilesDirectoriesList(path string, options *OrderOptions) (dataReadCloser io.ReadCloser, pcsError pcserror.Error)
+		PrepareFilesDirectoriesListPage(path string, options *OrderOptions, start, limit int) (dataReadCloser io.ReadCloser, pcsError pcserror.Error)
+		CacheFilesDirectoriesList(path string, options *OrderOptions) (fdl FileDirectoryList, pcsError pcserror.Error)
+		CacheFilesDirectoriesMeta(path string) (fd *FileDirectory, pcsError pcserror.Error)
+		ListIter(path string, opts *ListIterOptions) *ListIter
+		Walk(root string, opts *WalkOptions, fn WalkFunc) error
+		FilesDirectoriesRecurseList(path string, options *OrderOptions, handleFileDirectoryFunc HandleFileDirectoryFunc) (data FileDirectoryList)
+		Search(targetPath, keyword string, recursive bool) (fdl FileDirectoryList, pcsError pcserror.Error)
+		MatchPathByShellPattern(pattern string) (pcspaths []string, pcsError pcserror.Error)
+		Isdir(pcspath string) (isdir bool, pcsError pcserror.Error)
+		DiskCache() *diskcache.Cache
+
+		// 管理文件
+		Mkdir(pcspath string) (pcsError pcserror.Error)
+		Remove(paths ...string) (pcsError pcserror.Error)
+		BatchRemove(paths ...string) (results BatchResultList)
+		Copy(cpmvJSON ...*CpMvJSON) (pcsError pcserror.Error)
+		BatchCopy(cpmvJSON ...*CpMvJSON) (results BatchResultList)
+		Move(cpmvJSON ...*CpMvJSON) (pcsError pcserror.Error)
+		BatchMove(cpmvJSON ...*CpMvJSON) (results BatchResultList)
+		Rename(from, to string) (pcsError pcserror.Error)
+		QuotaInfo() (quota, used int64, pcsError pcserror.Error)
+
+		// 下载
+		DownloadFile(path string, downloadFunc DownloadFunc) (err error)
+		DownloadStreamFile(path string, downloadFunc DownloadFunc) (err error)
+		LocateDownload(pcspath string) (info *URLInfo, pcsError pcserror.Error)
+		LocatePanAPIDownload(fidList ...int64) (dlinkInfoList APIDownloadDlinkInfoList, pcsError pcserror.Error)
+
+		// 上传
+		UploadWithOnDup(targetPath string, ondup OnDup, uploadFunc UploadFunc) (pcsError pcserror.Error)
+		UploadTmpFile(uploadFunc UploadFunc) (md5 string, pcsError pcserror.Error)
+		UploadCreateSuperFileWithOnDup(targetPath string, ondup OnDup, blockList ...string) (pcsError pcserror.Error)
+		UploadPrecreateWithOnDup(targetPath string, ondup OnDup, contentMD5, sliceMD5, crc32 string, size int64, bolckList ...string) (precreateInfo *PrecreateInfo, pcsError pcserror.Error)
+		UploadSuperfile2(uploadid, targetPath string, partseq int, partOffset int64, uploadFunc UploadFunc) (md5sum string, pcsError pcserror.Error)
+		RapidUploadWithOnDup(targetPath string, ondup OnDup, contentMD5, sliceMD5, crc32 string, length int64) (pcsError pcserror.Error)
+
+		// 秒传信息
+		ExportByFileInfo(finfo *FileDirectory) (rinfo *RapidUploadInfo, pcsError pcserror.Error)
+		FixMD5ByFileInfo(finfo *FileDirectory) (pcsError pcserror.Error)
+		GetRapidUploadInfoByFileInfo(finfo *FileDirectory) (rinfo *RapidUploadInfo, pcsError pcserror.Error)
+		GetRapidUploadInfoByLink(link string, compareRInfo *RapidUploadInfo) (rinfo *RapidUploadInfo, pcsError pcserror.Error)
+
+		// 离线下载
+		CloudDlAddTask(sourceURL, savePath string) (taskID int64, pcsError pcserror.Error)
+		CloudDlQueryTask(taskIDs []int64) (cl CloudDlTaskList, pcsError pcserror.Error)
+		CloudDlListTask() (cl CloudDlTaskList, pcsError pcserror.Error)
+		CloudDlCancelTask(taskID int64) (pcsError pcserror.Error)
+		CloudDlDeleteTask(taskID int64) (pcsError pcserror.Error)
+		CloudDlClearTask() (total int, pcsError pcserror.Error)
+		CloudDlQueryTorrentInfo(torrentPath string) (info *CloudDlBtInfo, pcsError pcserror.Error)
+		CloudDlQueryMagnetInfo(magnetURL, savePath string) (info *CloudDlBtInfo, pcsError pcserror.Error)
+		CloudDlAddBtTask(info *CloudDlBtInfo, savePath string, selectedIdx []int) (taskID int64, pcsError pcserror.Error)
+
+		// 分享
+		ShareSet(paths []string, option *ShareOption) (s *Shared, pcsError pcserror.Error)
+		ShareCancel(shareIDs []int64) (pcsError pcserror.Error)
+		ShareList(page int) (records ShareRecordInfoList, pcsError pcserror.Error)
+
+		// 回收站
+		RecycleList(page int) (fdl RecycleFDInfoList, panError pcserror.Error)
+		RecycleRestore(fidList ...int64) (sussFsIDList []*FsIDJSON, pcsError pcserror.Error)
+		RecycleDelete(fidList ...int64) (panError pcserror.Error)
+		RecycleClear() (sussNum int, pcsError pcserror.Error)
+	}
+)
+
+var _ Client = (*BaiduPCS)(nil)
+
+// ClientWithContext 返回绑定 ctx 的 Client, c 为 *BaiduPCS 时返回 WithContext 的拷贝, 其他实现原样返回
+func ClientWithContext(c Client, ctx context.Context) Client {
+	if pcs, ok := c.(*BaiduPCS); ok {
+		return pcs.WithContext(ctx)
+	}
+	return c
+}
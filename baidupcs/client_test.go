@@ -0,0 +1,126 @@
+package baidupcs_test
+
+import (
+	"github.com/Erope/BaiduPCS-Go/baidupcs"
+	"github.com/Erope/BaiduPCS-Go/baidupcs/pcserror"
+	"path"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// memLister 内存中的目录树, 实现 baidupcs.WalkLister
+type memLister map[string]*baidupcs.FileDirectory
+
+func newMemLister(paths ...string) memLister {
+	ml := memLister{
+		"/": &baidupcs.FileDirectory{Path: "/", Isdir: true},
+	}
+	for _, p := range paths {
+		isdir := strings.HasSuffix(p, "/")
+		p = path.Clean(p)
+		ml[p] = &baidupcs.FileDirectory{Path: p, Filename: path.Base(p), Isdir: isdir}
+	}
+	return ml
+}
+
+func (ml memLister) FilesDirectoriesMeta(p string) (*baidupcs.FileDirectory, pcserror.Error) {
+	fd, ok := ml[p]
+	if !ok {
+		pcsError := pcserror.NewPCSErrorInfo(baidupcs.OperationFilesDirectoriesMeta)
+		pcsError.ErrType = pcserror.ErrTypeRemoteError
+		pcsError.ErrCode = 31066
+		return nil, pcsError
+	}
+	return fd, nil
+}
+
+func (ml memLister) FilesDirectoriesListPage(p string, options *baidupcs.OrderOptions, start, limit int) (baidupcs.FileDirectoryList, pcserror.Error) {
+	fdl := baidupcs.FileDirectoryList{}
+	for fdPath, fd := range ml {
+		if fdPath != "/" && path.Dir(fdPath) == p {
+			fdl = append(fdl, fd)
+		}
+	}
+	sort.Slice(fdl, func(i, j int) bool {
+		return fdl[i].Path < fdl[j].Path
+	})
+	if start > len(fdl) {
+		start = len(fdl)
+	}
+	if start+limit < len(fdl) {
+		fdl = fdl[:start+limit]
+	}
+	return fdl[start:], nil
+}
+
+func TestWalkWith(t *testing.T) {
+	ml := newMemLister("/a/", "/a/1", "/a/2", "/a/3", "/b/", "/b/c/", "/b/c/4", "/5")
+
+	// 分页大小小于目录下的项数
+	iter := baidupcs.NewListIter(ml, "/a", &baidupcs.ListIterOptions{PageSize: 2})
+	fdl, err := iter.All()
+	if err != nil || len(fdl) != 3 {
+		t.Fatalf("unexpected list: %v, %s", fdl, err)
+	}
+
+	var walked []string
+	walkErr := baidupcs.WalkWith(ml, "/", &baidupcs.WalkOptions{PageSize: 2}, func(fdPath string, fd *baidupcs.FileDirectory, pcsError pcserror.Error) error {
+		if pcsError != nil {
+			return pcsError
+		}
+		if fdPath == "/b/c" {
+			return baidupcs.SkipDir
+		}
+		walked = append(walked, fdPath)
+		return nil
+	})
+	if walkErr != nil {
+		t.Fatal(walkErr)
+	}
+	sort.Strings(walked)
+	if strings.Join(walked, ",") != "/,/5,/a,/a/1,/a/2,/a/3,/b" {
+		t.Fatalf("unexpected walk: %v", walked)
+	}
+}
+
+// countPager 记录获取列表的请求次数
+type countPager struct {
+	memLister
+	count int
+}
+
+func (cp *countPager) FilesDirectoriesListPage(p string, options *baidupcs.OrderOptions, start, limit int) (baidupcs.FileDirectoryList, pcserror.Error) {
+	cp.count++
+	return cp.memLister.FilesDirectoriesListPage(p, options, start, limit)
+}
+
+func TestListIterLastPage(t *testing.T) {
+	for _, c := range []struct {
+		paths []string
+		count int // 请求次数
+	}{
+		{[]string{"/a/"}, 1},
+		{[]string{"/a/", "/a/1"}, 1},
+		{[]string{"/a/", "/a/1", "/a/2", "/a/3"}, 2},
+		{[]string{"/a/", "/a/1", "/a/2", "/a/3", "/a/4"}, 3}, // 最后一页已满, 需再请求一次
+	} {
+		cp := &countPager{memLister: newMemLister(c.paths...)}
+		fdl, err := baidupcs.NewListIter(cp, "/a", &baidupcs.ListIterOptions{PageSize: 2}).All()
+		if err != nil || len(fdl) != len(c.paths)-1 {
+			t.Fatalf("unexpected list: %v, %s", fdl, err)
+		}
+		if cp.count != c.count {
+			t.Errorf("%v: %d requests, want %d", c.paths, cp.count, c.count)
+		}
+	}
+
+	iter := baidupcs.NewListIter(newMemLister("/a/", "/a/1", "/a/2", "/a/3"), "/a", &baidupcs.ListIterOptions{PageSize: 2})
+	var pages []int
+	for iter.NextPage() {
+		pages = append(pages, len(iter.Page()))
+	}
+	if iter.Err() != nil || len(pages) != 2 || pages[0] != 2 || pages[1] != 1 {
+		t.Fatalf("unexpected pages: %v, %s", pages, iter.Err())
+	}
+}
@@ -15,8 +15,9 @@ import (
 type (
 	// CloudDlFileInfo 离线下载的文件信息
 	CloudDlFileInfo struct {
-		FileName string `json:"file_name"`
-		FileSize int64  `json:"file_size"`
+		FileName     string `json:"file_name"`
+		FileSize     int64  `json:"file_size"`
+		FinishedSize int64  `json:"finished_size"` // 已下载大小, BT任务的每个文件单独统计
 	}
 
 	// CloudDlTaskInfo 离线下载的任务信息
@@ -53,8 +54,9 @@ type (
 		TaskName     string `json:"task_name"`
 		OdType       string `json:"od_type"`
 		FileList     []*struct {
-			FileName string `json:"file_name"`
-			FileSize string `json:"file_size"`
+			FileName     string `json:"file_name"`
+			FileSize     string `json:"file_size"`
+			FinishedSize string `json:"finished_size"`
 		} `json:"file_list"`
 		Result int `json:"result"`
 	}
@@ -110,8 +112,9 @@ func (ci *cloudDlTaskInfo) convert() *CloudDlTaskInfo {
 		}
 
 		ci2.FileList = append(ci2.FileList, &CloudDlFileInfo{
-			FileName: v.FileName,
-			FileSize: converter.MustInt64(v.FileSize),
+			FileName:     v.FileName,
+			FileSize:     converter.MustInt64(v.FileSize),
+			FinishedSize: converter.MustInt64(v.FinishedSize),
 		})
 	}
 
@@ -194,6 +197,8 @@ func (pcs *BaiduPCS) cloudDlQueryTask(op string, taskIDs []int64) (cl CloudDlTas
 		cl = append(cl, v2)
 	}
 
+	// 离线下载完成后, 保存路径下新增了文件
+	pcs.deleteCloudDlCache(cl)
 	return cl, nil
 }
 
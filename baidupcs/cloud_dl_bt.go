@@ -0,0 +1,154 @@
+package baidupcs
+
+import (
+	"github.com/Erope/BaiduPCS-Go/baidupcs/pcserror"
+	"strconv"
+	"strings"
+)
+
+type (
+	// CloudDlBtFileInfo BT资源中的文件信息
+	CloudDlBtFileInfo struct {
+		Index    int    `json:"index"` // 文件序号, 从1开始, 用于选择要下载的文件
+		FileName string `json:"file_name"`
+		Size     int64  `json:"size"`
+	}
+
+	// CloudDlBtInfo BT资源信息, 来自磁力链接或网盘中的种子文件
+	CloudDlBtInfo struct {
+		Source    string               `json:"source"`         // 磁力链接或网盘中的种子文件路径
+		SHA1      string               `json:"sha1,omitempty"` // 种子文件的 sha1, 磁力链接为空
+		TotalSize int64                `json:"total_size"`
+		FileList  []*CloudDlBtFileInfo `json:"file_list"`
+	}
+
+	// cloudDlSize 文件大小, 服务器返回的可能是数字, 也可能是字符串
+	cloudDlSize int64
+
+	cloudDlBtFileJSON struct {
+		FileName string      `json:"file_name"`
+		Size     cloudDlSize `json:"size"`
+	}
+
+	cloudDlTorrentInfoJSON struct {
+		TorrentInfo struct {
+			FileInfo  []*cloudDlBtFileJSON `json:"file_info"`
+			SHA1      string               `json:"sha1"`
+			TotalSize cloudDlSize          `json:"total_size"`
+		} `json:"torrent_info"`
+		*pcserror.PCSErrInfo
+	}
+
+	cloudDlMagnetInfoJSON struct {
+		MagnetInfo []*cloudDlBtFileJSON `json:"magnet_info"`
+		*pcserror.PCSErrInfo
+	}
+)
+
+// UnmarshalJSON 解析数字或字符串
+func (size *cloudDlSize) UnmarshalJSON(data []byte) error {
+	s := strings.Trim(string(data), `"`)
+	if s == "" || s == "null" {
+		*size = 0
+		return nil
+	}
+	i, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return err
+	}
+	*size = cloudDlSize(i)
+	return nil
+}
+
+// IsMagnetURL 判断 source 是否为磁力链接
+func IsMagnetURL(source string) bool {
+	return strings.HasPrefix(strings.ToLower(source), "magnet:")
+}
+
+func newCloudDlBtInfo(source, sha1 string, files []*cloudDlBtFileJSON) *CloudDlBtInfo {
+	info := &CloudDlBtInfo{
+		Source:   source,
+		SHA1:     sha1,
+		FileList: make([]*CloudDlBtFileInfo, 0, len(files)),
+	}
+	for k, f := range files {
+		if f == nil {
+			continue
+		}
+		info.FileList = append(info.FileList, &CloudDlBtFileInfo{
+			Index:    k + 1,
+			FileName: f.FileName,
+			Size:     int64(f.Size),
+		})
+		info.TotalSize += int64(f.Size)
+	}
+	return info
+}
+
+// CloudDlQueryTorrentInfo 查询网盘中的种子文件的信息, 获取其中的文件列表
+func (pcs *BaiduPCS) CloudDlQueryTorrentInfo(torrentPath string) (info *CloudDlBtInfo, pcsError pcserror.Error) {
+	dataReadCloser, pcsError := pcs.PrepareCloudDlQueryTorrentInfo(torrentPath)
+	if pcsError != nil {
+		return
+	}
+
+	defer dataReadCloser.Close()
+
+	jsonData := cloudDlTorrentInfoJSON{
+		PCSErrInfo: pcserror.NewPCSErrorInfo(OperationCloudDlQueryTorrentInfo),
+	}
+
+	pcsError = pcserror.HandleJSONParse(OperationCloudDlQueryTorrentInfo, dataReadCloser, &jsonData)
+	if pcsError != nil {
+		return
+	}
+
+	info = newCloudDlBtInfo(torrentPath, jsonData.TorrentInfo.SHA1, jsonData.TorrentInfo.FileInfo)
+	if jsonData.TorrentInfo.TotalSize > 0 {
+		info.TotalSize = int64(jsonData.TorrentInfo.TotalSize)
+	}
+	return info, nil
+}
+
+// CloudDlQueryMagnetInfo 查询磁力链接的信息, 获取其中的文件列表
+func (pcs *BaiduPCS) CloudDlQueryMagnetInfo(magnetURL, savePath string) (info *CloudDlBtInfo, pcsError pcserror.Error) {
+	dataReadCloser, pcsError := pcs.PrepareCloudDlQueryMagnetInfo(magnetURL, savePath)
+	if pcsError != nil {
+		return
+	}
+
+	defer dataReadCloser.Close()
+
+	jsonData := cloudDlMagnetInfoJSON{
+		PCSErrInfo: pcserror.NewPCSErrorInfo(OperationCloudDlQueryMagnetInfo),
+	}
+
+	pcsError = pcserror.HandleJSONParse(OperationCloudDlQueryMagnetInfo, dataReadCloser, &jsonData)
+	if pcsError != nil {
+		return
+	}
+
+	return newCloudDlBtInfo(magnetURL, "", jsonData.MagnetInfo), nil
+}
+
+// CloudDlAddBtTask 添加BT离线下载任务, info 由 CloudDlQueryTorrentInfo 或 CloudDlQueryMagnetInfo 获取,
+// selectedIdx 为选择的文件序号, 从1开始
+func (pcs *BaiduPCS) CloudDlAddBtTask(info *CloudDlBtInfo, savePath string, selectedIdx []int) (taskID int64, pcsError pcserror.Error) {
+	dataReadCloser, pcsError := pcs.PrepareCloudDlAddBtTask(info.Source, info.SHA1, savePath, selectedIdx)
+	if pcsError != nil {
+		return
+	}
+
+	defer dataReadCloser.Close()
+
+	taskInfo := cloudDlAddTaskJSON{
+		PCSErrInfo: pcserror.NewPCSErrorInfo(OperationCloudDlAddBtTask),
+	}
+
+	pcsError = pcserror.HandleJSONParse(OperationCloudDlAddBtTask, dataReadCloser, &taskInfo)
+	if pcsError != nil {
+		return
+	}
+
+	return taskInfo.TaskID, nil
+}
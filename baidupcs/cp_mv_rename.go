@@ -2,30 +2,38 @@ package baidupcs
 
 import (
 	"github.com/Erope/BaiduPCS-Go/baidupcs/pcserror"
-	"unsafe"
+	"path"
 )
 
 // Rename 重命名文件/目录
 func (pcs *BaiduPCS) Rename(from, to string) (pcsError pcserror.Error) {
-	return pcs.cpmvOp(OperationRename, &CpMvJSON{
+	pcsError = pcs.cpmvOp(OperationRename, &CpMvJSON{
 		From: from,
 		To:   to,
 	})
+	if pcsError != nil {
+		return
+	}
+
+	// 更新缓存
+	pcs.deleteCache([]string{path.Dir(from), path.Dir(to)})
+	return nil
 }
 
-// Copy 批量拷贝文件/目录
+// Copy 批量拷贝文件/目录, 自动分块, 返回第一个失败的项的错误
 func (pcs *BaiduPCS) Copy(cpmvJSON ...*CpMvJSON) (pcsError pcserror.Error) {
-	return pcs.cpmvOp(OperationCopy, cpmvJSON...)
+	return pcs.BatchCopy(cpmvJSON...).Err()
 }
 
-// Move 批量移动文件/目录
+// Move 批量移动文件/目录, 自动分块, 返回第一个失败的项的错误
 func (pcs *BaiduPCS) Move(cpmvJSON ...*CpMvJSON) (pcsError pcserror.Error) {
-	return pcs.cpmvOp(OperationMove, cpmvJSON...)
+	return pcs.BatchMove(cpmvJSON...).Err()
 }
 
+// cpmvOp 发送一次拷贝/移动请求, 不更新缓存
 func (pcs *BaiduPCS) cpmvOp(op string, cpmvJSON ...*CpMvJSON) (pcsError pcserror.Error) {
-	dataReadCloser, err := pcs.prepareCpMvOp(op, cpmvJSON...)
-	if err != nil {
+	dataReadCloser, pcsError := pcs.prepareCpMvOp(op, cpmvJSON...)
+	if pcsError != nil {
 		return
 	}
 
@@ -35,8 +43,5 @@ func (pcs *BaiduPCS) cpmvOp(op string, cpmvJSON ...*CpMvJSON) (pcsError pcserror
 	if errInfo != nil {
 		return errInfo
 	}
-
-	// 更新缓存
-	pcs.deleteCache((*CpMvJSONList)(unsafe.Pointer(&cpmvJSON)).AllRelatedDir())
 	return nil
 }
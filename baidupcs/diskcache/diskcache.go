@@ -0,0 +1,255 @@
+// Package diskcache 网盘元信息的本地持久化缓存,
+// 按网盘目录结构储存, 便于按目录失效
+package diskcache
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"github.com/json-iterator/go"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// entryPrefix 缓存项文件名前缀, 网盘路径编码后不会以此开头
+	entryPrefix = "_"
+	// entrySuffix 缓存项文件名后缀
+	entrySuffix = ".json"
+	// maxSegmentLen 编码后的路径分段超过此长度时, 使用 sha1 代替
+	maxSegmentLen = 200
+)
+
+type (
+	// Cache 本地持久化缓存
+	Cache struct {
+		dir string
+		ttl time.Duration
+		mu  sync.Mutex
+	}
+
+	// Stats 缓存统计信息
+	Stats struct {
+		Entries int   // 缓存项数量
+		Expired int   // 已过期的缓存项数量
+		Size    int64 // 占用空间
+	}
+
+	entryJSON struct {
+		Expires int64               `json:"expires"` // 过期时间, unix 时间戳
+		Data    jsoniter.RawMessage `json:"data"`
+	}
+)
+
+// New 在本地目录 dir 初始化缓存, 缓存项在 ttl 后过期
+func New(dir string, ttl time.Duration) *Cache {
+	return &Cache{
+		dir: dir,
+		ttl: ttl,
+	}
+}
+
+// Dir 返回缓存目录
+func (c *Cache) Dir() string {
+	return c.dir
+}
+
+// TTL 返回缓存有效期
+func (c *Cache) TTL() time.Duration {
+	return c.ttl
+}
+
+// encodeSegment 编码网盘路径中的一段, 保证在大小写不敏感的文件系统中也不冲突
+func encodeSegment(seg string) string {
+	const hexChars = "0123456789abcdef"
+	builder := strings.Builder{}
+	for i := 0; i < len(seg); i++ {
+		b := seg[i]
+		switch {
+		case 'a' <= b && b <= 'z', '0' <= b && b <= '9', b == '-', b == '.' && i > 0:
+			builder.WriteByte(b)
+		default:
+			builder.WriteByte('%')
+			builder.WriteByte(hexChars[b>>4])
+			builder.WriteByte(hexChars[b&15])
+		}
+	}
+	if builder.Len() > maxSegmentLen {
+		sum := sha1.Sum([]byte(seg))
+		return "%" + hex.EncodeToString(sum[:])
+	}
+	return builder.String()
+}
+
+// nodeDir 返回网盘路径 p 对应的本地目录
+func (c *Cache) nodeDir(p string) string {
+	p = path.Clean("/" + p)
+	elems := []string{c.dir, "tree"}
+	for _, seg := range strings.Split(p, "/") {
+		if seg == "" {
+			continue
+		}
+		elems = append(elems, encodeSegment(seg))
+	}
+	return filepath.Join(elems...)
+}
+
+func (c *Cache) entryPath(p, name string) string {
+	return filepath.Join(c.nodeDir(p), entryPrefix+name+entrySuffix)
+}
+
+// Get 获取网盘路径 p 下名为 name 的缓存项, 不存在或已过期则返回 false
+func (c *Cache) Get(p, name string) (data []byte, ok bool) {
+	if c == nil {
+		return nil, false
+	}
+
+	raw, err := ioutil.ReadFile(c.entryPath(p, name))
+	if err != nil {
+		return nil, false
+	}
+
+	entry := entryJSON{}
+	err = jsoniter.Unmarshal(raw, &entry)
+	if err != nil || time.Now().Unix() >= entry.Expires {
+		return nil, false
+	}
+	return entry.Data, true
+}
+
+// Set 设置网盘路径 p 下名为 name 的缓存项
+func (c *Cache) Set(p, name string, data []byte) error {
+	if c == nil || c.ttl <= 0 {
+		return nil
+	}
+
+	raw, err := jsoniter.Marshal(&entryJSON{
+		Expires: time.Now().Add(c.ttl).Unix(),
+		Data:    data,
+	})
+	if err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	dir := c.nodeDir(p)
+	err = os.MkdirAll(dir, 0700)
+	if err != nil {
+		return err
+	}
+
+	// 先写入临时文件再重命名, 避免其他进程读到不完整的数据
+	tmp, err := ioutil.TempFile(dir, ".tmp")
+	if err != nil {
+		return err
+	}
+	_, err = tmp.Write(raw)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	err = os.Rename(tmp.Name(), c.entryPath(p, name))
+	if err != nil {
+		os.Remove(tmp.Name())
+	}
+	return err
+}
+
+// Invalidate 使网盘路径 p 及其下所有路径的缓存失效,
+// 同时使 p 的各级父目录自身的缓存项失效 (不影响父目录下的其他路径)
+func (c *Cache) Invalidate(p string) error {
+	if c == nil {
+		return nil
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	err := os.RemoveAll(c.nodeDir(p))
+	if err != nil {
+		return err
+	}
+
+	p = path.Clean("/" + p)
+	for p != "/" {
+		p = path.Dir(p)
+		removeEntries(c.nodeDir(p))
+	}
+	return nil
+}
+
+// removeEntries 删除本地目录 dir 下的缓存项, 不包括子目录
+func removeEntries(dir string) {
+	names, err := readDirNames(dir)
+	if err != nil {
+		return
+	}
+	for _, name := range names {
+		if strings.HasPrefix(name, entryPrefix) {
+			os.Remove(filepath.Join(dir, name))
+		}
+	}
+}
+
+func readDirNames(dir string) ([]string, error) {
+	f, err := os.Open(dir)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return f.Readdirnames(-1)
+}
+
+// Clear 清空缓存
+func (c *Cache) Clear() error {
+	if c == nil {
+		return nil
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return os.RemoveAll(c.dir)
+}
+
+// Stats 统计缓存
+func (c *Cache) Stats() (stats Stats, err error) {
+	if c == nil {
+		return
+	}
+
+	now := time.Now().Unix()
+	err = filepath.Walk(c.dir, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		if info.IsDir() || !strings.HasPrefix(info.Name(), entryPrefix) {
+			return nil
+		}
+
+		stats.Entries++
+		stats.Size += info.Size()
+
+		raw, err := ioutil.ReadFile(p)
+		if err != nil {
+			return nil
+		}
+		entry := entryJSON{}
+		if jsoniter.Unmarshal(raw, &entry) != nil || now >= entry.Expires {
+			stats.Expired++
+		}
+		return nil
+	})
+	return
+}
@@ -0,0 +1,98 @@
+package diskcache
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestCache(t *testing.T) {
+	dir, err := ioutil.TempDir("", "diskcache")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	c := New(dir, time.Minute)
+	for _, p := range []string{"/", "/a", "/a/b", "/a/b/c", "/A", "/d"} {
+		err = c.Set(p, "meta", []byte(`"`+p+`"`))
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// 大小写不同的路径不冲突
+	data, ok := c.Get("/A", "meta")
+	if !ok || string(data) != `"/A"` {
+		t.Fatalf("unexpected data: %s", data)
+	}
+
+	// 使 /a/b 失效, 其父目录自身的缓存项也失效, 其他路径不受影响
+	err = c.Invalidate("/a/b")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for p, expected := range map[string]bool{"/": false, "/a": false, "/a/b": false, "/a/b/c": false, "/A": true, "/d": true} {
+		if _, ok = c.Get(p, "meta"); ok != expected {
+			t.Fatalf("%s: expect %v, got %v", p, expected, ok)
+		}
+	}
+
+	stats, err := c.Stats()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.Entries != 2 || stats.Expired != 0 {
+		t.Fatalf("unexpected stats: %+v", stats)
+	}
+
+	err = c.Clear()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok = c.Get("/d", "meta"); ok {
+		t.Fatal("expect cache cleared")
+	}
+}
+
+func TestCacheExpires(t *testing.T) {
+	dir, err := ioutil.TempDir("", "diskcache")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	c := New(dir, time.Nanosecond)
+	err = c.Set("/a", "meta", []byte(`1`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(time.Second)
+	if _, ok := c.Get("/a", "meta"); ok {
+		t.Fatal("expect expired")
+	}
+
+	stats, err := c.Stats()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.Entries != 1 || stats.Expired != 1 {
+		t.Fatalf("unexpected stats: %+v", stats)
+	}
+}
+
+func TestEncodeSegment(t *testing.T) {
+	for seg, expected := range map[string]string{
+		"abc":   "abc",
+		"Abc":   "%41bc",
+		"_meta": "%5fmeta",
+		".hide": "%2ehide",
+		"a.b":   "a.b",
+		"中":     "%e4%b8%ad",
+	} {
+		if got := encodeSegment(seg); got != expected {
+			t.Fatalf("%s: expect %s, got %s", seg, expected, got)
+		}
+	}
+}
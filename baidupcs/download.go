@@ -16,7 +16,7 @@ var (
 )
 
 type (
-	// DownloadFunc 下载文件处理函数
+	// DownloadFunc 下载文件处理函数, 应使用 BaiduPCS.Context 发起请求, 以便取消正在进行的下载
 	DownloadFunc func(downloadURL string, jar http.CookieJar) error
 
 	// URLInfo 下载链接详情
@@ -102,6 +102,10 @@ func (pcs *BaiduPCS) DownloadFile(path string, downloadFunc DownloadFunc) (err e
 	})
 	baiduPCSVerbose.Infof("%s URL: %s\n", OperationDownloadFile, pcsURL)
 
+	pcsError := pcs.beforeRequest(reqTypePCS, OperationDownloadFile)
+	if pcsError != nil {
+		return pcsError
+	}
 	return downloadFunc(pcsURL.String(), pcs.client.Jar)
 }
 
@@ -113,6 +117,10 @@ func (pcs *BaiduPCS) DownloadStreamFile(path string, downloadFunc DownloadFunc)
 	})
 	baiduPCSVerbose.Infof("%s URL: %s\n", OperationDownloadStreamFile, pcsURL)
 
+	pcsError := pcs.beforeRequest(reqTypePCS, OperationDownloadStreamFile)
+	if pcsError != nil {
+		return pcsError
+	}
 	return downloadFunc(pcsURL.String(), pcs.client.Jar)
 }
 
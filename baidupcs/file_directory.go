@@ -154,24 +154,9 @@ func (pcs *BaiduPCS) FilesDirectoriesBatchMeta(paths ...string) (data FileDirect
 
 // FilesDirectoriesList 获取目录下的文件和目录列表
 func (pcs *BaiduPCS) FilesDirectoriesList(path string, options *OrderOptions) (data FileDirectoryList, pcsError pcserror.Error) {
-	dataReadCloser, pcsError := pcs.PrepareFilesDirectoriesList(path, options)
-	if pcsError != nil {
-		return nil, pcsError
-	}
-
-	defer dataReadCloser.Close()
-
-	jsonData := fdData{
-		PCSErrInfo: pcserror.NewPCSErrorInfo(OperationFilesDirectoriesList),
-	}
-
-	pcsError = pcserror.HandleJSONParse(OperationFilesDirectoriesList, dataReadCloser, (*fdDataJSONExport)(unsafe.Pointer(&jsonData)))
-	if pcsError != nil {
-		return nil, pcsError
-	}
-
-	data = jsonData.List
-	return
+	return pcs.ListIter(path, &ListIterOptions{
+		OrderOptions: options,
+	}).All()
 }
 
 // Search 按文件名搜索文件, 不支持查找目录
@@ -1,6 +1,7 @@
 package panhome
 
 import (
+	"context"
 	"github.com/Erope/BaiduPCS-Go/baidupcs/expires"
 	"time"
 )
@@ -12,11 +13,11 @@ func (ph *PanHome) SetSignExpires() {
 	}
 }
 
-// CacheSignature 在有效期内返回缓存结果
-func (ph *PanHome) CacheSignature() (sign SignRes, err error) {
+// CacheSignature 在有效期内返回缓存结果, 否则使用 ctx 请求网盘首页重新签名
+func (ph *PanHome) CacheSignature(ctx context.Context) (sign SignRes, err error) {
 	if ph.signExpires == nil || ph.signExpires.IsExpires() {
 		// 先签名再设置有效期
-		ph.signRes, err = ph.Signature()
+		ph.signRes, err = ph.Signature(ctx)
 		if err != nil { // 空指针与空接口不等价
 			return nil, err
 		}
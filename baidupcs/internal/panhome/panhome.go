@@ -1,6 +1,7 @@
 package panhome
 
 import (
+	"context"
 	"github.com/Erope/BaiduPCS-Go/baidupcs/expires"
 	"github.com/Erope/BaiduPCS-Go/requester"
 	"net/url"
@@ -23,6 +24,7 @@ var (
 type (
 	PanHome struct {
 		client *requester.HTTPClient
+		panURL *url.URL
 		ua     string
 		bduss  string
 
@@ -31,6 +33,8 @@ type (
 
 		signRes     SignRes
 		signExpires expires.Expires
+
+		beforeRequest func(ctx context.Context) error // 请求网盘首页前调用, 如等待请求限流
 	}
 )
 
@@ -43,6 +47,16 @@ func NewPanHome(client *requester.HTTPClient) *PanHome {
 	return &ph
 }
 
+// SetPanURL 设置网盘首页地址, 为空则使用默认地址
+func (ph *PanHome) SetPanURL(u *url.URL) {
+	ph.panURL = u
+}
+
+// SetBeforeRequest 设置请求网盘首页前调用的函数, 返回错误则不发起请求
+func (ph *PanHome) SetBeforeRequest(f func(ctx context.Context) error) {
+	ph.beforeRequest = f
+}
+
 func (ph *PanHome) lazyInit() {
 	if ph.client == nil {
 		ph.client = requester.NewHTTPClient()
@@ -1,6 +1,7 @@
 package panhome
 
 import (
+	"context"
 	"github.com/Erope/Baidu-Login/bdcrypto"
 	"github.com/Erope/BaiduPCS-Go/pcsutil/converter"
 )
@@ -60,8 +61,8 @@ func Sign2(j, r []rune) []byte {
 	return o
 }
 
-func (ph *PanHome) Signature() (sign SignRes, err error) {
-	err = ph.getSignInfo()
+func (ph *PanHome) Signature(ctx context.Context) (sign SignRes, err error) {
+	err = ph.getSignInfo(ctx)
 	if err != nil {
 		return nil, err
 	}
@@ -35,6 +35,9 @@ type (
 	CpMvJSON struct {
 		From string `json:"from"` // 源文件或目录
 		To   string `json:"to"`   // 目标文件或目录
+		// OnDup 目标已存在时的处理方式, 服务器只支持整个请求的 ondup 参数, 不随各项发送.
+		// BatchCopy 和 BatchMove 按处理方式分组发送请求, PrepareCopy 和 PrepareMove 使用第一项的处理方式
+		OnDup OnDup `json:"-"`
 	}
 
 	// CpMvJSONList CpMvJSON 列表
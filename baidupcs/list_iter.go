@@ -0,0 +1,162 @@
+package baidupcs
+
+import (
+	"github.com/Erope/BaiduPCS-Go/baidupcs/pcserror"
+	"unsafe"
+)
+
+const (
+	// DefaultListPageSize 分页获取目录列表时, 默认每页的数量
+	DefaultListPageSize = 1000
+)
+
+type (
+	// ListPager 分页获取目录列表, 用于构造 ListIter
+	ListPager interface {
+		FilesDirectoriesListPage(path string, options *OrderOptions, start, limit int) (data FileDirectoryList, pcsError pcserror.Error)
+	}
+
+	// ListIterOptions 分页获取目录列表的可选项
+	ListIterOptions struct {
+		OrderOptions *OrderOptions // 排序, 为空则使用 DefaultOrderOptions
+		PageSize     int           // 每页的数量, 小于等于0则使用 DefaultListPageSize
+	}
+
+	// ListIter 目录列表迭代器, 按需分页获取目录下的文件和目录
+	//
+	//	iter := pcs.ListIter("/", nil)
+	//	for iter.Next() {
+	//		fd := iter.Value()
+	//	}
+	//	if iter.Err() != nil {
+	//		...
+	//	}
+	ListIter struct {
+		pager   ListPager
+		path    string
+		options *OrderOptions
+		size    int
+
+		page  FileDirectoryList
+		index int
+		start int
+		done  bool
+		value *FileDirectory
+		err   pcserror.Error
+	}
+)
+
+// ListIter 返回目录 path 的列表迭代器
+func (pcs *BaiduPCS) ListIter(path string, opts *ListIterOptions) *ListIter {
+	return NewListIter(pcs, path, opts)
+}
+
+// NewListIter 返回目录 path 的列表迭代器, 由 pager 分页获取目录列表
+func NewListIter(pager ListPager, path string, opts *ListIterOptions) *ListIter {
+	if opts == nil {
+		opts = &ListIterOptions{}
+	}
+	iter := &ListIter{
+		pager:   pager,
+		path:    path,
+		options: opts.OrderOptions,
+		size:    opts.PageSize,
+	}
+	if iter.options == nil {
+		iter.options = DefaultOrderOptions
+	}
+	if iter.size <= 0 {
+		iter.size = DefaultListPageSize
+	}
+	return iter
+}
+
+// Next 移动到下一项, 没有更多的项或出错时返回 false
+func (iter *ListIter) Next() bool {
+	if iter.index >= len(iter.page) && !iter.fetch() {
+		iter.value = nil
+		return false
+	}
+
+	iter.value = iter.page[iter.index]
+	iter.index++
+	return true
+}
+
+// NextPage 移动到下一页, 丢弃当前页中未读取的项, 没有更多的页或出错时返回 false
+func (iter *ListIter) NextPage() bool {
+	iter.value = nil
+	return iter.fetch()
+}
+
+// Page 返回 NextPage 获取的当前页
+func (iter *ListIter) Page() FileDirectoryList {
+	return iter.page
+}
+
+// fetch 获取下一页.
+// 返回的项数少于每页的数量时, 表示已是最后一页, 不再请求下一页
+func (iter *ListIter) fetch() bool {
+	if iter.err != nil || iter.done {
+		return false
+	}
+
+	iter.page, iter.err = iter.pager.FilesDirectoriesListPage(iter.path, iter.options, iter.start, iter.size)
+	iter.index = 0
+	if iter.err != nil {
+		iter.page = nil
+		return false
+	}
+	if len(iter.page) == 0 {
+		iter.done = true
+		return false
+	}
+	if len(iter.page) < iter.size {
+		iter.done = true
+	}
+	iter.start += len(iter.page)
+	return true
+}
+
+// Value 返回当前项
+func (iter *ListIter) Value() *FileDirectory {
+	return iter.value
+}
+
+// Err 返回迭代过程中的错误
+func (iter *ListIter) Err() pcserror.Error {
+	return iter.err
+}
+
+// All 获取剩余的所有项
+func (iter *ListIter) All() (fdl FileDirectoryList, pcsError pcserror.Error) {
+	fdl = FileDirectoryList{}
+	for iter.Next() {
+		fdl = append(fdl, iter.Value())
+	}
+	if iter.Err() != nil {
+		return nil, iter.Err()
+	}
+	return fdl, nil
+}
+
+// FilesDirectoriesListPage 分页获取目录下的文件和目录列表, 从第 start 项开始, 最多获取 limit 项
+func (pcs *BaiduPCS) FilesDirectoriesListPage(path string, options *OrderOptions, start, limit int) (data FileDirectoryList, pcsError pcserror.Error) {
+	dataReadCloser, pcsError := pcs.PrepareFilesDirectoriesListPage(path, options, start, limit)
+	if pcsError != nil {
+		return nil, pcsError
+	}
+
+	defer dataReadCloser.Close()
+
+	jsonData := fdData{
+		PCSErrInfo: pcserror.NewPCSErrorInfo(OperationFilesDirectoriesList),
+	}
+
+	pcsError = pcserror.HandleJSONParse(OperationFilesDirectoriesList, dataReadCloser, (*fdDataJSONExport)(unsafe.Pointer(&jsonData)))
+	if pcsError != nil {
+		return nil, pcsError
+	}
+
+	return jsonData.List, nil
+}
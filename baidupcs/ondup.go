@@ -0,0 +1,160 @@
+package baidupcs
+
+import (
+	"errors"
+	"fmt"
+	"github.com/Erope/BaiduPCS-Go/baidupcs/pcserror"
+	"path"
+	"strings"
+)
+
+const (
+	// OnDupDefault 使用各操作原有的默认处理方式
+	OnDupDefault OnDup = ""
+	// OnDupOverwrite 覆盖同名文件
+	OnDupOverwrite OnDup = "overwrite"
+	// OnDupSkip 跳过同名文件, 由客户端检查
+	OnDupSkip OnDup = "skip"
+	// OnDupNewCopy 生成文件副本并进行重命名, 命名规则为 "文件名_日期.后缀"
+	OnDupNewCopy OnDup = "newcopy"
+	// OnDupFail 同名文件存在时操作失败
+	OnDupFail OnDup = "fail"
+)
+
+var (
+	// ErrOnDupSkipped 目标已存在, 按 OnDupSkip 跳过
+	ErrOnDupSkipped = errors.New("目标已存在, 跳过")
+	// ErrOnDupUnknown 未知的同名文件处理方式
+	ErrOnDupUnknown = errors.New("未知的同名文件处理方式, 可选: overwrite, skip, newcopy, fail")
+)
+
+type (
+	// OnDup 目标路径存在同名文件时的处理方式
+	OnDup string
+)
+
+// ParseOnDup 解析同名文件处理方式, 空字符串返回 OnDupDefault
+func ParseOnDup(s string) (OnDup, error) {
+	switch ondup := OnDup(strings.ToLower(strings.TrimSpace(s))); ondup {
+	case OnDupDefault, OnDupOverwrite, OnDupSkip, OnDupNewCopy, OnDupFail:
+		return ondup, nil
+	}
+	return OnDupDefault, fmt.Errorf("%s: %s", ErrOnDupUnknown, s)
+}
+
+// pcsParam 返回 PCS api 的 ondup 参数, def 为 OnDupDefault 时使用的值.
+// OnDupSkip 由客户端检查, 请求时按 OnDupFail 处理; OnDupFail 即服务器的默认处理方式, 不设置参数.
+func (ondup OnDup) pcsParam(def OnDup) string {
+	if ondup == OnDupDefault {
+		ondup = def
+	}
+	switch ondup {
+	case OnDupOverwrite, OnDupNewCopy:
+		return string(ondup)
+	}
+	return ""
+}
+
+// setOnDupParam 设置请求参数 ondup, def 为 ondup 为 OnDupDefault 时使用的值
+func setOnDupParam(param map[string]string, ondup, def OnDup) {
+	if v := ondup.pcsParam(def); v != "" {
+		param["ondup"] = v
+	}
+}
+
+// precreateRType 返回网盘首页 api precreate 的 rtype 参数:
+// 0 不重命名, 返回冲突; 1 重命名; 2 block_list 不同时重命名; 3 覆盖
+func (ondup OnDup) precreateRType() string {
+	switch ondup {
+	case OnDupOverwrite:
+		return "3"
+	case OnDupNewCopy:
+		return "1"
+	case OnDupSkip, OnDupFail:
+		return "0"
+	}
+	return "2"
+}
+
+// IsOnDupSkipped 判断错误是否为按 OnDupSkip 跳过
+func IsOnDupSkipped(pcsError pcserror.Error) bool {
+	return pcsError != nil && errors.Is(pcsError, ErrOnDupSkipped)
+}
+
+func newOnDupSkippedError(op string) pcserror.Error {
+	return &pcserror.PCSErrInfo{
+		Operation: op,
+		ErrType:   pcserror.ErrTypeOthers,
+		Err:       ErrOnDupSkipped,
+	}
+}
+
+// checkOnDupSkip ondup 为 OnDupSkip 且目标路径已存在时, 返回 ErrOnDupSkipped
+func (pcs *BaiduPCS) checkOnDupSkip(op, targetPath string, ondup OnDup) pcserror.Error {
+	if ondup != OnDupSkip {
+		return nil
+	}
+
+	_, pcsError := pcs.FilesDirectoriesMeta(targetPath)
+	if pcsError == nil {
+		return newOnDupSkippedError(op)
+	}
+	// 目标路径不存在
+	if pcsError.GetErrType() == pcserror.ErrTypeRemoteError {
+		return nil
+	}
+	return pcsError
+}
+
+// targetState 操作前目标路径是否已存在
+type targetState struct {
+	dirNames map[string]map[string]bool // 目录 -> 目录下的文件名和目录名, 获取列表失败的目录为 nil
+}
+
+// listTargets 按目标目录获取列表, 记录操作前 targets 是否已存在
+func (pcs *BaiduPCS) listTargets(targets []string) *targetState {
+	ts := &targetState{
+		dirNames: map[string]map[string]bool{},
+	}
+	for _, target := range targets {
+		dir := path.Dir(path.Clean(target))
+		if _, ok := ts.dirNames[dir]; ok {
+			continue
+		}
+
+		fdl, pcsError := pcs.ListIter(dir, nil).All()
+		if pcsError != nil && pcsError.GetErrType() != pcserror.ErrTypeRemoteError {
+			baiduPCSVerbose.Warnf("%s, 无法检查目标是否存在: %s\n", pcsError, dir)
+			ts.dirNames[dir] = nil
+			continue
+		}
+		names := map[string]bool{}
+		for _, fd := range fdl {
+			names[fd.Filename] = true
+		}
+		ts.dirNames[dir] = names
+	}
+	return ts
+}
+
+// exists 返回操作前 target 是否已存在, 未获取或获取列表失败时 known 为 false
+func (ts *targetState) exists(target string) (exists, known bool) {
+	dir, name := path.Split(path.Clean(target))
+	names := ts.dirNames[path.Clean(dir)]
+	if names == nil {
+		return false, false
+	}
+	return names[name], true
+}
+
+// skipExistingCpMv 将 OnDup 为 OnDupSkip 且目标已存在的项标记为跳过, 无法检查的项交由服务器按 OnDupFail 处理
+func skipExistingCpMv(results BatchResultList, ts *targetState) {
+	for _, br := range results {
+		if br.cpmv.OnDup != OnDupSkip {
+			continue
+		}
+		if exists, _ := ts.exists(br.To); exists {
+			br.Status = BatchStatusSkipped
+		}
+	}
+}
@@ -0,0 +1,724 @@
+package pcsemu
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/json-iterator/go"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// OnDupOverwrite 覆盖同名文件
+	OnDupOverwrite = "overwrite"
+	// OnDupNewCopy 生成文件副本并重命名
+	OnDupNewCopy = "newcopy"
+	// OnDupFail 同名文件存在时失败
+	OnDupFail = "fail"
+
+	errCodeFileExists  = 31061
+	errCodeMD5NotFound = 31079
+	errCodeNotAFile    = 31074
+)
+
+var (
+	errFileExists = errors.New("file already exists")
+)
+
+type (
+	pathListJSON struct {
+		List []struct {
+			Path string `json:"path"`
+		} `json:"list"`
+	}
+
+	cpmvListJSON struct {
+		List []*cpmvJSON `json:"list"`
+	}
+
+	cpmvJSON struct {
+		From string `json:"from"`
+		To   string `json:"to"`
+	}
+
+	fsIDListJSON struct {
+		List []*fsIDJSON `json:"list"`
+	}
+
+	fsIDJSON struct {
+		FsID int64 `json:"fs_id"`
+	}
+
+	blockListJSON struct {
+		BlockList []string `json:"block_list"`
+	}
+)
+
+func (s *Server) handleQuota(w http.ResponseWriter, r *http.Request) {
+	var used int64
+	filepath.Walk(s.root, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return nil
+		}
+		if info.IsDir() {
+			if p == s.dataDir {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		used += info.Size()
+		return nil
+	})
+
+	s.writeJSON(w, http.StatusOK, map[string]interface{}{
+		"quota":      s.quota,
+		"used":       used,
+		"request_id": s.nextRequestID(),
+	})
+}
+
+func (s *Server) handlePCSFile(w http.ResponseWriter, r *http.Request) {
+	switch r.URL.Query().Get("method") {
+	case "list":
+		s.handleList(w, r)
+	case "meta":
+		s.handleMeta(w, r)
+	case "search":
+		s.handleSearch(w, r)
+	case "delete":
+		if r.URL.Query().Get("type") == "recycle" {
+			s.handleRecycleClear(w, r)
+			return
+		}
+		s.handleDelete(w, r)
+	case "mkdir":
+		s.handleMkdir(w, r)
+	case "copy", "move":
+		s.handleCopyMove(w, r)
+	case "rapidupload":
+		s.handleRapidUpload(w, r)
+	case "upload":
+		s.handleUpload(w, r)
+	case "createsuperfile":
+		s.handleCreateSuperFile(w, r)
+	case "locatedownload":
+		s.handleLocateDownload(w, r)
+	case "download":
+		s.handleDownload(w, r)
+	case "restore":
+		s.handleRecycleRestore(w, r)
+	default:
+		s.pcsError(w, http.StatusBadRequest, 3, "Unsupported openapi method")
+	}
+}
+
+// parseLimit 解析 limit 参数, 格式为 n1-n2, 返回 [n1, n2)
+func parseLimit(limit string, total int) (start, end int) {
+	start, end = 0, total
+	if limit == "" {
+		return
+	}
+
+	ss := strings.SplitN(limit, "-", 2)
+	if len(ss) != 2 {
+		return
+	}
+	n1, err1 := strconv.Atoi(ss[0])
+	n2, err2 := strconv.Atoi(ss[1])
+	if err1 != nil || err2 != nil || n1 < 0 || n2 < n1 {
+		return
+	}
+	if n1 > total {
+		n1 = total
+	}
+	if n2 > total {
+		n2 = total
+	}
+	return n1, n2
+}
+
+func (s *Server) handleList(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+
+	s.mu.Lock()
+	list, err := s.list(q.Get("path"), q.Get("by"), q.Get("order"))
+	s.mu.Unlock()
+	if err != nil {
+		s.pcsErrorNotFound(w)
+		return
+	}
+
+	start, end := parseLimit(q.Get("limit"), len(list))
+	s.writeJSON(w, http.StatusOK, map[string]interface{}{
+		"list":       list[start:end],
+		"request_id": s.nextRequestID(),
+	})
+}
+
+func (s *Server) handleMeta(w http.ResponseWriter, r *http.Request) {
+	var pl pathListJSON
+	err := jsoniter.UnmarshalFromString(r.FormValue("param"), &pl)
+	if err != nil || len(pl.List) == 0 {
+		s.pcsErrorParam(w)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	list := make([]*entryJSON, 0, len(pl.List))
+	for _, p := range pl.List {
+		e, err := s.entry(p.Path, true)
+		if err != nil {
+			s.pcsErrorNotFound(w)
+			return
+		}
+		list = append(list, e)
+	}
+
+	s.writeJSON(w, http.StatusOK, map[string]interface{}{
+		"list":       list,
+		"request_id": s.nextRequestID(),
+	})
+}
+
+func (s *Server) handleSearch(w http.ResponseWriter, r *http.Request) {
+	var (
+		q         = r.URL.Query()
+		wd        = strings.ToLower(q.Get("wd"))
+		recursive = q.Get("re") == "1"
+		list      = make([]*entryJSON, 0)
+	)
+	if wd == "" {
+		s.pcsErrorParam(w)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var search func(dir string) error
+	search = func(dir string) error {
+		fdl, err := s.list(dir, "name", "asc")
+		if err != nil {
+			return err
+		}
+		for _, e := range fdl {
+			if e.Isdir == 1 {
+				if recursive {
+					search(e.Path)
+				}
+				continue
+			}
+			if strings.Contains(strings.ToLower(e.ServerFilename), wd) {
+				list = append(list, e)
+			}
+		}
+		return nil
+	}
+
+	err := search(q.Get("path"))
+	if err != nil {
+		s.pcsErrorNotFound(w)
+		return
+	}
+
+	s.writeJSON(w, http.StatusOK, map[string]interface{}{
+		"list":       list,
+		"request_id": s.nextRequestID(),
+	})
+}
+
+func (s *Server) handleDelete(w http.ResponseWriter, r *http.Request) {
+	var pl pathListJSON
+	err := jsoniter.UnmarshalFromString(r.FormValue("param"), &pl)
+	if err != nil || len(pl.List) == 0 {
+		s.pcsErrorParam(w)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	check := func(pcspath string) bool {
+		lp, err := s.localPath(pcspath)
+		return err == nil && cleanPath(pcspath) != "/" && exists(lp)
+	}
+
+	// 先检查全部路径, 任意一个不存在则全部失败
+	if !s.partialBatch {
+		for _, p := range pl.List {
+			if !check(p.Path) {
+				s.pcsErrorNotFound(w)
+				return
+			}
+		}
+	}
+
+	for _, p := range pl.List {
+		if !check(p.Path) {
+			s.pcsErrorNotFound(w)
+			return
+		}
+		err = s.moveToRecycle(cleanPath(p.Path))
+		if err != nil {
+			s.pcsErrorInternal(w, err)
+			return
+		}
+	}
+
+	s.writeJSON(w, http.StatusOK, map[string]interface{}{
+		"request_id": s.nextRequestID(),
+	})
+}
+
+func (s *Server) handleMkdir(w http.ResponseWriter, r *http.Request) {
+	pcspath := cleanPath(r.URL.Query().Get("path"))
+	lp, err := s.localPath(pcspath)
+	if err != nil {
+		s.pcsErrorParam(w)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if exists(lp) {
+		s.pcsError(w, http.StatusBadRequest, errCodeFileExists, "file already exists")
+		return
+	}
+
+	err = os.MkdirAll(lp, 0777)
+	if err != nil {
+		s.pcsErrorInternal(w, err)
+		return
+	}
+
+	e, err := s.entry(pcspath, false)
+	if err != nil {
+		s.pcsErrorInternal(w, err)
+		return
+	}
+	s.writeJSON(w, http.StatusOK, e)
+}
+
+func (s *Server) handleCopyMove(w http.ResponseWriter, r *http.Request) {
+	var (
+		isMove = r.URL.Query().Get("method") == "move"
+		ondup  = r.URL.Query().Get("ondup")
+		cl     cpmvListJSON
+	)
+	err := jsoniter.UnmarshalFromString(r.FormValue("param"), &cl)
+	if err != nil || len(cl.List) == 0 {
+		s.pcsErrorParam(w)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// check 检查一项, 出错时写入错误并返回 false
+	check := func(cm *cpmvJSON) bool {
+		from, err1 := s.localPath(cm.From)
+		to, err2 := s.localPath(cm.To)
+		if err1 != nil || err2 != nil || cm.From == "/" || cm.From == cm.To || strings.HasPrefix(cm.To, cm.From+"/") {
+			s.pcsErrorParam(w)
+			return false
+		}
+		if !exists(from) {
+			s.pcsErrorNotFound(w)
+			return false
+		}
+		if exists(to) && ondup != OnDupOverwrite && ondup != OnDupNewCopy {
+			s.pcsError(w, http.StatusBadRequest, errCodeFileExists, "file already exists")
+			return false
+		}
+		return true
+	}
+
+	for _, cm := range cl.List {
+		if cm == nil {
+			s.pcsErrorParam(w)
+			return
+		}
+		cm.From, cm.To = cleanPath(cm.From), cleanPath(cm.To)
+	}
+
+	// 先检查全部路径
+	if !s.partialBatch {
+		for _, cm := range cl.List {
+			if !check(cm) {
+				return
+			}
+		}
+	}
+
+	extra := make([]*cpmvJSON, 0, len(cl.List))
+	for _, cm := range cl.List {
+		if s.partialBatch && !check(cm) {
+			return
+		}
+		from, _ := s.localPath(cm.From)
+		to, err := s.resolveOnDup(cm.To, ondup)
+		if err != nil {
+			s.pcsError(w, http.StatusBadRequest, errCodeFileExists, "file already exists")
+			return
+		}
+		lpTo, _ := s.localPath(to)
+
+		err = os.MkdirAll(filepath.Dir(lpTo), 0777)
+		if err == nil {
+			if isMove {
+				err = os.Rename(from, lpTo)
+				if err == nil {
+					s.renameFsID(cm.From, to)
+				}
+			} else {
+				err = copyFile(from, lpTo)
+			}
+		}
+		if err != nil {
+			s.pcsErrorInternal(w, err)
+			return
+		}
+		extra = append(extra, &cpmvJSON{
+			From: cm.From,
+			To:   to,
+		})
+	}
+
+	s.writeJSON(w, http.StatusOK, map[string]interface{}{
+		"extra": map[string]interface{}{
+			"list": extra,
+		},
+		"request_id": s.nextRequestID(),
+	})
+}
+
+// resolveOnDup 根据 ondup 处理同名文件, 返回最终保存的网盘路径, 调用者需持有锁
+func (s *Server) resolveOnDup(pcspath, ondup string) (string, error) {
+	pcspath = cleanPath(pcspath)
+	lp, err := s.localPath(pcspath)
+	if err != nil {
+		return "", err
+	}
+
+	info, err := os.Stat(lp)
+	if err != nil {
+		return pcspath, nil
+	}
+
+	switch ondup {
+	case OnDupOverwrite:
+		if info.IsDir() {
+			return "", errFileExists
+		}
+		err = os.Remove(lp)
+		if err != nil {
+			return "", err
+		}
+		s.forgetFsID(pcspath)
+		return pcspath, nil
+	case OnDupNewCopy:
+		var (
+			ext  = path.Ext(pcspath)
+			base = strings.TrimSuffix(pcspath, ext) + "_" + time.Now().Format("20060102_150405")
+		)
+		newPath := base + ext
+		for i := 1; ; i++ {
+			lp, _ = s.localPath(newPath)
+			if !exists(lp) {
+				return newPath, nil
+			}
+			newPath = base + "_" + strconv.Itoa(i) + ext
+		}
+	default:
+		return "", errFileExists
+	}
+}
+
+// saveFile 将 r 的内容保存到网盘路径, 返回元信息, 调用者需持有锁
+func (s *Server) saveFile(pcspath, ondup string, r io.Reader) (*entryJSON, error) {
+	pcspath, err := s.resolveOnDup(pcspath, ondup)
+	if err != nil {
+		return nil, err
+	}
+
+	lp, err := s.localPath(pcspath)
+	if err != nil {
+		return nil, err
+	}
+	err = os.MkdirAll(filepath.Dir(lp), 0777)
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := ioutil.TempFile(filepath.Dir(lp), "."+filepath.Base(lp)+".tmp")
+	if err != nil {
+		return nil, err
+	}
+	_, err = io.Copy(f, r)
+	if err == nil {
+		err = f.Close()
+	} else {
+		f.Close()
+	}
+	if err != nil {
+		os.Remove(f.Name())
+		return nil, err
+	}
+
+	err = os.Rename(f.Name(), lp)
+	if err != nil {
+		os.Remove(f.Name())
+		return nil, err
+	}
+	return s.entry(pcspath, true)
+}
+
+func (s *Server) writeSaveError(w http.ResponseWriter, err error) {
+	switch err {
+	case errFileExists:
+		s.pcsError(w, http.StatusBadRequest, errCodeFileExists, "file already exists")
+	case ErrInvalidPath:
+		s.pcsErrorParam(w)
+	default:
+		s.pcsErrorInternal(w, err)
+	}
+}
+
+func (s *Server) handleRapidUpload(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	length, err := strconv.ParseInt(q.Get("content-length"), 10, 64)
+	if err != nil || q.Get("path") == "" {
+		s.pcsErrorParam(w)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	src, ok := s.findByMD5(length, q.Get("content-md5"), q.Get("slice-md5"))
+	if !ok {
+		s.pcsError(w, http.StatusNotFound, errCodeMD5NotFound, "file md5 not found, you should use upload API to upload the whole file.")
+		return
+	}
+
+	f, err := os.Open(src)
+	if err != nil {
+		s.pcsErrorInternal(w, err)
+		return
+	}
+	defer f.Close()
+
+	e, err := s.saveFile(q.Get("path"), q.Get("ondup"), f)
+	if err != nil {
+		s.writeSaveError(w, err)
+		return
+	}
+	s.writeJSON(w, http.StatusOK, e)
+}
+
+// formFile 以流的方式读取表单中的第一个文件,
+// 客户端上传时 filename 可能为空, 故不使用 r.FormFile
+func formFile(r *http.Request) (io.ReadCloser, error) {
+	mr, err := r.MultipartReader()
+	if err != nil {
+		return nil, err
+	}
+
+	part, err := mr.NextPart()
+	if err != nil {
+		if err == io.EOF {
+			return nil, http.ErrMissingFile
+		}
+		return nil, err
+	}
+	return part, nil
+}
+
+// saveBlock 保存分片, 返回分片的 md5
+func (s *Server) saveBlock(r io.Reader) (string, error) {
+	f, err := ioutil.TempFile(s.blocksDir(), "block")
+	if err != nil {
+		return "", err
+	}
+
+	m := md5.New()
+	_, err = io.Copy(io.MultiWriter(f, m), r)
+	if err == nil {
+		err = f.Close()
+	} else {
+		f.Close()
+	}
+	if err != nil {
+		os.Remove(f.Name())
+		return "", err
+	}
+
+	sum := hex.EncodeToString(m.Sum(nil))
+	err = os.Rename(f.Name(), filepath.Join(s.blocksDir(), sum))
+	if err != nil {
+		os.Remove(f.Name())
+		return "", err
+	}
+	return sum, nil
+}
+
+func (s *Server) blocksDir() string {
+	return filepath.Join(s.dataDir, "blocks")
+}
+
+func (s *Server) handleUpload(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	f, err := formFile(r)
+	if err != nil {
+		s.pcsErrorParam(w)
+		return
+	}
+	defer f.Close()
+
+	if q.Get("type") == "tmpfile" {
+		sum, err := s.saveBlock(f)
+		if err != nil {
+			s.pcsErrorInternal(w, err)
+			return
+		}
+		s.writeJSON(w, http.StatusOK, map[string]interface{}{
+			"md5":        sum,
+			"request_id": s.nextRequestID(),
+		})
+		return
+	}
+
+	if q.Get("path") == "" {
+		s.pcsErrorParam(w)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	e, err := s.saveFile(q.Get("path"), q.Get("ondup"), f)
+	if err != nil {
+		s.writeSaveError(w, err)
+		return
+	}
+	s.writeJSON(w, http.StatusOK, e)
+}
+
+func (s *Server) handleCreateSuperFile(w http.ResponseWriter, r *http.Request) {
+	var (
+		q  = r.URL.Query()
+		bl blockListJSON
+	)
+	err := jsoniter.UnmarshalFromString(r.FormValue("param"), &bl)
+	if err != nil || len(bl.BlockList) == 0 || q.Get("path") == "" {
+		s.pcsErrorParam(w)
+		return
+	}
+
+	readers := make([]io.Reader, 0, len(bl.BlockList))
+	for _, sum := range bl.BlockList {
+		if sum != strings.ToLower(filepath.Base(sum)) {
+			s.pcsErrorParam(w)
+			return
+		}
+		f, err := os.Open(filepath.Join(s.blocksDir(), sum))
+		if err != nil {
+			s.pcsError(w, http.StatusBadRequest, 31363, "block miss in superfile2")
+			return
+		}
+		defer f.Close()
+		readers = append(readers, f)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	e, err := s.saveFile(q.Get("path"), q.Get("ondup"), io.MultiReader(readers...))
+	if err != nil {
+		s.writeSaveError(w, err)
+		return
+	}
+	s.writeJSON(w, http.StatusOK, e)
+}
+
+// downloadURL 返回网盘文件的下载链接
+func downloadURL(r *http.Request, pcspath string) string {
+	u := url.URL{
+		Scheme: "http",
+		Host:   r.Host,
+		Path:   "/rest/2.0/pcs/file",
+		RawQuery: (url.Values{
+			"method": []string{"download"},
+			"path":   []string{pcspath},
+		}).Encode(),
+	}
+	if r.TLS != nil {
+		u.Scheme = "https"
+	}
+	return u.String()
+}
+
+func (s *Server) handleLocateDownload(w http.ResponseWriter, r *http.Request) {
+	pcspath := cleanPath(r.URL.Query().Get("path"))
+	lp, err := s.localPath(pcspath)
+	if err != nil {
+		s.pcsErrorParam(w)
+		return
+	}
+
+	info, err := os.Stat(lp)
+	if err != nil {
+		s.pcsErrorNotFound(w)
+		return
+	}
+	if info.IsDir() {
+		s.pcsError(w, http.StatusBadRequest, errCodeNotAFile, "not a file")
+		return
+	}
+
+	s.writeJSON(w, http.StatusOK, map[string]interface{}{
+		"urls": []map[string]string{
+			{"url": downloadURL(r, pcspath)},
+		},
+		"request_id": s.nextRequestID(),
+	})
+}
+
+func (s *Server) handleDownload(w http.ResponseWriter, r *http.Request) {
+	lp, err := s.localPath(r.URL.Query().Get("path"))
+	if err != nil {
+		s.pcsErrorParam(w)
+		return
+	}
+
+	f, err := os.Open(lp)
+	if err != nil {
+		s.pcsErrorNotFound(w)
+		return
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil || info.IsDir() {
+		s.pcsErrorNotFound(w)
+		return
+	}
+
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment;filename=\"%s\"", url.PathEscape(info.Name())))
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Header().Set("x-bs-file-size", strconv.FormatInt(info.Size(), 10)) // 客户端据此获取文件大小
+	http.ServeContent(w, r, info.Name(), info.ModTime(), f)
+}
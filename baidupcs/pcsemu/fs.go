@@ -0,0 +1,367 @@
+package pcsemu
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"errors"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	sliceMD5Size = 256 * 1024
+)
+
+var (
+	// ErrInvalidPath 非法的网盘路径
+	ErrInvalidPath = errors.New("invalid path")
+)
+
+type (
+	// entryJSON 文件或目录的元信息, 与 PCS api 返回的格式一致
+	entryJSON struct {
+		FsID           int64    `json:"fs_id"`
+		AppID          int64    `json:"app_id"`
+		Path           string   `json:"path"`
+		ServerFilename string   `json:"server_filename"`
+		Ctime          int64    `json:"ctime"`
+		Mtime          int64    `json:"mtime"`
+		ServerCtime    int64    `json:"server_ctime"`
+		ServerMtime    int64    `json:"server_mtime"`
+		MD5            string   `json:"md5,omitempty"`
+		BlockList      []string `json:"block_list,omitempty"`
+		Size           int64    `json:"size"`
+		Isdir          int      `json:"isdir"`
+		Ifhassubdir    int      `json:"ifhassubdir"`
+	}
+
+	md5CacheItem struct {
+		size     int64
+		mtime    time.Time
+		md5      string
+		sliceMD5 string
+	}
+)
+
+// cleanPath 规范化网盘路径
+func cleanPath(pcspath string) string {
+	return path.Clean("/" + pcspath)
+}
+
+// localPath 网盘路径转换为本地路径, 禁止访问模拟器数据目录
+func (s *Server) localPath(pcspath string) (string, error) {
+	pcspath = cleanPath(pcspath)
+	if pcspath == "/"+DataDirName || strings.HasPrefix(pcspath, "/"+DataDirName+"/") {
+		return "", ErrInvalidPath
+	}
+	return filepath.Join(s.root, filepath.FromSlash(pcspath)), nil
+}
+
+// fsID 获取网盘路径对应的 fs_id, 不存在则分配, 调用者需持有锁
+func (s *Server) fsID(pcspath string) int64 {
+	id, ok := s.fsIDs[pcspath]
+	if ok {
+		return id
+	}
+	s.lastFsID++
+	s.fsIDs[pcspath] = s.lastFsID
+	s.fsPaths[s.lastFsID] = pcspath
+	return s.lastFsID
+}
+
+// renameFsID 移动路径后, 更新 from 及其子路径的 fs_id, 调用者需持有锁
+func (s *Server) renameFsID(from, to string) {
+	// 先找出所有需要更新的路径, 遍历时插入的新路径可能再次被遍历到
+	var matched []string
+	for p := range s.fsIDs {
+		if p == from || strings.HasPrefix(p, from+"/") {
+			matched = append(matched, p)
+		}
+	}
+
+	ids := make([]int64, len(matched))
+	for k, p := range matched {
+		ids[k] = s.fsIDs[p]
+		delete(s.fsIDs, p)
+	}
+	for k, p := range matched {
+		newPath := to + strings.TrimPrefix(p, from)
+		s.fsIDs[newPath] = ids[k]
+		s.fsPaths[ids[k]] = newPath
+	}
+}
+
+// forgetFsID 删除 p 及其子路径的 fs_id, 调用者需持有锁
+func (s *Server) forgetFsID(p string) {
+	for p2, id := range s.fsIDs {
+		if p2 == p || strings.HasPrefix(p2, p+"/") {
+			delete(s.fsIDs, p2)
+			delete(s.fsPaths, id)
+		}
+	}
+}
+
+// entry 获取网盘路径的元信息, 调用者需持有锁
+func (s *Server) entry(pcspath string, withMD5 bool) (*entryJSON, error) {
+	pcspath = cleanPath(pcspath)
+	lp, err := s.localPath(pcspath)
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := os.Stat(lp)
+	if err != nil {
+		return nil, err
+	}
+	return s.entryByInfo(pcspath, lp, info, withMD5)
+}
+
+func (s *Server) entryByInfo(pcspath, lp string, info os.FileInfo, withMD5 bool) (*entryJSON, error) {
+	mtime := info.ModTime().Unix()
+	e := &entryJSON{
+		FsID:           s.fsID(pcspath),
+		Path:           pcspath,
+		ServerFilename: path.Base(pcspath),
+		Ctime:          mtime,
+		Mtime:          mtime,
+		ServerCtime:    mtime,
+		ServerMtime:    mtime,
+	}
+	if pcspath == "/" {
+		e.ServerFilename = ""
+	}
+
+	if info.IsDir() {
+		e.Isdir = 1
+		if hasSubdir(lp) {
+			e.Ifhassubdir = 1
+		}
+		return e, nil
+	}
+
+	e.Size = info.Size()
+	if withMD5 {
+		item, err := s.fileMD5(lp, info)
+		if err != nil {
+			return nil, err
+		}
+		e.MD5 = item.md5
+		e.BlockList = []string{item.md5}
+	}
+	return e, nil
+}
+
+// list 列出目录, 调用者需持有锁
+func (s *Server) list(pcspath, by, order string) ([]*entryJSON, error) {
+	pcspath = cleanPath(pcspath)
+	lp, err := s.localPath(pcspath)
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := os.Stat(lp)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return nil, os.ErrNotExist
+	}
+
+	f, err := os.Open(lp)
+	if err != nil {
+		return nil, err
+	}
+	infos, err := f.Readdir(-1)
+	f.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	list := make([]*entryJSON, 0, len(infos))
+	for _, info := range infos {
+		if pcspath == "/" && info.Name() == DataDirName {
+			continue
+		}
+		e, err := s.entryByInfo(path.Join(pcspath, info.Name()), filepath.Join(lp, info.Name()), info, true)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, e)
+	}
+
+	sortEntries(list, by, order)
+	return list, nil
+}
+
+// sortEntries 排序, 目录总是排在文件前面
+func sortEntries(list []*entryJSON, by, order string) {
+	desc := order == "desc"
+	sort.SliceStable(list, func(i, j int) bool {
+		if list[i].Isdir != list[j].Isdir {
+			return list[i].Isdir > list[j].Isdir
+		}
+
+		var less bool
+		switch by {
+		case "time":
+			less = list[i].Mtime < list[j].Mtime
+			if list[i].Mtime == list[j].Mtime {
+				less = list[i].ServerFilename < list[j].ServerFilename
+			}
+		case "size":
+			less = list[i].Size < list[j].Size
+			if list[i].Size == list[j].Size {
+				less = list[i].ServerFilename < list[j].ServerFilename
+			}
+		default:
+			less = list[i].ServerFilename < list[j].ServerFilename
+		}
+		if desc {
+			return !less
+		}
+		return less
+	})
+}
+
+func hasSubdir(lp string) bool {
+	f, err := os.Open(lp)
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+
+	infos, err := f.Readdir(-1)
+	if err != nil {
+		return false
+	}
+	for _, info := range infos {
+		if info.IsDir() && info.Name() != DataDirName {
+			return true
+		}
+	}
+	return false
+}
+
+// fileMD5 计算文件的 md5 和 slice-md5, 结果按 大小+修改时间 缓存, 调用者需持有锁
+func (s *Server) fileMD5(lp string, info os.FileInfo) (*md5CacheItem, error) {
+	item, ok := s.md5Cache[lp]
+	if ok && item.size == info.Size() && item.mtime.Equal(info.ModTime()) {
+		return item, nil
+	}
+
+	f, err := os.Open(lp)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var (
+		m  = md5.New()
+		sm = md5.New()
+	)
+	_, err = io.Copy(io.MultiWriter(m, sm), io.LimitReader(f, sliceMD5Size))
+	if err != nil {
+		return nil, err
+	}
+	_, err = io.Copy(m, f)
+	if err != nil {
+		return nil, err
+	}
+
+	item = &md5CacheItem{
+		size:     info.Size(),
+		mtime:    info.ModTime(),
+		md5:      hex.EncodeToString(m.Sum(nil)),
+		sliceMD5: hex.EncodeToString(sm.Sum(nil)),
+	}
+	s.md5Cache[lp] = item
+	return item, nil
+}
+
+// findByMD5 在网盘中查找 md5 一致的文件, 用于秒传, 调用者需持有锁
+func (s *Server) findByMD5(size int64, contentMD5, sliceMD5 string) (lp string, ok bool) {
+	contentMD5, sliceMD5 = strings.ToLower(contentMD5), strings.ToLower(sliceMD5)
+	filepath.Walk(s.root, func(p string, info os.FileInfo, err error) error {
+		if err != nil || ok {
+			return nil
+		}
+		if info.IsDir() {
+			if p == s.dataDir {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if info.Size() != size {
+			return nil
+		}
+
+		item, err := s.fileMD5(p, info)
+		if err != nil {
+			return nil
+		}
+		if item.md5 == contentMD5 && (sliceMD5 == "" || item.sliceMD5 == sliceMD5) {
+			lp, ok = p, true
+		}
+		return nil
+	})
+	return
+}
+
+// copyFile 拷贝文件或目录
+func copyFile(src, dst string) error {
+	info, err := os.Stat(src)
+	if err != nil {
+		return err
+	}
+
+	if info.IsDir() {
+		err = os.MkdirAll(dst, 0777)
+		if err != nil {
+			return err
+		}
+		f, err := os.Open(src)
+		if err != nil {
+			return err
+		}
+		names, err := f.Readdirnames(-1)
+		f.Close()
+		if err != nil {
+			return err
+		}
+		for _, name := range names {
+			err = copyFile(filepath.Join(src, name), filepath.Join(dst, name))
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	srcFile, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer srcFile.Close()
+
+	dstFile, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(dstFile, srcFile)
+	if err != nil {
+		dstFile.Close()
+		return err
+	}
+	return dstFile.Close()
+}
+
+// exists 本地路径是否存在
+func exists(lp string) bool {
+	_, err := os.Stat(lp)
+	return err == nil
+}
@@ -0,0 +1,485 @@
+package pcsemu
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"github.com/json-iterator/go"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+)
+
+const (
+	recycleListPageSize = 100
+	// recycleLeftTime 回收站文件保留时间
+	recycleLeftTime = 10 * 24 * time.Hour
+)
+
+type (
+	recycleItem struct {
+		FsID     int64  `json:"fs_id"`
+		Isdir    int    `json:"isdir"`
+		LeftTime int64  `json:"leftTime"`
+		Path     string `json:"path"`
+		Filename string `json:"server_filename"`
+		Ctime    int64  `json:"server_ctime"`
+		Mtime    int64  `json:"server_mtime"`
+		MD5      string `json:"md5,omitempty"`
+		Size     int64  `json:"size"`
+
+		deleteTime time.Time
+	}
+
+	shareRecord struct {
+		ShareID         int64   `json:"shareId"`
+		FsIds           []int64 `json:"fsIds"`
+		Passwd          string  `json:"passwd"`
+		Shortlink       string  `json:"shortlink"`
+		Status          int     `json:"status"`
+		TypicalCategory int     `json:"typicalCategory"`
+		TypicalPath     string  `json:"typicalPath"`
+		Ctime           int64   `json:"ctime"`
+		ExpiredTime     int64   `json:"expiredTime"`
+	}
+)
+
+func randomHex(n int) string {
+	b := make([]byte, n)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+func (s *Server) recycleDir() string {
+	return filepath.Join(s.dataDir, "recycle")
+}
+
+func (s *Server) handleUserInfo(w http.ResponseWriter, r *http.Request) {
+	s.writeJSON(w, http.StatusOK, map[string]interface{}{
+		"errno": 0,
+		"records": []map[string]interface{}{
+			{
+				"uk":    DefaultUK,
+				"uname": "pcsemu",
+			},
+		},
+		"request_id": s.nextRequestID(),
+	})
+}
+
+func (s *Server) handlePrecreate(w http.ResponseWriter, r *http.Request) {
+	var (
+		pcspath   = r.FormValue("path")
+		blockList []string
+	)
+	size, err := strconv.ParseInt(r.FormValue("size"), 10, 64)
+	if err != nil || pcspath == "" {
+		s.panError(w, 2)
+		return
+	}
+	err = jsoniter.UnmarshalFromString(r.FormValue("block_list"), &blockList)
+	if err != nil {
+		s.panError(w, 2)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// 检测秒传
+	if contentMD5 := r.FormValue("content-md5"); contentMD5 != "" {
+		src, ok := s.findByMD5(size, contentMD5, r.FormValue("slice-md5"))
+		if ok {
+			f, err := os.Open(src)
+			if err != nil {
+				s.panError(w, -1)
+				return
+			}
+			defer f.Close()
+
+			ondup := OnDupNewCopy
+			switch r.FormValue("rtype") {
+			case "0":
+				ondup = OnDupFail
+			case "3":
+				ondup = OnDupOverwrite
+			}
+			e, err := s.saveFile(pcspath, ondup, f)
+			if err != nil {
+				s.panError(w, -8)
+				return
+			}
+			s.writeJSON(w, http.StatusOK, map[string]interface{}{
+				"errno":       0,
+				"return_type": 2,
+				"info":        e,
+				"request_id":  s.nextRequestID(),
+			})
+			return
+		}
+	}
+
+	uploadID := "P1-" + randomHex(16)
+	s.uploadIDs[uploadID] = struct{}{}
+
+	seqs := make([]int, len(blockList))
+	for k := range seqs {
+		seqs[k] = k
+	}
+	s.writeJSON(w, http.StatusOK, map[string]interface{}{
+		"errno":       0,
+		"return_type": 1,
+		"path":        cleanPath(pcspath),
+		"uploadid":    uploadID,
+		"block_list":  seqs,
+		"request_id":  s.nextRequestID(),
+	})
+}
+
+func (s *Server) handleSuperfile2(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	if q.Get("method") != "upload" {
+		s.pcsError(w, http.StatusBadRequest, 3, "Unsupported openapi method")
+		return
+	}
+
+	s.mu.Lock()
+	_, ok := s.uploadIDs[q.Get("uploadid")]
+	s.mu.Unlock()
+	if !ok {
+		s.pcsError(w, http.StatusBadRequest, 31299, "invalid uploadid")
+		return
+	}
+
+	f, err := formFile(r)
+	if err != nil {
+		s.pcsErrorParam(w)
+		return
+	}
+	defer f.Close()
+
+	sum, err := s.saveBlock(f)
+	if err != nil {
+		s.pcsErrorInternal(w, err)
+		return
+	}
+	s.writeJSON(w, http.StatusOK, map[string]interface{}{
+		"md5":        sum,
+		"partseq":    q.Get("partseq"),
+		"request_id": s.nextRequestID(),
+	})
+}
+
+func (s *Server) handlePanDownload(w http.ResponseWriter, r *http.Request) {
+	var fidList []int64
+	err := jsoniter.UnmarshalFromString(r.FormValue("fidlist"), &fidList)
+	if err != nil || len(fidList) == 0 {
+		s.panError(w, 2)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	dlinks := make([]map[string]string, 0, len(fidList))
+	for _, fid := range fidList {
+		pcspath, ok := s.fsPaths[fid]
+		if !ok {
+			s.panError(w, -9)
+			return
+		}
+		dlinks = append(dlinks, map[string]string{
+			"dlink": downloadURL(r, pcspath),
+			"fs_id": strconv.FormatInt(fid, 10),
+		})
+	}
+
+	s.writeJSON(w, http.StatusOK, map[string]interface{}{
+		"errno":      0,
+		"dlink":      dlinks,
+		"request_id": s.nextRequestID(),
+	})
+}
+
+// handleDiskHome 网盘首页, 只提供签名所需的数据
+func (s *Server) handleDiskHome(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write([]byte(`<html><script>var context={"sign1":"pcsemu","sign2":"","sign3":"pcsemu","timestamp":` + strconv.FormatInt(time.Now().Unix(), 10) + `,"uk":1};</script></html>`))
+}
+
+// moveToRecycle 将网盘路径移动到回收站, 调用者需持有锁
+func (s *Server) moveToRecycle(pcspath string) error {
+	e, err := s.entry(pcspath, true)
+	if err != nil {
+		return err
+	}
+
+	lp, _ := s.localPath(pcspath)
+	err = os.Rename(lp, filepath.Join(s.recycleDir(), strconv.FormatInt(e.FsID, 10)))
+	if err != nil {
+		return err
+	}
+	s.forgetFsID(pcspath)
+
+	s.recycle = append(s.recycle, &recycleItem{
+		FsID:       e.FsID,
+		Isdir:      e.Isdir,
+		LeftTime:   int64(recycleLeftTime / time.Second),
+		Path:       e.Path,
+		Filename:   e.ServerFilename,
+		Ctime:      e.Ctime,
+		Mtime:      e.Mtime,
+		MD5:        e.MD5,
+		Size:       e.Size,
+		deleteTime: time.Now(),
+	})
+	return nil
+}
+
+// findRecycle 查找回收站中的项目, 调用者需持有锁
+func (s *Server) findRecycle(fsID int64) int {
+	for k, item := range s.recycle {
+		if item.FsID == fsID {
+			return k
+		}
+	}
+	return -1
+}
+
+func (s *Server) handleRecycleList(w http.ResponseWriter, r *http.Request) {
+	page, err := strconv.Atoi(r.URL.Query().Get("page"))
+	if err != nil || page < 1 {
+		page = 1
+	}
+	num, err := strconv.Atoi(r.URL.Query().Get("num"))
+	if err != nil || num < 1 {
+		num = recycleListPageSize
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	start, end := (page-1)*num, page*num
+	if start > len(s.recycle) {
+		start = len(s.recycle)
+	}
+	if end > len(s.recycle) {
+		end = len(s.recycle)
+	}
+
+	list := make([]*recycleItem, 0, end-start)
+	for _, item := range s.recycle[start:end] {
+		left := recycleLeftTime - time.Since(item.deleteTime)
+		item.LeftTime = int64(left / time.Second)
+		list = append(list, item)
+	}
+
+	s.writeJSON(w, http.StatusOK, map[string]interface{}{
+		"errno":      0,
+		"list":       list,
+		"request_id": s.nextRequestID(),
+	})
+}
+
+func (s *Server) handleRecycleRestore(w http.ResponseWriter, r *http.Request) {
+	var fl fsIDListJSON
+	err := jsoniter.UnmarshalFromString(r.FormValue("param"), &fl)
+	if err != nil || len(fl.List) == 0 {
+		s.pcsErrorParam(w)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	succ := make([]*fsIDJSON, 0, len(fl.List))
+	for _, fid := range fl.List {
+		if fid == nil {
+			continue
+		}
+		k := s.findRecycle(fid.FsID)
+		if k < 0 {
+			continue
+		}
+
+		item := s.recycle[k]
+		pcspath, err := s.resolveOnDup(item.Path, OnDupNewCopy)
+		if err != nil {
+			continue
+		}
+		lp, _ := s.localPath(pcspath)
+		err = os.MkdirAll(filepath.Dir(lp), 0777)
+		if err != nil {
+			continue
+		}
+		err = os.Rename(filepath.Join(s.recycleDir(), strconv.FormatInt(item.FsID, 10)), lp)
+		if err != nil {
+			continue
+		}
+
+		s.fsIDs[pcspath] = item.FsID
+		s.fsPaths[item.FsID] = pcspath
+		s.recycle = append(s.recycle[:k], s.recycle[k+1:]...)
+		succ = append(succ, &fsIDJSON{
+			FsID: item.FsID,
+		})
+	}
+
+	if len(succ) == 0 {
+		s.pcsErrorNotFound(w)
+		return
+	}
+
+	s.writeJSON(w, http.StatusOK, map[string]interface{}{
+		"extra": map[string]interface{}{
+			"list": succ,
+		},
+		"request_id": s.nextRequestID(),
+	})
+}
+
+func (s *Server) handleRecycleDelete(w http.ResponseWriter, r *http.Request) {
+	var fidList []int64
+	err := jsoniter.UnmarshalFromString(r.FormValue("fidlist"), &fidList)
+	if err != nil || len(fidList) == 0 {
+		s.panError(w, 2)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, fid := range fidList {
+		if s.findRecycle(fid) < 0 {
+			s.panError(w, -9)
+			return
+		}
+	}
+	for _, fid := range fidList {
+		k := s.findRecycle(fid)
+		os.RemoveAll(filepath.Join(s.recycleDir(), strconv.FormatInt(fid, 10)))
+		s.recycle = append(s.recycle[:k], s.recycle[k+1:]...)
+	}
+	s.panError(w, 0)
+}
+
+func (s *Server) handleRecycleClear(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	n := len(s.recycle)
+	for _, item := range s.recycle {
+		os.RemoveAll(filepath.Join(s.recycleDir(), strconv.FormatInt(item.FsID, 10)))
+	}
+	s.recycle = nil
+
+	s.writeJSON(w, http.StatusOK, map[string]interface{}{
+		"extra": map[string]interface{}{
+			"succNum": n,
+			"list":    []interface{}{},
+		},
+		"request_id": s.nextRequestID(),
+	})
+}
+
+func (s *Server) handleSharePSet(w http.ResponseWriter, r *http.Request) {
+	var paths []string
+	err := jsoniter.UnmarshalFromString(r.FormValue("path_list"), &paths)
+	if err != nil || len(paths) == 0 {
+		s.panError(w, 2)
+		return
+	}
+	period, _ := strconv.Atoi(r.FormValue("period"))
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	fsIDs := make([]int64, 0, len(paths))
+	for _, p := range paths {
+		e, err := s.entry(p, false)
+		if err != nil {
+			s.panError(w, -9)
+			return
+		}
+		fsIDs = append(fsIDs, e.FsID)
+	}
+
+	var (
+		now     = time.Now()
+		shareID = int64(len(s.shares) + 1)
+		short   = "1" + randomHex(8)
+		link    = "http://" + r.Host + "/s/" + short
+		record  = &shareRecord{
+			ShareID:     shareID,
+			FsIds:       fsIDs,
+			Passwd:      r.FormValue("pwd"),
+			Shortlink:   link,
+			TypicalPath: cleanPath(paths[0]),
+			Ctime:       now.Unix(),
+		}
+	)
+	if period > 0 {
+		record.ExpiredTime = now.Add(time.Duration(period) * 24 * time.Hour).Unix()
+	}
+	s.shares = append(s.shares, record)
+
+	s.writeJSON(w, http.StatusOK, map[string]interface{}{
+		"errno":      0,
+		"shareid":    shareID,
+		"link":       link,
+		"shorturl":   link,
+		"ctime":      record.Ctime,
+		"request_id": s.nextRequestID(),
+	})
+}
+
+func (s *Server) handleShareRecord(w http.ResponseWriter, r *http.Request) {
+	page, err := strconv.Atoi(r.URL.Query().Get("page"))
+	if err != nil || page < 1 {
+		page = 1
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	list := make([]*shareRecord, 0)
+	if page == 1 {
+		// 按时间倒序
+		for k := len(s.shares) - 1; k >= 0; k-- {
+			if s.shares[k].Status == 0 {
+				list = append(list, s.shares[k])
+			}
+		}
+	}
+
+	s.writeJSON(w, http.StatusOK, map[string]interface{}{
+		"errno":      0,
+		"list":       list,
+		"count":      len(list),
+		"request_id": s.nextRequestID(),
+	})
+}
+
+func (s *Server) handleShareCancel(w http.ResponseWriter, r *http.Request) {
+	var shareIDs []int64
+	err := jsoniter.UnmarshalFromString(r.FormValue("shareid_list"), &shareIDs)
+	if err != nil || len(shareIDs) == 0 {
+		s.panError(w, 2)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, id := range shareIDs {
+		if id < 1 || id > int64(len(s.shares)) || s.shares[id-1].Status != 0 {
+			s.panError(w, -7)
+			return
+		}
+	}
+	for _, id := range shareIDs {
+		s.shares[id-1].Status = 1
+	}
+	s.panError(w, 0)
+}
@@ -0,0 +1,194 @@
+// Package pcsemu 本地网盘模拟器,
+// 将本地目录模拟为 PCS/网盘首页 api, 用于离线测试
+package pcsemu
+
+import (
+	"github.com/Erope/BaiduPCS-Go/pcsverbose"
+	"github.com/json-iterator/go"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+const (
+	// DataDirName 模拟器数据目录名, 位于根目录下, 不会出现在文件列表中
+	DataDirName = ".pcsemu"
+	// DefaultQuota 默认的空间配额
+	DefaultQuota int64 = 2 << 40
+	// DefaultUK 默认的用户 UK
+	DefaultUK int64 = 1
+)
+
+var (
+	pcsEmuVerbose = pcsverbose.New("PCSEMU")
+)
+
+type (
+	// Server 本地网盘模拟器
+	Server struct {
+		root    string // 模拟的网盘根目录
+		dataDir string // 模拟器数据目录
+		quota   int64
+
+		partialBatch bool // 批量操作出错时, 保留出错前已处理的项
+
+		mu        sync.Mutex
+		lastFsID  int64
+		fsIDs     map[string]int64 // 网盘路径 -> fs_id
+		fsPaths   map[int64]string // fs_id -> 网盘路径
+		md5Cache  map[string]*md5CacheItem
+		uploadIDs map[string]struct{}
+		shares    []*shareRecord
+		recycle   []*recycleItem
+		requestID int64
+	}
+
+	pcsErrorJSON struct {
+		ErrorCode int    `json:"error_code"`
+		ErrorMsg  string `json:"error_msg"`
+		RequestID int64  `json:"request_id"`
+	}
+
+	panErrorJSON struct {
+		Errno     int    `json:"errno"`
+		ErrMsg    string `json:"errmsg,omitempty"`
+		RequestID int64  `json:"request_id"`
+	}
+)
+
+// NewServer 以本地目录 root 作为网盘根目录, 返回模拟器
+func NewServer(root string) (*Server, error) {
+	root, err := filepath.Abs(root)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Server{
+		root:      root,
+		dataDir:   filepath.Join(root, DataDirName),
+		quota:     DefaultQuota,
+		fsIDs:     map[string]int64{},
+		fsPaths:   map[int64]string{},
+		md5Cache:  map[string]*md5CacheItem{},
+		uploadIDs: map[string]struct{}{},
+	}
+
+	// 回收站列表只保存在内存中, 清理上次运行遗留的回收站文件
+	err = os.RemoveAll(s.recycleDir())
+	if err != nil {
+		return nil, err
+	}
+
+	for _, dir := range []string{s.root, s.blocksDir(), s.recycleDir()} {
+		err = os.MkdirAll(dir, 0777)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+// Root 返回模拟的网盘根目录
+func (s *Server) Root() string {
+	return s.root
+}
+
+// SetQuota 设置空间配额
+func (s *Server) SetQuota(quota int64) {
+	s.quota = quota
+}
+
+// SetPartialBatch 设置批量删除, 拷贝, 移动出错时, 是否保留出错前已处理的项.
+// 默认先检查全部项, 任意一项出错则全部不处理
+func (s *Server) SetPartialBatch(partial bool) {
+	s.partialBatch = partial
+}
+
+// ServeHTTP 实现 http.Handler
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	pcsEmuVerbose.Infof("%s %s\n", r.Method, r.URL)
+
+	p := r.URL.Path
+	switch {
+	case p == "/rest/2.0/pcs/file":
+		s.handlePCSFile(w, r)
+	case p == "/rest/2.0/pcs/quota":
+		s.handleQuota(w, r)
+	case p == "/rest/2.0/pcs/stream":
+		s.handleDownload(w, r)
+	case p == "/rest/2.0/pcs/superfile2":
+		s.handleSuperfile2(w, r)
+	case p == "/api/precreate":
+		s.handlePrecreate(w, r)
+	case p == "/api/user/getinfo":
+		s.handleUserInfo(w, r)
+	case p == "/api/download":
+		s.handlePanDownload(w, r)
+	case p == "/api/recycle/list":
+		s.handleRecycleList(w, r)
+	case p == "/api/recycle/delete":
+		s.handleRecycleDelete(w, r)
+	case p == "/share/pset":
+		s.handleSharePSet(w, r)
+	case p == "/share/record":
+		s.handleShareRecord(w, r)
+	case p == "/share/cancel":
+		s.handleShareCancel(w, r)
+	case p == "/disk/home":
+		s.handleDiskHome(w, r)
+	case strings.HasPrefix(p, "/rest/2.0/"):
+		s.pcsError(w, http.StatusBadRequest, 3, "Unsupported openapi method")
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (s *Server) nextRequestID() int64 {
+	return atomic.AddInt64(&s.requestID, 1)
+}
+
+func (s *Server) writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	data, err := jsoniter.Marshal(v)
+	if err != nil {
+		// 不能再输出 JSON, 直接返回 500
+		pcsEmuVerbose.Warnf("marshal response error: %s\n", err)
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	w.Write(data)
+}
+
+// pcsError 输出 PCS api 格式的错误
+func (s *Server) pcsError(w http.ResponseWriter, status, code int, msg string) {
+	s.writeJSON(w, status, &pcsErrorJSON{
+		ErrorCode: code,
+		ErrorMsg:  msg,
+		RequestID: s.nextRequestID(),
+	})
+}
+
+// panError 输出网盘首页 api 格式的错误
+func (s *Server) panError(w http.ResponseWriter, errno int) {
+	s.writeJSON(w, http.StatusOK, &panErrorJSON{
+		Errno:     errno,
+		RequestID: s.nextRequestID(),
+	})
+}
+
+func (s *Server) pcsErrorNotFound(w http.ResponseWriter) {
+	s.pcsError(w, http.StatusNotFound, 31066, "file does not exist")
+}
+
+func (s *Server) pcsErrorParam(w http.ResponseWriter) {
+	s.pcsError(w, http.StatusBadRequest, 31023, "param error")
+}
+
+func (s *Server) pcsErrorInternal(w http.ResponseWriter, err error) {
+	pcsEmuVerbose.Warnf("internal error: %s\n", err)
+	s.pcsError(w, http.StatusInternalServerError, 31021, "network error")
+}
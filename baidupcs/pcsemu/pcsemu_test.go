@@ -0,0 +1,628 @@
+package pcsemu_test
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"errors"
+	"github.com/Erope/BaiduPCS-Go/baidupcs"
+	"github.com/Erope/BaiduPCS-Go/baidupcs/pcsemu"
+	"github.com/Erope/BaiduPCS-Go/baidupcs/pcserror"
+	"github.com/Erope/BaiduPCS-Go/requester"
+	"github.com/Erope/BaiduPCS-Go/requester/downloader"
+	"github.com/Erope/BaiduPCS-Go/requester/multipartreader"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+type bytesReaderLen64 struct {
+	*bytes.Reader
+}
+
+func (br *bytesReaderLen64) Len() int64 {
+	return int64(br.Reader.Len())
+}
+
+func newTestPCS(t *testing.T) (*baidupcs.BaiduPCS, *pcsemu.Server, func()) {
+	root, err := ioutil.TempDir("", "pcsemu")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s, err := pcsemu.NewServer(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := httptest.NewServer(s)
+
+	pcs := baidupcs.NewPCS(266719, "")
+	pcs.SetUID(1)
+	pcs.SetHTTPS(false)
+	if err = pcs.SetPCSAddr(server.URL); err != nil {
+		t.Fatal(err)
+	}
+	if err = pcs.SetPanAddr(server.URL); err != nil {
+		t.Fatal(err)
+	}
+
+	return pcs, s, func() {
+		server.Close()
+		os.RemoveAll(root)
+	}
+}
+
+func uploadFunc(data []byte) baidupcs.UploadFunc {
+	return func(uploadURL string, jar http.CookieJar) (*http.Response, error) {
+		mr := multipartreader.NewMultipartReader()
+		mr.AddFormFile("uploadedfile", "", &bytesReaderLen64{bytes.NewReader(data)})
+		mr.CloseMultipart()
+		return requester.NewHTTPClient().Req(http.MethodPost, uploadURL, mr, nil)
+	}
+}
+
+func md5Hex(data []byte) string {
+	sum := md5.Sum(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func TestFileOperations(t *testing.T) {
+	pcs, _, closeFn := newTestPCS(t)
+	defer closeFn()
+
+	if err := pcs.Mkdir("/a/b"); err != nil {
+		t.Fatal(err)
+	}
+	if err := pcs.Mkdir("/a/b"); err == nil {
+		t.Fatal("expect mkdir error")
+	}
+
+	// 分片上传
+	blocks := [][]byte{[]byte("hello "), []byte("world")}
+	checksums := make([]string, 0, len(blocks))
+	for _, block := range blocks {
+		checksum, err := pcs.UploadTmpFile(uploadFunc(block))
+		if err != nil {
+			t.Fatal(err)
+		}
+		checksums = append(checksums, checksum)
+	}
+	if err := pcs.UploadCreateSuperFile("/a/hello.txt", checksums...); err != nil {
+		t.Fatal(err)
+	}
+
+	fd, err := pcs.FilesDirectoriesMeta("/a/hello.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fd.Size != 11 || fd.MD5 != md5Hex([]byte("hello world")) {
+		t.Fatalf("unexpected meta: %+v", fd)
+	}
+
+	// 秒传
+	if err = pcs.RapidUpload("/a/b/rapid.txt", fd.MD5, fd.MD5, "0", fd.Size); err != nil {
+		t.Fatal(err)
+	}
+	if err = pcs.RapidUpload("/a/b/none.txt", md5Hex([]byte("none")), "", "0", 4); err == nil {
+		t.Fatal("expect rapidupload error")
+	}
+
+	if err = pcs.Copy(&baidupcs.CpMvJSON{From: "/a/hello.txt", To: "/a/copy.txt"}); err != nil {
+		t.Fatal(err)
+	}
+	if err = pcs.Move(&baidupcs.CpMvJSON{From: "/a/copy.txt", To: "/a/b/moved.txt"}); err != nil {
+		t.Fatal(err)
+	}
+
+	fdl, pcsError := pcs.FilesDirectoriesList("/a/b", nil)
+	if pcsError != nil {
+		t.Fatal(pcsError)
+	}
+	if len(fdl) != 2 || fdl[0].Filename != "moved.txt" || fdl[1].Filename != "rapid.txt" {
+		t.Fatalf("unexpected list: %s", fdl)
+	}
+
+	info, pcsError := pcs.LocateDownload("/a/b/moved.txt")
+	if pcsError != nil {
+		t.Fatal(pcsError)
+	}
+	body, fetchErr := requester.NewHTTPClient().Fetch(http.MethodGet, info.SingleURL(false).String(), nil, nil)
+	if fetchErr != nil {
+		t.Fatal(fetchErr)
+	}
+	if string(body) != "hello world" {
+		t.Fatalf("unexpected download body: %s", body)
+	}
+
+	// 删除和回收站
+	if err = pcs.Remove("/a/b/moved.txt"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = pcs.FilesDirectoriesMeta("/a/b/moved.txt"); err == nil || err.GetRemoteErrCode() != 31066 {
+		t.Fatalf("expect file not exist, got %v", err)
+	}
+	rl, err := pcs.RecycleList(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rl) != 1 || rl[0].Path != "/a/b/moved.txt" {
+		t.Fatalf("unexpected recycle list: %v", rl)
+	}
+	if _, err = pcs.RecycleRestore(rl[0].FsID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = pcs.FilesDirectoriesMeta("/a/b/moved.txt"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestPrecreateSuperfile2(t *testing.T) {
+	pcs, _, closeFn := newTestPCS(t)
+	defer closeFn()
+
+	data := []byte("superfile2")
+	sum := md5Hex(data)
+	info, err := pcs.UploadPrecreate("/p.txt", "", "", "", int64(len(data)), sum)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.IsRapidUpload || len(info.UploadSeqList) != 1 {
+		t.Fatalf("unexpected precreate info: %+v", info)
+	}
+
+	checksum, err := pcs.UploadSuperfile2(info.UploadID, "/p.txt", 0, 0, uploadFunc(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if checksum != sum {
+		t.Fatalf("checksum not match: %s", checksum)
+	}
+	if err = pcs.UploadCreateSuperFile("/p.txt", checksum); err != nil {
+		t.Fatal(err)
+	}
+
+	// 再次 precreate 应该秒传
+	info, err = pcs.UploadPrecreate("/p2.txt", sum, sum, "", int64(len(data)), sum)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !info.IsRapidUpload {
+		t.Fatalf("expect rapid upload")
+	}
+}
+
+func TestShare(t *testing.T) {
+	pcs, _, closeFn := newTestPCS(t)
+	defer closeFn()
+
+	if err := pcs.Mkdir("/share"); err != nil {
+		t.Fatal(err)
+	}
+	shared, err := pcs.ShareSet([]string{"/share"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	records, err := pcs.ShareList(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 1 || records[0].ShareID != shared.ShareID {
+		t.Fatalf("unexpected share list: %v", records)
+	}
+
+	if err = pcs.ShareCancel([]int64{shared.ShareID}); err != nil {
+		t.Fatal(err)
+	}
+	records, err = pcs.ShareList(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 0 {
+		t.Fatalf("unexpected share list: %v", records)
+	}
+}
+
+func TestListIter(t *testing.T) {
+	pcs, _, closeFn := newTestPCS(t)
+	defer closeFn()
+
+	names := []string{"a", "b", "c", "d", "e"}
+	for _, name := range names {
+		if err := pcs.Mkdir("/list/" + name); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	iter := pcs.ListIter("/list", &baidupcs.ListIterOptions{
+		PageSize: 2,
+	})
+	var got []string
+	for iter.Next() {
+		got = append(got, iter.Value().Filename)
+	}
+	if iter.Err() != nil {
+		t.Fatal(iter.Err())
+	}
+	if len(got) != len(names) {
+		t.Fatalf("unexpected list: %v", got)
+	}
+	for k := range names {
+		if got[k] != names[k] {
+			t.Fatalf("unexpected list: %v", got)
+		}
+	}
+
+	fdl, err := pcs.ListIter("/list/a", nil).All()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(fdl) != 0 {
+		t.Fatalf("expect empty list, got %s", fdl)
+	}
+
+	if _, err = pcs.ListIter("/none", nil).All(); err == nil {
+		t.Fatal("expect list error")
+	}
+}
+
+func TestWalk(t *testing.T) {
+	pcs, _, closeFn := newTestPCS(t)
+	defer closeFn()
+
+	for _, dir := range []string{"/w/a/a1", "/w/a/a2", "/w/b/b1/b11", "/w/c"} {
+		if err := pcs.Mkdir(dir); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var (
+		opts    = &baidupcs.WalkOptions{Parallel: 3, PageSize: 1}
+		visited []string
+		seen    = map[string]bool{}
+		done    []string
+		doneSet = map[string]bool{}
+	)
+	opts.DirDone = func(dir *baidupcs.FileDirectory) {
+		done = append(done, dir.Path)
+		doneSet[dir.Path] = true
+	}
+	err := pcs.Walk("/w", opts, func(fdPath string, fd *baidupcs.FileDirectory, pcsError pcserror.Error) error {
+		if pcsError != nil {
+			t.Fatal(pcsError)
+		}
+		if len(visited) > 0 && !seen[path.Dir(fdPath)] {
+			t.Fatalf("parent not visited before: %s", fdPath)
+		}
+		if doneSet[path.Dir(fdPath)] {
+			t.Fatalf("visited after parent done: %s", fdPath)
+		}
+		seen[fdPath] = true
+		visited = append(visited, fdPath)
+		if fdPath == "/w/b" {
+			return baidupcs.SkipDir
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if visited[0] != "/w" {
+		t.Fatalf("root not visited first: %v", visited)
+	}
+	sort.Strings(visited)
+	expected := []string{"/w", "/w/a", "/w/a/a1", "/w/a/a2", "/w/b", "/w/c"}
+	if len(visited) != len(expected) {
+		t.Fatalf("unexpected visited: %v", visited)
+	}
+	for k := range expected {
+		if visited[k] != expected[k] {
+			t.Fatalf("unexpected visited: %v", visited)
+		}
+	}
+
+	// 跳过的目录不获取列表
+	sort.Strings(done)
+	if strings.Join(done, ",") != "/w,/w/a,/w/a/a1,/w/a/a2,/w/c" {
+		t.Fatalf("unexpected done dirs: %v", done)
+	}
+	opts.DirDone = nil
+
+	var count int
+	err = pcs.Walk("/w", opts, func(fdPath string, fd *baidupcs.FileDirectory, pcsError pcserror.Error) error {
+		count++
+		if count == 2 {
+			return baidupcs.StopWalk
+		}
+		return nil
+	})
+	if err != nil || count != 2 {
+		t.Fatalf("stop walk failed, err: %v, count: %d", err, count)
+	}
+
+	err = pcs.Walk("/none", opts, func(fdPath string, fd *baidupcs.FileDirectory, pcsError pcserror.Error) error {
+		return pcsError
+	})
+	if err == nil {
+		t.Fatal("expect walk error")
+	}
+}
+
+func TestBatch(t *testing.T) {
+	pcs, _, closeFn := newTestPCS(t)
+	defer closeFn()
+
+	pcs.SetBatchSize(3)
+	var paths []string
+	for _, name := range []string{"a", "b", "c", "d", "e", "f", "g"} {
+		p := "/batch/" + name
+		if err := pcs.Mkdir(p); err != nil {
+			t.Fatal(err)
+		}
+		paths = append(paths, p)
+	}
+	if err := pcs.Mkdir("/batch2/c"); err != nil {
+		t.Fatal(err)
+	}
+
+	// 拷贝, 其中 c 目标已存在, x 不存在
+	cpmvJSON := []*baidupcs.CpMvJSON{}
+	for _, p := range append(paths, "/batch/x") {
+		cpmvJSON = append(cpmvJSON, &baidupcs.CpMvJSON{From: p, To: "/batch2/" + path.Base(p)})
+	}
+	results := pcs.BatchCopy(cpmvJSON...)
+	if len(results) != len(cpmvJSON) {
+		t.Fatalf("unexpected results length: %d", len(results))
+	}
+	for _, br := range results {
+		expected := baidupcs.BatchStatusSuccess
+		switch br.From {
+		case "/batch/c":
+			expected = baidupcs.BatchStatusConflict
+		case "/batch/x":
+			expected = baidupcs.BatchStatusNotFound
+		}
+		if br.Status != expected {
+			t.Fatalf("%s: expect %s, got %s, %v", br.From, expected, br.Status, br.PCSError)
+		}
+	}
+
+	// 删除, 其中 x 不存在
+	results = pcs.BatchRemove(append(paths, "/batch/x")...)
+	if failed := results.Failed(); len(failed) != 1 || failed[0].Path != "/batch/x" || failed[0].Status != baidupcs.BatchStatusNotFound {
+		t.Fatalf("unexpected failed: %v", failed)
+	}
+	fdl, err := pcs.FilesDirectoriesList("/batch", nil)
+	if err != nil || len(fdl) != 0 {
+		t.Fatalf("expect empty dir, got %v, %v", fdl, err)
+	}
+	if pcs.Remove("/batch2/a", "/batch2/b") != nil {
+		t.Fatal("remove failed")
+	}
+}
+
+func TestBatchPartial(t *testing.T) {
+	pcs, s, closeFn := newTestPCS(t)
+	defer closeFn()
+
+	// 服务器在出错前已处理了部分项, 这些项不应被重新发送
+	s.SetPartialBatch(true)
+	for _, p := range []string{"/src/a", "/src/b", "/src/c", "/dst/b"} {
+		if err := pcs.Mkdir(p); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cpmvJSON := []*baidupcs.CpMvJSON{}
+	for _, name := range []string{"a", "b", "c", "x"} {
+		cpmvJSON = append(cpmvJSON, &baidupcs.CpMvJSON{From: "/src/" + name, To: "/dst/" + name})
+	}
+	expected := []baidupcs.BatchStatus{baidupcs.BatchStatusSuccess, baidupcs.BatchStatusConflict, baidupcs.BatchStatusSuccess, baidupcs.BatchStatusNotFound}
+	check := func(op string, results baidupcs.BatchResultList) {
+		for k, br := range results {
+			if br.Status != expected[k] {
+				t.Fatalf("%s %s: expect %s, got %s, %v", op, br.From, expected[k], br.Status, br.PCSError)
+			}
+		}
+	}
+
+	check("copy", pcs.BatchCopy(cpmvJSON...))
+	for _, p := range []string{"/dst/a", "/dst/c"} {
+		if err := pcs.Remove(p); err != nil {
+			t.Fatal(err)
+		}
+	}
+	check("move", pcs.BatchMove(cpmvJSON...))
+
+	fdl, err := pcs.FilesDirectoriesList("/src", nil)
+	if err != nil || len(fdl) != 1 || fdl[0].Filename != "b" {
+		t.Fatalf("unexpected src: %v, %v", fdl, err)
+	}
+}
+
+func TestOnDup(t *testing.T) {
+	pcs, _, closeFn := newTestPCS(t)
+	defer closeFn()
+
+	for _, p := range []string{"/src/1.txt", "/src/2.txt", "/dst/1.txt"} {
+		if err := pcs.Upload(p, uploadFunc([]byte(p))); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cpmvJSON := func(ondup baidupcs.OnDup) []*baidupcs.CpMvJSON {
+		return []*baidupcs.CpMvJSON{
+			{From: "/src/1.txt", To: "/dst/1.txt", OnDup: ondup},
+			{From: "/src/2.txt", To: "/dst/2.txt", OnDup: ondup},
+		}
+	}
+
+	// 跳过已存在的目标
+	results := pcs.BatchCopy(cpmvJSON(baidupcs.OnDupSkip)...)
+	if results[0].Status != baidupcs.BatchStatusSkipped || results[1].Status != baidupcs.BatchStatusSuccess || results.Err() != nil {
+		t.Fatalf("unexpected results: %s, %s", results[0].Status, results[1].Status)
+	}
+
+	// 目标已存在时失败
+	results = pcs.BatchCopy(cpmvJSON(baidupcs.OnDupFail)...)
+	if results[0].Status != baidupcs.BatchStatusConflict || results[1].Status != baidupcs.BatchStatusConflict {
+		t.Fatalf("unexpected results: %s, %s", results[0].Status, results[1].Status)
+	}
+
+	// 覆盖
+	if err := pcs.Copy(cpmvJSON(baidupcs.OnDupOverwrite)...); err != nil {
+		t.Fatal(err)
+	}
+	fd, err := pcs.FilesDirectoriesMeta("/dst/1.txt")
+	if err != nil || fd.MD5 != md5Hex([]byte("/src/1.txt")) {
+		t.Fatalf("expect overwritten, got %v, %v", fd, err)
+	}
+
+	// 生成副本
+	if err = pcs.Copy(cpmvJSON(baidupcs.OnDupNewCopy)...); err != nil {
+		t.Fatal(err)
+	}
+	fdl, err := pcs.FilesDirectoriesList("/dst", nil)
+	if err != nil || len(fdl) != 4 {
+		t.Fatalf("expect 4 files, got %v, %v", fdl, err)
+	}
+
+	// 各项的处理方式不同时, 按处理方式分组发送
+	mixed := cpmvJSON(baidupcs.OnDupOverwrite)
+	mixed[1].OnDup = baidupcs.OnDupFail
+	results = pcs.BatchCopy(mixed...)
+	if results[0].Status != baidupcs.BatchStatusSuccess || results[1].Status != baidupcs.BatchStatusConflict {
+		t.Fatalf("unexpected results: %s, %s", results[0].Status, results[1].Status)
+	}
+
+	// 秒传和上传
+	if err = pcs.RapidUploadWithOnDup("/dst/1.txt", baidupcs.OnDupSkip, fd.MD5, fd.MD5, "0", fd.Size); !baidupcs.IsOnDupSkipped(err) {
+		t.Fatalf("expect skipped, got %v", err)
+	}
+	if err = pcs.RapidUploadWithOnDup("/dst/1.txt", baidupcs.OnDupFail, fd.MD5, fd.MD5, "0", fd.Size); err == nil || err.GetRemoteErrCode() != baidupcs.ErrCodeFileExists {
+		t.Fatalf("expect file exists, got %v", err)
+	}
+	if err = pcs.UploadWithOnDup("/dst/1.txt", baidupcs.OnDupSkip, uploadFunc([]byte("new"))); !baidupcs.IsOnDupSkipped(err) {
+		t.Fatalf("expect skipped, got %v", err)
+	}
+
+	if _, parseErr := baidupcs.ParseOnDup("rename"); parseErr == nil {
+		t.Fatal("expect parse error")
+	}
+}
+
+// slowReaderLen64 缓慢读取的数据, 模拟上传大文件
+type slowReaderLen64 struct{}
+
+func (slowReaderLen64) Read(p []byte) (int, error) {
+	time.Sleep(10 * time.Millisecond)
+	for i := range p {
+		p[i] = 'a'
+	}
+	return len(p), nil
+}
+
+func (slowReaderLen64) Len() int64 {
+	return 1 << 30
+}
+
+// cancelWriterAt 第一次写入时取消 context
+type cancelWriterAt struct {
+	cancel context.CancelFunc
+}
+
+func (cw *cancelWriterAt) WriteAt(p []byte, off int64) (int, error) {
+	cw.cancel()
+	return len(p), nil
+}
+
+func TestContextCancel(t *testing.T) {
+	pcs, _, closeFn := newTestPCS(t)
+	defer closeFn()
+
+	// 已取消的 context, 不发起请求
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := pcs.WithContext(ctx).FilesDirectoriesMeta("/"); !errors.Is(err, context.Canceled) {
+		t.Fatalf("canceled meta: %v", err)
+	}
+
+	// 取消正在进行的上传
+	ctx, cancel = context.WithCancel(context.Background())
+	pcs2 := pcs.WithContext(ctx)
+	time.AfterFunc(100*time.Millisecond, cancel)
+	done := make(chan error, 1)
+	go func() {
+		_, err := pcs2.UploadTmpFile(func(uploadURL string, jar http.CookieJar) (*http.Response, error) {
+			mr := multipartreader.NewMultipartReader()
+			mr.AddFormFile("uploadedfile", "", slowReaderLen64{})
+			mr.CloseMultipart()
+			return requester.NewHTTPClient().ReqWithContext(pcs2.Context(), http.MethodPost, uploadURL, mr, nil)
+		})
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		if !errors.Is(err, context.Canceled) {
+			t.Fatalf("canceled upload: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("upload not canceled")
+	}
+
+	// 取消正在进行的下载
+	data := bytes.Repeat([]byte("0123456789"), 100*1024)
+	if err := pcs.Upload("/big.bin", uploadFunc(data)); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel = context.WithCancel(context.Background())
+	pcs2 = pcs.WithContext(ctx)
+	err := pcs2.DownloadFile("/big.bin", func(downloadURL string, jar http.CookieJar) error {
+		cfg := downloader.NewConfig()
+		cfg.Context = pcs2.Context()
+		der := downloader.NewDownloader(downloadURL, &cancelWriterAt{cancel: cancel}, cfg)
+		return der.Execute()
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("canceled download: %v", err)
+	}
+}
+
+func TestMoveKeepsFsID(t *testing.T) {
+	pcs, _, closeFn := newTestPCS(t)
+	defer closeFn()
+
+	paths := []string{"/m/1.txt", "/m/sub/2.txt", "/m/sub/deep/3.txt"}
+	fsIDs := map[string]int64{}
+	for _, p := range paths {
+		if err := pcs.Upload(p, uploadFunc([]byte(p))); err != nil {
+			t.Fatal(err)
+		}
+		fd, err := pcs.FilesDirectoriesMeta(p)
+		if err != nil {
+			t.Fatal(err)
+		}
+		fsIDs[p] = fd.FsID
+	}
+
+	if err := pcs.Move(&baidupcs.CpMvJSON{From: "/m", To: "/n"}); err != nil {
+		t.Fatal(err)
+	}
+	for _, p := range paths {
+		newPath := "/n" + p[len("/m"):]
+		fd, err := pcs.FilesDirectoriesMeta(newPath)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if fd.FsID != fsIDs[p] {
+			t.Errorf("%s: fs_id %d, want %d", newPath, fd.FsID, fsIDs[p])
+		}
+	}
+}
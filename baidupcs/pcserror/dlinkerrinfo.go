@@ -64,6 +64,27 @@ func (dle *DlinkErrInfo) GetError() error {
 	return dle.Err
 }
 
+// Unwrap 返回原始错误, 支持 errors.Is 和 errors.As
+func (dle *DlinkErrInfo) Unwrap() error {
+	return dle.Err
+}
+
+// Is 判断远端服务器错误是否属于错误种类 target, 支持 errors.Is
+func (dle *DlinkErrInfo) Is(target error) bool {
+	kind := DlinkErrKind(dle.ErrNo)
+	return dle.ErrType == ErrTypeRemoteError && kind != nil && kind == target
+}
+
+// Temporary 是否为可自行恢复的错误
+func (dle *DlinkErrInfo) Temporary() bool {
+	return isTemporary(dle.ErrType, dle.ErrNo, nil) // 远端服务器错误均视为不可自行恢复
+}
+
+// Retryable 是否值得重试
+func (dle *DlinkErrInfo) Retryable() bool {
+	return isRetryable(dle.ErrType, dle.Temporary())
+}
+
 func (dle *DlinkErrInfo) Error() string {
 	if dle.Operation == "" {
 		if dle.Err != nil {
@@ -0,0 +1,119 @@
+package pcserror
+
+import (
+	"errors"
+)
+
+// 错误种类, 可通过 errors.Is 判断, 如 errors.Is(pcsError, pcserror.ErrNotFound)
+var (
+	// ErrNotFound 文件或目录不存在
+	ErrNotFound = errors.New("文件或目录不存在")
+	// ErrAlreadyExists 文件或目录已存在
+	ErrAlreadyExists = errors.New("文件或目录已存在")
+	// ErrQuotaExceeded 网盘空间不足
+	ErrQuotaExceeded = errors.New("网盘空间不足")
+	// ErrRateLimited 请求过于频繁
+	ErrRateLimited = errors.New("请求过于频繁")
+	// ErrAuthExpired 登录状态过期或帐号无效
+	ErrAuthExpired = errors.New("登录状态过期或帐号无效")
+	// ErrFileBanned 文件被限制访问或分享
+	ErrFileBanned = errors.New("文件被限制访问或分享")
+	// ErrParamInvalid 参数错误
+	ErrParamInvalid = errors.New("参数错误")
+)
+
+var (
+	// pcsErrKinds PCS 错误代码对应的错误种类
+	pcsErrKinds = map[int]error{
+		110:   ErrAuthExpired,   // Access token invalid or no longer valid
+		111:   ErrAuthExpired,   // Access token expired
+		31023: ErrParamInvalid,  // param error
+		31034: ErrRateLimited,   // hit frequence limit
+		31045: ErrAuthExpired,   // user not exists
+		31061: ErrAlreadyExists, // file already exists
+		31062: ErrParamInvalid,  // file name is invalid
+		31066: ErrNotFound,      // file does not exist
+		31112: ErrQuotaExceeded, // exceed quota
+	}
+
+	// pcsTemporaryErrCodes 可自行恢复的 PCS 错误代码
+	pcsTemporaryErrCodes = map[int]bool{
+		31021: true, // network error
+		31034: true, // hit frequence limit
+	}
+
+	// panErrKinds 网盘首页 api 错误代码对应的错误种类
+	panErrKinds = map[int]error{
+		-2:    ErrAuthExpired,   // 用户不存在
+		-3:    ErrNotFound,      // 文件不存在
+		-4:    ErrAuthExpired,   // 登录信息有误
+		-6:    ErrAuthExpired,   // 请重新登录
+		-9:    ErrNotFound,      // 文件不存在
+		-16:   ErrFileBanned,    // 该文件已经限制分享
+		-30:   ErrAlreadyExists, // 文件已存在
+		-33:   ErrParamInvalid,  // 一次支持操作999个
+		-70:   ErrFileBanned,    // 文件中包含病毒或疑似病毒
+		2:     ErrParamInvalid,  // 参数错误
+		3:     ErrAuthExpired,   // 未登录或帐号无效
+		108:   ErrFileBanned,    // 文件名有敏感词
+		115:   ErrFileBanned,    // 该文件禁止分享
+		31034: ErrRateLimited,   // 频率控制
+	}
+
+	// dlinkErrKinds dlink 服务器错误代码对应的错误种类, 暂无已知的错误代码
+	dlinkErrKinds = map[int]error{}
+
+	// panTemporaryErrCodes 可自行恢复的网盘首页 api 错误代码
+	panTemporaryErrCodes = map[int]bool{
+		4:     true, // 存储好像出问题了，请稍候再试
+		31034: true, // 频率控制
+	}
+)
+
+// PCSErrKind 返回 PCS 错误代码对应的错误种类, 未知则返回 nil
+func PCSErrKind(errCode int) error {
+	return pcsErrKinds[errCode]
+}
+
+// PanErrKind 返回网盘首页 api 错误代码对应的错误种类, 未知则返回 nil
+func PanErrKind(errno int) error {
+	return panErrKinds[errno]
+}
+
+// DlinkErrKind 返回 dlink 服务器错误代码对应的错误种类, 未知则返回 nil
+func DlinkErrKind(errno int) error {
+	return dlinkErrKinds[errno]
+}
+
+// isTemporary 判断错误是否可自行恢复: 网络错误, 或 temporaryCodes 中的远端服务器错误
+func isTemporary(errType ErrType, errCode int, temporaryCodes map[int]bool) bool {
+	switch errType {
+	case ErrTypeNetError:
+		return true
+	case ErrTypeRemoteError:
+		return temporaryCodes[errCode]
+	}
+	return false
+}
+
+// isRetryable 判断错误是否值得重试: 可自行恢复的错误, 或 json 数据解析失败 (响应可能不完整)
+func isRetryable(errType ErrType, temporary bool) bool {
+	return temporary || errType == ErrTypeJSONParseError
+}
+
+// IsTemporary 判断 err 是否为可自行恢复的错误, 如网络错误, 请求过于频繁.
+// Temporary 和 Retryable 方法不属于 Error 接口, 由 PCSErrInfo, PanErrorInfo, DlinkErrInfo 等按需实现
+func IsTemporary(err error) bool {
+	var t interface {
+		Temporary() bool
+	}
+	return errors.As(err, &t) && t.Temporary()
+}
+
+// IsRetryable 判断 err 是否值得重试, 是所有重试判断共用的分类
+func IsRetryable(err error) bool {
+	var r interface {
+		Retryable() bool
+	}
+	return errors.As(err, &r) && r.Retryable()
+}
@@ -0,0 +1,57 @@
+package pcserror
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestKind(t *testing.T) {
+	pcsErr := NewPCSErrorInfo("test")
+	pcsErr.ErrType = ErrTypeRemoteError
+	pcsErr.ErrCode = 31066
+	if !errors.Is(pcsErr, ErrNotFound) || errors.Is(pcsErr, ErrAlreadyExists) {
+		t.Fatal("expect ErrNotFound")
+	}
+	if pcsErr.Temporary() || pcsErr.Retryable() {
+		t.Fatal("expect not temporary")
+	}
+
+	pcsErr.ErrCode = 31034
+	if !errors.Is(pcsErr, ErrRateLimited) || !IsTemporary(pcsErr) || !IsRetryable(pcsErr) {
+		t.Fatal("expect ErrRateLimited, temporary")
+	}
+
+	panErr := NewPanErrorInfo("test")
+	panErr.ErrType = ErrTypeRemoteError
+	panErr.ErrNo = -9
+	if !errors.Is(panErr, ErrNotFound) {
+		t.Fatal("expect ErrNotFound")
+	}
+	panErr.ErrNo = 115
+	if !errors.Is(panErr, ErrFileBanned) {
+		t.Fatal("expect ErrFileBanned")
+	}
+	panErr.ErrNo = 31034
+	if !errors.Is(panErr, ErrRateLimited) || !IsTemporary(panErr) || !IsRetryable(panErr) {
+		t.Fatal("expect pan ErrRateLimited, retryable")
+	}
+
+	// 非远端服务器错误, 不按错误代码判断, 但可通过 Unwrap 判断原始错误
+	errTest := errors.New("test")
+	panErr.SetNetError(errTest)
+	if errors.Is(panErr, ErrFileBanned) || !errors.Is(panErr, errTest) {
+		t.Fatal("expect unwrap to errTest")
+	}
+	if !panErr.Temporary() {
+		t.Fatal("expect net error temporary")
+	}
+
+	dlinkErr := NewDlinkErrInfo("test")
+	dlinkErr.SetJSONError(errTest)
+	if dlinkErr.Temporary() || !dlinkErr.Retryable() || !IsRetryable(dlinkErr) {
+		t.Fatal("expect json parse error retryable only")
+	}
+	if errors.Is(dlinkErr, ErrNotFound) || !errors.Is(dlinkErr, errTest) {
+		t.Fatal("expect unwrap to errTest")
+	}
+}
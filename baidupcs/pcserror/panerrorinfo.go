@@ -65,6 +65,27 @@ func (pane *PanErrorInfo) GetError() error {
 	return pane.Err
 }
 
+// Unwrap 返回原始错误, 支持 errors.Is 和 errors.As
+func (pane *PanErrorInfo) Unwrap() error {
+	return pane.Err
+}
+
+// Is 判断远端服务器错误是否属于 target 种类, 如 ErrNotFound, 支持 errors.Is
+func (pane *PanErrorInfo) Is(target error) bool {
+	kind := PanErrKind(pane.ErrNo)
+	return pane.ErrType == ErrTypeRemoteError && kind != nil && kind == target
+}
+
+// Temporary 是否为可自行恢复的错误
+func (pane *PanErrorInfo) Temporary() bool {
+	return isTemporary(pane.ErrType, pane.ErrNo, panTemporaryErrCodes)
+}
+
+// Retryable 是否值得重试
+func (pane *PanErrorInfo) Retryable() bool {
+	return isRetryable(pane.ErrType, pane.Temporary())
+}
+
 func (pane *PanErrorInfo) Error() string {
 	if pane.Operation == "" {
 		if pane.Err != nil {
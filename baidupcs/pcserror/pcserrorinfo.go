@@ -66,6 +66,27 @@ func (pcse *PCSErrInfo) GetError() error {
 	return pcse.Err
 }
 
+// Unwrap 返回原始错误, 支持 errors.Is 和 errors.As
+func (pcse *PCSErrInfo) Unwrap() error {
+	return pcse.Err
+}
+
+// Is 判断远端服务器错误是否属于 target 种类, 如 ErrNotFound, 支持 errors.Is
+func (pcse *PCSErrInfo) Is(target error) bool {
+	kind := PCSErrKind(pcse.ErrCode)
+	return pcse.ErrType == ErrTypeRemoteError && kind != nil && kind == target
+}
+
+// Temporary 是否为可自行恢复的错误
+func (pcse *PCSErrInfo) Temporary() bool {
+	return isTemporary(pcse.ErrType, pcse.ErrCode, pcsTemporaryErrCodes)
+}
+
+// Retryable 是否值得重试
+func (pcse *PCSErrInfo) Retryable() bool {
+	return isRetryable(pcse.ErrType, pcse.Temporary())
+}
+
 func (pcse *PCSErrInfo) Error() string {
 	if pcse.Operation == "" {
 		if pcse.Err != nil {
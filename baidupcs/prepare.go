@@ -10,6 +10,7 @@ import (
 	"github.com/Erope/baidu-tools/tieba"
 	"github.com/json-iterator/go"
 	"io"
+	"math"
 	"net/http"
 	"net/url"
 	"strconv"
@@ -64,26 +65,98 @@ func (pcs *BaiduPCS) sendReqReturnResp(rt reqType, op, method, urlStr string, po
 		}
 	}
 
-	resp, err := pcs.client.Req(method, urlStr, post, header)
-	if err != nil {
-		handleRespClose(resp)
-		switch rt {
-		case reqTypePCS:
-			return nil, &pcserror.PCSErrInfo{
-				Operation: op,
-				ErrType:   pcserror.ErrTypeNetError,
-				Err:       err,
+	var (
+		rp          = pcs.RetryPolicy()
+		maxAttempts = rp.MaxAttempts
+		reset, ok   = replayablePost(post)
+	)
+	if !ok || maxAttempts < 1 {
+		// 数据流无法重复发送, 不重试
+		maxAttempts = 1
+	}
+
+	for attempt := 1; ; attempt++ {
+		if attempt > 1 {
+			err := reset()
+			if err != nil {
+				return nil, newNetErrorInfo(rt, op, err)
 			}
-		case reqTypePan:
-			return nil, &pcserror.PanErrorInfo{
-				Operation: op,
-				ErrType:   pcserror.ErrTypeNetError,
-				Err:       err,
+		}
+
+		pcsError = pcs.beforeRequest(rt, op)
+		if pcsError != nil {
+			return nil, pcsError
+		}
+
+		resp, err := pcs.client.ReqWithContext(pcs.Context(), method, urlStr, post, header)
+		if err != nil {
+			handleRespClose(resp)
+			pcsError = newNetErrorInfo(rt, op, err)
+			// 非幂等的操作, 请求可能已经执行, 不重试
+			if attempt >= maxAttempts || pcs.Context().Err() != nil || !rp.canRetryUnknownResult(op) || !rp.IsRetryable(pcsError) {
+				return nil, pcsError
 			}
+		} else if attempt >= maxAttempts || !rp.retryableResponse(rt, op, resp) {
+			return resp, nil
+		} else {
+			resp.Body.Close()
+		}
+
+		baiduPCSVerbose.Warnf("%s: 请求失败, 重试 %d/%d\n", op, attempt, maxAttempts-1)
+		err = rp.Sleep(pcs.Context(), attempt)
+		if err != nil {
+			return nil, newNetErrorInfo(rt, op, err)
 		}
-		panic("unreachable")
 	}
-	return resp, nil
+}
+
+// newRemoteErrorInfo 返回远端服务器错误代码为 code 的错误
+func newRemoteErrorInfo(rt reqType, op string, code int) pcserror.Error {
+	switch rt {
+	case reqTypePCS:
+		return &pcserror.PCSErrInfo{
+			Operation: op,
+			ErrType:   pcserror.ErrTypeRemoteError,
+			ErrCode:   code,
+		}
+	case reqTypePan:
+		return &pcserror.PanErrorInfo{
+			Operation: op,
+			ErrType:   pcserror.ErrTypeRemoteError,
+			ErrNo:     code,
+		}
+	}
+	panic("unreachable")
+}
+
+func newNetErrorInfo(rt reqType, op string, err error) pcserror.Error {
+	switch rt {
+	case reqTypePCS:
+		return &pcserror.PCSErrInfo{
+			Operation: op,
+			ErrType:   pcserror.ErrTypeNetError,
+			Err:       err,
+		}
+	case reqTypePan:
+		return &pcserror.PanErrorInfo{
+			Operation: op,
+			ErrType:   pcserror.ErrTypeNetError,
+			Err:       err,
+		}
+	}
+	panic("unreachable")
+}
+
+// beforeRequest 发送请求前检查 context 是否已取消或超时, 并等待请求限流
+func (pcs *BaiduPCS) beforeRequest(rt reqType, op string) pcserror.Error {
+	err := pcs.Context().Err()
+	if err == nil {
+		err = pcs.limiter.wait(pcs.Context(), rt, op)
+	}
+	if err != nil {
+		return newNetErrorInfo(rt, op, err)
+	}
+	return nil
 }
 
 func (pcs *BaiduPCS) sendReqReturnReadCloser(rt reqType, op, method, urlStr string, post interface{}, header map[string]string) (readCloser io.ReadCloser, pcsError pcserror.Error) {
@@ -97,9 +170,11 @@ func (pcs *BaiduPCS) sendReqReturnReadCloser(rt reqType, op, method, urlStr stri
 // PrepareUK 获取用户 UK, 只返回服务器响应数据和错误信息
 func (pcs *BaiduPCS) PrepareUK() (dataReadCloser io.ReadCloser, pcsError pcserror.Error) {
 	pcs.lazyInit()
-	pcsURL := GetHTTPScheme(pcs.isHTTPS) + "://pan.baidu.com/api/user/getinfo?need_selfinfo=1"
+	panURL := pcs.generatePanURL("user/getinfo", map[string]string{
+		"need_selfinfo": "1",
+	})
 
-	dataReadCloser, pcsError = pcs.sendReqReturnReadCloser(reqTypePCS, OperationGetUK, http.MethodGet, pcsURL, nil, nil)
+	dataReadCloser, pcsError = pcs.sendReqReturnReadCloser(reqTypePCS, OperationGetUK, http.MethodGet, panURL.String(), nil, nil)
 	return
 }
 
@@ -135,6 +210,12 @@ func (pcs *BaiduPCS) PrepareFilesDirectoriesBatchMeta(paths ...string) (dataRead
 
 // PrepareFilesDirectoriesList 获取目录下的文件和目录列表, 只返回服务器响应数据和错误信息
 func (pcs *BaiduPCS) PrepareFilesDirectoriesList(path string, options *OrderOptions) (dataReadCloser io.ReadCloser, pcsError pcserror.Error) {
+	return pcs.PrepareFilesDirectoriesListPage(path, options, 0, math.MaxInt32)
+}
+
+// PrepareFilesDirectoriesListPage 分页获取目录下的文件和目录列表, 从第 start 项开始, 最多获取 limit 项,
+// 只返回服务器响应数据和错误信息
+func (pcs *BaiduPCS) PrepareFilesDirectoriesListPage(path string, options *OrderOptions, start, limit int) (dataReadCloser io.ReadCloser, pcsError pcserror.Error) {
 	pcs.lazyInit()
 	if options == nil {
 		options = DefaultOrderOptions
@@ -147,7 +228,7 @@ func (pcs *BaiduPCS) PrepareFilesDirectoriesList(path string, options *OrderOpti
 		"path":  path,
 		"by":    *(*string)(unsafe.Pointer(&options.By)),
 		"order": *(*string)(unsafe.Pointer(&options.Order)),
-		"limit": "0-2147483647",
+		"limit": strconv.Itoa(start) + "-" + strconv.Itoa(start+limit),
 	})
 	baiduPCSVerbose.Infof("%s URL: %s\n", OperationFilesDirectoriesList, pcsURL)
 
@@ -227,7 +308,13 @@ func (pcs *BaiduPCS) prepareCpMvOp(op string, cpmvJSON ...*CpMvJSON) (dataReadCl
 		panic(err)
 	}
 
-	pcsURL := pcs.generatePCSURL("file", method)
+	// 服务器只支持整个请求的处理方式, 使用第一项的
+	param := map[string]string{}
+	if len(cpmvJSON) > 0 {
+		setOnDupParam(param, cpmvJSON[0].OnDup, OnDupDefault)
+	}
+
+	pcsURL := pcs.generatePCSURL("file", method, param)
 	baiduPCSVerbose.Infof("%s URL: %s\n", op, pcsURL)
 
 	// 表单上传
@@ -258,16 +345,18 @@ func (pcs *BaiduPCS) PrepareMove(cpmvJSON ...*CpMvJSON) (dataReadCloser io.ReadC
 }
 
 // prepareRapidUpload 秒传文件, 不进行文件夹检查
-func (pcs *BaiduPCS) prepareRapidUpload(targetPath, contentMD5, sliceMD5, crc32 string, length int64) (dataReadCloser io.ReadCloser, pcsError pcserror.Error) {
+func (pcs *BaiduPCS) prepareRapidUpload(targetPath string, ondup OnDup, contentMD5, sliceMD5, crc32 string, length int64) (dataReadCloser io.ReadCloser, pcsError pcserror.Error) {
 	pcs.lazyInit()
-	pcsURL := pcs.generatePCSURL("file", "rapidupload", map[string]string{
+	param := map[string]string{
 		"path":           targetPath,                    // 上传文件的全路径名
 		"content-length": strconv.FormatInt(length, 10), // 待秒传的文件长度
 		"content-md5":    contentMD5,                    // 待秒传的文件的MD5
 		"slice-md5":      sliceMD5,                      // 待秒传的文件前256kb的MD5
 		"content-crc32":  crc32,                         // 待秒传文件CRC32
-		"ondup":          "overwrite",                   // overwrite: 表示覆盖同名文件; newcopy: 表示生成文件副本并进行重命名，命名规则为“文件名_日期.后缀”
-	})
+	}
+	// overwrite: 表示覆盖同名文件; newcopy: 表示生成文件副本并进行重命名，命名规则为“文件名_日期.后缀”
+	setOnDupParam(param, ondup, OnDupOverwrite)
+	pcsURL := pcs.generatePCSURL("file", "rapidupload", param)
 	baiduPCSVerbose.Infof("%s URL: %s\n", OperationRapidUpload, pcsURL)
 
 	dataReadCloser, pcsError = pcs.sendReqReturnReadCloser(reqTypePCS, OperationRapidUpload, http.MethodGet, pcsURL.String(), nil, nil)
@@ -276,13 +365,18 @@ func (pcs *BaiduPCS) prepareRapidUpload(targetPath, contentMD5, sliceMD5, crc32
 
 // PrepareRapidUpload 秒传文件, 只返回服务器响应数据和错误信息
 func (pcs *BaiduPCS) PrepareRapidUpload(targetPath, contentMD5, sliceMD5, crc32 string, length int64) (dataReadCloser io.ReadCloser, pcsError pcserror.Error) {
+	return pcs.PrepareRapidUploadWithOnDup(targetPath, OnDupDefault, contentMD5, sliceMD5, crc32, length)
+}
+
+// PrepareRapidUploadWithOnDup 秒传文件, ondup 为目标文件已存在时的处理方式, 只返回服务器响应数据和错误信息
+func (pcs *BaiduPCS) PrepareRapidUploadWithOnDup(targetPath string, ondup OnDup, contentMD5, sliceMD5, crc32 string, length int64) (dataReadCloser io.ReadCloser, pcsError pcserror.Error) {
 	pcs.lazyInit()
-	pcsError = pcs.checkIsdir(OperationRapidUpload, targetPath)
+	pcsError = pcs.checkIsdir(OperationRapidUpload, targetPath, ondup)
 	if pcsError != nil {
 		return nil, pcsError
 	}
 
-	return pcs.prepareRapidUpload(targetPath, contentMD5, sliceMD5, crc32, length)
+	return pcs.prepareRapidUpload(targetPath, ondup, contentMD5, sliceMD5, crc32, length)
 }
 
 // PrepareLocateDownload 获取下载链接, 只返回服务器响应数据和错误信息
@@ -303,17 +397,14 @@ func (pcs *BaiduPCS) PrepareLocateDownload(pcspath string) (dataReadCloser io.Re
 	}
 
 	ns := netdisksign.NewLocateDownloadSign(pcs.uid, bduss)
-	pcsURL := &url.URL{
-		Scheme: GetHTTPScheme(pcs.isHTTPS),
-		Host:   PCSBaiduCom,
-		Path:   "/rest/2.0/pcs/file",
-		RawQuery: (url.Values{
-			"app_id": []string{PanAppID},
-			"method": []string{"locatedownload"},
-			"path":   []string{pcspath},
-			"ver":    []string{"2"},
-		}).Encode() + "&" + ns.URLParam(),
-	}
+	pcsURL := pcs.URL()
+	pcsURL.Path += "/rest/2.0/pcs/file"
+	pcsURL.RawQuery = (url.Values{
+		"app_id": []string{PanAppID},
+		"method": []string{"locatedownload"},
+		"path":   []string{pcspath},
+		"ver":    []string{"2"},
+	}).Encode() + "&" + ns.URLParam()
 	baiduPCSVerbose.Infof("%s URL: %s\n", OperationLocateDownload, pcsURL)
 
 	dataReadCloser, pcsError = pcs.sendReqReturnReadCloser(reqTypePCS, OperationLocateDownload, http.MethodGet, pcsURL.String(), nil, pcs.getPanUAHeader())
@@ -325,7 +416,7 @@ func (pcs *BaiduPCS) PrepareLocatePanAPIDownload(fidList ...int64) (dataReadClos
 	pcs.lazyInit()
 	// 初始化
 	var (
-		sign, err = pcs.ph.CacheSignature()
+		sign, err = pcs.ph.CacheSignature(pcs.Context())
 	)
 	if err != nil {
 		return nil, &pcserror.PanErrorInfo{
@@ -350,18 +441,29 @@ func (pcs *BaiduPCS) PrepareLocatePanAPIDownload(fidList ...int64) (dataReadClos
 
 // PrepareUpload 上传单个文件, 只返回服务器响应数据和错误信息
 func (pcs *BaiduPCS) PrepareUpload(targetPath string, uploadFunc UploadFunc) (dataReadCloser io.ReadCloser, pcsError pcserror.Error) {
+	return pcs.PrepareUploadWithOnDup(targetPath, OnDupDefault, uploadFunc)
+}
+
+// PrepareUploadWithOnDup 上传单个文件, ondup 为目标文件已存在时的处理方式, 只返回服务器响应数据和错误信息
+func (pcs *BaiduPCS) PrepareUploadWithOnDup(targetPath string, ondup OnDup, uploadFunc UploadFunc) (dataReadCloser io.ReadCloser, pcsError pcserror.Error) {
 	pcs.lazyInit()
-	pcsError = pcs.checkIsdir(OperationUpload, targetPath)
+	pcsError = pcs.checkIsdir(OperationUpload, targetPath, ondup)
 	if pcsError != nil {
 		return nil, pcsError
 	}
 
-	pcsURL := pcs.generatePCSURL("file", "upload", map[string]string{
-		"path":  targetPath,
-		"ondup": "overwrite",
-	})
+	param := map[string]string{
+		"path": targetPath,
+	}
+	setOnDupParam(param, ondup, OnDupOverwrite)
+	pcsURL := pcs.generatePCSURL("file", "upload", param)
 	baiduPCSVerbose.Infof("%s URL: %s\n", OperationUpload, pcsURL)
 
+	pcsError = pcs.beforeRequest(reqTypePCS, OperationUpload)
+	if pcsError != nil {
+		return
+	}
+
 	resp, err := uploadFunc(pcsURL.String(), pcs.client.Jar)
 	if err != nil {
 		handleRespClose(resp)
@@ -388,6 +490,11 @@ func (pcs *BaiduPCS) PrepareUploadTmpFile(uploadFunc UploadFunc) (dataReadCloser
 	})
 	baiduPCSVerbose.Infof("%s URL: %s\n", OperationUploadTmpFile, pcsURL)
 
+	pcsError = pcs.beforeRequest(reqTypePCS, OperationUploadTmpFile)
+	if pcsError != nil {
+		return
+	}
+
 	resp, err := uploadFunc(pcsURL.String(), pcs.client.Jar)
 	if err != nil {
 		handleRespClose(resp)
@@ -408,7 +515,17 @@ func (pcs *BaiduPCS) PrepareUploadTmpFile(uploadFunc UploadFunc) (dataReadCloser
 
 // PrepareUploadCreateSuperFile 分片上传—合并分片文件, 只返回服务器响应数据和错误信息
 func (pcs *BaiduPCS) PrepareUploadCreateSuperFile(targetPath string, blockList ...string) (dataReadCloser io.ReadCloser, pcsError pcserror.Error) {
+	return pcs.PrepareUploadCreateSuperFileWithOnDup(targetPath, OnDupDefault, blockList...)
+}
+
+// PrepareUploadCreateSuperFileWithOnDup 分片上传—合并分片文件, ondup 为目标文件已存在时的处理方式, 只返回服务器响应数据和错误信息
+func (pcs *BaiduPCS) PrepareUploadCreateSuperFileWithOnDup(targetPath string, ondup OnDup, blockList ...string) (dataReadCloser io.ReadCloser, pcsError pcserror.Error) {
 	pcs.lazyInit()
+	pcsError = pcs.checkOnDupSkip(OperationUploadCreateSuperFile, targetPath, ondup)
+	if pcsError != nil {
+		return nil, pcsError
+	}
+
 	bl := BlockListJSON{
 		BlockList: blockList,
 	}
@@ -418,10 +535,11 @@ func (pcs *BaiduPCS) PrepareUploadCreateSuperFile(targetPath string, blockList .
 		panic(err)
 	}
 
-	pcsURL := pcs.generatePCSURL("file", "createsuperfile", map[string]string{
-		"path":  targetPath,
-		"ondup": "newcopy",
-	})
+	param := map[string]string{
+		"path": targetPath,
+	}
+	setOnDupParam(param, ondup, OnDupNewCopy)
+	pcsURL := pcs.generatePCSURL("file", "createsuperfile", param)
 	baiduPCSVerbose.Infof("%s URL: %s\n", OperationUploadCreateSuperFile, pcsURL)
 
 	// 表单上传
@@ -435,12 +553,18 @@ func (pcs *BaiduPCS) PrepareUploadCreateSuperFile(targetPath string, blockList .
 
 // PrepareUploadPrecreate 分片上传—Precreate, 只返回服务器响应数据和错误信息
 func (pcs *BaiduPCS) PrepareUploadPrecreate(targetPath, contentMD5, sliceMD5, crc32 string, size int64, bolckList ...string) (dataReadCloser io.ReadCloser, panError pcserror.Error) {
+	return pcs.PrepareUploadPrecreateWithOnDup(targetPath, OnDupDefault, contentMD5, sliceMD5, crc32, size, bolckList...)
+}
+
+// PrepareUploadPrecreateWithOnDup 分片上传—Precreate, ondup 为目标文件已存在时的处理方式, 只返回服务器响应数据和错误信息
+func (pcs *BaiduPCS) PrepareUploadPrecreateWithOnDup(targetPath string, ondup OnDup, contentMD5, sliceMD5, crc32 string, size int64, bolckList ...string) (dataReadCloser io.ReadCloser, panError pcserror.Error) {
 	pcs.lazyInit()
-	panURL := &url.URL{
-		Scheme: GetHTTPScheme(pcs.isHTTPS),
-		Host:   PanBaiduCom,
-		Path:   "api/precreate",
+	panError = pcs.checkOnDupSkip(OperationUploadPrecreate, targetPath, ondup)
+	if panError != nil {
+		return nil, panError
 	}
+
+	panURL := pcs.generatePanURL("precreate", nil)
 	baiduPCSVerbose.Infof("%s URL: %s\n", OperationUploadPrecreate, panURL)
 
 	dataReadCloser, panError = pcs.sendReqReturnReadCloser(reqTypePan, OperationUploadPrecreate, http.MethodPost, panURL.String(), map[string]string{
@@ -452,7 +576,7 @@ func (pcs *BaiduPCS) PrepareUploadPrecreate(targetPath, contentMD5, sliceMD5, cr
 		"content-md5":  contentMD5,
 		"slice-md5":    sliceMD5,
 		"contentCrc32": crc32,
-		"rtype":        "2",
+		"rtype":        ondup.precreateRType(),
 	}, map[string]string{
 		"Content-Type": "application/x-www-form-urlencoded",
 	})
@@ -472,6 +596,11 @@ func (pcs *BaiduPCS) PrepareUploadSuperfile2(uploadid, targetPath string, partse
 	})
 	baiduPCSVerbose.Infof("%s URL: %s\n", OperationUploadSuperfile2, pcsURL)
 
+	pcsError = pcs.beforeRequest(reqTypePCS, OperationUploadSuperfile2)
+	if pcsError != nil {
+		return
+	}
+
 	resp, err := uploadFunc(pcsURL.String(), pcs.client.Jar)
 	if err != nil {
 		handleRespClose(resp)
@@ -567,14 +696,67 @@ func (pcs *BaiduPCS) PrepareCloudDlClearTask() (dataReadCloser io.ReadCloser, pc
 	return
 }
 
+// PrepareCloudDlQueryTorrentInfo 查询网盘中的种子文件的信息, 只返回服务器响应数据和错误信息
+func (pcs *BaiduPCS) PrepareCloudDlQueryTorrentInfo(torrentPath string) (dataReadCloser io.ReadCloser, pcsError pcserror.Error) {
+	pcs.lazyInit()
+	pcsURL2 := pcs.generatePCSURL2("services/cloud_dl", "query_sinfo", map[string]string{
+		"source_path": torrentPath,
+		"type":        "2",
+	})
+	baiduPCSVerbose.Infof("%s URL: %s\n", OperationCloudDlQueryTorrentInfo, pcsURL2)
+
+	dataReadCloser, pcsError = pcs.sendReqReturnReadCloser(reqTypePCS, OperationCloudDlQueryTorrentInfo, http.MethodPost, pcsURL2.String(), nil, nil)
+	return
+}
+
+// PrepareCloudDlQueryMagnetInfo 查询磁力链接的信息, 只返回服务器响应数据和错误信息
+func (pcs *BaiduPCS) PrepareCloudDlQueryMagnetInfo(magnetURL, savePath string) (dataReadCloser io.ReadCloser, pcsError pcserror.Error) {
+	pcs.lazyInit()
+	pcsURL2 := pcs.generatePCSURL2("services/cloud_dl", "query_magnetinfo", map[string]string{
+		"source_url": magnetURL,
+		"save_path":  savePath,
+		"type":       "4",
+	})
+	baiduPCSVerbose.Infof("%s URL: %s\n", OperationCloudDlQueryMagnetInfo, pcsURL2)
+
+	dataReadCloser, pcsError = pcs.sendReqReturnReadCloser(reqTypePCS, OperationCloudDlQueryMagnetInfo, http.MethodPost, pcsURL2.String(), nil, nil)
+	return
+}
+
+// PrepareCloudDlAddBtTask 添加BT离线下载任务, 只返回服务器响应数据和错误信息.
+// source 为磁力链接或网盘中的种子文件路径, 种子文件需提供 fileSHA1, selectedIdx 为选择的文件序号, 从1开始
+func (pcs *BaiduPCS) PrepareCloudDlAddBtTask(source, fileSHA1, savePath string, selectedIdx []int) (dataReadCloser io.ReadCloser, pcsError pcserror.Error) {
+	pcs.lazyInit()
+	idxStrs := make([]string, 0, len(selectedIdx))
+	for _, idx := range selectedIdx {
+		idxStrs = append(idxStrs, strconv.Itoa(idx))
+	}
+
+	params := map[string]string{
+		"save_path":    savePath,
+		"selected_idx": strings.Join(idxStrs, ","),
+		"task_from":    "1",
+		"timeout":      "2147483647",
+	}
+	if IsMagnetURL(source) {
+		params["source_url"] = source
+		params["type"] = "4"
+	} else {
+		params["source_path"] = source
+		params["file_sha1"] = fileSHA1
+		params["type"] = "2"
+	}
+	pcsURL2 := pcs.generatePCSURL2("services/cloud_dl", "add_task", params)
+	baiduPCSVerbose.Infof("%s URL: %s\n", OperationCloudDlAddBtTask, pcsURL2)
+
+	dataReadCloser, pcsError = pcs.sendReqReturnReadCloser(reqTypePCS, OperationCloudDlAddBtTask, http.MethodPost, pcsURL2.String(), nil, nil)
+	return
+}
+
 // PrepareSharePSet 私密分享文件, 只返回服务器响应数据和错误信息
 func (pcs *BaiduPCS) PrepareSharePSet(paths []string, period int) (dataReadCloser io.ReadCloser, panError pcserror.Error) {
 	pcs.lazyInit()
-	panURL := &url.URL{
-		Scheme: GetHTTPScheme(pcs.isHTTPS),
-		Host:   PanBaiduCom,
-		Path:   "share/pset",
-	}
+	panURL := pcs.generatePanHomeURL("/share/pset", nil)
 	baiduPCSVerbose.Infof("%s URL: %s\n", OperationShareSet, panURL)
 
 	dataReadCloser, panError = pcs.sendReqReturnReadCloser(reqTypePan, OperationShareSet, http.MethodPost, panURL.String(), map[string]string{
@@ -593,11 +775,7 @@ func (pcs *BaiduPCS) PrepareSharePSet(paths []string, period int) (dataReadClose
 // PrepareShareCancel 取消分享, 只返回服务器响应数据和错误信息
 func (pcs *BaiduPCS) PrepareShareCancel(shareIDs []int64) (dataReadCloser io.ReadCloser, panError pcserror.Error) {
 	pcs.lazyInit()
-	panURL := &url.URL{
-		Scheme: GetHTTPScheme(pcs.isHTTPS),
-		Host:   PanBaiduCom,
-		Path:   "share/cancel",
-	}
+	panURL := pcs.generatePanHomeURL("/share/cancel", nil)
 
 	baiduPCSVerbose.Infof("%s URL: %s\n", OperationShareCancel, panURL)
 
@@ -614,17 +792,11 @@ func (pcs *BaiduPCS) PrepareShareCancel(shareIDs []int64) (dataReadCloser io.Rea
 func (pcs *BaiduPCS) PrepareShareList(page int) (dataReadCloser io.ReadCloser, panError pcserror.Error) {
 	pcs.lazyInit()
 
-	query := url.Values{}
-	query.Set("page", strconv.Itoa(page))
-	query.Set("desc", "1")
-	query.Set("order", "time")
-
-	panURL := &url.URL{
-		Scheme:   GetHTTPScheme(pcs.isHTTPS),
-		Host:     PanBaiduCom,
-		Path:     "share/record",
-		RawQuery: query.Encode(),
-	}
+	panURL := pcs.generatePanHomeURL("/share/record", map[string]string{
+		"page":  strconv.Itoa(page),
+		"desc":  "1",
+		"order": "time",
+	})
 	baiduPCSVerbose.Infof("%s URL: %s\n", OperationShareList, panURL)
 
 	dataReadCloser, panError = pcs.sendReqReturnReadCloser(reqTypePan, OperationShareList, http.MethodGet, panURL.String(), nil, nil)
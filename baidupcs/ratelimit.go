@@ -0,0 +1,282 @@
+package baidupcs
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+type (
+	// OpClass 操作类别, 用于请求限流
+	OpClass int
+
+	// RateLimit 请求频率限制, 单位为 次/秒, 0 代表不限制,
+	// 请求需同时满足所属 api 地址 (PCS/网盘首页) 和所属操作类别的限制
+	RateLimit struct {
+		PCS     float64             // PCS api 请求频率
+		Pan     float64             // 网盘首页 api 请求频率
+		Classes map[OpClass]float64 // 各操作类别的请求频率
+	}
+
+	// rateLimiter 请求限流器
+	rateLimiter struct {
+		pcs     *tokenBucket
+		pan     *tokenBucket
+		classes map[OpClass]*tokenBucket
+	}
+
+	// tokenBucket 令牌桶
+	tokenBucket struct {
+		mu     sync.Mutex
+		rate   float64 // 每秒产生的令牌数
+		burst  float64 // 令牌桶容量
+		tokens float64
+		last   time.Time
+	}
+)
+
+const (
+	// OpClassRead 读取类操作, 如列目录, 获取元信息, 搜索
+	OpClassRead OpClass = iota
+	// OpClassWrite 修改类操作, 如创建目录, 删除, 拷贝, 移动, 分享
+	OpClassWrite
+	// OpClassUpload 上传类操作
+	OpClassUpload
+	// OpClassDownload 获取下载链接类操作
+	OpClassDownload
+)
+
+var (
+	opClassNames = map[OpClass]string{
+		OpClassRead:     "read",
+		OpClassWrite:    "write",
+		OpClassUpload:   "upload",
+		OpClassDownload: "download",
+	}
+)
+
+func (oc OpClass) String() string {
+	name, ok := opClassNames[oc]
+	if !ok {
+		return "OpClass(" + strconv.Itoa(int(oc)) + ")"
+	}
+	return name
+}
+
+// OperationClass 返回操作所属的类别
+func OperationClass(op string) OpClass {
+	switch op {
+	case OperationRemove, OperationMkdir, OperationRename, OperationCopy, OperationMove,
+		OperationCloudDlAddTask, OperationCloudDlCancelTask, OperationCloudDlDeleteTask, OperationCloudDlClearTask,
+		OperationShareSet, OperationShareCancel,
+		OperationRecycleRestore, OperationRecycleDelete, OperationRecycleClear:
+		return OpClassWrite
+	case OperationRapidUpload, OperationUpload, OperationUploadTmpFile, OperationUploadCreateSuperFile,
+		OperationUploadPrecreate, OperationUploadSuperfile2:
+		return OpClassUpload
+	case OperationDownloadFile, OperationDownloadStreamFile, OperationLocateDownload, OperationLocatePanAPIDownload:
+		return OpClassDownload
+	}
+	return OpClassRead
+}
+
+// NewDefaultRateLimit 返回推荐的请求频率限制, 默认不限制, 需要时可使用此设置
+func NewDefaultRateLimit() *RateLimit {
+	return &RateLimit{
+		PCS: 10,
+		Pan: 5,
+	}
+}
+
+// ParseRateLimit 解析请求频率限制,
+// 格式如 pcs=10,pan=5,write=2, 可用的键为 pcs, pan, 以及操作类别 read, write, upload, download,
+// 为空则不限制
+func ParseRateLimit(s string) (*RateLimit, error) {
+	rl := &RateLimit{}
+	for _, item := range strings.Split(s, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+
+		kv := strings.SplitN(item, "=", 2)
+		if len(kv) != 2 {
+			return nil, fmt.Errorf("格式错误: %s", item)
+		}
+		key := strings.ToLower(strings.TrimSpace(kv[0]))
+		rate, err := strconv.ParseFloat(strings.TrimSpace(kv[1]), 64)
+		if err != nil || rate < 0 || math.IsInf(rate, 0) || math.IsNaN(rate) {
+			return nil, fmt.Errorf("频率错误: %s", item)
+		}
+
+		switch key {
+		case "pcs":
+			rl.PCS = rate
+			continue
+		case "pan":
+			rl.Pan = rate
+			continue
+		}
+
+		found := false
+		for oc, name := range opClassNames {
+			if name == key {
+				if rl.Classes == nil {
+					rl.Classes = map[OpClass]float64{}
+				}
+				rl.Classes[oc] = rate
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("未知的限制项: %s", key)
+		}
+	}
+	return rl, nil
+}
+
+// String 返回 ParseRateLimit 可解析的格式
+func (rl *RateLimit) String() string {
+	if rl == nil {
+		return ""
+	}
+
+	items := make([]string, 0, len(rl.Classes)+2)
+	if rl.PCS > 0 {
+		items = append(items, "pcs="+strconv.FormatFloat(rl.PCS, 'f', -1, 64))
+	}
+	if rl.Pan > 0 {
+		items = append(items, "pan="+strconv.FormatFloat(rl.Pan, 'f', -1, 64))
+	}
+
+	classes := make([]int, 0, len(rl.Classes))
+	for oc := range rl.Classes {
+		classes = append(classes, int(oc))
+	}
+	sort.Ints(classes)
+	for _, oc := range classes {
+		rate := rl.Classes[OpClass(oc)]
+		if rate > 0 {
+			items = append(items, OpClass(oc).String()+"="+strconv.FormatFloat(rate, 'f', -1, 64))
+		}
+	}
+	return strings.Join(items, ",")
+}
+
+func newRateLimiter(rl *RateLimit) *rateLimiter {
+	if rl == nil {
+		return nil
+	}
+
+	limiter := &rateLimiter{
+		pcs:     newTokenBucket(rl.PCS),
+		pan:     newTokenBucket(rl.Pan),
+		classes: map[OpClass]*tokenBucket{},
+	}
+	for oc, rate := range rl.Classes {
+		if tb := newTokenBucket(rate); tb != nil {
+			limiter.classes[oc] = tb
+		}
+	}
+	return limiter
+}
+
+// wait 等待直到请求被允许, ctx 取消或超时则返回错误
+func (limiter *rateLimiter) wait(ctx context.Context, rt reqType, op string) error {
+	if limiter == nil {
+		return nil
+	}
+
+	var hostBucket *tokenBucket
+	switch rt {
+	case reqTypePCS:
+		hostBucket = limiter.pcs
+	case reqTypePan:
+		hostBucket = limiter.pan
+	}
+
+	err := hostBucket.wait(ctx)
+	if err != nil {
+		return err
+	}
+	err = limiter.classes[OperationClass(op)].wait(ctx)
+	if err != nil {
+		// 请求未发出, 归还已取出的令牌
+		hostBucket.release()
+		return err
+	}
+	return nil
+}
+
+// newTokenBucket 每秒产生 rate 个令牌, 容量为 rate 向上取整, rate <= 0 返回 nil, 代表不限制
+func newTokenBucket(rate float64) *tokenBucket {
+	if rate <= 0 {
+		return nil
+	}
+	burst := math.Ceil(rate)
+	return &tokenBucket{
+		rate:   rate,
+		burst:  burst,
+		tokens: burst,
+		last:   time.Now(),
+	}
+}
+
+// reserve 取出一个令牌, 返回需要等待的时间
+func (tb *tokenBucket) reserve() time.Duration {
+	tb.mu.Lock()
+	defer tb.mu.Unlock()
+
+	now := time.Now()
+	tb.tokens += now.Sub(tb.last).Seconds() * tb.rate
+	if tb.tokens > tb.burst {
+		tb.tokens = tb.burst
+	}
+	tb.last = now
+
+	tb.tokens--
+	if tb.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-tb.tokens / tb.rate * float64(time.Second))
+}
+
+// release 归还一个取出后未使用的令牌
+func (tb *tokenBucket) release() {
+	if tb == nil {
+		return
+	}
+	tb.mu.Lock()
+	defer tb.mu.Unlock()
+	tb.tokens++
+	if tb.tokens > tb.burst {
+		tb.tokens = tb.burst
+	}
+}
+
+func (tb *tokenBucket) wait(ctx context.Context) error {
+	if tb == nil {
+		return nil
+	}
+
+	d := tb.reserve()
+	if d <= 0 {
+		return nil
+	}
+
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		tb.release()
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,93 @@
+package baidupcs_test
+
+import (
+	"context"
+	"github.com/Erope/BaiduPCS-Go/baidupcs"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestParseRateLimit(t *testing.T) {
+	rl, err := baidupcs.ParseRateLimit(" pan=5, pcs=10,write=0.5,read=3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rl.PCS != 10 || rl.Pan != 5 || rl.Classes[baidupcs.OpClassWrite] != 0.5 || rl.Classes[baidupcs.OpClassRead] != 3 {
+		t.Fatalf("unexpected rate limit: %+v", rl)
+	}
+	if rl.String() != "pcs=10,pan=5,read=3,write=0.5" {
+		t.Fatalf("unexpected string: %s", rl)
+	}
+
+	for _, s := range []string{"pcs", "pcs=-1", "unknown=1", "pan=abc"} {
+		if _, err = baidupcs.ParseRateLimit(s); err == nil {
+			t.Fatalf("expect error: %s", s)
+		}
+	}
+}
+
+func TestRateLimit(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"quota":100,"used":10}`))
+	}))
+	defer server.Close()
+
+	pcs := baidupcs.NewPCS(266719, "")
+	if err := pcs.SetPCSAddr(server.URL); err != nil {
+		t.Fatal(err)
+	}
+	pcs.SetRateLimit(&baidupcs.RateLimit{
+		PCS: 100,
+		Classes: map[baidupcs.OpClass]float64{
+			baidupcs.OpClassRead: 20,
+		},
+	})
+
+	// 容量为20, 之后每个请求间隔50ms
+	start := time.Now()
+	for i := 0; i < 25; i++ {
+		if _, _, err := pcs.QuotaInfo(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if elapsed := time.Since(start); elapsed < 200*time.Millisecond {
+		t.Fatalf("rate limit not work, elapsed: %s", elapsed)
+	}
+}
+
+func TestRateLimitCancel(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"quota":100,"used":10}`))
+	}))
+	defer server.Close()
+
+	pcs := baidupcs.NewPCS(266719, "")
+	if err := pcs.SetPCSAddr(server.URL); err != nil {
+		t.Fatal(err)
+	}
+	pcs.SetRateLimit(&baidupcs.RateLimit{PCS: 10})
+
+	for i := 0; i < 10; i++ {
+		if _, _, err := pcs.QuotaInfo(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// 等待时取消的请求归还令牌, 不影响之后的请求
+	for i := 0; i < 20; i++ {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+		if _, _, err := pcs.WithContext(ctx).QuotaInfo(); err == nil {
+			t.Fatal("expect error")
+		}
+		cancel()
+	}
+	start := time.Now()
+	if _, _, err := pcs.QuotaInfo(); err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("canceled requests consumed tokens, elapsed: %s", elapsed)
+	}
+}
@@ -80,7 +80,13 @@ func (pcs *BaiduPCS) RecycleRestore(fidList ...int64) (sussFsIDList []*FsIDJSON,
 	}
 
 	pcsError = pcserror.HandleJSONParse(OperationRecycleRestore, dataReadCloser, &jsonData)
-	return jsonData.Extra.List, pcsError
+	if pcsError != nil {
+		return nil, pcsError
+	}
+
+	// 还原的结果不含原路径, 删除所有缓存
+	pcs.deleteAllCache()
+	return jsonData.Extra.List, nil
 }
 
 // RecycleDelete 删除回收站文件或目录
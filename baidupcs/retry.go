@@ -0,0 +1,223 @@
+package baidupcs
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"github.com/Erope/BaiduPCS-Go/baidupcs/pcserror"
+	"github.com/json-iterator/go"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	// DefaultMaxAttempts 默认的最大请求次数, 包括第一次请求
+	DefaultMaxAttempts = 3
+
+	// retryPeekSize 检测响应中的错误代码时, 最多读取的响应数据大小
+	retryPeekSize = 64 * 1024
+)
+
+const (
+	// ErrCodeTooFrequent 请求过于频繁
+	ErrCodeTooFrequent = 31034
+)
+
+type (
+	// RetryPolicy 请求重试策略
+	RetryPolicy struct {
+		MaxAttempts          int                // 最大请求次数, 包括第一次请求, 小于等于1时不重试
+		BaseDelay            time.Duration      // 第一次重试前的等待时间, 之后按指数增长
+		MaxDelay             time.Duration      // 最大等待时间
+		RetryableErrTypes    []pcserror.ErrType // 除 pcserror.IsRetryable 判断可重试的错误外, 额外可重试的错误类型
+		RetryableRemoteCodes []int              // 除 pcserror.IsRetryable 判断可重试的错误外, 额外可重试的远端服务器错误代码
+		RetryOnServerError   bool               // 是否重试 http 5xx 响应
+
+		// RetryNonIdempotent 是否重试非幂等的操作 (如删除, 移动, 拷贝, 添加离线下载任务) 的网络错误和 http 5xx 响应,
+		// 响应丢失时操作可能已经执行, 重试会重复执行或误报失败. 服务器明确拒绝的请求 (如请求过于频繁) 总是可以重试
+		RetryNonIdempotent bool
+	}
+)
+
+var (
+	// idempotentOperations 可以安全地重复请求的操作, 只读取数据或重复执行结果不变
+	idempotentOperations = map[string]bool{
+		OperationGetUK:                   true,
+		OperationQuotaInfo:               true,
+		OperationFilesDirectoriesMeta:    true,
+		OperationFilesDirectoriesList:    true,
+		OperationSearch:                  true,
+		OperationUploadTmpFile:           true,
+		OperationUploadPrecreate:         true,
+		OperationUploadSuperfile2:        true,
+		OperationLocateDownload:          true,
+		OperationLocatePanAPIDownload:    true,
+		OperationCloudDlQueryTask:        true,
+		OperationCloudDlListTask:         true,
+		OperationCloudDlQueryTorrentInfo: true,
+		OperationCloudDlQueryMagnetInfo:  true,
+		OperationShareList:               true,
+		OperationRecycleList:             true,
+		OperationExportFileInfo:          true,
+		OperationGetRapidUploadInfo:      true,
+	}
+)
+
+// NewDefaultRetryPolicy 返回默认的重试策略,
+// 重试 pcserror.IsRetryable 判断可重试的错误 (如网络错误, 请求过于频繁) 和 http 5xx 响应,
+// 非幂等的操作只重试请求过于频繁
+func NewDefaultRetryPolicy() *RetryPolicy {
+	return &RetryPolicy{
+		MaxAttempts:        DefaultMaxAttempts,
+		BaseDelay:          500 * time.Millisecond,
+		MaxDelay:           10 * time.Second,
+		RetryOnServerError: true,
+	}
+}
+
+// Backoff 返回第 attempt 次重试前的等待时间 (attempt 从1开始),
+// 指数增长, 带随机抖动
+func (rp *RetryPolicy) Backoff(attempt int) time.Duration {
+	if attempt < 1 {
+		attempt = 1
+	}
+	d := rp.BaseDelay
+	for i := 1; i < attempt && (rp.MaxDelay <= 0 || d < rp.MaxDelay); i++ {
+		d *= 2
+	}
+	if rp.MaxDelay > 0 && d > rp.MaxDelay {
+		d = rp.MaxDelay
+	}
+	if d <= 0 {
+		return 0
+	}
+
+	// 在 [d/2, d) 之间随机
+	half := d / 2
+	return half + time.Duration(rand.Int63n(int64(d-half)))
+}
+
+// IsRetryable 判断错误是否可重试, 先按 pcserror.IsRetryable 判断, 再按策略额外指定的错误类型和错误代码判断
+func (rp *RetryPolicy) IsRetryable(pcsError pcserror.Error) bool {
+	if pcsError == nil {
+		return false
+	}
+	if pcserror.IsRetryable(pcsError) {
+		return true
+	}
+
+	switch pcsError.GetErrType() {
+	case pcserror.ErrTypeRemoteError:
+		return rp.isRetryableRemoteCode(pcsError.GetRemoteErrCode())
+	}
+
+	for _, errType := range rp.RetryableErrTypes {
+		if pcsError.GetErrType() == errType {
+			return true
+		}
+	}
+	return false
+}
+
+// IsIdempotentOperation 判断操作 op 能否安全地重复请求
+func IsIdempotentOperation(op string) bool {
+	return idempotentOperations[op]
+}
+
+// canRetryUnknownResult 判断请求结果未知 (网络错误, http 5xx 响应) 时, 操作 op 能否重试
+func (rp *RetryPolicy) canRetryUnknownResult(op string) bool {
+	return rp.RetryNonIdempotent || IsIdempotentOperation(op)
+}
+
+func (rp *RetryPolicy) isRetryableRemoteCode(code int) bool {
+	if code == 0 {
+		return false
+	}
+	for _, c := range rp.RetryableRemoteCodes {
+		if c == code {
+			return true
+		}
+	}
+	return false
+}
+
+// Sleep 等待第 attempt 次重试, ctx 取消或超时则立即返回错误
+func (rp *RetryPolicy) Sleep(ctx context.Context, attempt int) error {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	t := time.NewTimer(rp.Backoff(attempt))
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// retryableResponse 检测操作 op 的响应是否需要重试, 必要时读取部分响应数据,
+// 读取过的数据会重新放回 resp.Body
+func (rp *RetryPolicy) retryableResponse(rt reqType, op string, resp *http.Response) bool {
+	if resp.StatusCode/100 == 5 {
+		return rp.RetryOnServerError && rp.canRetryUnknownResult(op)
+	}
+	if resp.ContentLength > retryPeekSize {
+		return false
+	}
+
+	contentType := resp.Header.Get("Content-Type")
+	if contentType != "" && !strings.Contains(contentType, "json") && !strings.HasPrefix(contentType, "text/") {
+		return false
+	}
+
+	data, err := ioutil.ReadAll(io.LimitReader(resp.Body, retryPeekSize+1))
+	resp.Body = &multiReadCloser{
+		Reader: io.MultiReader(bytes.NewReader(data), resp.Body),
+		Closer: resp.Body,
+	}
+	if err != nil || len(data) > retryPeekSize {
+		return false
+	}
+
+	code := jsoniter.Get(data, "error_code").ToInt()
+	if code == 0 {
+		code = jsoniter.Get(data, "errno").ToInt()
+	}
+	if code == 0 {
+		return false
+	}
+
+	pcsError := newRemoteErrorInfo(rt, op, code)
+	if !rp.IsRetryable(pcsError) {
+		return false
+	}
+	// 服务器明确拒绝的请求总是可以重试, 其他错误 (如服务器网络错误) 操作可能已经执行
+	return errors.Is(pcsError, pcserror.ErrRateLimited) || rp.canRetryUnknownResult(op)
+}
+
+type multiReadCloser struct {
+	io.Reader
+	io.Closer
+}
+
+// replayablePost 判断 post 数据能否重复发送, 可以则返回每次发送前的重置函数
+func replayablePost(post interface{}) (reset func() error, ok bool) {
+	switch value := post.(type) {
+	case nil, string, []byte, map[string]string, map[string]interface{}, map[interface{}]interface{}:
+		return func() error { return nil }, true
+	case io.Seeker:
+		if _, isReader := value.(io.Reader); !isReader {
+			return nil, false
+		}
+		return func() error {
+			_, err := value.Seek(0, io.SeekStart)
+			return err
+		}, true
+	}
+	return nil, false
+}
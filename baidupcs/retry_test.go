@@ -0,0 +1,160 @@
+package baidupcs_test
+
+import (
+	"github.com/Erope/BaiduPCS-Go/baidupcs"
+	"github.com/Erope/BaiduPCS-Go/baidupcs/pcserror"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func newRetryTestPCS(t *testing.T, handler http.HandlerFunc, maxAttempts int) (*baidupcs.BaiduPCS, func()) {
+	server := httptest.NewServer(handler)
+	pcs := baidupcs.NewPCS(266719, "")
+	if err := pcs.SetPCSAddr(server.URL); err != nil {
+		t.Fatal(err)
+	}
+
+	rp := baidupcs.NewDefaultRetryPolicy()
+	rp.MaxAttempts = maxAttempts
+	rp.BaseDelay = time.Millisecond
+	pcs.SetRetryPolicy(rp)
+	return pcs, server.Close
+}
+
+func TestRetryTooFrequent(t *testing.T) {
+	var count int32
+	pcs, closeFn := newRetryTestPCS(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if atomic.AddInt32(&count, 1) <= 2 {
+			w.WriteHeader(http.StatusForbidden)
+			w.Write([]byte(`{"error_code":31034,"error_msg":"hit frequent"}`))
+			return
+		}
+		w.Write([]byte(`{"quota":100,"used":10}`))
+	}, 3)
+	defer closeFn()
+
+	quota, used, err := pcs.QuotaInfo()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if quota != 100 || used != 10 || count != 3 {
+		t.Fatalf("quota: %d, used: %d, count: %d", quota, used, count)
+	}
+}
+
+func TestRetryNotRetryable(t *testing.T) {
+	var count int32
+	pcs, closeFn := newRetryTestPCS(t, func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&count, 1)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"error_code":31066,"error_msg":"file does not exist"}`))
+	}, 3)
+	defer closeFn()
+
+	_, _, err := pcs.QuotaInfo()
+	if err == nil || err.GetRemoteErrCode() != 31066 {
+		t.Fatalf("expect 31066, got %v", err)
+	}
+	if count != 1 {
+		t.Fatalf("expect no retry, count: %d", count)
+	}
+}
+
+func TestRetryServerError(t *testing.T) {
+	var count int32
+	pcs, closeFn := newRetryTestPCS(t, func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&count, 1)
+		w.WriteHeader(http.StatusBadGateway)
+	}, 2)
+	defer closeFn()
+
+	_, _, err := pcs.QuotaInfo()
+	if err == nil {
+		t.Fatal("expect error")
+	}
+	if count != 2 {
+		t.Fatalf("expect 2 attempts, count: %d", count)
+	}
+}
+
+func TestRetryNonIdempotent(t *testing.T) {
+	var count int32
+	pcs, closeFn := newRetryTestPCS(t, func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&count, 1)
+		w.WriteHeader(http.StatusBadGateway)
+	}, 3)
+	defer closeFn()
+
+	// 创建目录可能已经执行, 不重试
+	if err := pcs.Mkdir("/a"); err == nil {
+		t.Fatal("expect error")
+	}
+	if count != 1 {
+		t.Fatalf("expect no retry, count: %d", count)
+	}
+
+	// 允许重试非幂等的操作
+	rp := pcs.RetryPolicy()
+	rp.RetryNonIdempotent = true
+	pcs.SetRetryPolicy(rp)
+	atomic.StoreInt32(&count, 0)
+	pcs.Mkdir("/a")
+	if count != 3 {
+		t.Fatalf("expect 3 attempts, count: %d", count)
+	}
+}
+
+func TestRetryNonIdempotentTooFrequent(t *testing.T) {
+	var count int32
+	pcs, closeFn := newRetryTestPCS(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if atomic.AddInt32(&count, 1) <= 1 {
+			w.WriteHeader(http.StatusForbidden)
+			w.Write([]byte(`{"error_code":31034,"error_msg":"hit frequent"}`))
+			return
+		}
+		w.Write([]byte(`{"request_id":1}`))
+	}, 3)
+	defer closeFn()
+
+	// 服务器拒绝的请求未执行, 可以重试
+	if err := pcs.Mkdir("/a"); err != nil {
+		t.Fatal(err)
+	}
+	if count != 2 {
+		t.Fatalf("expect 2 attempts, count: %d", count)
+	}
+}
+
+func TestRetryRemoteNetError(t *testing.T) {
+	var count int32
+	pcs, closeFn := newRetryTestPCS(t, func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&count, 1)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"error_code":31021,"error_msg":"network error"}`))
+	}, 3)
+	defer closeFn()
+
+	// 与 pcserror.IsRetryable 一致, 幂等的操作重试服务器网络错误
+	if _, _, err := pcs.QuotaInfo(); err == nil || !pcserror.IsRetryable(err) {
+		t.Fatalf("expect retryable error, got %v", err)
+	}
+	if count != 3 {
+		t.Fatalf("expect 3 attempts, count: %d", count)
+	}
+
+	// 非幂等的操作可能已经执行, 不重试
+	atomic.StoreInt32(&count, 0)
+	if err := pcs.Mkdir("/a"); err == nil {
+		t.Fatal("expect error")
+	}
+	if count != 1 {
+		t.Fatalf("expect no retry, count: %d", count)
+	}
+}
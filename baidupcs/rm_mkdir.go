@@ -5,8 +5,13 @@ import (
 	"path"
 )
 
-// Remove 批量删除文件/目录
+// Remove 批量删除文件/目录, 自动分块, 返回第一个失败的项的错误
 func (pcs *BaiduPCS) Remove(paths ...string) (pcsError pcserror.Error) {
+	return pcs.BatchRemove(paths...).Err()
+}
+
+// remove 发送一次删除请求, 不更新缓存
+func (pcs *BaiduPCS) remove(paths ...string) (pcsError pcserror.Error) {
 	dataReadCloser, pcsError := pcs.PrepareRemove(paths...)
 	if pcsError != nil {
 		return
@@ -18,9 +23,6 @@ func (pcs *BaiduPCS) Remove(paths ...string) (pcsError pcserror.Error) {
 	if errInfo != nil {
 		return errInfo
 	}
-
-	// 更新缓存
-	pcs.deleteCache(allRelatedDir(paths))
 	return nil
 }
 
@@ -13,6 +13,8 @@ const (
 	MaxUploadBlockSize = 2 * converter.GB
 	// MinUploadBlockSize 最小的上传的文件分片大小
 	MinUploadBlockSize = 4 * converter.MB
+	// MaxUploadBlockNum 合并分片文件时最多的分片数量
+	MaxUploadBlockNum = 1024
 	// MaxRapidUploadSize 秒传文件支持的最大文件大小
 	MaxRapidUploadSize = 20 * converter.GB
 	// RecommendUploadBlockSize 推荐的上传的文件分片大小
@@ -33,7 +35,7 @@ var (
 )
 
 type (
-	// UploadFunc 上传文件处理函数
+	// UploadFunc 上传文件处理函数, 应使用 BaiduPCS.Context 发起请求, 以便取消正在进行的上传
 	UploadFunc func(uploadURL string, jar http.CookieJar) (resp *http.Response, err error)
 
 	// RapidUploadInfo 文件秒传信息
@@ -84,7 +86,12 @@ type (
 
 // RapidUpload 秒传文件
 func (pcs *BaiduPCS) RapidUpload(targetPath, contentMD5, sliceMD5, crc32 string, length int64) (pcsError pcserror.Error) {
-	dataReadCloser, pcsError := pcs.PrepareRapidUpload(targetPath, contentMD5, sliceMD5, crc32, length)
+	return pcs.RapidUploadWithOnDup(targetPath, OnDupDefault, contentMD5, sliceMD5, crc32, length)
+}
+
+// RapidUploadWithOnDup 秒传文件, ondup 为目标文件已存在时的处理方式
+func (pcs *BaiduPCS) RapidUploadWithOnDup(targetPath string, ondup OnDup, contentMD5, sliceMD5, crc32 string, length int64) (pcsError pcserror.Error) {
+	dataReadCloser, pcsError := pcs.PrepareRapidUploadWithOnDup(targetPath, ondup, contentMD5, sliceMD5, crc32, length)
 	if pcsError != nil {
 		return
 	}
@@ -103,7 +110,13 @@ func (pcs *BaiduPCS) RapidUpload(targetPath, contentMD5, sliceMD5, crc32 string,
 
 // RapidUploadNoCheckDir 秒传文件, 不进行目录检查, 会覆盖掉同名的目录!
 func (pcs *BaiduPCS) RapidUploadNoCheckDir(targetPath, contentMD5, sliceMD5, crc32 string, length int64) (pcsError pcserror.Error) {
-	dataReadCloser, pcsError := pcs.prepareRapidUpload(targetPath, contentMD5, sliceMD5, crc32, length)
+	return pcs.RapidUploadNoCheckDirWithOnDup(targetPath, OnDupDefault, contentMD5, sliceMD5, crc32, length)
+}
+
+// RapidUploadNoCheckDirWithOnDup 秒传文件, 不进行目录检查, 会覆盖掉同名的目录!
+// 由于不进行检查, ondup 为 OnDupSkip 时按 OnDupFail 处理
+func (pcs *BaiduPCS) RapidUploadNoCheckDirWithOnDup(targetPath string, ondup OnDup, contentMD5, sliceMD5, crc32 string, length int64) (pcsError pcserror.Error) {
+	dataReadCloser, pcsError := pcs.prepareRapidUpload(targetPath, ondup, contentMD5, sliceMD5, crc32, length)
 	if pcsError != nil {
 		return
 	}
@@ -120,7 +133,12 @@ func (pcs *BaiduPCS) RapidUploadNoCheckDir(targetPath, contentMD5, sliceMD5, crc
 
 // Upload 上传单个文件
 func (pcs *BaiduPCS) Upload(targetPath string, uploadFunc UploadFunc) (pcsError pcserror.Error) {
-	dataReadCloser, pcsError := pcs.PrepareUpload(targetPath, uploadFunc)
+	return pcs.UploadWithOnDup(targetPath, OnDupDefault, uploadFunc)
+}
+
+// UploadWithOnDup 上传单个文件, ondup 为目标文件已存在时的处理方式
+func (pcs *BaiduPCS) UploadWithOnDup(targetPath string, ondup OnDup, uploadFunc UploadFunc) (pcsError pcserror.Error) {
+	dataReadCloser, pcsError := pcs.PrepareUploadWithOnDup(targetPath, ondup, uploadFunc)
 	if pcsError != nil {
 		return pcsError
 	}
@@ -179,7 +197,12 @@ func (pcs *BaiduPCS) UploadTmpFile(uploadFunc UploadFunc) (md5 string, pcsError
 
 // UploadCreateSuperFile 分片上传—合并分片文件
 func (pcs *BaiduPCS) UploadCreateSuperFile(targetPath string, blockList ...string) (pcsError pcserror.Error) {
-	dataReadCloser, pcsError := pcs.PrepareUploadCreateSuperFile(targetPath, blockList...)
+	return pcs.UploadCreateSuperFileWithOnDup(targetPath, OnDupDefault, blockList...)
+}
+
+// UploadCreateSuperFileWithOnDup 分片上传—合并分片文件, ondup 为目标文件已存在时的处理方式
+func (pcs *BaiduPCS) UploadCreateSuperFileWithOnDup(targetPath string, ondup OnDup, blockList ...string) (pcsError pcserror.Error) {
+	dataReadCloser, pcsError := pcs.PrepareUploadCreateSuperFileWithOnDup(targetPath, ondup, blockList...)
 	if pcsError != nil {
 		return pcsError
 	}
@@ -199,7 +222,13 @@ func (pcs *BaiduPCS) UploadCreateSuperFile(targetPath string, blockList ...strin
 // UploadPrecreate 分片上传—Precreate,
 // 支持检验秒传
 func (pcs *BaiduPCS) UploadPrecreate(targetPath, contentMD5, sliceMD5, crc32 string, size int64, bolckList ...string) (precreateInfo *PrecreateInfo, pcsError pcserror.Error) {
-	dataReadCloser, pcsError := pcs.PrepareUploadPrecreate(targetPath, contentMD5, sliceMD5, crc32, size, bolckList...)
+	return pcs.UploadPrecreateWithOnDup(targetPath, OnDupDefault, contentMD5, sliceMD5, crc32, size, bolckList...)
+}
+
+// UploadPrecreateWithOnDup 分片上传—Precreate, ondup 为目标文件已存在时的处理方式,
+// 支持检验秒传
+func (pcs *BaiduPCS) UploadPrecreateWithOnDup(targetPath string, ondup OnDup, contentMD5, sliceMD5, crc32 string, size int64, bolckList ...string) (precreateInfo *PrecreateInfo, pcsError pcserror.Error) {
+	dataReadCloser, pcsError := pcs.PrepareUploadPrecreateWithOnDup(targetPath, ondup, contentMD5, sliceMD5, crc32, size, bolckList...)
 	if pcsError != nil {
 		return
 	}
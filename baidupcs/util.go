@@ -2,9 +2,11 @@ package baidupcs
 
 import (
 	"errors"
+	"fmt"
 	"github.com/Erope/BaiduPCS-Go/baidupcs/pcserror"
 	"github.com/Erope/BaiduPCS-Go/pcsutil"
 	"github.com/Erope/BaiduPCS-Go/pcsutil/converter"
+	"net/url"
 	"path"
 	"strings"
 )
@@ -23,7 +25,8 @@ func (pcs *BaiduPCS) Isdir(pcspath string) (isdir bool, pcsError pcserror.Error)
 	return f.Isdir, nil
 }
 
-func (pcs *BaiduPCS) checkIsdir(op string, targetPath string) pcserror.Error {
+// checkIsdir 检查保存路径, 保存路径为目录时返回错误; ondup 为 OnDupSkip 且保存路径已存在时, 返回 ErrOnDupSkipped
+func (pcs *BaiduPCS) checkIsdir(op string, targetPath string, ondup OnDup) pcserror.Error {
 	// 检测文件是否存在于网盘路径
 	// 很重要, 如果文件存在会直接覆盖!!! 即使是根目录!
 	isdir, pcsError := pcs.Isdir(targetPath)
@@ -40,6 +43,9 @@ func (pcs *BaiduPCS) checkIsdir(op string, targetPath string) pcserror.Error {
 		errInfo.Err = errors.New("保存路径不可以覆盖目录")
 		return errInfo
 	}
+	if pcsError == nil && ondup == OnDupSkip {
+		return newOnDupSkippedError(op)
+	}
 	return nil
 }
 
@@ -71,3 +77,27 @@ func GetHTTPScheme(https bool) (scheme string) {
 	}
 	return "http"
 }
+
+// parseAddr 解析自定义的 api 地址, 未指定协议时使用 http
+func parseAddr(addr string) (*url.URL, error) {
+	if addr == "" {
+		return nil, nil
+	}
+	if !strings.Contains(addr, "://") {
+		addr = "http://" + addr
+	}
+
+	u, err := url.Parse(addr)
+	if err != nil {
+		return nil, err
+	}
+	if u.Host == "" {
+		return nil, fmt.Errorf("invalid addr: %s", addr)
+	}
+
+	u.Path = strings.TrimSuffix(u.Path, "/")
+	u.RawPath = ""
+	u.RawQuery = ""
+	u.Fragment = ""
+	return u, nil
+}
@@ -0,0 +1,245 @@
+package baidupcs
+
+import (
+	"errors"
+	"github.com/Erope/BaiduPCS-Go/baidupcs/pcserror"
+	"sync"
+)
+
+const (
+	// DefaultWalkParallel 默认的遍历目录并发量
+	DefaultWalkParallel = 4
+)
+
+var (
+	// SkipDir 由 WalkFunc 返回, 跳过当前目录;
+	// 若当前项为文件, 则跳过其所在目录中剩余的项
+	SkipDir = errors.New("skip this directory")
+	// StopWalk 由 WalkFunc 返回, 停止遍历, Walk 返回 nil
+	StopWalk = errors.New("stop walk")
+)
+
+type (
+	// WalkFunc 遍历回调函数.
+	// 获取 fdPath 的元信息或列表出错时, fd 可能为空, pcsError 不为空,
+	// 此时返回 nil 会跳过该目录并继续遍历, 返回其他错误则停止遍历.
+	// 返回 SkipDir 跳过当前目录, 返回 StopWalk 停止遍历, 返回其他错误则停止遍历并由 Walk 返回
+	WalkFunc func(fdPath string, fd *FileDirectory, pcsError pcserror.Error) error
+
+	// WalkOptions 遍历可选项
+	WalkOptions struct {
+		OrderOptions *OrderOptions // 同一目录下各项的排序
+		Parallel     int           // 同时获取目录列表的最大数量, 小于等于0则使用 DefaultWalkParallel
+		PageSize     int           // 分页获取目录列表时, 每页的数量
+
+		// DirDone 目录下的项都已回调后调用, 与 WalkFunc 一样不会被并发调用, 可为空.
+		// 用于按目录顺序输出时, 判断目录的列表是否已完整
+		DirDone func(dir *FileDirectory)
+	}
+
+	// WalkLister 获取元信息和分页获取目录列表, 用于遍历目录
+	WalkLister interface {
+		ListPager
+		FilesDirectoriesMeta(path string) (data *FileDirectory, pcsError pcserror.Error)
+	}
+
+	walker struct {
+		lister WalkLister
+		opts   *WalkOptions
+		fn     WalkFunc
+		fnMu   sync.Mutex
+
+		mu      sync.Mutex
+		cond    *sync.Cond
+		queue   []*FileDirectory // 待获取列表的目录
+		active  int              // 正在获取列表的目录数量
+		stopped bool
+		err     error
+	}
+)
+
+// Walk 并发遍历 root 及其下所有的文件和目录, 对每一项调用 fn.
+//
+// 顺序保证:
+// fn 不会被并发调用;
+// root 总是最先被回调;
+// 目录总是先于其下的项被回调;
+// 同一目录下的项按 OrderOptions 的顺序回调;
+// 不同目录下的项之间不保证顺序. Parallel 为1时, 按目录逐个遍历.
+func (pcs *BaiduPCS) Walk(root string, opts *WalkOptions, fn WalkFunc) error {
+	pcs.lazyInit() // 避免并发初始化
+	return WalkWith(pcs, root, opts, fn)
+}
+
+// WalkWith 同 Walk, 由 lister 获取元信息和目录列表
+func WalkWith(lister WalkLister, root string, opts *WalkOptions, fn WalkFunc) error {
+	if opts == nil {
+		opts = &WalkOptions{}
+	}
+	if root == "" {
+		root = PathSeparator
+	}
+
+	w := &walker{
+		lister: lister,
+		opts:   opts,
+		fn:     fn,
+	}
+	w.cond = sync.NewCond(&w.mu)
+
+	fd, pcsError := lister.FilesDirectoriesMeta(root)
+	if pcsError != nil {
+		return w.result(fn(root, nil, pcsError))
+	}
+
+	err := fn(root, fd, nil)
+	if err != nil || !fd.Isdir {
+		return w.result(err)
+	}
+
+	parallel := opts.Parallel
+	if parallel <= 0 {
+		parallel = DefaultWalkParallel
+	}
+
+	w.queue = append(w.queue, fd)
+	wg := sync.WaitGroup{}
+	wg.Add(parallel)
+	for i := 0; i < parallel; i++ {
+		go func() {
+			defer wg.Done()
+			for {
+				dir, ok := w.pop()
+				if !ok {
+					return
+				}
+				w.walkDir(dir)
+				w.done()
+			}
+		}()
+	}
+	wg.Wait()
+	return w.err
+}
+
+func (w *walker) result(err error) error {
+	if err == SkipDir || err == StopWalk {
+		return nil
+	}
+	return err
+}
+
+// pop 取出一个待获取列表的目录, 没有更多的目录时返回 false
+func (w *walker) pop() (*FileDirectory, bool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for len(w.queue) == 0 && w.active > 0 && !w.stopped {
+		w.cond.Wait()
+	}
+	if w.stopped || len(w.queue) == 0 {
+		return nil, false
+	}
+
+	// 后进先出, 使队列尽可能小
+	dir := w.queue[len(w.queue)-1]
+	w.queue = w.queue[:len(w.queue)-1]
+	w.active++
+	return dir, true
+}
+
+func (w *walker) push(dirs []*FileDirectory) {
+	if len(dirs) == 0 {
+		return
+	}
+	w.mu.Lock()
+	// 逆序加入, 使先列出的目录先被取出
+	for i := len(dirs) - 1; i >= 0; i-- {
+		w.queue = append(w.queue, dirs[i])
+	}
+	w.mu.Unlock()
+	w.cond.Broadcast()
+}
+
+func (w *walker) done() {
+	w.mu.Lock()
+	w.active--
+	w.mu.Unlock()
+	w.cond.Broadcast()
+}
+
+func (w *walker) stop(err error) {
+	w.mu.Lock()
+	if !w.stopped {
+		w.stopped = true
+		w.err = w.result(err)
+	}
+	w.mu.Unlock()
+	w.cond.Broadcast()
+}
+
+func (w *walker) isStopped() bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.stopped
+}
+
+// call 串行调用回调函数, 已停止则返回 false
+func (w *walker) call(fdPath string, fd *FileDirectory, pcsError pcserror.Error) (ok bool, err error) {
+	w.fnMu.Lock()
+	defer w.fnMu.Unlock()
+	if w.isStopped() {
+		return false, nil
+	}
+	return true, w.fn(fdPath, fd, pcsError)
+}
+
+// walkDir 获取目录列表, 回调目录下的各项, 并将子目录加入队列
+func (w *walker) walkDir(dir *FileDirectory) {
+	var (
+		iter = NewListIter(w.lister, dir.Path, &ListIterOptions{
+			OrderOptions: w.opts.OrderOptions,
+			PageSize:     w.opts.PageSize,
+		})
+		subDirs []*FileDirectory
+	)
+	defer func() {
+		w.push(subDirs)
+	}()
+
+list:
+	for iter.Next() {
+		fd := iter.Value()
+		ok, err := w.call(fd.Path, fd, nil)
+		if !ok {
+			return
+		}
+		switch err {
+		case nil:
+			if fd.Isdir {
+				subDirs = append(subDirs, fd)
+			}
+		case SkipDir:
+			if !fd.Isdir {
+				// 跳过所在目录中剩余的项
+				break list
+			}
+		default:
+			w.stop(err)
+			return
+		}
+	}
+
+	if iter.Err() != nil {
+		ok, err := w.call(dir.Path, dir, iter.Err())
+		if ok && err != nil && err != SkipDir {
+			w.stop(err)
+			return
+		}
+	}
+
+	if w.opts.DirDone != nil && !w.isStopped() {
+		w.fnMu.Lock()
+		w.opts.DirDone(dir)
+		w.fnMu.Unlock()
+	}
+}
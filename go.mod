@@ -9,6 +9,7 @@ require (
 	github.com/astaxie/beego v1.12.3 // indirect
 	github.com/bitly/go-simplejson v0.5.1
 	github.com/fatih/color v1.17.0
+	github.com/fsnotify/fsnotify v1.7.0
 	github.com/golang-jwt/jwt v3.2.1+incompatible
 	github.com/golang/protobuf v1.5.4
 	github.com/json-iterator/go v1.1.12
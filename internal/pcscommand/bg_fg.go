@@ -1,89 +1,544 @@
 package pcscommand
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"github.com/Erope/BaiduPCS-Go/pcstable"
+	"github.com/Erope/BaiduPCS-Go/pcsutil/converter"
+	"github.com/Erope/BaiduPCS-Go/requester/transfer"
+	"io"
 	"os"
+	"os/signal"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
 	"sync/atomic"
+	"time"
+)
+
+const (
+	// BgTaskRunning 进行中
+	BgTaskRunning BgTaskState = iota
+	// BgTaskPaused 已暂停
+	BgTaskPaused
+	// BgTaskCanceled 已取消
+	BgTaskCanceled
+	// BgTaskFinished 已结束
+	BgTaskFinished
+
+	// bgOutputMaxSize 后台任务保留的输出的最大长度
+	bgOutputMaxSize = 256 * 1024
 )
 
 var (
 	// BgMap 后台
-	BgMap = BgTasks{
-		tasks: sync.Map{},
-		sig:   make(chan int64),
-	}
+	BgMap = BgTasks{}
+
+	// ErrBgTaskNotFound 后台任务不存在
+	ErrBgTaskNotFound = errors.New("后台任务不存在")
+	// ErrBgTaskNotRunning 后台任务不在进行中
+	ErrBgTaskNotRunning = errors.New("后台任务不在进行中")
+	// ErrBgTaskNotPaused 后台任务未暂停
+	ErrBgTaskNotPaused = errors.New("后台任务未暂停")
+	// ErrBgTaskEnded 后台任务已结束
+	ErrBgTaskEnded = errors.New("后台任务已结束")
 )
 
 type (
+	// BgTaskState 后台任务状态
+	BgTaskState int
+
 	// BgTasks 后台任务
 	BgTasks struct {
-		lastID  int64
-		tasks   sync.Map
-		started bool
-		sig     chan int64
+		lastID int64
+		tasks  sync.Map
+	}
+
+	// BgTask 后台任务, 一个后台任务包含一次下载或上传的所有文件
+	BgTask struct {
+		id        int64
+		direction string // transfer.DirectionDownload 或 transfer.DirectionUpload
+		paths     []string
+		startTime time.Time
+		endTime   time.Time
+		output    *bgOutput
+		next      transfer.ProgressHandler // 用户设置的传输事件处理
+
+		mu          sync.Mutex
+		resumeCond  *sync.Cond
+		canceledCh  chan struct{} // 取消时关闭
+		ctx         context.Context
+		cancelCtx   context.CancelFunc
+		state       BgTaskState
+		controllers map[int]transferController      // 正在传输的文件, 键为任务id
+		events      map[int]*transfer.ProgressEvent // 每个文件最新的传输事件, 键为任务id
+	}
+
+	// transferController 可暂停, 恢复, 取消的传输, 由 downloader.Downloader 和 uploader.MultiUploader 实现
+	transferController interface {
+		Pause()
+		Resume()
+		Cancel()
 	}
-	// BgDTaskItem 后台任务详情
-	BgDTaskItem struct {
-		id              int
-		downloadOptions *DownloadOptions
-		pcspaths        []string
+
+	// bgOutput 保存后台任务的输出, 只保留最后 bgOutputMaxSize 字节, 可附加到终端
+	bgOutput struct {
+		mu       sync.Mutex
+		buf      []byte
+		attached io.Writer
 	}
 )
 
+func (s BgTaskState) String() string {
+	switch s {
+	case BgTaskRunning:
+		return "进行中"
+	case BgTaskPaused:
+		return "已暂停"
+	case BgTaskCanceled:
+		return "已取消"
+	case BgTaskFinished:
+		return "已结束"
+	}
+	return "未知"
+}
+
 // NewID 返回生成的 ID
 func (b *BgTasks) NewID() int64 {
 	id := atomic.AddInt64(&b.lastID, 1)
 	return id
 }
 
-// TaskID 返回后台任务 id
-func (t *BgDTaskItem) TaskID() int {
-	return t.id
+func (b *BgTasks) newTask(direction string, paths []string, next transfer.ProgressHandler) *BgTask {
+	task := newBgTask(direction, paths, next)
+	task.id = b.NewID()
+	b.tasks.Store(task.id, task)
+	return task
+}
+
+// newBgTask 初始化任务, 不加入后台任务列表, 前台传输也用于响应中断信号
+func newBgTask(direction string, paths []string, next transfer.ProgressHandler) *BgTask {
+	task := &BgTask{
+		direction:   direction,
+		paths:       paths,
+		startTime:   time.Now(),
+		output:      &bgOutput{},
+		next:        next,
+		controllers: map[int]transferController{},
+		events:      map[int]*transfer.ProgressEvent{},
+		canceledCh:  make(chan struct{}),
+	}
+	task.resumeCond = sync.NewCond(&task.mu)
+	task.ctx, task.cancelCtx = context.WithCancel(context.Background())
+	return task
+}
+
+// Get 获取后台任务
+func (b *BgTasks) Get(id int64) (*BgTask, error) {
+	v, ok := b.tasks.Load(id)
+	if !ok {
+		return nil, ErrBgTaskNotFound
+	}
+	return v.(*BgTask), nil
+}
+
+// list 返回按 id 排序的所有后台任务
+func (b *BgTasks) list() []*BgTask {
+	tasks := make([]*BgTask, 0)
+	b.tasks.Range(func(_, v interface{}) bool {
+		tasks = append(tasks, v.(*BgTask))
+		return true
+	})
+	sort.Slice(tasks, func(i, j int) bool {
+		return tasks[i].id < tasks[j].id
+	})
+	return tasks
+}
+
+// Clean 删除已结束和已取消的后台任务, 返回删除的数量
+func (b *BgTasks) Clean() (n int) {
+	for _, task := range b.list() {
+		switch task.State() {
+		case BgTaskCanceled, BgTaskFinished:
+			b.tasks.Delete(task.id)
+			n++
+		}
+	}
+	return
 }
 
 // PrintAllBgTask 输出所有的后台任务
 func (b *BgTasks) PrintAllBgTask() {
 	tb := pcstable.NewTable(os.Stdout)
-	tb.SetHeader([]string{"task_id", "files"})
-	b.tasks.Range(func(id, v interface{}) bool {
-		tb.Append([]string{strconv.FormatInt(id.(int64), 10), strings.Join(v.(*BgDTaskItem).pcspaths, ",")})
-		return true
+	tb.SetHeader([]string{"task_id", "类型", "状态", "进度", "速度", "文件数", "files"})
+	for _, task := range b.list() {
+		stat := task.stat()
+		tb.Append([]string{
+			strconv.FormatInt(task.id, 10),
+			task.direction,
+			task.State().String(),
+			stat.progressString(),
+			converter.ConvertFileSize(stat.speed, 2) + "/s",
+			fmt.Sprintf("%d/%d", stat.done, stat.total),
+			strings.Join(task.paths, ","),
+		})
+	}
+	tb.Render()
+}
+
+// ID 返回后台任务 id
+func (t *BgTask) ID() int64 {
+	return t.id
+}
+
+// Context 返回任务取消时取消的 context, 用于中断正在进行的网盘请求
+func (t *BgTask) Context() context.Context {
+	return t.ctx
+}
+
+// State 返回后台任务状态
+func (t *BgTask) State() BgTaskState {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.state
+}
+
+// HandleProgress 记录传输事件, 用于统计进度
+func (t *BgTask) HandleProgress(event *transfer.ProgressEvent) {
+	t.mu.Lock()
+	t.events[event.TaskID] = event
+	t.mu.Unlock()
+	emitProgress(t.next, event)
+}
+
+// Pause 暂停后台任务, 正在传输的文件暂停, 未开始的文件等待恢复
+func (t *BgTask) Pause() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.state != BgTaskRunning {
+		return ErrBgTaskNotRunning
+	}
+	t.state = BgTaskPaused
+	for _, c := range t.controllers {
+		c.Pause()
+	}
+	return nil
+}
+
+// Resume 恢复后台任务
+func (t *BgTask) Resume() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.state != BgTaskPaused {
+		return ErrBgTaskNotPaused
+	}
+	t.state = BgTaskRunning
+	for _, c := range t.controllers {
+		c.Resume()
+	}
+	t.resumeCond.Broadcast()
+	return nil
+}
+
+// Cancel 取消后台任务, 已下载或上传的部分可断点续传
+func (t *BgTask) Cancel() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	switch t.state {
+	case BgTaskCanceled, BgTaskFinished:
+		return ErrBgTaskEnded
+	}
+	t.state = BgTaskCanceled
+	for _, c := range t.controllers {
+		c.Cancel()
+	}
+	close(t.canceledCh)
+	t.cancelCtx()
+	t.resumeCond.Broadcast()
+	return nil
+}
+
+// stopped 暂停时阻塞直到恢复或取消, 返回后台任务是否已取消.
+// 在每个文件开始传输前调用, t 为 nil 时返回 false
+func (t *BgTask) stopped() bool {
+	if t == nil {
+		return false
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for t.state == BgTaskPaused {
+		t.resumeCond.Wait()
+	}
+	return t.state == BgTaskCanceled
+}
+
+// canceled 返回任务是否已取消, t 为 nil 时返回 false
+func (t *BgTask) canceled() bool {
+	if t == nil {
+		return false
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.state == BgTaskCanceled
+}
+
+// done 返回任务取消时关闭的 chan, 用于持续运行的任务, 如监视目录上传
+func (t *BgTask) done() <-chan struct{} {
+	return t.canceledCh
+}
+
+// attach 登记正在传输的文件, 以便暂停, 恢复, 取消, t 为 nil 时不做任何事
+func (t *BgTask) attach(id int, c transferController) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.controllers[id] = c
+	switch t.state {
+	case BgTaskPaused:
+		c.Pause()
+	case BgTaskCanceled:
+		c.Cancel()
+	}
+}
+
+// detach 取消登记, t 为 nil 时不做任何事
+func (t *BgTask) detach(id int) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	delete(t.controllers, id)
+}
+
+// finish 后台任务执行完毕
+func (t *BgTask) finish() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.state != BgTaskCanceled {
+		t.state = BgTaskFinished
+	}
+	t.endTime = time.Now()
+}
+
+type bgTaskStat struct {
+	size, bytes, speed int64
+	done, total        int
+}
+
+func (stat *bgTaskStat) progressString() string {
+	if stat.size <= 0 {
+		return "-"
+	}
+	return fmt.Sprintf("%s/%s %.2f%%", converter.ConvertFileSize(stat.bytes, 2), converter.ConvertFileSize(stat.size, 2), float64(stat.bytes)/float64(stat.size)*100)
+}
+
+// stat 根据传输事件统计进度, 不包括目录
+func (t *BgTask) stat() (stat bgTaskStat) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for _, event := range t.events {
+		if event.Isdir {
+			continue
+		}
+		stat.total++
+		stat.size += event.Size
+		stat.bytes += event.Bytes
+		switch event.Event {
+		case transfer.ProgressProgress:
+			stat.speed += event.Speed
+		case transfer.ProgressFinished, transfer.ProgressSkipped, transfer.ProgressFailed, transfer.ProgressCanceled:
+			stat.done++
+		}
+	}
+	return
+}
+
+// PrintStatus 输出后台任务中每个文件的传输状态
+func (t *BgTask) PrintStatus() {
+	t.mu.Lock()
+	events := make([]*transfer.ProgressEvent, 0, len(t.events))
+	for _, event := range t.events {
+		if !event.Isdir {
+			events = append(events, event)
+		}
+	}
+	state, startTime, endTime := t.state, t.startTime, t.endTime
+	t.mu.Unlock()
+
+	sort.Slice(events, func(i, j int) bool {
+		return events[i].TaskID < events[j].TaskID
 	})
+
+	if endTime.IsZero() {
+		endTime = time.Now()
+	}
+	stat := t.stat()
+	fmt.Printf("后台任务: %d, 类型: %s, 状态: %s, 进度: %s, 速度: %s/s, 文件数: %d/%d, 时间: %s\n",
+		t.id, t.direction, state, stat.progressString(), converter.ConvertFileSize(stat.speed, 2), stat.done, stat.total, endTime.Sub(startTime)/1e9*1e9)
+
+	tb := pcstable.NewTable(os.Stdout)
+	tb.SetHeader([]string{"#", "状态", "进度", "速度", "剩余时间", "路径", "错误"})
+	for _, event := range events {
+		eta := "-"
+		if event.Event == transfer.ProgressProgress && event.ETA >= 0 {
+			eta = (time.Duration(event.ETA) * time.Second).String()
+		}
+		tb.Append([]string{
+			strconv.Itoa(event.TaskID),
+			string(event.Event),
+			converter.ConvertFileSize(event.Bytes, 2) + "/" + converter.ConvertFileSize(event.Size, 2),
+			converter.ConvertFileSize(event.Speed, 2) + "/s",
+			eta,
+			event.Path,
+			event.Error,
+		})
+	}
 	tb.Render()
 }
 
+// Write 写入输出, 已附加到终端时同时写入终端
+func (o *bgOutput) Write(p []byte) (n int, err error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.buf = append(o.buf, p...)
+	if over := len(o.buf) - bgOutputMaxSize; over > 0 {
+		o.buf = append(o.buf[:0], o.buf[over:]...)
+	}
+	if o.attached != nil {
+		o.attached.Write(p)
+	}
+	return len(p), nil
+}
+
+// attach 附加到 w, 先写入已保留的输出
+func (o *bgOutput) attach(w io.Writer) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	w.Write(o.buf)
+	o.attached = w
+}
+
+func (o *bgOutput) detach() {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.attached = nil
+}
+
+func (o *bgOutput) WriteTo(w io.Writer) (n int64, err error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	nn, err := w.Write(o.buf)
+	return int64(nn), err
+}
+
+// runBgTask 在后台执行 f
+func runBgTask(task *BgTask, f func()) {
+	fmt.Printf("已加入后台任务: %d, 使用 bg status %d 查看进度, bg fg %d 查看输出\n", task.id, task.id, task.id)
+	go func() {
+		f()
+		task.finish()
+		fmt.Printf("\n后台任务 %d %s\n", task.id, task.State())
+	}()
+}
+
 // RunBgDownload 执行后台下载
 func RunBgDownload(paths []string, options *DownloadOptions) {
-	if !BgMap.started {
-		go func() {
-			for {
-				select {
-				case id := <-BgMap.sig:
-					BgMap.tasks.Delete(id)
-					fmt.Printf("任务：%d 已完成\n", id)
-				}
-			}
-		}()
-	} else {
-		BgMap.started = true
+	if options == nil {
+		options = &DownloadOptions{}
+	}
+
+	task := BgMap.newTask(transfer.DirectionDownload, paths, options.Progress)
+	options.Out = task.output
+	options.Progress = task
+	options.bgTask = task
+
+	runBgTask(task, func() {
+		RunDownload(paths, options)
+	})
+}
+
+// RunBgUpload 执行后台上传
+func RunBgUpload(localPaths []string, savePath string, opt *UploadOptions) {
+	if opt == nil {
+		opt = &UploadOptions{}
 	}
 
-	if options.Out == nil {
-		options.Out, _ = os.Open(os.DevNull)
+	task := BgMap.newTask(transfer.DirectionUpload, localPaths, opt.Progress)
+	opt.Out = task.output
+	opt.Progress = task
+	opt.bgTask = task
+
+	runBgTask(task, func() {
+		RunUpload(localPaths, savePath, opt)
+	})
+}
+
+// RunBgTaskCommand 执行后台任务的操作, op 可选: status, pause, resume, cancel, logs
+func RunBgTaskCommand(op string, id int64) {
+	task, err := BgMap.Get(id)
+	if err != nil {
+		fmt.Printf("%s: %d\n", err, id)
+		return
 	}
 
-	task := new(BgDTaskItem)
-	task.pcspaths = paths
+	switch op {
+	case "status":
+		task.PrintStatus()
+		return
+	case "logs":
+		task.output.WriteTo(os.Stdout)
+		fmt.Println()
+		return
+	case "pause":
+		err = task.Pause()
+	case "resume":
+		err = task.Resume()
+	case "cancel":
+		err = task.Cancel()
+	default:
+		fmt.Printf("未知的操作: %s\n", op)
+		return
+	}
+	if err != nil {
+		fmt.Printf("后台任务 %d: %s\n", id, err)
+		return
+	}
+	fmt.Printf("后台任务 %d %s\n", id, task.State())
+}
+
+// RunBgFg 将后台任务的输出附加到终端, 直到任务结束或按下 Ctrl+C, 任务继续在后台进行
+func RunBgFg(id int64) {
+	task, err := BgMap.Get(id)
+	if err != nil {
+		fmt.Printf("%s: %d\n", err, id)
+		return
+	}
 
-	id := BgMap.NewID()
-	BgMap.tasks.Store(id, task)
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt)
+	defer signal.Stop(interrupt)
 
-	go func(taskID int64) {
-		RunDownload(paths, options)
-		BgMap.sig <- taskID
-	}(id)
+	task.output.attach(os.Stdout)
+	defer task.output.detach()
+
+	ticker := time.NewTicker(500 * time.Millisecond)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-interrupt:
+			fmt.Printf("\n已返回, 后台任务 %d 继续进行\n", id)
+			return
+		case <-ticker.C:
+			switch task.State() {
+			case BgTaskCanceled, BgTaskFinished:
+				fmt.Printf("\n后台任务 %d %s\n", id, task.State())
+				return
+			}
+		}
+	}
 }
@@ -0,0 +1,54 @@
+package pcscommand
+
+import (
+	"github.com/Erope/BaiduPCS-Go/requester/transfer"
+	"testing"
+)
+
+type fakeController struct {
+	ops []string
+}
+
+func (fc *fakeController) Pause()  { fc.ops = append(fc.ops, "pause") }
+func (fc *fakeController) Resume() { fc.ops = append(fc.ops, "resume") }
+func (fc *fakeController) Cancel() { fc.ops = append(fc.ops, "cancel") }
+
+func TestBgTask(t *testing.T) {
+	bg := BgTasks{}
+	task := bg.newTask(transfer.DirectionDownload, []string{"/a"}, nil)
+
+	fc := &fakeController{}
+	task.attach(1, fc)
+	if err := task.Resume(); err != ErrBgTaskNotPaused {
+		t.Fatalf("expect ErrBgTaskNotPaused, got %v", err)
+	}
+	task.Pause()
+	task.Resume()
+
+	// 暂停时开始传输的文件也会被暂停
+	task.Pause()
+	late := &fakeController{}
+	task.attach(2, late)
+	task.Cancel()
+	if !task.stopped() {
+		t.Fatal("expect stopped after cancel")
+	}
+	if err := task.Cancel(); err != ErrBgTaskEnded {
+		t.Fatalf("expect ErrBgTaskEnded, got %v", err)
+	}
+
+	if len(fc.ops) != 4 || fc.ops[3] != "cancel" || len(late.ops) != 2 || late.ops[0] != "pause" {
+		t.Fatalf("unexpected ops: %v, %v", fc.ops, late.ops)
+	}
+
+	task.HandleProgress(&transfer.ProgressEvent{Event: transfer.ProgressFinished, TaskID: 1, Size: 10, Bytes: 10})
+	task.HandleProgress(&transfer.ProgressEvent{Event: transfer.ProgressProgress, TaskID: 2, Size: 10, Bytes: 5, Speed: 1})
+	if stat := task.stat(); stat.done != 1 || stat.total != 2 || stat.bytes != 15 || stat.speed != 1 {
+		t.Fatalf("unexpected stat: %+v", stat)
+	}
+
+	task.finish()
+	if task.State() != BgTaskCanceled || bg.Clean() != 1 {
+		t.Fatal("expect canceled task to be cleaned")
+	}
+}
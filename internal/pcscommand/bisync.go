@@ -0,0 +1,308 @@
+package pcscommand
+
+import (
+	"fmt"
+	"github.com/Erope/BaiduPCS-Go/baidupcs"
+	"github.com/Erope/BaiduPCS-Go/internal/pcsfunctions/pcssync"
+	"github.com/Erope/BaiduPCS-Go/pcscore"
+	"github.com/Erope/BaiduPCS-Go/pcstable"
+	"github.com/Erope/BaiduPCS-Go/pcsutil/converter"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+type (
+	// BisyncOptions 双向同步可选项
+	BisyncOptions struct {
+		DryRun   bool     // 只输出同步计划, 不执行
+		Force    bool     // 跳过同步计划的安全检查, 见 pcssync.CheckBiPlan
+		Include  []string // 只同步匹配的文件
+		Exclude  []string // 排除匹配的文件和目录
+		Upload   *UploadOptions
+		Download *DownloadOptions
+	}
+)
+
+var (
+	bisyncActionNames = map[pcssync.ActionType]string{
+		pcssync.ActionUpload:      "上传",
+		pcssync.ActionDownload:    "下载",
+		pcssync.ActionDelete:      "删除网盘文件",
+		pcssync.ActionDeleteLocal: "删除本地文件",
+		pcssync.ActionMove:        "移动网盘文件",
+		pcssync.ActionMoveLocal:   "移动本地文件",
+		pcssync.ActionConflict:    "冲突",
+		pcssync.ActionSkip:        "跳过",
+	}
+)
+
+// RunBisync 双向同步本地目录 localDir 和网盘目录 remoteDir.
+// 上次同步后的状态保存在配置目录, 据此判断哪一边有新建, 修改, 删除或重命名, 并同步到另一边.
+// 两边都有修改时保留两边的文件, 本地文件重命名为带有冲突时间的文件名后上传, 再下载网盘的文件
+func RunBisync(localDir, remoteDir string, opt *BisyncOptions) {
+	if opt == nil {
+		opt = &BisyncOptions{}
+	}
+	opt.Upload = checkUploadOptions(opt.Upload)
+	if opt.Download == nil {
+		opt.Download = &DownloadOptions{}
+	}
+	// 结构化输出时, 同步计划输出到标准输出, 传输的进度输出到标准错误
+	if isStructuredOutput() {
+		opt.Upload.Out, opt.Download.Out = os.Stderr, os.Stderr
+	}
+	out := opt.Upload.Out
+
+	err := matchPathByShellPatternOnce(&remoteDir)
+	if err != nil {
+		printError(fmt.Errorf("获取网盘路径 %s 错误, %s", remoteDir, err))
+		return
+	}
+
+	// 回收目录不参与同步
+	filter, err := pcssync.NewFilter(opt.Include, append(opt.Exclude, pcssync.BiTrashDirName))
+	if err != nil {
+		printError(err)
+		return
+	}
+
+	localDir, err = filepath.Abs(localDir)
+	if err != nil {
+		printError(err)
+		return
+	}
+
+	stateDB := pcssync.NewBiStateDB()
+	state, err := stateDB.Get(GetActiveUser().UID, localDir, remoteDir)
+	if err != nil {
+		printError(fmt.Errorf("读取同步状态错误, %s", err))
+		return
+	}
+
+	pcs := GetBaiduPCS()
+	local, remote, err := listBisyncFiles(pcs, localDir, remoteDir, filter)
+	if err != nil {
+		printError(err)
+		return
+	}
+
+	if state.SyncTime == 0 {
+		fmt.Fprintf(out, "首次同步, 两边都存在而内容不同的文件将作为冲突处理\n")
+	}
+	md5Cache := pcssync.NewMD5Cache()
+	plan := pcssync.BiCompare(local, remote, remoteDir, state, filter, md5Cache.Sum)
+	defer func() {
+		if err := md5Cache.Save(); err != nil {
+			pcsCommandVerbose.Warnf("save md5 cache error: %s\n", err)
+		}
+	}()
+
+	renderBisyncPlan(plan)
+	err = pcssync.CheckBiPlan(plan, local, remote, state, filter, pcssync.DefaultMaxDeleteRatio)
+	if err != nil && !opt.Force {
+		printError(fmt.Errorf("%s, 确认无误后使用 -force 强制同步", err))
+		return
+	}
+	if opt.DryRun {
+		return
+	}
+
+	counter, failed := runBisyncPlan(out, plan, localDir, remoteDir, opt)
+
+	// 重新获取两边的文件, 只记录与同步计划预期一致的文件
+	before := &pcssync.BiFiles{Local: local, Remote: remote}
+	local, remote, err = listBisyncFiles(pcs, localDir, remoteDir, filter)
+	if err != nil {
+		printError(fmt.Errorf("%s, 未更新同步状态", err))
+		return
+	}
+	state.Update(plan, before, &pcssync.BiFiles{Local: local, Remote: remote}, filter, failed, md5Cache.Cached)
+	err = stateDB.Put(state)
+	if err != nil {
+		printError(fmt.Errorf("保存同步状态错误, %s", err))
+	}
+
+	fmt.Fprintf(out, "\n同步结束, 成功 %d, 失败 %d, 未变化 %d, 冲突 %d, 排除 %d\n",
+		counter.finished, len(failed), plan.Unchanged, plan.Count(pcssync.ActionConflict), plan.Excluded)
+	if plan.Count(pcssync.ActionDeleteLocal) > 0 {
+		fmt.Fprintf(out, "删除的本地文件已移动到 %s\n", filepath.Join(localDir, pcssync.BiTrashDirName))
+	}
+	if len(failed) > 0 {
+		fmt.Fprintf(out, "部分文件未同步, 重新执行 bisync 可继续同步\n")
+	}
+}
+
+// listBisyncFiles 获取本地和网盘的文件
+func listBisyncFiles(pcs baidupcs.Client, localDir, remoteDir string, filter *pcssync.Filter) (*pcssync.Local, map[string]*baidupcs.FileDirectory, error) {
+	local, err := pcssync.WalkLocal(localDir, filter)
+	if err != nil {
+		return nil, nil, fmt.Errorf("遍历本地目录错误, %s", err)
+	}
+	remote, err := pcssync.ListRemote(pcs, remoteDir)
+	if err != nil {
+		return nil, nil, fmt.Errorf("获取网盘文件列表错误, %s", err)
+	}
+	return local, remote, nil
+}
+
+// runBisyncPlan 执行同步计划, 依次为移动, 删除, 处理冲突, 上传和下载, 返回传输的统计和失败的文件的相对路径
+func runBisyncPlan(out io.Writer, plan *pcssync.BiPlan, localDir, remoteDir string, opt *BisyncOptions) (counter *syncCounter, failed map[string]bool) {
+	var (
+		pcs         = GetBaiduPCS()
+		now         = time.Now()
+		moves       []*baidupcs.CpMvJSON
+		moveActions []*pcssync.Action
+		deletes     []string
+		delActions  []*pcssync.Action
+		uploads     []*uploadFile
+		downloads   []string
+		savePaths   = map[string]string{}
+	)
+	counter = &syncCounter{}
+	failed = map[string]bool{}
+
+	for _, action := range plan.Actions {
+		switch action.Type {
+		case pcssync.ActionMove:
+			moves = append(moves, &baidupcs.CpMvJSON{
+				From: path.Join(remoteDir, action.From),
+				To:   action.RemotePath,
+			})
+			moveActions = append(moveActions, action)
+		case pcssync.ActionMoveLocal:
+			from := filepath.Join(localDir, filepath.FromSlash(action.From))
+			err := os.MkdirAll(filepath.Dir(action.LocalPath), 0777)
+			if err == nil {
+				err = os.Rename(from, action.LocalPath)
+			}
+			if err != nil {
+				fmt.Fprintf(out, "移动本地文件失败: %s -> %s, %s\n", action.From, action.RelPath, err)
+				failed[action.From], failed[action.RelPath] = true, true
+				continue
+			}
+			counter.finished++
+		case pcssync.ActionDelete:
+			deletes = append(deletes, action.RemotePath)
+			delActions = append(delActions, action)
+		case pcssync.ActionDeleteLocal:
+			// 移动到回收目录, 不直接删除
+			trashPath := pcssync.TrashPath(localDir, action.RelPath, now)
+			err := os.MkdirAll(filepath.Dir(trashPath), 0777)
+			if err == nil {
+				err = os.Rename(action.LocalPath, trashPath)
+			}
+			if err != nil && !os.IsNotExist(err) {
+				fmt.Fprintf(out, "删除本地文件失败: %s, %s\n", action.RelPath, err)
+				failed[action.RelPath] = true
+				continue
+			}
+			counter.finished++
+		case pcssync.ActionConflict:
+			conflictRel := pcssync.ConflictPath(action.RelPath, now)
+			conflictPath := filepath.Join(localDir, filepath.FromSlash(conflictRel))
+			err := os.Rename(action.LocalPath, conflictPath)
+			if err != nil {
+				fmt.Fprintf(out, "处理冲突失败: %s, %s\n", action.RelPath, err)
+				failed[action.RelPath] = true
+				continue
+			}
+			fmt.Fprintf(out, "冲突: %s, 本地文件已重命名为 %s\n", action.RelPath, conflictRel)
+			uploads = append(uploads, &uploadFile{
+				localPath: conflictPath,
+				savePath:  path.Join(remoteDir, conflictRel),
+			})
+			downloads = append(downloads, action.RemotePath)
+			savePaths[action.RemotePath] = action.LocalPath
+		case pcssync.ActionUpload:
+			uploads = append(uploads, &uploadFile{
+				localPath: action.LocalPath,
+				savePath:  action.RemotePath,
+			})
+		case pcssync.ActionDownload:
+			downloads = append(downloads, action.RemotePath)
+			savePaths[action.RemotePath] = action.LocalPath
+		}
+	}
+
+	if len(moves) > 0 {
+		for k, result := range pcs.BatchMove(moves...) {
+			if result.Status != baidupcs.BatchStatusSuccess {
+				fmt.Fprintf(out, "移动网盘文件失败: %s -> %s, %s\n", result.From, result.To, result.Status)
+				failed[moveActions[k].From], failed[moveActions[k].RelPath] = true, true
+				continue
+			}
+			counter.finished++
+		}
+	}
+
+	if len(deletes) > 0 {
+		for k, result := range pcs.BatchRemove(deletes...) {
+			if result.Status != baidupcs.BatchStatusSuccess && result.Status != baidupcs.BatchStatusNotFound {
+				fmt.Fprintf(out, "删除网盘文件失败: %s, %s\n", result.Path, result.Status)
+				failed[delActions[k].RelPath] = true
+				continue
+			}
+			counter.finished++
+		}
+	}
+
+	prefix := strings.TrimSuffix(remoteDir, baidupcs.PathSeparator) + baidupcs.PathSeparator
+	if len(uploads) > 0 {
+		counter.next = opt.Upload.Progress
+		opt.Upload.Progress = counter
+		opt.Upload.OnDup = baidupcs.OnDupOverwrite
+		opt.Upload.noJournal = true
+		runUpload([]string{localDir}, remoteDir, uploads, opt.Upload)
+	}
+	if len(downloads) > 0 {
+		counter.next = opt.Download.Progress
+		opt.Download.Progress = counter
+		opt.Download.IsOverwrite = true
+		opt.Download.noJournal = true
+		opt.Download.savePaths = savePaths
+		RunDownload(downloads, opt.Download)
+	}
+	for _, remotePath := range counter.failedPaths {
+		failed[strings.TrimPrefix(remotePath, prefix)] = true
+	}
+	return counter, failed
+}
+
+// renderBisyncPlan 输出双向同步计划
+func renderBisyncPlan(plan *pcssync.BiPlan) {
+	records := make([]*pcscore.SyncActionRecord, 0, len(plan.Actions))
+	for _, action := range plan.Actions {
+		records = append(records, &pcscore.SyncActionRecord{
+			Action:     string(action.Type),
+			Reason:     action.Reason,
+			Path:       action.RelPath,
+			From:       action.From,
+			LocalPath:  action.LocalPath,
+			RemotePath: action.RemotePath,
+			Size:       action.Size,
+		})
+	}
+
+	renderList(records, func() {
+		if len(plan.Actions) > 0 {
+			tb := pcstable.NewTable(os.Stdout)
+			tb.SetHeader([]string{"#", "操作", "原因", "文件大小", "路径"})
+			for k, action := range plan.Actions {
+				p := action.RelPath
+				if action.From != "" {
+					p = action.From + " -> " + action.RelPath
+				}
+				tb.Append([]string{fmt.Sprint(k), bisyncActionNames[action.Type], action.Reason, converter.ConvertFileSize(action.Size, 2), p})
+			}
+			tb.Render()
+		}
+
+		fmt.Printf("同步计划: 上传 %d, 下载 %d, 删除网盘文件 %d, 删除本地文件 %d, 移动 %d, 冲突 %d, 未变化 %d, 排除 %d\n",
+			plan.Count(pcssync.ActionUpload), plan.Count(pcssync.ActionDownload), plan.Count(pcssync.ActionDelete), plan.Count(pcssync.ActionDeleteLocal),
+			plan.Count(pcssync.ActionMove)+plan.Count(pcssync.ActionMoveLocal), plan.Count(pcssync.ActionConflict), plan.Unchanged, plan.Excluded)
+	})
+}
@@ -0,0 +1,44 @@
+package pcscommand
+
+import (
+	"fmt"
+	"github.com/Erope/BaiduPCS-Go/baidupcs/diskcache"
+	"github.com/Erope/BaiduPCS-Go/internal/pcsconfig"
+	"github.com/Erope/BaiduPCS-Go/pcsutil/converter"
+)
+
+// activeUserDiskCache 返回当前登录帐号的本地元信息缓存, 未启用缓存时也可用于统计和清空
+func activeUserDiskCache() *diskcache.Cache {
+	c := GetBaiduPCS().DiskCache()
+	if c != nil {
+		return c
+	}
+	return diskcache.New(pcsconfig.MetaCacheDir(GetActiveUser().UID), 0)
+}
+
+// RunCacheStats 执行 统计本地元信息缓存
+func RunCacheStats() {
+	c := activeUserDiskCache()
+	stats, err := c.Stats()
+	if err != nil {
+		fmt.Printf("统计缓存失败, %s\n", err)
+		return
+	}
+
+	ttl := "未启用"
+	if c.TTL() > 0 {
+		ttl = c.TTL().String()
+	}
+	fmt.Printf("缓存目录: %s\n有效期: %s\n缓存项: %d, 已过期: %d, 占用空间: %s\n", c.Dir(), ttl, stats.Entries, stats.Expired, converter.ConvertFileSize(stats.Size, 2))
+}
+
+// RunCacheClear 执行 清空本地元信息缓存
+func RunCacheClear() {
+	c := activeUserDiskCache()
+	err := c.Clear()
+	if err != nil {
+		fmt.Printf("清空缓存失败, %s\n", err)
+		return
+	}
+	fmt.Printf("清空缓存成功, 缓存目录: %s\n", c.Dir())
+}
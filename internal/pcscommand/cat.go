@@ -0,0 +1,262 @@
+package pcscommand
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/Erope/BaiduPCS-Go/baidupcs"
+	"github.com/Erope/BaiduPCS-Go/baidupcs/pcserror"
+	"github.com/Erope/BaiduPCS-Go/internal/pcsconfig"
+	"github.com/Erope/BaiduPCS-Go/internal/pcsfunctions/pcsdownload"
+	"github.com/Erope/BaiduPCS-Go/pcsutil/converter"
+	"github.com/Erope/BaiduPCS-Go/pcsutil/streamcrypto"
+	"github.com/Erope/BaiduPCS-Go/requester/downloader"
+	"github.com/Erope/BaiduPCS-Go/requester/transfer"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// DefaultCatBlockSize 输出文件内容时, 每个下载分块的大小, 较小的分块使输出更连续
+	DefaultCatBlockSize = 4 * converter.MB
+	// DefaultCatBufferSize 输出文件内容时, 缓存乱序数据的最大内存
+	DefaultCatBufferSize = 64 * converter.MB
+)
+
+type (
+	// CatOptions 输出文件内容可选参数
+	CatOptions struct {
+		Range    string // 输出的范围, 格式同 HTTP Range, 如 0-1023, 1024-, -1024
+		Decrypt  bool   // 解密客户端加密的文件, 范围为明文的范围
+		Parallel int
+		MaxRetry int
+		Out      io.Writer // 文件内容的输出
+		Err      io.Writer // 提示信息的输出
+
+		// Context 取消或超时后中断输出, 为 nil 时使用 context.Background()
+		Context context.Context
+	}
+)
+
+// parseCatRange 解析输出的范围, 结束位置包含在内, 超出文件大小的部分会被忽略.
+// 如 0-1023 为前 1024 字节, 1024- 为从 1024 字节开始的全部, -1024 为最后 1024 字节
+func parseCatRange(s string, size int64) (r *transfer.Range, err error) {
+	r = &transfer.Range{End: size}
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return r, nil
+	}
+
+	i := strings.Index(s, "-")
+	if i < 0 {
+		return nil, fmt.Errorf("范围格式错误: %s, 应为 start-end, start- 或 -length", s)
+	}
+	beginStr, endStr := strings.TrimSpace(s[:i]), strings.TrimSpace(s[i+1:])
+
+	var begin, end int64 = 0, -1
+	if beginStr != "" {
+		begin, err = strconv.ParseInt(beginStr, 10, 64)
+		if err != nil || begin < 0 {
+			return nil, fmt.Errorf("范围起始位置错误: %s", beginStr)
+		}
+	}
+	if endStr != "" {
+		end, err = strconv.ParseInt(endStr, 10, 64)
+		if err != nil || end < 0 {
+			return nil, fmt.Errorf("范围结束位置错误: %s", endStr)
+		}
+	}
+
+	switch {
+	case beginStr == "" && endStr == "":
+		return nil, fmt.Errorf("范围格式错误: %s, 应为 start-end, start- 或 -length", s)
+	case beginStr == "": // 最后的 length 字节
+		if end < size {
+			r.Begin = size - end
+		}
+		return r, nil
+	case endStr != "" && end < begin:
+		return nil, fmt.Errorf("范围结束位置 %d 小于起始位置 %d", end, begin)
+	case begin > size:
+		return nil, fmt.Errorf("范围起始位置 %d 超出文件大小 %d", begin, size)
+	}
+
+	r.Begin = begin
+	if endStr != "" && end+1 < size {
+		r.End = end + 1
+	}
+	return r, nil
+}
+
+// RunCat 将网盘文件 pcspath 的内容输出到 opt.Out, 如标准输出, 不保存到本地.
+// 使用多线程下载, 乱序下载的数据在内存中按顺序重组后输出, 提示信息输出到 opt.Err.
+// 下载中断时从已输出的位置重试, 已输出的数据不会重复输出.
+// 解密时只下载范围所在的加密分块, 解密后输出
+func RunCat(pcspath string, opt *CatOptions) error {
+	if opt == nil {
+		opt = &CatOptions{}
+	}
+	if opt.Out == nil {
+		opt.Out = os.Stdout
+	}
+	if opt.Err == nil {
+		opt.Err = os.Stderr
+	}
+	if opt.Parallel < 1 {
+		opt.Parallel = pcsconfig.Config.MaxParallel
+	}
+	if opt.MaxRetry < 0 {
+		opt.MaxRetry = DefaultDownloadMaxRetry
+	}
+	if opt.Context == nil {
+		opt.Context = context.Background()
+	}
+
+	err := matchPathByShellPatternOnce(&pcspath)
+	if err != nil {
+		return err
+	}
+
+	pcs := baidupcs.ClientWithContext(GetBaiduPCS(), opt.Context)
+	fd, pcsError := pcs.FilesDirectoriesMeta(pcspath)
+	if pcsError != nil {
+		return pcsError
+	}
+	if fd.Isdir {
+		return fmt.Errorf("%s 是目录, 只能输出文件的内容", fd.Path)
+	}
+
+	var (
+		size   = fd.Size
+		key    []byte
+		header []byte
+	)
+	if opt.Decrypt {
+		key, err = pcsconfig.Config.EncryptionKey()
+		if err != nil {
+			return err
+		}
+		header, err = catReadEncryptHeader(pcs, fd, opt)
+		switch {
+		case errors.Is(err, streamcrypto.ErrNotEncrypted):
+			fmt.Fprintf(opt.Err, "文件未加密, 不解密\n")
+		case err != nil:
+			return fmt.Errorf("%s, %s", StrDownloadInitError, err)
+		default:
+			h, _ := streamcrypto.ParseHeader(header)
+			size, err = h.PlainSize(fd.Size)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	r, err := parseCatRange(opt.Range, size)
+	if err != nil {
+		return err
+	}
+	if r.Len() <= 0 {
+		return nil
+	}
+
+	if header == nil {
+		ow := downloader.NewOrderedWriter(opt.Out, r.Begin, DefaultCatBufferSize)
+		defer ow.Close()
+		return catDownload(pcs, fd.Path, ow, r.End, opt)
+	}
+
+	// 下载明文范围所在的完整分块, 按顺序解密后输出
+	pr, pw := io.Pipe()
+	dr, err := streamcrypto.NewDecryptRangeReader(key, header, fd.Size, r.Begin, r.End, pr)
+	if err != nil {
+		return fmt.Errorf("%s, %s", StrDownloadInitError, err)
+	}
+	decrypted := make(chan error, 1)
+	go func() {
+		_, err := io.Copy(opt.Out, dr)
+		pr.CloseWithError(err)
+		decrypted <- err
+	}()
+
+	begin, end := dr.EncryptedRange()
+	ow := downloader.NewOrderedWriter(pw, begin, DefaultCatBufferSize)
+	err = catDownload(pcs, fd.Path, ow, end, opt)
+	ow.Close()
+	pw.CloseWithError(err)
+
+	// 解密或输出出错时, 下载因管道关闭而中断, 返回解密的错误
+	if decErr := <-decrypted; decErr != nil {
+		return decErr
+	}
+	return err
+}
+
+// catReadEncryptHeader 读取网盘文件 fd 开头的加密头部
+func catReadEncryptHeader(pcs baidupcs.Client, fd *baidupcs.FileDirectory, opt *CatOptions) (header []byte, err error) {
+	pcsError := pcs.DownloadFile(fd.Path, func(downloadURL string, jar http.CookieJar) error {
+		h := pcsconfig.Config.PCSHTTPClient()
+		h.SetCookiejar(jar)
+		header, err = readEncryptHeader(opt.Context, h, downloadURL, fd.Size)
+		return nil
+	})
+	if pcsError != nil {
+		return nil, pcsError
+	}
+	return header, err
+}
+
+// catDownload 多线程下载网盘文件 pcspath 到位置 end, 按顺序写入 ow.
+// 下载中断时从已输出的位置重试
+func catDownload(pcs baidupcs.Client, pcspath string, ow *downloader.OrderedWriter, end int64, opt *CatOptions) (err error) {
+	for retry := 0; ; retry++ {
+		// 从已输出的位置开始下载
+		cfg := &downloader.Config{
+			Mode:        transfer.RangeGenMode_BlockSize,
+			MaxParallel: opt.Parallel,
+			CacheSize:   pcsconfig.Config.CacheSize,
+			BlockSize:   DefaultCatBlockSize,
+			MaxRate:     pcsconfig.Config.MaxDownloadRate,
+			TryHTTP:     !pcsconfig.Config.EnableHTTPS,
+			Range:       &transfer.Range{Begin: ow.Offset(), End: end},
+			Context:     opt.Context,
+		}
+		err = pcs.DownloadFile(pcspath, func(downloadURL string, jar http.CookieJar) error {
+			h := pcsconfig.Config.PCSHTTPClient()
+			h.SetCookiejar(jar)
+			h.SetKeepAlive(true)
+			h.SetTimeout(10 * time.Minute)
+
+			der := downloader.NewDownloader(downloadURL, ow, cfg)
+			der.SetClient(h)
+			der.SetDURLCheckFunc(pcsdownload.BaiduPCSURLCheckFunc)
+			der.SetStatusCodeBodyCheckFunc(func(respBody io.Reader) error {
+				return pcserror.DecodePCSJSONError(baidupcs.OperationDownloadFile, respBody)
+			})
+			return der.Execute()
+		})
+		if err == nil && ow.Offset() < end {
+			err = errors.New("下载结束, 但输出的数据不完整")
+		}
+		if err == nil {
+			return nil
+		}
+
+		// 输出出错, 如管道已关闭, 不重试
+		if ow.Err() != nil {
+			return fmt.Errorf("%s, %s", StrDownloadFailed, ow.Err())
+		}
+		if opt.Context.Err() != nil || errors.Is(err, context.Canceled) || errors.Is(err, downloader.ErrInvalidRange) || errors.Is(err, downloader.ErrRangeNotSupported) || retry >= opt.MaxRetry {
+			return fmt.Errorf("%s, %s", StrDownloadFailed, err)
+		}
+		fmt.Fprintf(opt.Err, "%s, %s, 从 %d 字节处重试 %d/%d\n", StrDownloadFailed, err, ow.Offset(), retry+1, opt.MaxRetry)
+		select {
+		case <-time.After(3 * time.Duration(retry+1) * time.Second):
+		case <-opt.Context.Done():
+			return fmt.Errorf("%s, %s", StrDownloadFailed, opt.Context.Err())
+		}
+	}
+}
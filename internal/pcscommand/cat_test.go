@@ -0,0 +1,36 @@
+package pcscommand
+
+import (
+	"testing"
+)
+
+func TestParseCatRange(t *testing.T) {
+	for s, want := range map[string][2]int64{
+		"":        {0, 100},
+		"0-9":     {0, 10},
+		"10-":     {10, 100},
+		"90-200":  {90, 100},
+		"-10":     {90, 100},
+		"-1000":   {0, 100},
+		" 5 - 5 ": {5, 6},
+		"100-":    {100, 100},
+		"99-99":   {99, 100},
+		"0-99999": {0, 100},
+		"50-50":   {50, 51},
+	} {
+		r, err := parseCatRange(s, 100)
+		if err != nil {
+			t.Errorf("%q: %s", s, err)
+			continue
+		}
+		if r.Begin != want[0] || r.End != want[1] {
+			t.Errorf("%q: got %d-%d, want %d-%d", s, r.Begin, r.End, want[0], want[1])
+		}
+	}
+
+	for _, s := range []string{"-", "abc", "10", "10-5", "101-", "-5-", "a-b"} {
+		if _, err := parseCatRange(s, 100); err == nil {
+			t.Errorf("%q: want error", s)
+		}
+	}
+}
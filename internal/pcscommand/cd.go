@@ -15,7 +15,7 @@ func RunChangeDirectory(targetPath string, isList bool) error {
 		return err
 	}
 
-	data, err := pcs.FilesDirectoriesMeta(targetPath)
+	data, err := pcs.CacheFilesDirectoriesMeta(targetPath)
 	if err != nil {
 		fmt.Println(err)
 		return err
@@ -3,10 +3,30 @@ package pcscommand
 import (
 	"fmt"
 	"github.com/Erope/BaiduPCS-Go/baidupcs"
+	"github.com/Erope/BaiduPCS-Go/baidupcs/pcserror"
+	"github.com/Erope/BaiduPCS-Go/pcscore"
+	"github.com/Erope/BaiduPCS-Go/pcsutil/converter"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"time"
 )
 
-// RunCloudDlAddTask 执行添加离线下载任务
-func RunCloudDlAddTask(sourceURLs []string, savePath string) {
+const (
+	// cloudDlQueryMaxTaskIDs 一次最多查询的离线下载任务数量
+	cloudDlQueryMaxTaskIDs = 100
+	// cloudDlStatusRunning 离线下载任务进行中的状态码
+	cloudDlStatusRunning = 1
+)
+
+// RunCloudDlAddTask 执行添加离线下载任务, 返回添加成功的任务ID.
+// 磁力链接和种子文件会先输出文件列表, 再按 opt.Select 选择要下载的文件, 本地的种子文件会先上传到临时目录, 添加任务后删除
+func RunCloudDlAddTask(sourceURLs []string, savePath string, opt *CloudDlAddOptions) (taskIDs []int64) {
+	if opt == nil {
+		opt = &CloudDlAddOptions{}
+	}
+
 	var (
 		err error
 		pcs = GetBaiduPCS()
@@ -17,38 +37,182 @@ func RunCloudDlAddTask(sourceURLs []string, savePath string) {
 		return
 	}
 
-	var taskid int64
+	selector, err := ParseCloudDlBtSelector(opt.Select)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	var (
+		taskid int64
+		out    = messageWriter()
+	)
+	taskIDs = make([]int64, 0, len(sourceURLs))
 	for k := range sourceURLs {
-		taskid, err = pcs.CloudDlAddTask(sourceURLs[k], savePath+baidupcs.PathSeparator)
+		if isCloudDlBtSource(sourceURLs[k]) {
+			taskid, err = addCloudDlBtTask(out, pcs, sourceURLs[k], savePath, selector, opt.ListOnly)
+			if opt.ListOnly && err == nil {
+				continue
+			}
+		} else {
+			taskid, err = pcs.CloudDlAddTask(sourceURLs[k], savePath+baidupcs.PathSeparator)
+		}
 		if err != nil {
-			fmt.Printf("[%d] %s, 地址: %s\n", k+1, err, sourceURLs[k])
+			fmt.Fprintf(out, "[%d] %s, 地址: %s\n", k+1, err, sourceURLs[k])
 			continue
 		}
 
-		fmt.Printf("[%d] 添加离线任务成功, 任务ID(task_id): %d, 源地址: %s, 保存路径: %s\n", k+1, taskid, sourceURLs[k], savePath)
+		fmt.Fprintf(out, "[%d] 添加离线任务成功, 任务ID(task_id): %d, 源地址: %s, 保存路径: %s\n", k+1, taskid, sourceURLs[k], savePath)
+		taskIDs = append(taskIDs, taskid)
 	}
+	return
 }
 
 // RunCloudDlQueryTask 精确查询离线下载任务
 func RunCloudDlQueryTask(taskIDs []int64) {
-	cl, err := GetBaiduPCS().CloudDlQueryTask(taskIDs)
+	cl, err := GetSession().CloudDlQueryTask(taskIDs)
 	if err != nil {
-		fmt.Printf("%s\n", err)
+		printError(err)
 		return
 	}
 
-	fmt.Println(cl)
+	renderList(pcscore.NewCloudDlTaskRecords(cl), func() {
+		fmt.Println(cl)
+		renderCloudDlFileProgress(cl)
+	})
+}
+
+// RunCloudDlWaitTask 每隔 interval 查询一次离线下载任务, 直到任务全部结束, 输出结果.
+// 超过 timeout (为0时不限制) 或收到中断信号时停止等待, 输出已结束的任务, 任务仍在网盘继续下载
+func RunCloudDlWaitTask(taskIDs []int64, interval, timeout time.Duration) {
+	if len(taskIDs) == 0 {
+		return
+	}
+	if interval <= 0 {
+		interval = 5 * time.Second
+	}
+
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt)
+	defer signal.Stop(interrupt)
+
+	var deadline <-chan time.Time
+	if timeout > 0 {
+		timer := time.NewTimer(timeout)
+		defer timer.Stop()
+		deadline = timer.C
+	}
+
+	// 结构化输出时, 进度输出到标准错误
+	out := messageWriter()
+
+	var (
+		pending   = taskIDs
+		results   = make(baidupcs.CloudDlTaskList, 0, len(taskIDs))
+		lastSizes = map[int64]int64{}
+	)
+	fmt.Fprintf(out, "等待 %d 个离线下载任务结束, 每 %s 查询一次...\n", len(taskIDs), interval)
+	for {
+		var (
+			running = make([]int64, 0, len(pending))
+			found   = map[int64]bool{}
+		)
+		for start := 0; start < len(pending); start += cloudDlQueryMaxTaskIDs {
+			end := start + cloudDlQueryMaxTaskIDs
+			if end > len(pending) {
+				end = len(pending)
+			}
+
+			cl, err := GetSession().CloudDlQueryTask(pending[start:end])
+			if err != nil {
+				if !pcserror.IsRetryable(err) {
+					fmt.Fprintf(out, "查询离线下载任务错误, %s\n", err)
+					return
+				}
+				// 查询出错, 下次重试
+				fmt.Fprintf(out, "查询离线下载任务错误, %s, 稍后重试\n", err)
+				running = append(running, pending[start:end]...)
+				for _, id := range pending[start:end] {
+					found[id] = true
+				}
+				continue
+			}
+
+			for _, task := range cl {
+				found[task.TaskID] = true
+				if task.Status == cloudDlStatusRunning {
+					running = append(running, task.TaskID)
+					if task.FinishedSize != lastSizes[task.TaskID] {
+						lastSizes[task.TaskID] = task.FinishedSize
+						fmt.Fprintf(out, "[%d] %s, %s/%s\n", task.TaskID, task.StatusText, converter.ConvertFileSize(task.FinishedSize, 2), converter.ConvertFileSize(task.FileSize, 2))
+					}
+					continue
+				}
+				fmt.Fprintf(out, "[%d] %s, 任务名称: %s\n", task.TaskID, task.StatusText, task.TaskName)
+				results = append(results, task)
+			}
+		}
+		for _, id := range pending {
+			if !found[id] {
+				fmt.Fprintf(out, "[%d] 任务不存在\n", id)
+			}
+		}
+
+		pending = running
+		if len(pending) == 0 {
+			break
+		}
+
+		var stopReason string
+		select {
+		case <-time.After(interval):
+		case <-deadline:
+			stopReason = "等待超时"
+		case <-interrupt:
+			stopReason = "收到中断信号"
+		}
+		if stopReason != "" {
+			printError(fmt.Errorf("%s, 停止等待, 未结束的任务: %s", stopReason, formatTaskIDs(pending)))
+			break
+		}
+	}
+
+	var succeeded int
+	for _, task := range results {
+		if task.Status == 0 {
+			succeeded++
+		}
+	}
+	renderList(pcscore.NewCloudDlTaskRecords(results), func() {
+		if len(pending) == 0 {
+			fmt.Printf("\n离线下载任务全部结束, 成功: %d, 失败: %d\n", succeeded, len(results)-succeeded)
+		} else {
+			fmt.Printf("\n已结束 %d 个离线下载任务, 成功: %d, 失败: %d, 未结束: %d\n", len(results), succeeded, len(results)-succeeded, len(pending))
+		}
+		renderCloudDlFileProgress(results)
+	})
+}
+
+// formatTaskIDs 以逗号分隔任务ID
+func formatTaskIDs(taskIDs []int64) string {
+	ids := make([]string, 0, len(taskIDs))
+	for _, id := range taskIDs {
+		ids = append(ids, strconv.FormatInt(id, 10))
+	}
+	return strings.Join(ids, ", ")
 }
 
 // RunCloudDlListTask 查询离线下载任务列表
 func RunCloudDlListTask() {
-	cl, err := GetBaiduPCS().CloudDlListTask()
+	cl, err := GetSession().CloudDlListTask()
 	if err != nil {
-		fmt.Printf("%s\n", err)
+		printError(err)
 		return
 	}
 
-	fmt.Println(cl)
+	renderList(pcscore.NewCloudDlTaskRecords(cl), func() {
+		fmt.Println(cl)
+	})
 }
 
 // RunCloudDlCancelTask 取消离线下载任务
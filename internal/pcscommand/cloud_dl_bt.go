@@ -0,0 +1,314 @@
+package pcscommand
+
+import (
+	"errors"
+	"fmt"
+	"github.com/Erope/BaiduPCS-Go/baidupcs"
+	"github.com/Erope/BaiduPCS-Go/internal/pcsconfig"
+	"github.com/Erope/BaiduPCS-Go/pcscore"
+	"github.com/Erope/BaiduPCS-Go/pcstable"
+	"github.com/Erope/BaiduPCS-Go/pcsutil/converter"
+	"github.com/Erope/BaiduPCS-Go/requester/multipartreader"
+	"github.com/Erope/BaiduPCS-Go/requester/rio"
+	"github.com/olekukonko/tablewriter"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// cloudDlTorrentDir 上传本地种子文件的网盘临时目录
+	cloudDlTorrentDir = "/apps/BaiduPCS-Go/torrent"
+)
+
+var (
+	// ErrCloudDlBtNoFileSelected 没有选择要下载的文件
+	ErrCloudDlBtNoFileSelected = errors.New("没有选择要下载的文件")
+)
+
+type (
+	// CloudDlAddOptions 添加离线下载任务可选项
+	CloudDlAddOptions struct {
+		Select   string // BT资源选择要下载的文件, 见 ParseCloudDlBtSelector, 为空则全部下载
+		ListOnly bool   // BT资源只列出文件, 不添加任务
+	}
+
+	// CloudDlBtSelector 选择BT资源中要下载的文件
+	CloudDlBtSelector struct {
+		ranges      [][2]int           // 文件序号的范围
+		globs       []string           // 文件名的通配符
+		sizeFilters []func(int64) bool // 文件大小的条件, 需全部满足
+	}
+)
+
+// ParseCloudDlBtSelector 解析文件选择表达式, 多个条件以逗号分隔.
+// 条件可以是文件序号 (如 3), 序号范围 (如 1-5), 文件名的通配符 (如 *.mkv),
+// 或文件大小 (如 >100MB, <=1GB). 满足任一序号或通配符, 且满足所有大小条件的文件会被选择,
+// 只有大小条件时, 所有满足大小条件的文件会被选择
+func ParseCloudDlBtSelector(expr string) (*CloudDlBtSelector, error) {
+	selector := &CloudDlBtSelector{}
+	for _, token := range strings.Split(expr, ",") {
+		token = strings.TrimSpace(token)
+		if token == "" {
+			continue
+		}
+
+		switch {
+		case token[0] == '>' || token[0] == '<':
+			filter, err := parseSizeFilter(token)
+			if err != nil {
+				return nil, err
+			}
+			selector.sizeFilters = append(selector.sizeFilters, filter)
+		case token[0] >= '0' && token[0] <= '9':
+			r, err := parseIndexRange(token)
+			if err != nil {
+				return nil, err
+			}
+			selector.ranges = append(selector.ranges, r)
+		default:
+			if _, err := path.Match(token, ""); err != nil {
+				return nil, fmt.Errorf("通配符不合法: %s, %s", token, err)
+			}
+			selector.globs = append(selector.globs, token)
+		}
+	}
+	return selector, nil
+}
+
+func parseIndexRange(token string) (r [2]int, err error) {
+	from, to := token, token
+	if i := strings.IndexByte(token, '-'); i >= 0 {
+		from, to = token[:i], token[i+1:]
+	}
+	r[0], err = strconv.Atoi(from)
+	if err == nil {
+		r[1], err = strconv.Atoi(to)
+	}
+	if err != nil || r[0] < 1 || r[0] > r[1] {
+		return r, fmt.Errorf("文件序号不合法: %s", token)
+	}
+	return r, nil
+}
+
+func parseSizeFilter(token string) (func(int64) bool, error) {
+	op := token[:1]
+	if len(token) > 1 && token[1] == '=' {
+		op = token[:2]
+	}
+	size, err := converter.ParseFileSizeStr(strings.TrimSpace(token[len(op):]))
+	if err != nil {
+		return nil, fmt.Errorf("文件大小不合法: %s", token)
+	}
+
+	switch op {
+	case ">":
+		return func(s int64) bool { return s > size }, nil
+	case ">=":
+		return func(s int64) bool { return s >= size }, nil
+	case "<":
+		return func(s int64) bool { return s < size }, nil
+	default: // "<="
+		return func(s int64) bool { return s <= size }, nil
+	}
+}
+
+// Match 判断文件是否被选择, selector 为 nil 时选择所有文件
+func (selector *CloudDlBtSelector) Match(file *baidupcs.CloudDlBtFileInfo) bool {
+	if selector == nil {
+		return true
+	}
+
+	for _, filter := range selector.sizeFilters {
+		if !filter(file.Size) {
+			return false
+		}
+	}
+
+	if len(selector.ranges) == 0 && len(selector.globs) == 0 {
+		return true
+	}
+	for _, r := range selector.ranges {
+		if file.Index >= r[0] && file.Index <= r[1] {
+			return true
+		}
+	}
+	for _, glob := range selector.globs {
+		if ok, _ := path.Match(glob, file.FileName); ok {
+			return true
+		}
+		if ok, _ := path.Match(glob, path.Base(file.FileName)); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// isLocalTorrentFile 判断 source 是否为本地的种子文件
+func isLocalTorrentFile(source string) bool {
+	if !strings.HasSuffix(strings.ToLower(source), ".torrent") {
+		return false
+	}
+	info, err := os.Stat(source)
+	return err == nil && !info.IsDir()
+}
+
+// uploadTorrentFile 上传本地的种子文件到网盘的临时目录, 返回网盘路径和删除临时目录的函数.
+// 每次上传使用新的临时目录, 不覆盖网盘中已有的文件
+func uploadTorrentFile(pcs baidupcs.Client, localPath string) (torrentPath string, cleanup func(), err error) {
+	f, err := os.Open(localPath)
+	if err != nil {
+		return "", nil, err
+	}
+	defer f.Close()
+
+	tmpDir := path.Join(cloudDlTorrentDir, strconv.FormatInt(time.Now().UnixNano(), 36))
+	torrentPath = path.Join(tmpDir, filepath.Base(localPath))
+	pcsError := pcs.UploadWithOnDup(torrentPath, baidupcs.OnDupNewCopy, func(uploadURL string, jar http.CookieJar) (*http.Response, error) {
+		client := pcsconfig.Config.PCSHTTPClient()
+		client.SetCookiejar(jar)
+
+		mr := multipartreader.NewMultipartReader()
+		mr.AddFormFile("uploadedfile", "", rio.NewFileReaderLen64(f))
+		mr.CloseMultipart()
+		return client.ReqWithContext(pcs.Context(), http.MethodPost, uploadURL, mr, nil)
+	})
+	if pcsError != nil {
+		return "", nil, pcsError
+	}
+	return torrentPath, func() {
+		pcsError := pcs.Remove(tmpDir)
+		if pcsError != nil {
+			pcsCommandVerbose.Warnf("remove torrent file error: %s\n", pcsError)
+		}
+	}, nil
+}
+
+// queryCloudDlBtInfo 获取磁力链接或种子文件的信息, 本地的种子文件会先上传到临时目录,
+// 不再需要种子文件时调用 cleanup 删除
+func queryCloudDlBtInfo(out io.Writer, pcs baidupcs.Client, source, savePath string) (info *baidupcs.CloudDlBtInfo, cleanup func(), err error) {
+	cleanup = func() {}
+	if baidupcs.IsMagnetURL(source) {
+		info, pcsError := pcs.CloudDlQueryMagnetInfo(source, savePath+baidupcs.PathSeparator)
+		if pcsError != nil {
+			return nil, cleanup, pcsError
+		}
+		return info, cleanup, nil
+	}
+
+	torrentPath := source
+	if isLocalTorrentFile(source) {
+		torrentPath, cleanup, err = uploadTorrentFile(pcs, source)
+		if err != nil {
+			return nil, func() {}, fmt.Errorf("上传种子文件错误, %s", err)
+		}
+		fmt.Fprintf(out, "种子文件已上传到: %s\n", torrentPath)
+	} else {
+		err = matchPathByShellPatternOnce(&torrentPath)
+		if err != nil {
+			return nil, cleanup, err
+		}
+	}
+
+	info, pcsError := pcs.CloudDlQueryTorrentInfo(torrentPath)
+	if pcsError != nil {
+		cleanup()
+		return nil, func() {}, pcsError
+	}
+	return info, cleanup, nil
+}
+
+// isCloudDlBtSource 判断 source 是否为磁力链接或种子文件
+func isCloudDlBtSource(source string) bool {
+	return baidupcs.IsMagnetURL(source) || strings.HasSuffix(strings.ToLower(source), ".torrent")
+}
+
+// addCloudDlBtTask 添加BT离线下载任务, 输出文件列表, 按 selector 选择文件
+func addCloudDlBtTask(out io.Writer, pcs baidupcs.Client, source, savePath string, selector *CloudDlBtSelector, listOnly bool) (taskID int64, err error) {
+	info, cleanup, err := queryCloudDlBtInfo(out, pcs, source, savePath)
+	if err != nil {
+		return 0, err
+	}
+	defer cleanup()
+
+	var (
+		selectedIdx  []int
+		selectedSize int64
+		records      = make([]*pcscore.CloudDlBtFileRecord, 0, len(info.FileList))
+	)
+	for _, file := range info.FileList {
+		selected := selector.Match(file)
+		if selected {
+			selectedIdx = append(selectedIdx, file.Index)
+			selectedSize += file.Size
+		}
+		records = append(records, &pcscore.CloudDlBtFileRecord{
+			Index:    file.Index,
+			FileName: file.FileName,
+			Size:     file.Size,
+			Selected: selected,
+		})
+	}
+
+	if listOnly {
+		renderList(records, func() {
+			renderCloudDlBtFileList(os.Stdout, records)
+			fmt.Printf("共 %d 个文件, 总大小: %s, 已选择 %d 个文件, 大小: %s\n", len(records), converter.ConvertFileSize(info.TotalSize, 2), len(selectedIdx), converter.ConvertFileSize(selectedSize, 2))
+		})
+		return 0, nil
+	}
+
+	renderCloudDlBtFileList(out, records)
+	fmt.Fprintf(out, "共 %d 个文件, 已选择 %d 个文件, 大小: %s\n", len(records), len(selectedIdx), converter.ConvertFileSize(selectedSize, 2))
+	if len(selectedIdx) == 0 {
+		return 0, ErrCloudDlBtNoFileSelected
+	}
+
+	taskID, pcsError := pcs.CloudDlAddBtTask(info, savePath+baidupcs.PathSeparator, selectedIdx)
+	if pcsError != nil {
+		return 0, pcsError
+	}
+	return taskID, nil
+}
+
+// renderCloudDlBtFileList 输出BT资源的文件列表到 w
+func renderCloudDlBtFileList(w io.Writer, records []*pcscore.CloudDlBtFileRecord) {
+	tb := pcstable.NewTable(w)
+	tb.SetHeader([]string{"序号", "选择", "文件大小", "文件名"})
+	tb.SetColumnAlignment([]int{tablewriter.ALIGN_RIGHT, tablewriter.ALIGN_CENTER, tablewriter.ALIGN_RIGHT, tablewriter.ALIGN_LEFT})
+	for _, record := range records {
+		var selected string
+		if record.Selected {
+			selected = "*"
+		}
+		tb.Append([]string{strconv.Itoa(record.Index), selected, converter.ConvertFileSize(record.Size, 2), record.FileName})
+	}
+	tb.Render()
+}
+
+// renderCloudDlFileProgress 输出BT任务中每个文件的进度
+func renderCloudDlFileProgress(cl baidupcs.CloudDlTaskList) {
+	for _, task := range cl {
+		if task == nil || len(task.FileList) == 0 {
+			continue
+		}
+
+		fmt.Printf("\n任务ID: %d, %s\n", task.TaskID, task.TaskName)
+		tb := pcstable.NewTable(os.Stdout)
+		tb.SetHeader([]string{"#", "文件大小", "已下载", "进度", "文件名"})
+		tb.SetColumnAlignment([]int{tablewriter.ALIGN_DEFAULT, tablewriter.ALIGN_RIGHT, tablewriter.ALIGN_RIGHT, tablewriter.ALIGN_RIGHT, tablewriter.ALIGN_LEFT})
+		for k, file := range task.FileList {
+			progress := "-"
+			if file.FileSize > 0 {
+				progress = strconv.FormatFloat(float64(file.FinishedSize)*100/float64(file.FileSize), 'f', 1, 64) + "%"
+			}
+			tb.Append([]string{strconv.Itoa(k), converter.ConvertFileSize(file.FileSize, 2), converter.ConvertFileSize(file.FinishedSize, 2), progress, file.FileName})
+		}
+		tb.Render()
+	}
+}
@@ -0,0 +1,55 @@
+package pcscommand
+
+import (
+	"github.com/Erope/BaiduPCS-Go/baidupcs"
+	"testing"
+)
+
+func TestCloudDlBtSelector(t *testing.T) {
+	files := []*baidupcs.CloudDlBtFileInfo{
+		{Index: 1, FileName: "movie/a.mkv", Size: 200 << 20},
+		{Index: 2, FileName: "movie/b.mkv", Size: 50 << 20},
+		{Index: 3, FileName: "movie/a.srt", Size: 10 << 10},
+		{Index: 4, FileName: "readme.txt", Size: 1 << 10},
+	}
+
+	cases := []struct {
+		expr     string
+		selected []int
+	}{
+		{"", []int{1, 2, 3, 4}},
+		{"2-3", []int{2, 3}},
+		{"4, 1", []int{1, 4}},
+		{"*.mkv", []int{1, 2}},
+		{"movie/*.srt", []int{3}},
+		{">100MB", []int{1}},
+		{"*.mkv,>=50MB", []int{1, 2}},
+		{"3,*.mkv,<100MB", []int{2, 3}},
+	}
+	for _, c := range cases {
+		selector, err := ParseCloudDlBtSelector(c.expr)
+		if err != nil {
+			t.Fatalf("%q: %s", c.expr, err)
+		}
+		var selected []int
+		for _, file := range files {
+			if selector.Match(file) {
+				selected = append(selected, file.Index)
+			}
+		}
+		if len(selected) != len(c.selected) {
+			t.Fatalf("%q: expect %v, got %v", c.expr, c.selected, selected)
+		}
+		for k := range selected {
+			if selected[k] != c.selected[k] {
+				t.Fatalf("%q: expect %v, got %v", c.expr, c.selected, selected)
+			}
+		}
+	}
+
+	for _, expr := range []string{"0", "3-1", "1-x", ">abc", "[a"} {
+		if _, err := ParseCloudDlBtSelector(expr); err == nil {
+			t.Fatalf("%q: expect error", expr)
+		}
+	}
+}
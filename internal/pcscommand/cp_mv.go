@@ -8,17 +8,17 @@ import (
 	"strings"
 )
 
-// RunCopy 执行 批量拷贝文件/目录
-func RunCopy(paths ...string) (err error) {
-	return runCpMvOp("copy", paths...)
+// RunCopy 执行 批量拷贝文件/目录, ondup 为目标已存在时的处理方式
+func RunCopy(ondup baidupcs.OnDup, paths ...string) (err error) {
+	return runCpMvOp("copy", ondup, paths...)
 }
 
-// RunMove 执行 批量 重命名/移动 文件/目录
-func RunMove(paths ...string) (err error) {
-	return runCpMvOp("move", paths...)
+// RunMove 执行 批量 重命名/移动 文件/目录, ondup 为目标已存在时的处理方式
+func RunMove(ondup baidupcs.OnDup, paths ...string) (err error) {
+	return runCpMvOp("move", ondup, paths...)
 }
 
-func runCpMvOp(op string, paths ...string) (err error) {
+func runCpMvOp(op string, ondup baidupcs.OnDup, paths ...string) (err error) {
 	err = cpmvPathValid(paths...) // 检查路径的有效性, 目前只是判断数量
 	if err != nil {
 		fmt.Printf("%s path error, %s\n", op, err)
@@ -66,8 +66,9 @@ func runCpMvOp(op string, paths ...string) (err error) {
 
 		if op == "copy" { // 拷贝
 			err = pcs.Copy(&baidupcs.CpMvJSON{
-				From: froms[0],
-				To:   to,
+				From:  froms[0],
+				To:    to,
+				OnDup: ondup,
 			})
 			if err != nil {
 				fmt.Println(err)
@@ -94,44 +95,52 @@ func runCpMvOp(op string, paths ...string) (err error) {
 		return
 	}
 
-	if !toInfo.Isdir {
-		fmt.Printf("目标 %s 不是一个目录, 操作失败\n", toInfo.Path)
-		return
-	}
-
 	cj := new(baidupcs.CpMvListJSON)
-	cj.List = make([]*baidupcs.CpMvJSON, len(froms))
-	for k := range froms {
-		cj.List[k] = &baidupcs.CpMvJSON{
-			From: froms[k],
-			To:   path.Clean(to + baidupcs.PathSeparator + path.Base(froms[k])),
+	switch {
+	case toInfo.Isdir:
+		cj.List = make([]*baidupcs.CpMvJSON, len(froms))
+		for k := range froms {
+			cj.List[k] = &baidupcs.CpMvJSON{
+				From:  froms[k],
+				To:    path.Clean(to + baidupcs.PathSeparator + path.Base(froms[k])),
+				OnDup: ondup,
+			}
+		}
+	case len(froms) == 1 && ondup != baidupcs.OnDupDefault && ondup != baidupcs.OnDupFail:
+		// 目标为已存在的文件, 按 ondup 处理
+		cj.List = []*baidupcs.CpMvJSON{
+			{
+				From:  froms[0],
+				To:    to,
+				OnDup: ondup,
+			},
 		}
+	default:
+		fmt.Printf("目标 %s 不是一个目录, 操作失败\n", toInfo.Path)
+		return
 	}
 
+	var (
+		results baidupcs.BatchResultList
+		opName  string
+	)
 	switch op {
 	case "copy":
-		err = pcs.Copy(cj.List...)
-		if err != nil {
-			fmt.Println(err)
-			fmt.Println("操作失败, 以下文件/目录拷贝失败: ")
-			fmt.Println(cj)
-			return
-		}
-		fmt.Println("操作成功, 以下文件/目录拷贝成功: ")
-		fmt.Println(cj)
+		results = pcs.BatchCopy(cj.List...)
+		opName = "拷贝"
 	case "move":
-		err = pcs.Move(cj.List...)
-		if err != nil {
-			fmt.Println(err)
-			fmt.Println("操作失败, 以下文件/目录移动失败: ")
-			fmt.Println(cj)
-			return
-		}
-		fmt.Println("操作成功, 以下文件/目录移动成功: ")
-		fmt.Println(cj)
+		results = pcs.BatchMove(cj.List...)
+		opName = "移动"
 	default:
 		panic("Unknown operation:" + op)
 	}
+
+	if printBatchSummary(results, opName, true) {
+		return results.Err()
+	}
+
+	fmt.Printf("操作成功, 以下文件/目录%s成功: \n", opName)
+	printBatchResults(results, true)
 	return
 }
 
@@ -1,6 +1,7 @@
 package pcscommand
 
 import (
+	"context"
 	"encoding/hex"
 	"errors"
 	"fmt"
@@ -11,6 +12,7 @@ import (
 	"github.com/Erope/BaiduPCS-Go/pcstable"
 	"github.com/Erope/BaiduPCS-Go/pcsutil/checksum"
 	"github.com/Erope/BaiduPCS-Go/pcsutil/converter"
+	"github.com/Erope/BaiduPCS-Go/pcsutil/streamcrypto"
 	"github.com/Erope/BaiduPCS-Go/pcsutil/waitgroup"
 	"github.com/Erope/BaiduPCS-Go/requester"
 	"github.com/Erope/BaiduPCS-Go/requester/downloader"
@@ -73,7 +75,15 @@ type (
 		Load                   int
 		MaxRetry               int
 		NoCheck                bool
-		Out                    io.Writer
+		Decrypt                bool                     // 解密使用 upload -encrypt 上传的文件和文件名, 不支持断点续传
+		Out                    io.Writer                `json:"-"`
+		Progress               transfer.ProgressHandler `json:"-"` // 传输事件, 为 nil 则不发送
+
+		bgTask    *BgTask           // 所属的后台任务
+		journal   *transferJournal  // 传输日志, 恢复任务时预先设置
+		noJournal bool              // 不记录到传输日志, 如 bisync 重新执行即可继续
+		savePaths map[string]string // 网盘文件对应的本地保存路径, 不为空时 paths 为确切的网盘路径, 不匹配通配符
+		encKey    []byte            // 客户端加密的主密钥
 	}
 
 	// LocateDownloadOption 获取下载链接可选参数
@@ -93,11 +103,27 @@ func download(id int, fileInfo *baidupcs.FileDirectory, downloadURL, savePath st
 	var (
 		writer downloader.Writer
 		file   *os.File
+		dw     *streamcrypto.DecryptWriterAt
+		header []byte
 		err    error
 	)
 
+	if downloadOptions.Decrypt && !newCfg.IsTest {
+		header, err = readEncryptHeader(newCfg.Context, client, downloadURL, fileInfo.Size)
+		switch {
+		case errors.Is(err, streamcrypto.ErrNotEncrypted):
+			fmt.Fprintf(downloadOptions.Out, "[%d] 文件未加密, 不解密\n", id)
+		case err != nil:
+			return err
+		}
+	}
+
 	if !newCfg.IsTest {
 		newCfg.InstanceStatePath = savePath + DownloadSuffix
+		if header != nil {
+			// 未完整的分块只在内存中, 不记录断点
+			newCfg.InstanceStatePath = ""
+		}
 
 		// 创建下载的目录
 		dir := filepath.Dir(savePath)
@@ -119,6 +145,14 @@ func download(id int, fileInfo *baidupcs.FileDirectory, downloadURL, savePath st
 		defer file.Close()
 	}
 
+	if header != nil {
+		dw, err = streamcrypto.NewDecryptWriterAt(downloadOptions.encKey, header, file, fileInfo.Size)
+		if err != nil {
+			return fmt.Errorf("%s, %s", StrDownloadInitError, err)
+		}
+		writer = dw
+	}
+
 	download := downloader.NewDownloader(downloadURL, writer, &newCfg)
 	// download.SetFirstInfo(&downloader.DownloadFirstInfo{
 	// 	ContentLength: fileInfo.Size,
@@ -158,6 +192,8 @@ func download(id int, fileInfo *baidupcs.FileDirectory, downloadURL, savePath st
 			leftStr = left.String()
 		}
 
+		emitProgress(downloadOptions.Progress, newDownloadEvent(transfer.ProgressProgress, id, fileInfo.Path, savePath).SetStatus(status, status.Downloaded()))
+
 		fmt.Fprintf(downloadOptions.Out, format, id,
 			converter.ConvertFileSize(status.Downloaded(), 2),
 			converter.ConvertFileSize(status.TotalSize(), 2),
@@ -166,7 +202,14 @@ func download(id int, fileInfo *baidupcs.FileDirectory, downloadURL, savePath st
 		)
 	})
 
+	downloadOptions.bgTask.attach(id, download)
+	defer downloadOptions.bgTask.detach(id)
+
 	download.OnExecute(func() {
+		event := newDownloadEvent(transfer.ProgressStarted, id, fileInfo.Path, savePath)
+		event.Size = fileInfo.Size
+		emitProgress(downloadOptions.Progress, event)
+
 		if newCfg.IsTest {
 			fmt.Fprintf(downloadOptions.Out, "[%d] 测试下载开始\n\n", id)
 		}
@@ -174,7 +217,22 @@ func download(id int, fileInfo *baidupcs.FileDirectory, downloadURL, savePath st
 
 	err = download.Execute()
 	fmt.Fprintf(downloadOptions.Out, "\n")
+	if err == nil && dw != nil {
+		err = dw.Finish()
+		if err == nil {
+			err = file.Truncate(dw.PlainSize())
+		}
+	}
 	if err != nil {
+		if dw != nil {
+			// 解密下载不能断点续传, 删去不完整的文件
+			file.Close()
+			removeErr := os.Remove(savePath)
+			if removeErr != nil {
+				pcsCommandVerbose.Infof("[%d] remove file error: %s\n", id, removeErr)
+			}
+			return err
+		}
 		if !newCfg.IsTest {
 			// 下载失败, 删去空文件
 			if info, infoErr := file.Stat(); infoErr == nil {
@@ -206,6 +264,62 @@ func download(id int, fileInfo *baidupcs.FileDirectory, downloadURL, savePath st
 	return nil
 }
 
+func newDownloadEvent(eventType transfer.ProgressEventType, id int, pcspath, savePath string) *transfer.ProgressEvent {
+	event := transfer.NewProgressEvent(eventType, transfer.DirectionDownload, id)
+	event.Path = pcspath
+	event.SavePath = savePath
+	return event
+}
+
+func (task *dtask) newEvent(eventType transfer.ProgressEventType) *transfer.ProgressEvent {
+	event := newDownloadEvent(eventType, task.ID, task.path, task.savePath)
+	if task.downloadInfo != nil {
+		event.Size = task.downloadInfo.Size
+		event.Isdir = task.downloadInfo.Isdir
+	}
+	return event
+}
+
+func (task *dtask) retryEvent(errManifest string, err error) *transfer.ProgressEvent {
+	event := task.newEvent(transfer.ProgressRetry)
+	event.Retry, event.MaxRetry = task.retry, task.MaxRetry
+	event.Error = errManifest + ", " + err.Error()
+	return event
+}
+
+func (task *dtask) failedEvent(errManifest string, err error) *transfer.ProgressEvent {
+	event := task.newEvent(transfer.ProgressFailed)
+	event.Retry, event.MaxRetry = task.retry, task.MaxRetry
+	event.Error = errManifest + ", " + err.Error()
+	return event
+}
+
+func (task *dtask) finishedEvent() *transfer.ProgressEvent {
+	event := task.newEvent(transfer.ProgressFinished)
+	event.Bytes = event.Size
+	return event
+}
+
+// checksumEvent 文件校验结果事件, err 为 checkFileValid 的返回值
+func (task *dtask) checksumEvent(err error) *transfer.ProgressEvent {
+	event := task.newEvent(transfer.ProgressChecksum)
+	event.Bytes = event.Size
+	switch err {
+	case nil:
+		event.Checksum = "ok"
+	case ErrDownloadChecksumFailed:
+		event.Checksum = "mismatch"
+	case ErrDownloadNotSupportChecksum, ErrDownloadFileBanned:
+		event.Checksum = "unsupported"
+	default:
+		event.Checksum = "error"
+	}
+	if err != nil {
+		event.Error = err.Error()
+	}
+	return event
+}
+
 // checkFileValid 检测文件有效性
 func checkFileValid(filePath string, fileInfo *baidupcs.FileDirectory) error {
 	if len(fileInfo.BlockList) != 1 {
@@ -254,6 +368,15 @@ func RunDownload(paths []string, options *DownloadOptions) {
 		options.MaxRetry = DefaultDownloadMaxRetry
 	}
 
+	if options.Decrypt && options.encKey == nil {
+		key, err := pcsconfig.Config.EncryptionKey()
+		if err != nil {
+			fmt.Fprintf(options.Out, "%s\n", err)
+			return
+		}
+		options.encKey = key
+	}
+
 	// 设置下载配置
 	cfg := &downloader.Config{
 		Mode:                       transfer.RangeGenMode_BlockSize,
@@ -270,17 +393,33 @@ func RunDownload(paths []string, options *DownloadOptions) {
 		options.Parallel = pcsconfig.Config.MaxParallel
 	}
 
-	paths, err := matchPathByShellPattern(paths...)
-	if err != nil {
-		fmt.Println(err)
-		return
+	var err error
+	if options.savePaths == nil {
+		paths, err = matchPathByShellPattern(paths...)
+		if err != nil {
+			fmt.Fprintln(options.Out, err)
+			return
+		}
 	}
 
+	// 前台下载, 收到中断信号时取消下载
+	if options.bgTask == nil {
+		options.bgTask = newBgTask(transfer.DirectionDownload, paths, nil)
+		defer handleInterrupt(options.Out, options.bgTask)()
+	}
+	cfg.Context = options.bgTask.Context()
+
+	// 记录到传输日志, 中断后可使用 resume 恢复
+	if !options.IsTest && options.journal == nil && !options.noJournal {
+		options.journal = newTransferJournal(options.Out, transfer.DirectionDownload, paths, "", options)
+	}
+	options.Progress = options.journal.wrap(options.Progress)
+
 	fmt.Fprintf(options.Out, "\n")
 	fmt.Fprintf(options.Out, "[0] 提示: 当前下载最大并发量为: %d, 下载缓存为: %d\n", options.Parallel, cfg.CacheSize)
 
 	var (
-		pcs       = GetBaiduPCS()
+		pcs       = baidupcs.ClientWithContext(GetBaiduPCS(), options.bgTask.Context())
 		dlist     = lane.NewDeque()
 		lastID    = 0
 		loadCount = 0
@@ -289,19 +428,19 @@ func RunDownload(paths []string, options *DownloadOptions) {
 	// 预测要下载的文件数量
 	// TODO: pcscache
 	for k := range paths {
-		pcs.FilesDirectoriesRecurseList(paths[k], baidupcs.DefaultOrderOptions, func(depth int, _ string, fd *baidupcs.FileDirectory, pcsError pcserror.Error) bool {
+		pcs.Walk(paths[k], pcsconfig.Config.WalkOptions(), func(_ string, fd *baidupcs.FileDirectory, pcsError pcserror.Error) error {
 			if pcsError != nil {
 				pcsCommandVerbose.Warnf("%s\n", pcsError)
-				return true
+				return nil
 			}
 
 			if !fd.Isdir {
 				loadCount++
 				if loadCount >= options.Load {
-					return false
+					return baidupcs.StopWalk
 				}
 			}
-			return true
+			return nil
 		})
 
 		if loadCount >= options.Load {
@@ -327,12 +466,18 @@ func RunDownload(paths []string, options *DownloadOptions) {
 			},
 			path: paths[k],
 		}
-		if options.SaveTo != "" {
+		if savePath, ok := options.savePaths[paths[k]]; ok {
+			ptask.savePath = savePath
+		} else if options.SaveTo != "" {
 			ptask.savePath = filepath.Join(options.SaveTo, filepath.Base(paths[k]))
 		} else {
 			ptask.savePath = GetActiveUser().GetSavePath(paths[k])
 		}
+		if options.Decrypt {
+			ptask.savePath = decryptLocalPath(options.encKey, ptask.savePath)
+		}
 		dlist.Append(ptask)
+		emitProgress(options.Progress, newDownloadEvent(transfer.ProgressQueued, lastID, ptask.path, ptask.savePath))
 		fmt.Fprintf(options.Out, "[%d] 加入下载队列: %s\n", lastID, paths[k])
 	}
 
@@ -350,10 +495,24 @@ func RunDownload(paths []string, options *DownloadOptions) {
 
 			// 不重试的情况
 			switch {
+			case errors.Is(err, context.Canceled):
+				fmt.Fprintf(options.Out, "[%d] 下载已取消\n", task.ID)
+				emitProgress(options.Progress, task.newEvent(transfer.ProgressCanceled))
+				return
 			case err == ErrDownloadNotSupportChecksum:
-				fallthrough
+				// 文件已下载, 只是不支持校验
+				fmt.Fprintf(options.Out, "[%d] %s, %s\n", task.ID, errManifest, err)
+				emitProgress(options.Progress, task.finishedEvent())
+				return
+			case errors.Is(err, streamcrypto.ErrAuthFailed):
+				// 密钥错误或数据已损坏, 重试无效
+				fmt.Fprintf(options.Out, "[%d] %s, %s\n", task.ID, errManifest, err)
+				emitProgress(options.Progress, task.failedEvent(errManifest, err))
+				failedList = append(failedList, task.path)
+				return
 			case errManifest == StrDownloadFailed && strings.Contains(err.Error(), StrDownloadInitError):
 				fmt.Fprintf(options.Out, "[%d] %s, %s\n", task.ID, errManifest, err)
+				emitProgress(options.Progress, task.failedEvent(errManifest, err))
 				return
 			}
 
@@ -361,10 +520,12 @@ func RunDownload(paths []string, options *DownloadOptions) {
 			if task.retry < task.MaxRetry {
 				task.retry++
 				fmt.Fprintf(options.Out, "[%d] %s, %s, 重试 %d/%d\n", task.ID, errManifest, err, task.retry, task.MaxRetry)
+				emitProgress(options.Progress, task.retryEvent(errManifest, err))
 				dlist.Append(task)
 				time.Sleep(3 * time.Duration(task.retry) * time.Second)
 			} else {
 				fmt.Fprintf(options.Out, "[%d] %s, %s\n", task.ID, errManifest, err)
+				emitProgress(options.Progress, task.failedEvent(errManifest, err))
 				failedList = append(failedList, task.path)
 			}
 
@@ -398,11 +559,25 @@ func RunDownload(paths []string, options *DownloadOptions) {
 			go func() {
 				defer wg.Done()
 
+				if options.bgTask.stopped() {
+					emitProgress(options.Progress, task.newEvent(transfer.ProgressCanceled))
+					return
+				}
+
+				if options.journal.isDone(task.path) {
+					fmt.Fprintf(options.Out, "[%d] 文件已在之前下载完成: %s, 跳过...\n", task.ID, task.path)
+					event := task.newEvent(transfer.ProgressSkipped)
+					event.Message = "已在之前下载完成"
+					emitProgress(options.Progress, event)
+					return
+				}
+
 				if task.downloadInfo == nil {
 					task.downloadInfo, err = pcs.FilesDirectoriesMeta(task.path)
 					if err != nil {
 						// 不重试
-						fmt.Printf("[%d] 获取路径信息错误, %s\n", task.ID, err)
+						fmt.Fprintf(options.Out, "[%d] 获取路径信息错误, %s\n", task.ID, err)
+						emitProgress(options.Progress, task.failedEvent("获取路径信息错误", err))
 						return
 					}
 				}
@@ -416,33 +591,42 @@ func RunDownload(paths []string, options *DownloadOptions) {
 						os.MkdirAll(task.savePath, 0777) // 首先在本地创建目录, 保证空目录也能被保存
 					}
 
-					fileList, err := pcs.FilesDirectoriesList(task.path, baidupcs.DefaultOrderOptions)
-					if err != nil {
-						// 不重试
-						fmt.Fprintf(options.Out, "[%d] 获取目录信息错误, %s\n", task.ID, err)
-						return
-					}
-
-					for k := range fileList {
+					iter := pcs.ListIter(task.path, nil)
+					for iter.Next() {
+						fd := iter.Value()
 						lastID++
 						subTask := &dtask{
 							ListTask: ListTask{
 								ID:       lastID,
 								MaxRetry: options.MaxRetry,
 							},
-							path:         fileList[k].Path,
-							downloadInfo: fileList[k],
+							path:         fd.Path,
+							downloadInfo: fd,
 						}
 
 						if options.SaveTo != "" {
-							subTask.savePath = filepath.Join(task.savePath, fileList[k].Filename)
+							subTask.savePath = filepath.Join(task.savePath, fd.Filename)
 						} else {
 							subTask.savePath = GetActiveUser().GetSavePath(subTask.path)
 						}
+						if options.Decrypt {
+							subTask.savePath = decryptLocalPath(options.encKey, subTask.savePath)
+						}
 
 						dlist.Append(subTask)
-						fmt.Fprintf(options.Out, "[%d] 加入下载队列: %s\n", lastID, fileList[k].Path)
+						emitProgress(options.Progress, newDownloadEvent(transfer.ProgressQueued, lastID, subTask.path, subTask.savePath))
+						fmt.Fprintf(options.Out, "[%d] 加入下载队列: %s\n", lastID, fd.Path)
 					}
+					if iter.Err() != nil {
+						// 不重试
+						fmt.Fprintf(options.Out, "[%d] 获取目录信息错误, %s\n", task.ID, iter.Err())
+						emitProgress(options.Progress, task.failedEvent("获取目录信息错误", iter.Err()))
+						return
+					}
+
+					event := newDownloadEvent(transfer.ProgressFinished, task.ID, task.path, task.savePath)
+					event.Isdir = true
+					emitProgress(options.Progress, event)
 					return
 				}
 
@@ -450,6 +634,9 @@ func RunDownload(paths []string, options *DownloadOptions) {
 
 				if !options.IsTest && !options.IsOverwrite && fileExist(task.savePath) {
 					fmt.Fprintf(options.Out, "[%d] 文件已经存在: %s, 跳过...\n", task.ID, task.savePath)
+					event := newDownloadEvent(transfer.ProgressSkipped, task.ID, task.path, task.savePath)
+					event.Message = "文件已经存在"
+					emitProgress(options.Progress, event)
 					return
 				}
 
@@ -538,16 +725,18 @@ func RunDownload(paths []string, options *DownloadOptions) {
 					return
 				}
 
-				// 检验文件有效性
-				if !cfg.IsTest && !options.NoCheck {
+				// 检验文件有效性, 解密后的文件与服务器记录的 md5 不同, 分块已经过认证
+				if !cfg.IsTest && !options.NoCheck && !options.Decrypt {
 					if task.downloadInfo.Size >= 128*converter.MB {
 						fmt.Fprintf(options.Out, "[%d] 开始检验文件有效性, 请稍候...\n", task.ID)
 					}
 					err = checkFileValid(task.savePath, task.downloadInfo)
+					emitProgress(options.Progress, task.checksumEvent(err))
 					if err != nil {
 						switch err {
 						case ErrDownloadFileBanned:
 							fmt.Fprintf(options.Out, "[%d] 检验文件有效性: %s\n", task.ID, err)
+							emitProgress(options.Progress, task.finishedEvent())
 							return
 						default:
 							handleTaskErr(task, "检验文件有效性出错", err)
@@ -559,6 +748,7 @@ func RunDownload(paths []string, options *DownloadOptions) {
 				}
 
 				atomic.AddInt64(&totalSize, task.downloadInfo.Size)
+				emitProgress(options.Progress, task.finishedEvent())
 			}()
 		}
 		wg.Wait()
@@ -571,13 +761,14 @@ func RunDownload(paths []string, options *DownloadOptions) {
 
 	fmt.Fprintf(options.Out, "\n任务结束, 时间: %s, 数据总量: %s\n", time.Since(startTime)/1e6*1e6, converter.ConvertFileSize(totalSize))
 	if len(failedList) != 0 {
-		fmt.Printf("以下文件下载失败: \n")
-		tb := pcstable.NewTable(os.Stdout)
+		fmt.Fprintf(options.Out, "以下文件下载失败: \n")
+		tb := pcstable.NewTable(options.Out)
 		for k := range failedList {
 			tb.Append([]string{strconv.Itoa(k), failedList[k]})
 		}
 		tb.Render()
 	}
+	options.journal.finish(options.Out, options.bgTask.canceled())
 }
 
 // RunLocateDownload 执行获取直链
@@ -697,7 +888,7 @@ func getLocateDownloadLinks(pcspath string) (dlinks []*url.URL, err error) {
 	return us, nil
 }
 
-func getLocatePanLink(pcs *baidupcs.BaiduPCS, fsID int64) (dlink string, err error) {
+func getLocatePanLink(pcs baidupcs.Client, fsID int64) (dlink string, err error) {
 	list, err := pcs.LocatePanAPIDownload(fsID)
 	if err != nil {
 		return
@@ -0,0 +1,29 @@
+package pcscommand
+
+import (
+	"fmt"
+	"github.com/Erope/BaiduPCS-Go/baidupcs/pcsemu"
+	"net"
+	"net/http"
+)
+
+// RunEmulate 启动本地网盘模拟器, 将本地目录 root 模拟为网盘
+func RunEmulate(addr, root string) error {
+	s, err := pcsemu.NewServer(root)
+	if err != nil {
+		return err
+	}
+
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+
+	apiAddr := "http://" + l.Addr().String()
+	fmt.Printf("网盘模拟器已启动, 根目录: %s, 地址: %s\n", s.Root(), apiAddr)
+	fmt.Printf("执行以下命令, 使程序连接到模拟器:\n")
+	fmt.Printf("  config set -pcs_addr=%s -pan_addr=%s -enable_https=false\n", apiAddr, apiAddr)
+	fmt.Printf("恢复连接到百度网盘:\n")
+	fmt.Printf("  config set -pcs_addr=\"\" -pan_addr=\"\" -enable_https=true\n")
+	return http.Serve(l, s)
+}
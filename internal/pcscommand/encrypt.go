@@ -0,0 +1,70 @@
+package pcscommand
+
+import (
+	"context"
+	"fmt"
+	"github.com/Erope/BaiduPCS-Go/pcsutil/streamcrypto"
+	"github.com/Erope/BaiduPCS-Go/requester"
+	"io"
+	"net/http"
+	"path/filepath"
+	"strings"
+)
+
+// encryptRelPath 加密网盘相对路径 rel 的每一级名称
+func encryptRelPath(key []byte, rel string) (string, error) {
+	names := strings.Split(rel, "/")
+	for k := range names {
+		if names[k] == "" {
+			continue
+		}
+		encrypted, err := streamcrypto.EncryptName(key, names[k])
+		if err != nil {
+			return "", err
+		}
+		names[k] = encrypted
+	}
+	return strings.Join(names, "/"), nil
+}
+
+// decryptLocalPath 解密本地路径 p 中加密的名称, 不是使用 key 加密的名称保持不变
+func decryptLocalPath(key []byte, p string) string {
+	names := strings.Split(p, string(filepath.Separator))
+	for k := range names {
+		if decrypted, err := streamcrypto.DecryptName(key, names[k]); err == nil && decrypted != "" {
+			names[k] = decrypted
+		}
+	}
+	return strings.Join(names, string(filepath.Separator))
+}
+
+// readEncryptHeader 读取网盘文件开头的加密头部, 文件未加密返回 streamcrypto.ErrNotEncrypted
+func readEncryptHeader(ctx context.Context, client *requester.HTTPClient, downloadURL string, size int64) ([]byte, error) {
+	if size < streamcrypto.HeaderSize+streamcrypto.TagSize {
+		return nil, streamcrypto.ErrNotEncrypted
+	}
+
+	resp, err := client.ReqWithContext(ctx, http.MethodGet, downloadURL, nil, map[string]string{
+		"Range": fmt.Sprintf("bytes=0-%d", streamcrypto.HeaderSize-1),
+	})
+	if resp != nil {
+		defer resp.Body.Close()
+	}
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode/100 != 2 {
+		return nil, fmt.Errorf("读取加密头部失败, %s", resp.Status)
+	}
+
+	header := make([]byte, streamcrypto.HeaderSize)
+	_, err = io.ReadFull(resp.Body, header)
+	if err != nil {
+		return nil, err
+	}
+	_, err = streamcrypto.ParseHeader(header)
+	if err != nil {
+		return nil, err
+	}
+	return header, nil
+}
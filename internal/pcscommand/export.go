@@ -2,15 +2,17 @@ package pcscommand
 
 import (
 	"container/list"
+	"errors"
 	"fmt"
 	"github.com/Erope/BaiduPCS-Go/baidupcs"
 	"github.com/Erope/BaiduPCS-Go/baidupcs/pcserror"
+	"github.com/Erope/BaiduPCS-Go/internal/pcsconfig"
 	"github.com/Erope/BaiduPCS-Go/pcsutil/converter"
 	"github.com/Erope/BaiduPCS-Go/pcsutil/pcstime"
 	"os"
 	"path"
+	"sort"
 	"strings"
-	"time"
 )
 
 type (
@@ -47,13 +49,18 @@ func (task *etask) handleExportTaskError(l *list.List, failedList *list.List) {
 		failedList.PushBack(task)
 		return
 	}
+	// 网络错误等已在请求时按重试策略重试过
+	if errors.Is(task.err, pcserror.ErrNotFound) || errors.Is(task.err, pcserror.ErrAuthExpired) || task.err.GetErrType() == pcserror.ErrTypeNetError {
+		fmt.Printf("[%d] - [%s] 导出失败, %s\n", task.ID, task.path, task.err)
+		failedList.PushBack(task)
+		return
+	}
 
 	// 未达到失败重试最大次数, 将任务推送到队列末尾
 	if task.retry < task.MaxRetry {
 		task.retry++
 		fmt.Printf("[%d] - [%s] 导出错误, %s, 重试 %d/%d\n", task.ID, task.path, task.err, task.retry, task.MaxRetry)
 		l.PushBack(task)
-		time.Sleep(3 * time.Duration(task.retry) * time.Second)
 	} else {
 		fmt.Printf("[%d] - [%s] 导出错误, %s\n", task.ID, task.path, task.err)
 		failedList.PushBack(task)
@@ -132,7 +139,6 @@ func RunExport(pcspaths []string, opt *ExportOptions) {
 		l.Remove(e) // 载入任务后, 移除队列
 
 		task := e.Value.(*etask)
-		root := task.fd == nil
 
 		// 获取文件信息
 		if task.fd == nil { // 第一次初始化
@@ -146,30 +152,68 @@ func RunExport(pcspaths []string, opt *ExportOptions) {
 		}
 
 		if task.fd.Isdir { // 导出目录
-			if !root && !opt.Recursive { // 非递归
-				continue
-			}
+			var (
+				files     []*baidupcs.FileDirectory
+				childrenN = map[string]int{} // 目录 -> 子文件和子目录数量, 用于导出空目录
+			)
+			walkErr := pcs.Walk(task.path, pcsconfig.Config.WalkOptions(), func(fdPath string, fd *baidupcs.FileDirectory, pcsError pcserror.Error) error {
+				if pcsError != nil {
+					if fdPath == task.path {
+						return pcsError
+					}
+					// 子目录出错, 不影响其他目录
+					id++
+					fmt.Printf("[%d] - [%s] 导出错误, %s\n", id, fdPath, pcsError)
+					failedList.PushBack(&etask{
+						ListTask: &ListTask{
+							ID: id,
+						},
+						path: fdPath,
+						err:  pcsError,
+					})
+					return nil
+				}
+				if fdPath == task.path {
+					childrenN[fdPath] = 0
+					return nil
+				}
 
-			fds, pcsError := pcs.FilesDirectoriesList(task.path, baidupcs.DefaultOrderOptions)
-			if pcsError != nil {
-				task.err = pcsError
+				childrenN[path.Dir(fdPath)]++
+				if fd.Isdir {
+					if !opt.Recursive { // 非递归
+						return baidupcs.SkipDir
+					}
+					childrenN[fdPath] = 0 // 目录先于其子项回调
+					return nil
+				}
+				files = append(files, fd)
+				return nil
+			})
+			if walkErr != nil {
+				task.err = walkErr.(pcserror.Error) // 只会返回获取 task.path 列表的错误
 				task.handleExportTaskError(l, failedList)
 				continue
 			}
 
-			if len(fds) == 0 {
-				_, writeErr = saveFile.Write(converter.ToBytes(fmt.Sprintf("BaiduPCS-Go mkdir \"%s\"\n", changeRootPath(task.rootPath, task.path, opt.RootPath))))
+			// 按路径排序, 使导出的结果稳定
+			emptyDirs := make([]string, 0, len(childrenN))
+			for dir, n := range childrenN {
+				if n == 0 {
+					emptyDirs = append(emptyDirs, dir)
+				}
+			}
+			sort.Strings(emptyDirs)
+			for _, dir := range emptyDirs {
+				_, writeErr = saveFile.Write(converter.ToBytes(fmt.Sprintf("BaiduPCS-Go mkdir \"%s\"\n", changeRootPath(task.rootPath, dir, opt.RootPath))))
 				if writeErr != nil {
 					fmt.Printf("写入文件失败: %s\n", writeErr)
 					return // 直接返回
 				}
-				fmt.Printf("[%d] - [%s] 导出成功\n", task.ID, task.path)
-				continue
+				fmt.Printf("[%d] - [%s] 导出成功\n", task.ID, dir)
 			}
 
 			// 加入队列
-			for _, fd := range fds {
-				// 加入队列
+			for _, fd := range files {
 				id++
 				l.PushBack(&etask{
 					ListTask: &ListTask{
@@ -0,0 +1,267 @@
+package pcscommand
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/Erope/BaiduPCS-Go/internal/pcsfunctions/pcsjournal"
+	"github.com/Erope/BaiduPCS-Go/pcstable"
+	"github.com/Erope/BaiduPCS-Go/pcsutil/pcstime"
+	"github.com/Erope/BaiduPCS-Go/requester/transfer"
+	"io"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+var (
+	// TransferJournal 传输日志, 保存未完成的下载和上传任务
+	TransferJournal = pcsjournal.NewJournal()
+)
+
+type (
+	// transferJournal 记录一次下载或上传中已完成的文件, 同时转发传输事件
+	transferJournal struct {
+		job        *pcsjournal.Job
+		next       transfer.ProgressHandler
+		mu         sync.Mutex
+		done       map[string]struct{}
+		unfinished int // 失败或取消的数量
+	}
+)
+
+// newTransferJournal 将任务加入传输日志, 出错时返回 nil, 不影响传输
+func newTransferJournal(out io.Writer, direction string, paths []string, savePath string, options interface{}) *transferJournal {
+	rawOptions, err := json.Marshal(options)
+	if err != nil {
+		pcsCommandVerbose.Warnf("marshal options error: %s\n", err)
+	}
+
+	job := &pcsjournal.Job{
+		Direction: direction,
+		UID:       GetActiveUser().UID,
+		Paths:     paths,
+		SavePath:  savePath,
+		Options:   rawOptions,
+	}
+	err = TransferJournal.Add(job)
+	if err != nil {
+		fmt.Fprintf(out, "警告: 写入传输日志错误, %s, 中断后将无法恢复任务\n", err)
+		return nil
+	}
+	return resumeTransferJournal(job)
+}
+
+// resumeTransferJournal 从传输日志中的任务恢复
+func resumeTransferJournal(job *pcsjournal.Job) *transferJournal {
+	tj := &transferJournal{
+		job:  job,
+		done: make(map[string]struct{}, len(job.Done)),
+	}
+	for _, p := range job.Done {
+		tj.done[p] = struct{}{}
+	}
+	return tj
+}
+
+// wrap 设置转发的传输事件处理, 返回 tj 自身, tj 为 nil 时返回 next
+func (tj *transferJournal) wrap(next transfer.ProgressHandler) transfer.ProgressHandler {
+	if tj == nil {
+		return next
+	}
+	tj.next = next
+	return tj
+}
+
+// isDone 文件是否已在之前完成, 下载的文件为网盘路径, 上传的文件为保存的网盘路径
+func (tj *transferJournal) isDone(pcspath string) bool {
+	if tj == nil {
+		return false
+	}
+	tj.mu.Lock()
+	defer tj.mu.Unlock()
+	_, ok := tj.done[pcspath]
+	return ok
+}
+
+// HandleProgress 记录完成, 失败和取消的文件
+func (tj *transferJournal) HandleProgress(event *transfer.ProgressEvent) {
+	switch event.Event {
+	case transfer.ProgressFinished, transfer.ProgressSkipped:
+		if event.Isdir {
+			break
+		}
+		pcspath := event.Path
+		if event.Direction == transfer.DirectionUpload {
+			pcspath = event.SavePath
+		}
+
+		tj.mu.Lock()
+		_, ok := tj.done[pcspath]
+		tj.done[pcspath] = struct{}{}
+		tj.mu.Unlock()
+		if ok {
+			break
+		}
+
+		err := TransferJournal.MarkDone(tj.job.ID, pcspath)
+		if err != nil {
+			pcsCommandVerbose.Warnf("write transfer journal error: %s\n", err)
+		}
+	case transfer.ProgressFailed, transfer.ProgressCanceled:
+		tj.mu.Lock()
+		tj.unfinished++
+		tj.mu.Unlock()
+	}
+	emitProgress(tj.next, event)
+}
+
+// finish 传输结束, 全部完成则从传输日志中移除, 否则打印恢复的提示, tj 为 nil 时不做任何事
+func (tj *transferJournal) finish(out io.Writer, canceled bool) {
+	if tj == nil {
+		return
+	}
+	tj.mu.Lock()
+	defer tj.mu.Unlock()
+
+	if !canceled && tj.unfinished == 0 {
+		err := TransferJournal.Remove(tj.job.ID)
+		if err != nil {
+			pcsCommandVerbose.Warnf("remove transfer journal error: %s\n", err)
+		}
+		return
+	}
+	fmt.Fprintf(out, "任务未完成, 已完成 %d 个文件, 未完成 %d 个文件, 已保存到传输日志, 使用 resume %d 继续\n", len(tj.done), tj.unfinished, tj.job.ID)
+}
+
+// handleInterrupt 前台传输时, 收到中断信号则取消传输, 已完成的部分可使用 resume 继续, 再次收到则立即退出.
+// 返回的函数用于停止监听
+func handleInterrupt(out io.Writer, task *BgTask) (stop func()) {
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt)
+	quit := make(chan struct{})
+	go func() {
+		for {
+			select {
+			case <-interrupt:
+				if task.Cancel() == ErrBgTaskEnded {
+					os.Exit(130)
+				}
+				fmt.Fprintf(out, "\n收到中断信号, 正在保存传输状态, 再次按下 Ctrl+C 立即退出...\n")
+			case <-quit:
+				return
+			}
+		}
+	}()
+	return func() {
+		signal.Stop(interrupt)
+		close(quit)
+	}
+}
+
+// RunResumeList 列出传输日志中未完成的任务
+func RunResumeList() {
+	jobs, err := TransferJournal.List()
+	if err != nil {
+		fmt.Printf("读取传输日志错误, %s\n", err)
+		return
+	}
+	if len(jobs) == 0 {
+		fmt.Printf("没有未完成的传输任务\n")
+		return
+	}
+
+	tb := pcstable.NewTable(os.Stdout)
+	tb.SetHeader([]string{"id", "类型", "uid", "已完成", "路径", "保存路径", "更新时间"})
+	for _, job := range jobs {
+		tb.Append([]string{strconv.FormatInt(job.ID, 10), job.Direction, strconv.FormatUint(job.UID, 10), strconv.Itoa(len(job.Done)), strings.Join(job.Paths, ", "), job.SavePath, pcstime.FormatTime(job.UpdateTime)})
+	}
+	tb.Render()
+}
+
+// RunResume 恢复传输日志中未完成的任务, isBg 为 true 时加入后台任务
+func RunResume(ids []int64, isBg bool) {
+	for _, id := range ids {
+		job, err := TransferJournal.Get(id)
+		if err != nil {
+			fmt.Printf("%s: %d\n", err, id)
+			continue
+		}
+		resumeJob(job, isBg)
+	}
+}
+
+// RunResumeDelete 从传输日志中删除任务, 不再恢复
+func RunResumeDelete(ids []int64) {
+	for _, id := range ids {
+		err := TransferJournal.Remove(id)
+		if err != nil {
+			fmt.Printf("%s: %d\n", err, id)
+			continue
+		}
+		fmt.Printf("已删除传输任务: %d\n", id)
+	}
+}
+
+// RestoreTransferJobs 恢复当前帐号所有未完成的任务, isBg 为 true 时加入后台任务, 返回恢复的数量
+func RestoreTransferJobs(isBg bool) int {
+	jobs, err := TransferJournal.List()
+	if err != nil {
+		pcsCommandVerbose.Warnf("read transfer journal error: %s\n", err)
+		return 0
+	}
+
+	n := 0
+	for _, job := range jobs {
+		if job.UID != GetActiveUser().UID {
+			continue
+		}
+		if resumeJob(job, isBg) {
+			n++
+		}
+	}
+	return n
+}
+
+// resumeJob 恢复任务, 返回是否已开始
+func resumeJob(job *pcsjournal.Job, isBg bool) bool {
+	if job.UID != GetActiveUser().UID {
+		fmt.Printf("传输任务 %d 属于其他帐号 (uid: %d), 请先切换帐号\n", job.ID, job.UID)
+		return false
+	}
+
+	tj := resumeTransferJournal(job)
+	switch job.Direction {
+	case transfer.DirectionDownload:
+		options := &DownloadOptions{}
+		if err := json.Unmarshal(job.Options, options); err != nil {
+			fmt.Printf("传输任务 %d 的选项解析错误, %s\n", job.ID, err)
+			return false
+		}
+		options.journal = tj
+		fmt.Printf("恢复下载任务 %d, 已完成 %d 个文件\n", job.ID, len(job.Done))
+		if isBg {
+			RunBgDownload(job.Paths, options)
+		} else {
+			RunDownload(job.Paths, options)
+		}
+	case transfer.DirectionUpload:
+		opt := &UploadOptions{}
+		if err := json.Unmarshal(job.Options, opt); err != nil {
+			fmt.Printf("传输任务 %d 的选项解析错误, %s\n", job.ID, err)
+			return false
+		}
+		opt.journal = tj
+		fmt.Printf("恢复上传任务 %d, 已完成 %d 个文件\n", job.ID, len(job.Done))
+		if isBg {
+			RunBgUpload(job.Paths, job.SavePath, opt)
+		} else {
+			RunUpload(job.Paths, job.SavePath, opt)
+		}
+	default:
+		fmt.Printf("传输任务 %d 的类型未知: %s\n", job.ID, job.Direction)
+		return false
+	}
+	return true
+}
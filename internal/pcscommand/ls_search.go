@@ -3,6 +3,7 @@ package pcscommand
 import (
 	"fmt"
 	"github.com/Erope/BaiduPCS-Go/baidupcs"
+	"github.com/Erope/BaiduPCS-Go/pcscore"
 	"github.com/Erope/BaiduPCS-Go/pcstable"
 	"github.com/Erope/BaiduPCS-Go/pcsutil/converter"
 	"github.com/Erope/BaiduPCS-Go/pcsutil/pcstime"
@@ -21,9 +22,15 @@ type (
 	SearchOptions struct {
 		Total   bool
 		Recurse bool
+		Walk    bool // 遍历目录进行搜索, 而不是使用服务器的搜索接口
 	}
 )
 
+var (
+	// ErrSearchPathNotDir 搜索路径不是目录
+	ErrSearchPathNotDir = pcscore.ErrSearchPathNotDir
+)
+
 const (
 	opLs int = iota
 	opSearch
@@ -31,33 +38,69 @@ const (
 
 // RunLs 执行列目录
 func RunLs(pcspath string, lsOptions *LsOptions, orderOptions *baidupcs.OrderOptions) {
-	err := matchPathByShellPatternOnce(&pcspath)
+	session := GetSession()
+	pcspath, err := session.MatchPathOnce(pcspath)
 	if err != nil {
-		fmt.Println(err)
+		printError(err)
 		return
 	}
 
-	files, err := GetBaiduPCS().FilesDirectoriesList(pcspath, orderOptions)
+	if lsOptions == nil {
+		lsOptions = &LsOptions{}
+	}
+
+	// 分页获取并输出, 表格格式时收到下一页才输出上一页, 最后一页输出统计信息
+	var (
+		ls     *listStream
+		prev   baidupcs.FileDirectoryList
+		start  int
+		total  = &tableTotal{}
+		header = func() {
+			fmt.Printf("\n当前目录: %s\n----\n", pcspath)
+		}
+	)
+	if isStructuredOutput() {
+		ls = newListStream(outputWriter, Output, []*pcscore.FileRecord(nil))
+	}
+	err = session.LsPages(pcspath, orderOptions, func(page baidupcs.FileDirectoryList) error {
+		if ls != nil {
+			return ls.write(pcscore.NewFileRecords(page))
+		}
+		if prev == nil {
+			header()
+		} else {
+			renderTablePage(opLs, lsOptions.Total, pcspath, start, prev, nil)
+			start += len(prev)
+		}
+		total.add(page)
+		prev = page
+		return nil
+	})
 	if err != nil {
-		fmt.Println(err)
+		printError(err)
 		return
 	}
 
-	fmt.Printf("\n当前目录: %s\n----\n", pcspath)
-
-	if lsOptions == nil {
-		lsOptions = &LsOptions{}
+	if ls != nil {
+		err = ls.close()
+		if err != nil {
+			printError(err)
+		}
+		return
 	}
-
-	renderTable(opLs, lsOptions.Total, pcspath, files)
+	if prev == nil {
+		header()
+	}
+	renderTablePage(opLs, lsOptions.Total, pcspath, start, prev, total)
 	return
 }
 
 // RunSearch 执行搜索
 func RunSearch(targetPath, keyword string, opt *SearchOptions) {
-	err := matchPathByShellPatternOnce(&targetPath)
+	session := GetSession()
+	targetPath, err := session.MatchPathOnce(targetPath)
 	if err != nil {
-		fmt.Println(err)
+		printError(err)
 		return
 	}
 
@@ -65,22 +108,52 @@ func RunSearch(targetPath, keyword string, opt *SearchOptions) {
 		opt = &SearchOptions{}
 	}
 
-	files, err := GetBaiduPCS().Search(targetPath, keyword, opt.Recurse)
+	files, err := session.Search(targetPath, keyword, &pcscore.SearchOptions{
+		Recurse: opt.Recurse,
+		Walk:    opt.Walk,
+	})
 	if err != nil {
-		fmt.Println(err)
+		printError(err)
 		return
 	}
 
-	renderTable(opSearch, opt.Total, targetPath, files)
+	renderList(pcscore.NewFileRecords(files), func() {
+		renderTable(opSearch, opt.Total, targetPath, files)
+	})
 	return
 }
 
+// showDirName 返回表格中显示的目录名, 搜索结果显示完整路径
+func showDirName(op int, dir *baidupcs.FileDirectory) string {
+	if op == opSearch {
+		return dir.Path + baidupcs.PathSeparator
+	}
+	return dir.Filename + baidupcs.PathSeparator
+}
+
+// tableTotal 表格的统计信息
+type tableTotal struct {
+	size   int64
+	fN, dN int64
+}
+
+func (tt *tableTotal) add(files baidupcs.FileDirectoryList) {
+	fN, dN := files.Count()
+	tt.fN += fN
+	tt.dN += dN
+	tt.size += files.TotalSize()
+}
+
 func renderTable(op int, isTotal bool, path string, files baidupcs.FileDirectoryList) {
+	total := &tableTotal{}
+	total.add(files)
+	renderTablePage(op, isTotal, path, 0, files, total)
+}
+
+// renderTablePage 输出表格的一页, 序号从 start 开始, total 不为 nil 时为最后一页, 输出统计信息
+func renderTablePage(op int, isTotal bool, path string, start int, files baidupcs.FileDirectoryList, total *tableTotal) {
 	tb := pcstable.NewTable(os.Stdout)
-	var (
-		fN, dN   int64
-		showPath string
-	)
+	var showPath string
 
 	switch op {
 	case opLs:
@@ -94,7 +167,7 @@ func renderTable(op int, isTotal bool, path string, files baidupcs.FileDirectory
 		tb.SetColumnAlignment([]int{tablewriter.ALIGN_DEFAULT, tablewriter.ALIGN_RIGHT, tablewriter.ALIGN_RIGHT, tablewriter.ALIGN_LEFT, tablewriter.ALIGN_LEFT, tablewriter.ALIGN_LEFT, tablewriter.ALIGN_LEFT})
 		for k, file := range files {
 			if file.Isdir {
-				tb.Append([]string{strconv.Itoa(k), strconv.FormatInt(file.FsID, 10), strconv.FormatInt(file.AppID, 10), "-", pcstime.FormatTime(file.Ctime), pcstime.FormatTime(file.Mtime), file.MD5, file.Filename + baidupcs.PathSeparator})
+				tb.Append([]string{strconv.Itoa(start + k), strconv.FormatInt(file.FsID, 10), strconv.FormatInt(file.AppID, 10), "-", pcstime.FormatTime(file.Ctime), pcstime.FormatTime(file.Mtime), file.MD5, showDirName(op, file)})
 				continue
 			}
 
@@ -107,36 +180,41 @@ func renderTable(op int, isTotal bool, path string, files baidupcs.FileDirectory
 
 			switch op {
 			case opLs:
-				tb.Append([]string{strconv.Itoa(k), strconv.FormatInt(file.FsID, 10), strconv.FormatInt(file.AppID, 10), converter.ConvertFileSize(file.Size, 2), pcstime.FormatTime(file.Ctime), pcstime.FormatTime(file.Mtime), md5, file.Filename})
+				tb.Append([]string{strconv.Itoa(start + k), strconv.FormatInt(file.FsID, 10), strconv.FormatInt(file.AppID, 10), converter.ConvertFileSize(file.Size, 2), pcstime.FormatTime(file.Ctime), pcstime.FormatTime(file.Mtime), md5, file.Filename})
 			case opSearch:
-				tb.Append([]string{strconv.Itoa(k), strconv.FormatInt(file.FsID, 10), strconv.FormatInt(file.AppID, 10), converter.ConvertFileSize(file.Size, 2), pcstime.FormatTime(file.Ctime), pcstime.FormatTime(file.Mtime), md5, file.Path})
+				tb.Append([]string{strconv.Itoa(start + k), strconv.FormatInt(file.FsID, 10), strconv.FormatInt(file.AppID, 10), converter.ConvertFileSize(file.Size, 2), pcstime.FormatTime(file.Ctime), pcstime.FormatTime(file.Mtime), md5, file.Path})
 			}
 		}
-		fN, dN = files.Count()
-		tb.Append([]string{"", "", "总: " + converter.ConvertFileSize(files.TotalSize(), 2), "", "", "", fmt.Sprintf("文件总数: %d, 目录总数: %d", fN, dN)})
+		if total != nil {
+			tb.Append([]string{"", "", "总: " + converter.ConvertFileSize(total.size, 2), "", "", "", fmt.Sprintf("文件总数: %d, 目录总数: %d", total.fN, total.dN)})
+		}
 	} else {
 		tb.SetHeader([]string{"#", "文件大小", "修改日期", showPath})
 		tb.SetColumnAlignment([]int{tablewriter.ALIGN_DEFAULT, tablewriter.ALIGN_RIGHT, tablewriter.ALIGN_LEFT, tablewriter.ALIGN_LEFT})
 		for k, file := range files {
 			if file.Isdir {
-				tb.Append([]string{strconv.Itoa(k), "-", pcstime.FormatTime(file.Mtime), file.Filename + baidupcs.PathSeparator})
+				tb.Append([]string{strconv.Itoa(start + k), "-", pcstime.FormatTime(file.Mtime), showDirName(op, file)})
 				continue
 			}
 
 			switch op {
 			case opLs:
-				tb.Append([]string{strconv.Itoa(k), converter.ConvertFileSize(file.Size, 2), pcstime.FormatTime(file.Mtime), file.Filename})
+				tb.Append([]string{strconv.Itoa(start + k), converter.ConvertFileSize(file.Size, 2), pcstime.FormatTime(file.Mtime), file.Filename})
 			case opSearch:
-				tb.Append([]string{strconv.Itoa(k), converter.ConvertFileSize(file.Size, 2), pcstime.FormatTime(file.Mtime), file.Path})
+				tb.Append([]string{strconv.Itoa(start + k), converter.ConvertFileSize(file.Size, 2), pcstime.FormatTime(file.Mtime), file.Path})
 			}
 		}
-		fN, dN = files.Count()
-		tb.Append([]string{"", "总: " + converter.ConvertFileSize(files.TotalSize(), 2), "", fmt.Sprintf("文件总数: %d, 目录总数: %d", fN, dN)})
+		if total != nil {
+			tb.Append([]string{"", "总: " + converter.ConvertFileSize(total.size, 2), "", fmt.Sprintf("文件总数: %d, 目录总数: %d", total.fN, total.dN)})
+		}
 	}
 
 	tb.Render()
+	if total == nil {
+		return
+	}
 
-	if fN+dN >= 50 {
+	if total.fN+total.dN >= 50 {
 		fmt.Printf("\n当前目录: %s\n", path)
 	}
 
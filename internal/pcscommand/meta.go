@@ -2,24 +2,36 @@ package pcscommand
 
 import (
 	"fmt"
+	"github.com/Erope/BaiduPCS-Go/baidupcs"
+	"github.com/Erope/BaiduPCS-Go/pcscore"
 )
 
 // RunGetMeta 执行 获取文件/目录的元信息
 func RunGetMeta(targetPaths ...string) {
-	targetPaths, err := matchPathByShellPattern(targetPaths...)
+	session := GetSession()
+	targetPaths, err := session.MatchPath(targetPaths...)
 	if err != nil {
-		fmt.Println(err)
+		printError(err)
 		return
 	}
 
-	for k, targetPath := range targetPaths {
-		fmt.Printf("[%d] - [%s] --------------\n", k, targetPath)
-		data, err := GetBaiduPCS().FilesDirectoriesMeta(targetPath)
-		if err != nil {
-			fmt.Println(err)
-			return
+	files, err := session.Meta(targetPaths...)
+	renderList(pcscore.NewFileRecords(files), func() {
+		renderMeta(files)
+	})
+	if err != nil {
+		if !isStructuredOutput() {
+			fmt.Printf("[%d] - [%s] --------------\n", len(files), targetPaths[len(files)])
 		}
+		printError(err)
+	}
+}
+
+// renderMeta 输出文件/目录的元信息
+func renderMeta(files baidupcs.FileDirectoryList) {
+	for k, file := range files {
+		fmt.Printf("[%d] - [%s] --------------\n", k, file.Path)
 		fmt.Println()
-		fmt.Println(data)
+		fmt.Println(file)
 	}
 }
@@ -0,0 +1,304 @@
+package pcscommand
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"github.com/json-iterator/go"
+	"io"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync/atomic"
+)
+
+const (
+	// EnvOutput 输出格式环境变量
+	EnvOutput = "BAIDUPCS_GO_OUTPUT"
+
+	// OutputTable 表格, 供人阅读, 格式不保证稳定
+	OutputTable OutputFormat = "table"
+	// OutputJSON json, 列表输出为数组, 单项输出为对象
+	OutputJSON OutputFormat = "json"
+	// OutputNDJSON 每行一个 json 对象
+	OutputNDJSON OutputFormat = "ndjson"
+	// OutputCSV csv, 第一行为列名
+	OutputCSV OutputFormat = "csv"
+)
+
+type (
+	// OutputFormat 命令结果的输出格式
+	OutputFormat string
+)
+
+var (
+	// ErrOutputFormatUnknown 未知的输出格式
+	ErrOutputFormatUnknown = errors.New("未知的输出格式, 可选: table, json, ndjson, csv")
+
+	// Output 命令结果的输出格式, 由全局参数 --output 设置
+	Output = OutputTable
+
+	// outputWriter 结构化输出的目标
+	outputWriter io.Writer = os.Stdout
+
+	// outputFailed 结构化输出时, 命令是否出错
+	outputFailed int32
+)
+
+// SetOutputFormat 设置命令结果的输出格式, 为空则使用表格
+func SetOutputFormat(format string) error {
+	switch f := OutputFormat(strings.ToLower(strings.TrimSpace(format))); f {
+	case "":
+		Output = OutputTable
+	case OutputTable, OutputJSON, OutputNDJSON, OutputCSV:
+		Output = f
+	default:
+		return fmt.Errorf("%s: %s", ErrOutputFormatUnknown, format)
+	}
+	return nil
+}
+
+// isStructuredOutput 是否为结构化输出 (json, ndjson, csv)
+func isStructuredOutput() bool {
+	return Output != OutputTable
+}
+
+// ExitCode 返回程序的退出码, 结构化输出时命令出错返回 1, 供脚本判断命令是否成功
+func ExitCode() int {
+	if atomic.LoadInt32(&outputFailed) != 0 {
+		return 1
+	}
+	return 0
+}
+
+// messageWriter 返回提示信息的输出目标, 结构化输出时为标准错误, 避免破坏输出的数据
+func messageWriter() io.Writer {
+	if isStructuredOutput() {
+		return os.Stderr
+	}
+	return os.Stdout
+}
+
+// printError 输出错误, 结构化输出时输出到标准错误, 避免破坏输出的数据, 并记录命令出错
+func printError(err error) {
+	if isStructuredOutput() {
+		atomic.StoreInt32(&outputFailed, 1)
+		fmt.Fprintln(os.Stderr, err)
+		return
+	}
+	fmt.Println(err)
+}
+
+// renderList 输出列表, list 为结构体指针的切片 (如 []*pcscore.FileRecord), 表格格式时调用 renderTable
+func renderList(list interface{}, renderTable func()) {
+	if !isStructuredOutput() {
+		renderTable()
+		return
+	}
+
+	err := writeList(outputWriter, Output, list)
+	if err != nil {
+		printError(err)
+	}
+}
+
+// renderObject 输出单项, obj 为结构体指针, 表格格式时调用 renderTable
+func renderObject(obj interface{}, renderTable func()) {
+	if !isStructuredOutput() {
+		renderTable()
+		return
+	}
+
+	var err error
+	switch Output {
+	case OutputJSON:
+		err = writeJSON(outputWriter, obj, true)
+	default:
+		// ndjson 和 csv 按只有一项的列表输出
+		list := reflect.MakeSlice(reflect.SliceOf(reflect.TypeOf(obj)), 0, 1)
+		list = reflect.Append(list, reflect.ValueOf(obj))
+		err = writeList(outputWriter, Output, list.Interface())
+	}
+	if err != nil {
+		printError(err)
+	}
+}
+
+func writeJSON(w io.Writer, v interface{}, indent bool) error {
+	var (
+		data []byte
+		err  error
+	)
+	if indent {
+		data, err = jsoniter.MarshalIndent(v, "", "  ")
+	} else {
+		data, err = jsoniter.Marshal(v)
+	}
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(data, '\n'))
+	return err
+}
+
+func writeList(w io.Writer, format OutputFormat, list interface{}) error {
+	ls := newListStream(w, format, list)
+	err := ls.write(list)
+	if err != nil {
+		return err
+	}
+	return ls.close()
+}
+
+// listStream 分批输出列表, 用于分页获取的列表, 输出的结果与一次输出整个列表相同
+type listStream struct {
+	w        io.Writer
+	format   OutputFormat
+	elemType reflect.Type
+	n        int
+	cw       *csv.Writer
+}
+
+// newListStream 返回输出 list 类型的列表的 listStream, list 可为 nil 切片
+func newListStream(w io.Writer, format OutputFormat, list interface{}) *listStream {
+	return &listStream{
+		w:        w,
+		format:   format,
+		elemType: reflect.TypeOf(list).Elem(),
+	}
+}
+
+// write 输出列表的一部分
+func (ls *listStream) write(list interface{}) error {
+	lv := reflect.ValueOf(list)
+	switch ls.format {
+	case OutputJSON:
+		for i := 0; i < lv.Len(); i++ {
+			data, err := jsoniter.MarshalIndent(lv.Index(i).Interface(), "", "  ")
+			if err != nil {
+				return err
+			}
+			// 数组元素缩进一级, json 字符串中的换行已转义
+			data = bytes.ReplaceAll(data, []byte("\n"), []byte("\n  "))
+			sep := ",\n  "
+			if ls.n == 0 {
+				sep = "[\n  "
+			}
+			_, err = io.WriteString(ls.w, sep+string(data))
+			if err != nil {
+				return err
+			}
+			ls.n++
+		}
+		return nil
+	case OutputNDJSON:
+		for i := 0; i < lv.Len(); i++ {
+			err := writeJSON(ls.w, lv.Index(i).Interface(), false)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	case OutputCSV:
+		ls.csvHeader()
+		for i := 0; i < lv.Len(); i++ {
+			ls.cw.Write(csvRecord(reflect.Indirect(lv.Index(i))))
+		}
+		ls.cw.Flush()
+		return ls.cw.Error()
+	}
+	return ErrOutputFormatUnknown
+}
+
+// close 结束输出
+func (ls *listStream) close() error {
+	switch ls.format {
+	case OutputJSON:
+		if ls.n == 0 {
+			_, err := io.WriteString(ls.w, "[]\n")
+			return err
+		}
+		_, err := io.WriteString(ls.w, "\n]\n")
+		return err
+	case OutputCSV:
+		ls.csvHeader()
+		ls.cw.Flush()
+		return ls.cw.Error()
+	}
+	return nil
+}
+
+// csvHeader 未输出 csv 列名时, 输出列名
+func (ls *listStream) csvHeader() {
+	if ls.cw != nil {
+		return
+	}
+	elemType := ls.elemType
+	if elemType.Kind() == reflect.Ptr {
+		elemType = elemType.Elem()
+	}
+	ls.cw = csv.NewWriter(ls.w)
+	ls.cw.Write(csvHeader(elemType))
+}
+
+// csvHeader 返回结构体 t 的 csv 列名, 即 json 的键名
+func csvHeader(t reflect.Type) []string {
+	header := make([]string, 0, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		header = append(header, jsonFieldName(t.Field(i)))
+	}
+	return header
+}
+
+func jsonFieldName(field reflect.StructField) string {
+	name := strings.Split(field.Tag.Get("json"), ",")[0]
+	if name == "" {
+		return field.Name
+	}
+	return name
+}
+
+// csvRecord 返回结构体 v 的 csv 行, 切片的元素以分号分隔
+func csvRecord(v reflect.Value) []string {
+	record := make([]string, 0, v.NumField())
+	for i := 0; i < v.NumField(); i++ {
+		record = append(record, csvValue(v.Field(i)))
+	}
+	return record
+}
+
+func csvValue(v reflect.Value) string {
+	switch v.Kind() {
+	case reflect.String:
+		return v.String()
+	case reflect.Bool:
+		return strconv.FormatBool(v.Bool())
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(v.Int(), 10)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(v.Uint(), 10)
+	case reflect.Float32, reflect.Float64:
+		return strconv.FormatFloat(v.Float(), 'f', -1, 64)
+	case reflect.Slice:
+		elems := make([]string, 0, v.Len())
+		for i := 0; i < v.Len(); i++ {
+			elems = append(elems, csvValue(v.Index(i)))
+		}
+		return strings.Join(elems, ";")
+	case reflect.Ptr:
+		if v.IsNil() {
+			return ""
+		}
+		return csvValue(v.Elem())
+	case reflect.Struct:
+		// 嵌套的记录, 字段以冒号 : 分隔
+		fields := make([]string, 0, v.NumField())
+		for i := 0; i < v.NumField(); i++ {
+			fields = append(fields, csvValue(v.Field(i)))
+		}
+		return strings.Join(fields, ":")
+	}
+	return fmt.Sprint(v.Interface())
+}
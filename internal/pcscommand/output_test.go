@@ -0,0 +1,86 @@
+package pcscommand
+
+import (
+	"bytes"
+	"errors"
+	"github.com/Erope/BaiduPCS-Go/pcscore"
+	"testing"
+)
+
+func TestWriteList(t *testing.T) {
+	records := []*pcscore.ShareRecord{
+		{ShareID: 1, FsIDs: []int64{2, 3}, Link: "https://pan.baidu.com/s/1", TypicalPath: "/a,b"},
+	}
+
+	for format, expected := range map[OutputFormat]string{
+		OutputCSV:    "share_id,fs_ids,link,passwd,status,typical_category,typical_path\n1,2;3,https://pan.baidu.com/s/1,,0,0,\"/a,b\"\n",
+		OutputNDJSON: `{"share_id":1,"fs_ids":[2,3],"link":"https://pan.baidu.com/s/1","passwd":"","status":0,"typical_category":0,"typical_path":"/a,b"}` + "\n",
+	} {
+		buf := &bytes.Buffer{}
+		err := writeList(buf, format, records)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if buf.String() != expected {
+			t.Fatalf("%s: unexpected output: %s", format, buf.String())
+		}
+	}
+
+	// 空列表时, json 输出空数组, csv 只输出列名
+	buf := &bytes.Buffer{}
+	writeList(buf, OutputJSON, []*pcscore.FileRecord(nil))
+	if buf.String() != "[]\n" {
+		t.Fatalf("unexpected json output: %s", buf.String())
+	}
+	buf.Reset()
+	writeList(buf, OutputCSV, []*pcscore.QuotaInfo{})
+	if buf.String() != "quota,used\n" {
+		t.Fatalf("unexpected csv output: %s", buf.String())
+	}
+}
+
+func TestListStream(t *testing.T) {
+	records := []*pcscore.FileRecord{
+		{FsID: 1, Path: "/a"}, {FsID: 2, Path: "/b"}, {FsID: 3, Path: "/c"},
+	}
+
+	// 分批输出与一次输出整个列表的结果相同
+	for _, format := range []OutputFormat{OutputJSON, OutputNDJSON, OutputCSV} {
+		expected := &bytes.Buffer{}
+		writeList(expected, format, records)
+
+		buf := &bytes.Buffer{}
+		ls := newListStream(buf, format, []*pcscore.FileRecord(nil))
+		for _, page := range [][]*pcscore.FileRecord{records[:2], {}, records[2:]} {
+			if err := ls.write(page); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := ls.close(); err != nil {
+			t.Fatal(err)
+		}
+		if buf.String() != expected.String() {
+			t.Fatalf("%s: unexpected output: %s, expected: %s", format, buf.String(), expected.String())
+		}
+	}
+}
+
+func TestExitCode(t *testing.T) {
+	defer func(o OutputFormat) {
+		Output = o
+		outputFailed = 0
+	}(Output)
+
+	// 表格输出时不改变退出码
+	Output = OutputTable
+	printError(errors.New("test"))
+	if ExitCode() != 0 {
+		t.Fatal("expect exit code 0")
+	}
+
+	Output = OutputJSON
+	printError(errors.New("test"))
+	if ExitCode() != 1 {
+		t.Fatal("expect exit code 1")
+	}
+}
@@ -4,6 +4,7 @@ package pcscommand
 import (
 	"github.com/Erope/BaiduPCS-Go/baidupcs"
 	"github.com/Erope/BaiduPCS-Go/internal/pcsconfig"
+	"github.com/Erope/BaiduPCS-Go/pcscore"
 	"github.com/Erope/BaiduPCS-Go/pcsverbose"
 )
 
@@ -17,6 +18,13 @@ func GetActiveUser() *pcsconfig.Baidu {
 }
 
 // GetBaiduPCS 从配置读取BaiduPCS
-func GetBaiduPCS() *baidupcs.BaiduPCS {
+func GetBaiduPCS() baidupcs.Client {
 	return pcsconfig.Config.ActiveUserBaiduPCS()
 }
+
+// GetSession 返回当前登录的百度帐号执行命令的会话
+func GetSession() *pcscore.Session {
+	session := pcscore.NewSession(GetBaiduPCS(), GetActiveUser().Workdir)
+	session.WalkOptions = pcsconfig.Config.WalkOptions()
+	return session
+}
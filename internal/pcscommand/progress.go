@@ -0,0 +1,87 @@
+package pcscommand
+
+import (
+	"errors"
+	"fmt"
+	"github.com/Erope/BaiduPCS-Go/requester/transfer"
+	"io"
+	"os"
+	"strings"
+	"sync"
+)
+
+const (
+	// ProgressText 文本进度, 供人阅读
+	ProgressText = "text"
+	// ProgressNDJSON 每行一个 json 传输事件
+	ProgressNDJSON = "ndjson"
+)
+
+var (
+	// ErrProgressFormatUnknown 未知的进度输出格式
+	ErrProgressFormatUnknown = errors.New("未知的进度输出格式, 可选: text, ndjson")
+)
+
+type (
+	// ProgressWriter 将传输事件以 ndjson 格式写入 w, 可并发调用
+	ProgressWriter struct {
+		w  io.Writer
+		mu sync.Mutex
+	}
+)
+
+// NewProgressWriter 初始化 ProgressWriter
+func NewProgressWriter(w io.Writer) *ProgressWriter {
+	return &ProgressWriter{
+		w: w,
+	}
+}
+
+// HandleProgress 写入传输事件
+func (pw *ProgressWriter) HandleProgress(event *transfer.ProgressEvent) {
+	pw.mu.Lock()
+	defer pw.mu.Unlock()
+	err := writeJSON(pw.w, event, false)
+	if err != nil {
+		pcsCommandVerbose.Warnf("write progress event error: %s\n", err)
+	}
+}
+
+// NewProgressHandler 根据进度输出格式创建 ProgressHandler, 事件写入文件描述符 fd, 默认为标准错误.
+// 文本格式返回 nil
+func NewProgressHandler(format string, fd int) (transfer.ProgressHandler, error) {
+	switch strings.ToLower(strings.TrimSpace(format)) {
+	case "", ProgressText:
+		return nil, nil
+	case ProgressNDJSON:
+	default:
+		return nil, fmt.Errorf("%s: %s", ErrProgressFormatUnknown, format)
+	}
+
+	switch {
+	case fd <= 0:
+		// 0 为标准输入, 不能写入
+		return nil, fmt.Errorf("无效的文件描述符: %d", fd)
+	case fd == 2:
+		return NewProgressWriter(os.Stderr), nil
+	case fd == 1:
+		return NewProgressWriter(os.Stdout), nil
+	}
+
+	f := os.NewFile(uintptr(fd), fmt.Sprintf("fd%d", fd))
+	if f == nil {
+		return nil, fmt.Errorf("无效的文件描述符: %d", fd)
+	}
+	if _, err := f.Stat(); err != nil {
+		return nil, fmt.Errorf("无效的文件描述符: %d, %s", fd, err)
+	}
+	return NewProgressWriter(f), nil
+}
+
+// emitProgress 发送传输事件, handler 为 nil 时不做任何事
+func emitProgress(handler transfer.ProgressHandler, event *transfer.ProgressEvent) {
+	if handler == nil {
+		return
+	}
+	handler.HandleProgress(event)
+}
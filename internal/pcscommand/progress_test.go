@@ -0,0 +1,47 @@
+package pcscommand
+
+import (
+	"bytes"
+	"github.com/Erope/BaiduPCS-Go/requester/transfer"
+	"testing"
+	"time"
+)
+
+type fakeStatus struct{}
+
+func (fakeStatus) TotalSize() int64           { return 1000 }
+func (fakeStatus) SpeedsPerSecond() int64     { return 100 }
+func (fakeStatus) TimeElapsed() time.Duration { return 3 * time.Second }
+
+func TestProgressWriter(t *testing.T) {
+	handler, err := NewProgressHandler("text", 2)
+	if err != nil || handler != nil {
+		t.Fatalf("expect nil handler for text progress, got %v, %v", handler, err)
+	}
+	_, err = NewProgressHandler("xml", 2)
+	if err == nil {
+		t.Fatal("expect error for unknown progress format")
+	}
+	_, err = NewProgressHandler("ndjson", 0)
+	if err == nil {
+		t.Fatal("expect error for stdin fd")
+	}
+
+	buf := &bytes.Buffer{}
+	pw := NewProgressWriter(buf)
+	event := transfer.NewProgressEvent(transfer.ProgressProgress, transfer.DirectionDownload, 1).SetStatus(fakeStatus{}, 400)
+	event.Time = 0
+	emitProgress(pw, event)
+	emitProgress(nil, event)
+
+	expected := `{"event":"progress","direction":"download","task_id":1,"time":0,"size":1000,"bytes":400,"speed":100,"elapsed":3,"eta":6}` + "\n"
+	if buf.String() != expected {
+		t.Fatalf("unexpected event: %s", buf.String())
+	}
+
+	// 预计剩余时间不截断为整数秒
+	event.SetStatus(fakeStatus{}, 350)
+	if event.ETA != 6.5 {
+		t.Fatalf("unexpected eta: %v", event.ETA)
+	}
+}
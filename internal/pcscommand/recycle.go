@@ -3,6 +3,7 @@ package pcscommand
 import (
 	"fmt"
 	"github.com/Erope/BaiduPCS-Go/baidupcs"
+	"github.com/Erope/BaiduPCS-Go/pcscore"
 	"github.com/Erope/BaiduPCS-Go/pcstable"
 	"github.com/Erope/BaiduPCS-Go/pcsutil/converter"
 	"github.com/Erope/BaiduPCS-Go/pcsutil/pcstime"
@@ -11,19 +12,28 @@ import (
 	"strconv"
 )
 
-// RunRecycleList 执行列出回收站文件列表
+// RunRecycleList 执行列出回收站文件列表, page 为 0 时列出所有页
 func RunRecycleList(page int) {
-	if page < 1 {
-		page = 1
+	var (
+		fdl baidupcs.RecycleFDInfoList
+		err error
+	)
+	if page == 0 {
+		fdl, err = GetSession().RecycleListAll()
+	} else {
+		fdl, err = GetSession().RecycleList(page)
 	}
-
-	pcs := GetBaiduPCS()
-	fdl, err := pcs.RecycleList(page)
 	if err != nil {
-		fmt.Println(err)
+		printError(err)
 		return
 	}
+	renderList(pcscore.NewRecycleRecords(fdl), func() {
+		renderRecycleList(fdl)
+	})
+}
 
+// renderRecycleList 输出回收站列表
+func renderRecycleList(fdl baidupcs.RecycleFDInfoList) {
 	tb := pcstable.NewTable(os.Stdout)
 	tb.SetHeader([]string{"#", "fs_id", "文件大小", "创建日期", "修改日期", "md5(截图请打码)", "剩余时间", "路径"})
 	tb.SetColumnAlignment([]int{tablewriter.ALIGN_DEFAULT, tablewriter.ALIGN_RIGHT, tablewriter.ALIGN_RIGHT, tablewriter.ALIGN_LEFT, tablewriter.ALIGN_LEFT, tablewriter.ALIGN_LEFT, tablewriter.ALIGN_DEFAULT, tablewriter.ALIGN_LEFT})
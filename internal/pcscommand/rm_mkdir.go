@@ -2,9 +2,6 @@ package pcscommand
 
 import (
 	"fmt"
-	"github.com/Erope/BaiduPCS-Go/pcstable"
-	"os"
-	"strconv"
 )
 
 // RunRemove 执行 批量删除文件/目录
@@ -15,25 +12,13 @@ func RunRemove(paths ...string) (err error) {
 		return
 	}
 
-	pnt := func() {
-		tb := pcstable.NewTable(os.Stdout)
-		tb.SetHeader([]string{"#", "文件/目录"})
-		for k := range paths {
-			tb.Append([]string{strconv.Itoa(k), paths[k]})
-		}
-		tb.Render()
-	}
-
-	err = GetBaiduPCS().Remove(paths...)
-	if err != nil {
-		fmt.Println(err)
-		fmt.Println("操作失败, 以下文件/目录删除失败: ")
-		pnt()
-		return
+	results := GetBaiduPCS().BatchRemove(paths...)
+	if printBatchSummary(results, "删除", false) {
+		return results.Err()
 	}
 
 	fmt.Println("操作成功, 以下文件/目录已删除, 可在网盘文件回收站找回: ")
-	pnt()
+	printBatchResults(results, false)
 	return
 }
 
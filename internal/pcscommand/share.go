@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"github.com/Erope/BaiduPCS-Go/baidupcs"
 	"github.com/Erope/BaiduPCS-Go/internal/pcsconfig"
+	"github.com/Erope/BaiduPCS-Go/pcscore"
 	"github.com/Erope/BaiduPCS-Go/pcstable"
 	"os"
 	"path"
@@ -52,15 +53,18 @@ func RunShareCancel(shareIDs []int64) {
 
 // RunShareList 执行列出分享列表
 func RunShareList(page int) {
-	if page < 1 {
-		page = 1
-	}
-	records, err := GetBaiduPCS().ShareList(page)
+	records, err := GetSession().ShareList(page)
 	if err != nil {
-		fmt.Printf("%s失败: %s\n", baidupcs.OperationShareList, err)
+		printError(fmt.Errorf("%s失败: %s", baidupcs.OperationShareList, err))
 		return
 	}
+	renderList(pcscore.NewShareRecords(records), func() {
+		renderShareList(records)
+	})
+}
 
+// renderShareList 输出分享列表
+func renderShareList(records baidupcs.ShareRecordInfoList) {
 	tb := pcstable.NewTable(os.Stdout)
 	tb.SetHeader([]string{"#", "ShareID", "分享链接", "提取密码", "特征目录", "特征路径"})
 	for k, record := range records {
@@ -0,0 +1,244 @@
+package pcscommand
+
+import (
+	"fmt"
+	"github.com/Erope/BaiduPCS-Go/baidupcs"
+	"github.com/Erope/BaiduPCS-Go/internal/pcsfunctions/pcssync"
+	"github.com/Erope/BaiduPCS-Go/pcscore"
+	"github.com/Erope/BaiduPCS-Go/pcstable"
+	"github.com/Erope/BaiduPCS-Go/pcsutil/converter"
+	"github.com/Erope/BaiduPCS-Go/requester/transfer"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+type (
+	// SyncOptions 同步可选项
+	SyncOptions struct {
+		DryRun    bool     // 只输出同步计划, 不执行
+		Delete    bool     // 删除网盘中多余的文件或目录, 移动到回收站
+		Permanent bool     // 删除时不保留在回收站, 需要 Delete
+		Force     bool     // 跳过误删文件的安全检查
+		Include   []string // 只同步匹配的文件
+		Exclude   []string // 排除匹配的文件和目录
+		Upload    *UploadOptions
+	}
+
+	// syncCounter 统计上传结果, 同时转发传输事件
+	syncCounter struct {
+		next                                transfer.ProgressHandler
+		mu                                  sync.Mutex
+		finished, skipped, failed, canceled int
+		failedPaths                         []string // 失败或取消的文件的网盘路径
+	}
+)
+
+// HandleProgress 统计完成, 跳过, 失败和取消的文件, 记录失败和取消的文件
+func (sc *syncCounter) HandleProgress(event *transfer.ProgressEvent) {
+	sc.mu.Lock()
+	switch event.Event {
+	case transfer.ProgressFinished:
+		sc.finished++
+	case transfer.ProgressSkipped:
+		sc.skipped++
+	case transfer.ProgressFailed, transfer.ProgressCanceled:
+		if event.Event == transfer.ProgressFailed {
+			sc.failed++
+		} else {
+			sc.canceled++
+		}
+		if event.Direction == transfer.DirectionUpload {
+			sc.failedPaths = append(sc.failedPaths, event.SavePath)
+		} else {
+			sc.failedPaths = append(sc.failedPaths, event.Path)
+		}
+	}
+	sc.mu.Unlock()
+	emitProgress(sc.next, event)
+}
+
+// RunSync 单向同步本地目录 localDir 到网盘目录 savePath, 只上传新的和有变化的文件,
+// 可选删除网盘中多余的文件或目录
+func RunSync(localDir, savePath string, opt *SyncOptions) {
+	if opt == nil {
+		opt = &SyncOptions{}
+	}
+	opt.Upload = checkUploadOptions(opt.Upload)
+	// 结构化输出时, 同步计划输出到标准输出, 上传的进度输出到标准错误
+	if isStructuredOutput() {
+		opt.Upload.Out = os.Stderr
+	}
+	out := opt.Upload.Out
+
+	err := matchPathByShellPatternOnce(&savePath)
+	if err != nil {
+		printError(fmt.Errorf("获取网盘路径 %s 错误, %s", savePath, err))
+		return
+	}
+
+	filter, err := pcssync.NewFilter(opt.Include, opt.Exclude)
+	if err != nil {
+		printError(err)
+		return
+	}
+
+	localDir, err = filepath.Abs(localDir)
+	if err != nil {
+		printError(err)
+		return
+	}
+	local, err := pcssync.WalkLocal(localDir, filter)
+	if err != nil {
+		printError(fmt.Errorf("遍历本地目录错误, %s", err))
+		return
+	}
+
+	pcs := GetBaiduPCS()
+	remote, err := pcssync.ListRemote(pcs, savePath)
+	if err != nil {
+		printError(fmt.Errorf("获取网盘文件列表错误, %s", err))
+		return
+	}
+
+	fmt.Fprintf(out, "正在比较 %d 个本地文件和 %d 个网盘文件/目录...\n", len(local.Files), len(remote))
+	md5Cache := pcssync.NewMD5Cache()
+	plan := pcssync.Compare(local, remote, savePath, filter, md5Cache.Sum)
+	err = md5Cache.Save()
+	if err != nil {
+		pcsCommandVerbose.Warnf("save md5 cache error: %s\n", err)
+	}
+
+	renderSyncPlan(plan, opt.Delete)
+	if opt.Delete {
+		err = pcssync.CheckPlan(plan, local, remote, filter, pcssync.DefaultMaxDeleteRatio)
+		if err != nil && !opt.Force {
+			printError(fmt.Errorf("%s, 确认无误后使用 -force 强制同步", err))
+			return
+		}
+	}
+	if opt.DryRun {
+		return
+	}
+
+	var deleted int
+	if opt.Delete && len(plan.Deletes) > 0 {
+		deleted = runSyncDelete(out, plan.Deletes, opt.Permanent)
+	}
+
+	counter := &syncCounter{
+		next: opt.Upload.Progress,
+	}
+	if len(plan.Uploads) > 0 {
+		files := make([]*uploadFile, 0, len(plan.Uploads))
+		for _, action := range plan.Uploads {
+			files = append(files, &uploadFile{
+				localPath: action.LocalPath,
+				savePath:  action.RemotePath,
+			})
+		}
+		opt.Upload.Progress = counter
+		opt.Upload.noJournal = true
+		runUpload([]string{localDir}, savePath, files, opt.Upload)
+	}
+
+	fmt.Fprintf(out, "\n同步结束, 上传成功 %d, 跳过 %d, 失败 %d, 取消 %d, 未变化 %d, 删除 %d, 冲突 %d, 排除 %d\n",
+		counter.finished, counter.skipped, counter.failed, counter.canceled, plan.Unchanged, deleted, len(plan.Conflicts), plan.Excluded)
+	if counter.failed > 0 || counter.canceled > 0 {
+		fmt.Fprintf(out, "部分文件未上传, 重新执行 sync 可继续同步\n")
+	}
+}
+
+// renderSyncPlan 输出同步计划
+func renderSyncPlan(plan *pcssync.Plan, isDelete bool) {
+	actions := make([]*pcssync.Action, 0, len(plan.Uploads)+len(plan.Deletes)+len(plan.Conflicts))
+	actions = append(actions, plan.Uploads...)
+	actions = append(actions, plan.Deletes...)
+	actions = append(actions, plan.Conflicts...)
+
+	records := make([]*pcscore.SyncActionRecord, 0, len(actions))
+	for _, action := range actions {
+		records = append(records, &pcscore.SyncActionRecord{
+			Action:     string(action.Type),
+			Reason:     action.Reason,
+			Path:       action.RelPath,
+			LocalPath:  action.LocalPath,
+			RemotePath: action.RemotePath,
+			Isdir:      action.Isdir,
+			Size:       action.Size,
+		})
+	}
+
+	renderList(records, func() {
+		if len(actions) > 0 {
+			tb := pcstable.NewTable(os.Stdout)
+			tb.SetHeader([]string{"#", "操作", "原因", "文件大小", "路径"})
+			for k, action := range actions {
+				var (
+					op   string
+					size = converter.ConvertFileSize(action.Size, 2)
+				)
+				switch action.Type {
+				case pcssync.ActionUpload:
+					op = "上传"
+				case pcssync.ActionDelete:
+					op = "删除"
+					if !isDelete {
+						op = "多余"
+					}
+				default:
+					op = "冲突"
+				}
+				if action.Isdir {
+					size = "-"
+				}
+				tb.Append([]string{fmt.Sprint(k), op, action.Reason, size, action.RelPath})
+			}
+			tb.Render()
+		}
+
+		fmt.Printf("同步计划: 上传 %d 个文件, 总大小 %s, 未变化 %d, 网盘中多余 %d, 冲突 %d, 排除 %d\n",
+			len(plan.Uploads), converter.ConvertFileSize(plan.UploadSize(), 2), plan.Unchanged, len(plan.Deletes), len(plan.Conflicts), plan.Excluded)
+		if len(plan.Deletes) > 0 && !isDelete {
+			fmt.Printf("网盘中多余的文件或目录不会被删除, 使用 -delete 删除\n")
+		}
+	})
+}
+
+// runSyncDelete 删除网盘中多余的文件或目录, permanent 为 true 时再从回收站删除, 返回删除成功的数量
+func runSyncDelete(out io.Writer, deletes []*pcssync.Action, permanent bool) int {
+	paths := make([]string, 0, len(deletes))
+	for _, action := range deletes {
+		paths = append(paths, action.RemotePath)
+	}
+
+	var (
+		pcs     = GetBaiduPCS()
+		results = pcs.BatchRemove(paths...)
+		fsIDs   = make([]int64, 0, len(results))
+	)
+	for k, result := range results {
+		if result.Status != baidupcs.BatchStatusSuccess {
+			fmt.Fprintf(out, "删除失败: %s, %s\n", result.Path, result.Status)
+			continue
+		}
+		fsIDs = append(fsIDs, deletes[k].FsID)
+	}
+	if len(fsIDs) == 0 {
+		return 0
+	}
+
+	if !permanent {
+		fmt.Fprintf(out, "已删除 %d 个网盘中多余的文件/目录, 可在网盘文件回收站找回\n", len(fsIDs))
+		return len(fsIDs)
+	}
+
+	pcsError := pcs.RecycleDelete(fsIDs...)
+	if pcsError != nil {
+		fmt.Fprintf(out, "从回收站删除错误, 文件/目录已移动到回收站, %s\n", pcsError)
+		return len(fsIDs)
+	}
+	fmt.Fprintf(out, "已彻底删除 %d 个网盘中多余的文件/目录\n", len(fsIDs))
+	return len(fsIDs)
+}
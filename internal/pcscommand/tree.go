@@ -3,7 +3,12 @@ package pcscommand
 import (
 	"fmt"
 	"github.com/Erope/BaiduPCS-Go/baidupcs"
+	"github.com/Erope/BaiduPCS-Go/baidupcs/pcserror"
+	"github.com/Erope/BaiduPCS-Go/internal/pcsconfig"
+	"github.com/Erope/BaiduPCS-Go/pcscore"
+	"path"
 	"strings"
+	"sync"
 )
 
 const (
@@ -12,48 +17,164 @@ const (
 	lastFilePrefix = "└──"
 )
 
-func getTree(pcspath string, depth int) {
-	var (
-		err   error
-		files baidupcs.FileDirectoryList
-	)
-	if depth == 0 {
-		err := matchPathByShellPatternOnce(&pcspath)
-		if err != nil {
-			fmt.Println(err)
-			return
+// treePrinter 并发遍历目录, 按树形图的顺序输出, 目录的列表完整后即可输出, 不必等待遍历结束
+type treePrinter struct {
+	ls *listStream // 结构化输出时不为空
+
+	mu       sync.Mutex
+	cond     *sync.Cond
+	rootPath string
+	children map[string]baidupcs.FileDirectoryList // 目录 -> 子文件和子目录
+	dirDone  map[string]bool                       // 列表已完整的目录
+	dirErrs  map[string]error                      // 获取列表出错的目录
+	finished bool                                  // 遍历已结束
+	stopped  bool                                  // 输出出错, 停止遍历
+}
+
+func newTreePrinter() *treePrinter {
+	tp := &treePrinter{
+		children: map[string]baidupcs.FileDirectoryList{},
+		dirDone:  map[string]bool{},
+		dirErrs:  map[string]error{},
+	}
+	tp.cond = sync.NewCond(&tp.mu)
+	return tp
+}
+
+// walkFn 遍历回调, 记录各目录的子项
+func (tp *treePrinter) walkFn(fdPath string, fd *baidupcs.FileDirectory, pcsError pcserror.Error) error {
+	tp.mu.Lock()
+	defer tp.mu.Unlock()
+	defer tp.cond.Broadcast()
+
+	switch {
+	case tp.stopped:
+		return baidupcs.StopWalk
+	case pcsError != nil:
+		if tp.rootPath == "" {
+			tp.rootPath = fdPath
 		}
+		tp.dirErrs[fdPath] = pcsError
+	case tp.rootPath == "": // 最先回调的是 pcspath 本身
+		tp.rootPath = fd.Path
+	default:
+		dir := path.Dir(fd.Path)
+		tp.children[dir] = append(tp.children[dir], fd)
 	}
+	return nil
+}
 
-	files, err = GetBaiduPCS().FilesDirectoriesList(pcspath, baidupcs.DefaultOrderOptions)
-	if err != nil {
-		fmt.Println(err)
-		return
+func (tp *treePrinter) markDone(dir *baidupcs.FileDirectory) {
+	tp.mu.Lock()
+	tp.dirDone[dir.Path] = true
+	tp.mu.Unlock()
+	tp.cond.Broadcast()
+}
+
+func (tp *treePrinter) finish() {
+	tp.mu.Lock()
+	tp.finished = true
+	tp.mu.Unlock()
+	tp.cond.Broadcast()
+}
+
+// root 等待并返回根目录的路径
+func (tp *treePrinter) root() string {
+	tp.mu.Lock()
+	defer tp.mu.Unlock()
+	for tp.rootPath == "" && !tp.finished {
+		tp.cond.Wait()
+	}
+	return tp.rootPath
+}
+
+// list 等待目录 dir 的列表完整, 返回其下的项和获取列表的错误
+func (tp *treePrinter) list(dir string) (baidupcs.FileDirectoryList, error) {
+	tp.mu.Lock()
+	defer tp.mu.Unlock()
+	for !tp.dirDone[dir] && !tp.finished {
+		tp.cond.Wait()
+	}
+	files := tp.children[dir]
+	delete(tp.children, dir)
+	return files, tp.dirErrs[dir]
+}
+
+func (tp *treePrinter) print(file *baidupcs.FileDirectory, indentPrefixStr, prefix string) error {
+	if tp.ls != nil {
+		return tp.ls.write([]*pcscore.FileRecord{pcscore.NewFileRecord(file)})
+	}
+	if file.Isdir {
+		fmt.Printf("%v%v %v/\n", indentPrefixStr, prefix, file.Filename)
+		return nil
 	}
+	fmt.Printf("%v%v %v\n", indentPrefixStr, prefix, file.Filename)
+	return nil
+}
 
+func (tp *treePrinter) printDir(pcspath string, depth int) error {
 	var (
-		prefix          = pathPrefix
-		fN              = len(files)
+		files, listErr  = tp.list(pcspath)
 		indentPrefixStr = strings.Repeat(indentPrefix, depth)
 	)
 	for i, file := range files {
-		if file.Isdir {
-			fmt.Printf("%v%v %v/\n", indentPrefixStr, pathPrefix, file.Filename)
-			getTree(file.Path, depth+1)
-			continue
-		}
-
-		if i+1 == fN {
+		prefix := pathPrefix
+		if !file.Isdir && i+1 == len(files) && listErr == nil {
 			prefix = lastFilePrefix
 		}
-
-		fmt.Printf("%v%v %v\n", indentPrefixStr, prefix, file.Filename)
+		err := tp.print(file, indentPrefixStr, prefix)
+		if err != nil {
+			return err
+		}
+		if file.Isdir {
+			err = tp.printDir(file.Path, depth+1)
+			if err != nil {
+				return err
+			}
+		}
 	}
 
-	return
+	if listErr != nil {
+		// 跳过获取列表出错的目录
+		printError(listErr)
+	}
+	return nil
 }
 
 // RunTree 列出树形图
-func RunTree(path string) {
-	getTree(path, 0)
+func RunTree(pcspath string) {
+	err := matchPathByShellPatternOnce(&pcspath)
+	if err != nil {
+		printError(err)
+		return
+	}
+
+	tp := newTreePrinter()
+	if isStructuredOutput() {
+		tp.ls = newListStream(outputWriter, Output, []*pcscore.FileRecord(nil))
+	}
+
+	opts := pcsconfig.Config.WalkOptions()
+	opts.DirDone = tp.markDone
+	go func() {
+		defer tp.finish()
+		GetBaiduPCS().Walk(pcspath, opts, tp.walkFn)
+	}()
+
+	rootPath := tp.root()
+	if rootPath == "" {
+		return
+	}
+	err = tp.printDir(rootPath, 0)
+	if err != nil {
+		tp.mu.Lock()
+		tp.stopped = true
+		tp.mu.Unlock()
+	}
+	if err == nil && tp.ls != nil {
+		err = tp.ls.close()
+	}
+	if err != nil {
+		printError(err)
+	}
 }
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"container/list"
 	"encoding/hex"
+	"errors"
 	"fmt"
 	"github.com/Erope/BaiduPCS-Go/baidupcs"
 	"github.com/Erope/BaiduPCS-Go/baidupcs/pcserror"
@@ -12,8 +13,11 @@ import (
 	"github.com/Erope/BaiduPCS-Go/pcsutil"
 	"github.com/Erope/BaiduPCS-Go/pcsutil/checksum"
 	"github.com/Erope/BaiduPCS-Go/pcsutil/converter"
+	"github.com/Erope/BaiduPCS-Go/pcsutil/streamcrypto"
 	"github.com/Erope/BaiduPCS-Go/requester/rio"
+	"github.com/Erope/BaiduPCS-Go/requester/transfer"
 	"github.com/Erope/BaiduPCS-Go/requester/uploader"
+	"io"
 	"os"
 	"path"
 	"path/filepath"
@@ -29,10 +33,28 @@ const (
 type (
 	// UploadOptions 上传可选项
 	UploadOptions struct {
-		Parallel       int
-		MaxRetry       int
-		NotRapidUpload bool
-		NotSplitFile   bool // 禁用分片上传
+		Parallel        int
+		MaxRetry        int
+		NotRapidUpload  bool
+		NotSplitFile    bool                     // 禁用分片上传
+		StreamBlockSize int64                    // 从数据流上传时的分块大小, 为 0 则使用默认值
+		OnDup           baidupcs.OnDup           // 目标文件已存在时的处理方式
+		Encrypt         bool                     // 上传时在本地加密文件内容, 不使用秒传和断点续传
+		EncryptName     bool                     // 同时加密文件名, 需启用 Encrypt
+		Progress        transfer.ProgressHandler `json:"-"` // 传输事件, 为 nil 则不发送
+		Out             io.Writer                `json:"-"`
+
+		bgTask    *BgTask          // 所属的后台任务
+		journal   *transferJournal // 传输日志, 恢复任务时预先设置
+		noJournal bool             // 不记录到传输日志, 如 sync 重新执行即可继续
+		lastID    int              // 已使用的任务 id, 多次调用 runUpload 时 id 连续
+		encKey    []byte           // 客户端加密的主密钥
+	}
+
+	// uploadFile 要上传的本地文件和保存的网盘路径
+	uploadFile struct {
+		localPath string
+		savePath  string
 	}
 
 	// StepUpload 上传步骤
@@ -56,15 +78,19 @@ const (
 )
 
 // RunRapidUpload 执行秒传文件, 前提是知道文件的大小, md5, 前256KB切片的 md5, crc32
-func RunRapidUpload(targetPath, contentMD5, sliceMD5, crc32 string, length int64) {
+func RunRapidUpload(targetPath string, ondup baidupcs.OnDup, contentMD5, sliceMD5, crc32 string, length int64) {
 	err := matchPathByShellPatternOnce(&targetPath)
 	if err != nil {
 		fmt.Printf("警告: %s, 获取网盘路径 %s 错误, %s\n", baidupcs.OperationRapidUpload, targetPath, err)
 	}
 
-	err = GetBaiduPCS().RapidUpload(targetPath, contentMD5, sliceMD5, crc32, length)
-	if err != nil {
-		fmt.Printf("%s失败, 消息: %s\n", baidupcs.OperationRapidUpload, err)
+	pcsError := GetBaiduPCS().RapidUploadWithOnDup(targetPath, ondup, contentMD5, sliceMD5, crc32, length)
+	if baidupcs.IsOnDupSkipped(pcsError) {
+		fmt.Printf("目标文件 %s 已存在, 跳过\n", targetPath)
+		return
+	}
+	if pcsError != nil {
+		fmt.Printf("%s失败, 消息: %s\n", baidupcs.OperationRapidUpload, pcsError)
 		return
 	}
 
@@ -73,15 +99,19 @@ func RunRapidUpload(targetPath, contentMD5, sliceMD5, crc32 string, length int64
 }
 
 // RunCreateSuperFile 执行分片上传—合并分片文件
-func RunCreateSuperFile(targetPath string, blockList ...string) {
+func RunCreateSuperFile(targetPath string, ondup baidupcs.OnDup, blockList ...string) {
 	err := matchPathByShellPatternOnce(&targetPath)
 	if err != nil {
 		fmt.Printf("警告: %s, 获取网盘路径 %s 错误, %s\n", baidupcs.OperationUploadCreateSuperFile, targetPath, err)
 	}
 
-	err = GetBaiduPCS().UploadCreateSuperFile(targetPath, blockList...)
-	if err != nil {
-		fmt.Printf("%s失败, 消息: %s\n", baidupcs.OperationUploadCreateSuperFile, err)
+	pcsError := GetBaiduPCS().UploadCreateSuperFileWithOnDup(targetPath, ondup, blockList...)
+	if baidupcs.IsOnDupSkipped(pcsError) {
+		fmt.Printf("目标文件 %s 已存在, 跳过\n", targetPath)
+		return
+	}
+	if pcsError != nil {
+		fmt.Printf("%s失败, 消息: %s\n", baidupcs.OperationUploadCreateSuperFile, pcsError)
 		return
 	}
 
@@ -91,41 +121,39 @@ func RunCreateSuperFile(targetPath string, blockList ...string) {
 
 // RunUpload 执行文件上传
 func RunUpload(localPaths []string, savePath string, opt *UploadOptions) {
-	if opt == nil {
-		opt = &UploadOptions{}
-	}
-
-	// 检测opt
-	if opt.Parallel <= 0 {
-		opt.Parallel = pcsconfig.Config.MaxUploadParallel
-	}
+	opt = checkUploadOptions(opt)
 
-	if opt.MaxRetry < 0 {
-		opt.MaxRetry = DefaultUploadMaxRetry
+	err := matchPathByShellPatternOnce(&savePath)
+	if err != nil {
+		fmt.Fprintf(opt.Out, "警告: 上传文件, 获取网盘路径 %s 错误, %s\n", savePath, err)
 	}
 
-	err := matchPathByShellPatternOnce(&savePath)
+	err = opt.loadEncryptKey()
 	if err != nil {
-		fmt.Printf("警告: 上传文件, 获取网盘路径 %s 错误, %s\n", savePath, err)
+		fmt.Fprintf(opt.Out, "%s\n", err)
+		return
 	}
 
 	switch len(localPaths) {
 	case 0:
-		fmt.Printf("本地路径为空\n")
+		fmt.Fprintf(opt.Out, "本地路径为空\n")
 		return
+	case 1:
+		if localPaths[0] == StdinPath {
+			RunStreamUpload(os.Stdin, savePath, opt)
+			return
+		}
 	}
 
 	var (
-		pcs         = GetBaiduPCS()
-		ulist       = list.New()
-		lastID      int
+		files       []*uploadFile
 		subSavePath string
 	)
 
 	for k := range localPaths {
 		walkedFiles, err := pcsutil.WalkDir(localPaths[k], "")
 		if err != nil {
-			fmt.Printf("警告: 遍历错误: %s\n", err)
+			fmt.Fprintf(opt.Out, "警告: 遍历错误: %s\n", err)
 			continue
 		}
 
@@ -145,33 +173,119 @@ func RunUpload(localPaths []string, savePath string, opt *UploadOptions) {
 			}
 
 			subSavePath = strings.TrimPrefix(walkedFiles[k3], localPathDir)
-
-			lastID++
-			ulist.PushBack(&utask{
-				ListTask: ListTask{
-					ID:       lastID,
-					MaxRetry: opt.MaxRetry,
-				},
-				localFileChecksum: checksum.NewLocalFileChecksum(walkedFiles[k3], int(baidupcs.SliceMD5Size)),
-				savePath:          path.Clean(savePath + baidupcs.PathSeparator + subSavePath),
+			if opt.EncryptName {
+				subSavePath, err = encryptRelPath(opt.encKey, subSavePath)
+				if err != nil {
+					fmt.Fprintf(opt.Out, "加密文件名错误: %s\n", err)
+					return
+				}
+			}
+			files = append(files, &uploadFile{
+				localPath: walkedFiles[k3],
+				savePath:  path.Clean(savePath + baidupcs.PathSeparator + subSavePath),
 			})
+		}
+	}
+
+	runUpload(localPaths, savePath, files, opt)
+}
+
+// checkUploadOptions 检测opt, 设置默认值
+func checkUploadOptions(opt *UploadOptions) *UploadOptions {
+	if opt == nil {
+		opt = &UploadOptions{}
+	}
+
+	if opt.Out == nil {
+		opt.Out = os.Stdout
+	}
+
+	if opt.Parallel <= 0 {
+		opt.Parallel = pcsconfig.Config.MaxUploadParallel
+	}
+
+	if opt.MaxRetry < 0 {
+		opt.MaxRetry = DefaultUploadMaxRetry
+	}
+
+	if opt.EncryptName {
+		opt.Encrypt = true
+	}
+	return opt
+}
+
+// loadEncryptKey 启用加密时, 读取客户端加密的主密钥
+func (opt *UploadOptions) loadEncryptKey() error {
+	if !opt.Encrypt || opt.encKey != nil {
+		return nil
+	}
+	key, err := pcsconfig.Config.EncryptionKey()
+	if err != nil {
+		return err
+	}
+	opt.encKey = key
+	return nil
+}
+
+// runUpload 上传 files, localPaths 和 savePath 用于后台任务和传输日志, opt 需先经过 checkUploadOptions
+func runUpload(localPaths []string, savePath string, files []*uploadFile, opt *UploadOptions) {
+	var (
+		pcs    = GetBaiduPCS()
+		ulist  = list.New()
+		lastID = opt.lastID
+		err    error
+	)
 
-			fmt.Printf("[%d] 加入上传队列: %s\n", lastID, walkedFiles[k3])
+	for _, file := range files {
+		lastID++
+		task := &utask{
+			ListTask: ListTask{
+				ID:       lastID,
+				MaxRetry: opt.MaxRetry,
+			},
+			localFileChecksum: checksum.NewLocalFileChecksum(file.localPath, int(baidupcs.SliceMD5Size)),
+			savePath:          file.savePath,
 		}
+		ulist.PushBack(task)
+		emitProgress(opt.Progress, task.newEvent(transfer.ProgressQueued))
+
+		fmt.Fprintf(opt.Out, "[%d] 加入上传队列: %s\n", lastID, file.localPath)
 	}
 
-	if lastID == 0 {
-		fmt.Printf("未检测到上传的文件.\n")
+	if lastID == opt.lastID {
+		fmt.Fprintf(opt.Out, "未检测到上传的文件.\n")
 		return
 	}
+	opt.lastID = lastID
 
 	uploadDatabase, err := pcsupload.NewUploadingDatabase()
 	if err != nil {
-		fmt.Printf("打开上传未完成数据库错误: %s\n", err)
+		fmt.Fprintf(opt.Out, "打开上传未完成数据库错误: %s\n", err)
 		return
 	}
 	defer uploadDatabase.Close()
 
+	// 前台上传, 收到中断信号时取消上传
+	if opt.bgTask == nil {
+		opt.bgTask = newBgTask(transfer.DirectionUpload, localPaths, nil)
+		defer handleInterrupt(opt.Out, opt.bgTask)()
+	}
+	pcs = baidupcs.ClientWithContext(pcs, opt.bgTask.Context())
+
+	// 记录到传输日志, 中断后可使用 resume 恢复, 本地路径转换为绝对路径
+	if opt.journal == nil && !opt.noJournal {
+		absPaths := make([]string, 0, len(localPaths))
+		for k := range localPaths {
+			absPath, err := filepath.Abs(localPaths[k])
+			if err != nil {
+				absPath = localPaths[k]
+			}
+			absPaths = append(absPaths, absPath)
+		}
+		opt.journal = newTransferJournal(opt.Out, transfer.DirectionUpload, absPaths, savePath, opt)
+	}
+	opt.Progress = opt.journal.wrap(opt.Progress)
+
 	var (
 		handleTaskErr = func(task *utask, errManifest string, pcsError pcserror.Error) {
 			if task == nil {
@@ -190,12 +304,14 @@ func RunUpload(localPaths []string, savePath string, opt *UploadOptions) {
 				case 31200: //[Method:Insert][Error:Insert Request Forbid]
 				// do nothing, continue
 				default:
-					fmt.Printf("[%d] %s, %s\n", task.ID, errManifest, pcsError)
+					fmt.Fprintf(opt.Out, "[%d] %s, %s\n", task.ID, errManifest, pcsError)
+					emitProgress(opt.Progress, task.failedEvent(errManifest, pcsError))
 					return
 				}
 			case pcserror.ErrTypeNetError:
 				if strings.Contains(pcsError.GetError().Error(), "413 Request Entity Too Large") {
-					fmt.Printf("[%d] %s, %s\n", task.ID, errManifest, pcsError)
+					fmt.Fprintf(opt.Out, "[%d] %s, %s\n", task.ID, errManifest, pcsError)
+					emitProgress(opt.Progress, task.failedEvent(errManifest, pcsError))
 					return
 				}
 			}
@@ -203,12 +319,14 @@ func RunUpload(localPaths []string, savePath string, opt *UploadOptions) {
 			// 未达到失败重试最大次数, 将任务推送到队列末尾
 			if task.retry < task.MaxRetry {
 				task.retry++
-				fmt.Printf("[%d] %s, %s, 重试 %d/%d\n", task.ID, errManifest, pcsError, task.retry, task.MaxRetry)
+				fmt.Fprintf(opt.Out, "[%d] %s, %s, 重试 %d/%d\n", task.ID, errManifest, pcsError, task.retry, task.MaxRetry)
+				emitProgress(opt.Progress, task.retryEvent(errManifest, pcsError))
 				ulist.PushBack(task)
 				time.Sleep(3 * time.Duration(task.retry) * time.Second)
 			} else {
 				// on failed
-				fmt.Printf("[%d] %s, %s\n", task.ID, errManifest, pcsError)
+				fmt.Fprintf(opt.Out, "[%d] %s, %s\n", task.ID, errManifest, pcsError)
+				emitProgress(opt.Progress, task.failedEvent(errManifest, pcsError))
 			}
 		}
 		totalSize int64
@@ -225,22 +343,58 @@ func RunUpload(localPaths []string, savePath string, opt *UploadOptions) {
 		task := e.Value.(*utask)
 
 		func() {
-			fmt.Printf("[%d] 准备上传: %s\n", task.ID, task.localFileChecksum.Path)
+			if opt.bgTask.stopped() {
+				emitProgress(opt.Progress, task.newEvent(transfer.ProgressCanceled))
+				return
+			}
+
+			if opt.journal.isDone(task.savePath) {
+				fmt.Fprintf(opt.Out, "[%d] 文件已在之前上传完成: %s, 跳过...\n", task.ID, task.localFileChecksum.Path)
+				event := task.newEvent(transfer.ProgressSkipped)
+				event.Message = "已在之前上传完成"
+				emitProgress(opt.Progress, event)
+				return
+			}
+
+			fmt.Fprintf(opt.Out, "[%d] 准备上传: %s\n", task.ID, task.localFileChecksum.Path)
 
 			err = task.localFileChecksum.OpenPath()
 			if err != nil {
-				fmt.Printf("[%d] 文件不可读, 错误信息: %s, 跳过...\n", task.ID, err)
+				fmt.Fprintf(opt.Out, "[%d] 文件不可读, 错误信息: %s, 跳过...\n", task.ID, err)
+				emitProgress(opt.Progress, task.failedEvent("文件不可读", err))
 				return
 			}
 			defer task.localFileChecksum.Close() // 关闭文件
 
+			emitProgress(opt.Progress, task.newEvent(transfer.ProgressStarted))
+
 			var (
 				panDir, panFile = path.Split(task.savePath)
 			)
 			panDir = path.Clean(panDir)
 
+			// 跳过已存在的目标文件
+			if opt.OnDup == baidupcs.OnDupSkip {
+				_, pcsError := pcs.FilesDirectoriesMeta(task.savePath)
+				if pcsError == nil {
+					fmt.Fprintf(opt.Out, "[%d] 目标文件, %s, 已存在, 跳过...\n", task.ID, task.savePath)
+					emitProgress(opt.Progress, task.skippedEvent())
+					return
+				}
+				if pcsError.GetErrType() != pcserror.ErrTypeRemoteError {
+					handleTaskErr(task, "检查目标文件失败", pcsError)
+					return
+				}
+			}
+
 			// 检测断点续传
 			state := uploadDatabase.Search(&task.localFileChecksum.LocalFileMeta)
+			if opt.Encrypt {
+				// 每次加密的结果都不同, 不使用秒传和断点续传
+				state = nil
+				task.step = StepUploadUpload
+				goto stepControl
+			}
 			if state != nil || task.localFileChecksum.LocalFileMeta.MD5 != nil { // 读取到了md5
 				task.step = StepUploadUpload
 				goto stepControl
@@ -252,7 +406,7 @@ func RunUpload(localPaths []string, savePath string, opt *UploadOptions) {
 			}
 
 			if task.localFileChecksum.Length > baidupcs.MaxRapidUploadSize {
-				fmt.Printf("[%d] 文件超过20GB, 无法使用秒传功能, 跳过秒传...\n", task.ID)
+				fmt.Fprintf(opt.Out, "[%d] 文件超过20GB, 无法使用秒传功能, 跳过秒传...\n", task.ID)
 				task.step = StepUploadUpload
 				goto stepControl
 			}
@@ -276,13 +430,14 @@ func RunUpload(localPaths []string, savePath string, opt *UploadOptions) {
 					case pcserror.ErrTypeRemoteError:
 						// do nothing
 					default:
-						fmt.Printf("获取文件列表错误, %s\n", pcsError)
+						fmt.Fprintf(opt.Out, "获取文件列表错误, %s\n", pcsError)
+						emitProgress(opt.Progress, task.failedEvent("获取文件列表错误", pcsError))
 						return
 					}
 				}
 
 				if task.localFileChecksum.Length >= 128*converter.MB {
-					fmt.Printf("[%d] 检测秒传中, 请稍候...\n", task.ID)
+					fmt.Fprintf(opt.Out, "[%d] 检测秒传中, 请稍候...\n", task.ID)
 				}
 
 				// 经测试, 文件的 crc32 值并非秒传文件所必需
@@ -294,33 +449,38 @@ func RunUpload(localPaths []string, savePath string, opt *UploadOptions) {
 						if fd.Filename == panFile {
 							decodedMD5, _ := hex.DecodeString(fd.MD5)
 							if bytes.Compare(decodedMD5, task.localFileChecksum.MD5) == 0 {
-								fmt.Printf("[%d] 目标文件, %s, 已存在, 跳过...\n", task.ID, task.savePath)
+								fmt.Fprintf(opt.Out, "[%d] 目标文件, %s, 已存在, 跳过...\n", task.ID, task.savePath)
+								emitProgress(opt.Progress, task.skippedEvent())
 								return
 							}
 						}
 					}
 				}
 
-				pcsError = pcs.RapidUpload(task.savePath, hex.EncodeToString(task.localFileChecksum.MD5), hex.EncodeToString(task.localFileChecksum.SliceMD5), fmt.Sprint(task.localFileChecksum.CRC32), task.localFileChecksum.Length)
+				pcsError = pcs.RapidUploadWithOnDup(task.savePath, opt.OnDup, hex.EncodeToString(task.localFileChecksum.MD5), hex.EncodeToString(task.localFileChecksum.SliceMD5), fmt.Sprint(task.localFileChecksum.CRC32), task.localFileChecksum.Length)
 				if pcsError == nil {
-					fmt.Printf("[%d] 秒传成功, 保存到网盘路径: %s\n\n", task.ID, task.savePath)
+					fmt.Fprintf(opt.Out, "[%d] 秒传成功, 保存到网盘路径: %s\n\n", task.ID, task.savePath)
 					totalSize += task.localFileChecksum.Length
+					event := task.finishedEvent()
+					event.Message = "秒传成功"
+					emitProgress(opt.Progress, event)
+					return
+				}
+				if baidupcs.IsOnDupSkipped(pcsError) {
+					fmt.Fprintf(opt.Out, "[%d] 目标文件, %s, 已存在, 跳过...\n", task.ID, task.savePath)
+					emitProgress(opt.Progress, task.skippedEvent())
 					return
 				}
 
 				// 判断配额是否已满
-				switch pcsError.GetErrType() {
-				// 远程服务器错误
-				case pcserror.ErrTypeRemoteError:
-					switch pcsError.GetRemoteErrCode() {
-					case 31112: //exceed quota
-						fmt.Printf("[%d] 秒传失败, 超出配额, 网盘容量已满\n\n", task.ID)
-						return
-					}
+				if errors.Is(pcsError, pcserror.ErrQuotaExceeded) {
+					fmt.Fprintf(opt.Out, "[%d] 秒传失败, 超出配额, 网盘容量已满\n\n", task.ID)
+					emitProgress(opt.Progress, task.failedEvent("秒传失败", pcsError))
+					return
 				}
 			}
 
-			fmt.Printf("[%d] 秒传失败, 开始上传文件...\n\n", task.ID)
+			fmt.Fprintf(opt.Out, "[%d] 秒传失败, 开始上传文件...\n\n", task.ID)
 
 			// 保存秒传信息
 			uploadDatabase.UpdateUploading(&task.localFileChecksum.LocalFileMeta, nil)
@@ -330,17 +490,28 @@ func RunUpload(localPaths []string, savePath string, opt *UploadOptions) {
 		stepUploadUpload:
 			task.step = StepUploadUpload
 			{
+				var file rio.ReaderAtLen64 = rio.NewFileReaderAtLen64(task.localFileChecksum.GetFile())
+				if opt.Encrypt {
+					file, err = streamcrypto.NewEncryptReaderAt(opt.encKey, task.localFileChecksum.GetFile(), task.localFileChecksum.Length)
+					if err != nil {
+						fmt.Fprintf(opt.Out, "[%d] 加密文件错误: %s\n", task.ID, err)
+						emitProgress(opt.Progress, task.failedEvent("加密文件错误", err))
+						return
+					}
+				}
+
 				var blockSize int64
 				if opt.NotSplitFile {
-					blockSize = task.localFileChecksum.Length
+					blockSize = file.Len()
 				} else {
-					blockSize = getBlockSize(task.localFileChecksum.Length)
+					blockSize = getBlockSize(file.Len())
 				}
 
-				muer := uploader.NewMultiUploader(pcsupload.NewPCSUpload(pcs, task.savePath), rio.NewFileReaderAtLen64(task.localFileChecksum.GetFile()), &uploader.MultiUploaderConfig{
+				muer := uploader.NewMultiUploader(pcsupload.NewPCSUpload(pcs, task.savePath, opt.OnDup), file, &uploader.MultiUploaderConfig{
 					Parallel:  opt.Parallel,
 					BlockSize: blockSize,
 					MaxRate:   pcsconfig.Config.MaxUploadRate,
+					Context:   opt.bgTask.Context(),
 				})
 
 				// 设置断点续传
@@ -352,12 +523,16 @@ func RunUpload(localPaths []string, savePath string, opt *UploadOptions) {
 				muer.OnUploadStatusEvent(func(status uploader.Status, updateChan <-chan struct{}) {
 					select {
 					case <-updateChan:
-						uploadDatabase.UpdateUploading(&task.localFileChecksum.LocalFileMeta, muer.InstanceState())
-						uploadDatabase.Save()
+						if !opt.Encrypt {
+							uploadDatabase.UpdateUploading(&task.localFileChecksum.LocalFileMeta, muer.InstanceState())
+							uploadDatabase.Save()
+						}
 					default:
 					}
 
-					fmt.Printf("\r[%d] ↑ %s/%s %s/s in %s ............", task.ID,
+					emitProgress(opt.Progress, task.newEvent(transfer.ProgressProgress).SetStatus(status, status.Uploaded()))
+
+					fmt.Fprintf(opt.Out, "\r[%d] ↑ %s/%s %s/s in %s ............", task.ID,
 						converter.ConvertFileSize(status.Uploaded(), 2),
 						converter.ConvertFileSize(status.TotalSize(), 2),
 						converter.ConvertFileSize(status.SpeedsPerSecond(), 2),
@@ -366,17 +541,31 @@ func RunUpload(localPaths []string, savePath string, opt *UploadOptions) {
 				})
 				muer.OnSuccess(func() {
 					close(exitChan)
-					fmt.Printf("\n")
-					fmt.Printf("[%d] 上传文件成功, 保存到网盘路径: %s\n", task.ID, task.savePath)
+					fmt.Fprintf(opt.Out, "\n")
+					fmt.Fprintf(opt.Out, "[%d] 上传文件成功, 保存到网盘路径: %s\n", task.ID, task.savePath)
 					totalSize += task.localFileChecksum.Length
 					uploadDatabase.Delete(&task.localFileChecksum.LocalFileMeta) // 删除
 					uploadDatabase.Save()
+					emitProgress(opt.Progress, task.finishedEvent())
+				})
+				muer.OnCancel(func() {
+					close(exitChan)
+					fmt.Fprintf(opt.Out, "\n[%d] 上传已取消\n", task.ID)
+					emitProgress(opt.Progress, task.newEvent(transfer.ProgressCanceled))
 				})
 				muer.OnError(func(err error) {
 					close(exitChan)
 					pcsError, ok := err.(pcserror.Error)
 					if !ok {
-						fmt.Printf("[%d] 上传文件错误: %s\n", task.ID, err)
+						fmt.Fprintf(opt.Out, "[%d] 上传文件错误: %s\n", task.ID, err)
+						emitProgress(opt.Progress, task.failedEvent("上传文件错误", err))
+						return
+					}
+					if baidupcs.IsOnDupSkipped(pcsError) {
+						uploadDatabase.Delete(&task.localFileChecksum.LocalFileMeta)
+						uploadDatabase.Save()
+						fmt.Fprintf(opt.Out, "\n[%d] 目标文件, %s, 已存在, 跳过...\n", task.ID, task.savePath)
+						emitProgress(opt.Progress, task.skippedEvent())
 						return
 					}
 
@@ -384,20 +573,58 @@ func RunUpload(localPaths []string, savePath string, opt *UploadOptions) {
 					case 31363: // block miss in superfile2, 上传状态过期
 						uploadDatabase.Delete(&task.localFileChecksum.LocalFileMeta)
 						uploadDatabase.Save()
-						fmt.Printf("[%d] 上传文件错误: 上传状态过期, 请重新上传\n", task.ID)
+						fmt.Fprintf(opt.Out, "[%d] 上传文件错误: 上传状态过期, 请重新上传\n", task.ID)
+						emitProgress(opt.Progress, task.failedEvent("上传文件错误", errors.New("上传状态过期, 请重新上传")))
 						return
 					}
 
 					handleTaskErr(task, "上传文件失败", pcsError)
 					return
 				})
+				opt.bgTask.attach(task.ID, muer)
 				muer.Execute()
+				opt.bgTask.detach(task.ID)
 			}
 		}()
 	}
 
-	fmt.Printf("\n")
-	fmt.Printf("全部上传完毕, 总大小: %s\n", converter.ConvertFileSize(totalSize))
+	fmt.Fprintf(opt.Out, "\n")
+	fmt.Fprintf(opt.Out, "全部上传完毕, 总大小: %s\n", converter.ConvertFileSize(totalSize))
+	opt.journal.finish(opt.Out, opt.bgTask.canceled())
+}
+
+func (task *utask) newEvent(eventType transfer.ProgressEventType) *transfer.ProgressEvent {
+	event := transfer.NewProgressEvent(eventType, transfer.DirectionUpload, task.ID)
+	event.Path = task.localFileChecksum.Path
+	event.SavePath = task.savePath
+	event.Size = task.localFileChecksum.Length
+	return event
+}
+
+func (task *utask) retryEvent(errManifest string, err error) *transfer.ProgressEvent {
+	event := task.newEvent(transfer.ProgressRetry)
+	event.Retry, event.MaxRetry = task.retry, task.MaxRetry
+	event.Error = errManifest + ", " + err.Error()
+	return event
+}
+
+func (task *utask) failedEvent(errManifest string, err error) *transfer.ProgressEvent {
+	event := task.newEvent(transfer.ProgressFailed)
+	event.Retry, event.MaxRetry = task.retry, task.MaxRetry
+	event.Error = errManifest + ", " + err.Error()
+	return event
+}
+
+func (task *utask) skippedEvent() *transfer.ProgressEvent {
+	event := task.newEvent(transfer.ProgressSkipped)
+	event.Message = "目标文件已存在"
+	return event
+}
+
+func (task *utask) finishedEvent() *transfer.ProgressEvent {
+	event := task.newEvent(transfer.ProgressFinished)
+	event.Bytes = event.Size
+	return event
 }
 
 func getBlockSize(fileSize int64) int64 {
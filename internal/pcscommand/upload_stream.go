@@ -0,0 +1,175 @@
+package pcscommand
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/Erope/BaiduPCS-Go/baidupcs"
+	"github.com/Erope/BaiduPCS-Go/baidupcs/pcserror"
+	"github.com/Erope/BaiduPCS-Go/internal/pcsconfig"
+	"github.com/Erope/BaiduPCS-Go/internal/pcsfunctions/pcsupload"
+	"github.com/Erope/BaiduPCS-Go/pcsutil/converter"
+	"github.com/Erope/BaiduPCS-Go/pcsutil/streamcrypto"
+	"github.com/Erope/BaiduPCS-Go/requester/transfer"
+	"github.com/Erope/BaiduPCS-Go/requester/uploader"
+	"io"
+	"path"
+	"strings"
+)
+
+const (
+	// StdinPath 表示从标准输入上传的本地路径
+	StdinPath = "-"
+)
+
+// RunStreamUpload 上传长度未知的数据流 r, 如标准输入, 保存到网盘文件 savePath.
+// 数据流按 opt.StreamBlockSize 分块, 分块读满即上传, 不保存到临时文件,
+// 内存占用约为 (并发量+1) * 分块大小, 数据流不支持断点续传
+func RunStreamUpload(r io.Reader, savePath string, opt *UploadOptions) {
+	opt = checkUploadOptions(opt)
+
+	err := matchPathByShellPatternOnce(&savePath)
+	if err != nil {
+		fmt.Fprintf(opt.Out, "警告: 上传文件, 获取网盘路径 %s 错误, %s\n", savePath, err)
+	}
+	if strings.HasSuffix(savePath, baidupcs.PathSeparator) {
+		fmt.Fprintf(opt.Out, "从数据流上传时, 目标路径需为文件路径: %s\n", savePath)
+		return
+	}
+
+	err = opt.loadEncryptKey()
+	if err != nil {
+		fmt.Fprintf(opt.Out, "%s\n", err)
+		return
+	}
+	if opt.EncryptName {
+		dir, name := path.Split(savePath)
+		name, err = streamcrypto.EncryptName(opt.encKey, name)
+		if err != nil {
+			fmt.Fprintf(opt.Out, "加密文件名错误: %s\n", err)
+			return
+		}
+		savePath = dir + name
+	}
+
+	var (
+		pcs   = GetBaiduPCS()
+		event = func(eventType transfer.ProgressEventType) *transfer.ProgressEvent {
+			e := transfer.NewProgressEvent(eventType, transfer.DirectionUpload, 1)
+			e.Path = StdinPath
+			e.SavePath = savePath
+			return e
+		}
+		failed = func(errManifest string, err error) {
+			fmt.Fprintf(opt.Out, "%s, %s\n", errManifest, err)
+			e := event(transfer.ProgressFailed)
+			e.Error = errManifest + ", " + err.Error()
+			emitProgress(opt.Progress, e)
+		}
+	)
+
+	// 目标为目录时不上传, 跳过已存在的目标文件
+	fd, pcsError := pcs.FilesDirectoriesMeta(savePath)
+	if pcsError == nil {
+		if fd.Isdir {
+			fmt.Fprintf(opt.Out, "目标路径 %s 是目录, 请指定保存的文件名\n", savePath)
+			return
+		}
+		if opt.OnDup == baidupcs.OnDupSkip {
+			fmt.Fprintf(opt.Out, "目标文件, %s, 已存在, 跳过...\n", savePath)
+			e := event(transfer.ProgressSkipped)
+			e.Message = "目标文件已存在"
+			emitProgress(opt.Progress, e)
+			return
+		}
+	} else if pcsError.GetErrType() != pcserror.ErrTypeRemoteError {
+		failed("检查目标文件失败", pcsError)
+		return
+	}
+
+	// 前台上传, 收到中断信号时取消上传
+	if opt.bgTask == nil {
+		opt.bgTask = newBgTask(transfer.DirectionUpload, []string{StdinPath}, nil)
+		defer handleInterrupt(opt.Out, opt.bgTask)()
+	}
+	pcs = baidupcs.ClientWithContext(pcs, opt.bgTask.Context())
+
+	if opt.Encrypt {
+		r, err = streamcrypto.NewEncryptReader(opt.encKey, r)
+		if err != nil {
+			failed("加密数据流错误", err)
+			return
+		}
+	}
+
+	blockSize := opt.StreamBlockSize
+	if blockSize <= 0 {
+		blockSize = uploader.DefaultStreamBlockSize
+	}
+	fmt.Fprintf(opt.Out, "从数据流上传到: %s, 分块大小: %s\n", savePath, converter.ConvertFileSize(blockSize, 2))
+	emitProgress(opt.Progress, event(transfer.ProgressStarted))
+
+	su := uploader.NewStreamUploader(pcsupload.NewPCSUpload(pcs, savePath, opt.OnDup), r, &uploader.StreamUploaderConfig{
+		Parallel:     opt.Parallel,
+		BlockSize:    blockSize,
+		MaxBlocks:    baidupcs.MaxUploadBlockNum,
+		MaxRetry:     opt.MaxRetry,
+		MaxRate:      pcsconfig.Config.MaxUploadRate,
+		SliceMD5Size: baidupcs.SliceMD5Size,
+		Context:      opt.bgTask.Context(),
+	})
+	su.OnUploadStatusEvent(func(status uploader.Status, updateChan <-chan struct{}) {
+		e := event(transfer.ProgressProgress).SetStatus(status, status.Uploaded())
+		e.ETA = -1 // 数据流的总大小未知
+		emitProgress(opt.Progress, e)
+
+		fmt.Fprintf(opt.Out, "\r↑ %s/%s %s/s in %s ............",
+			converter.ConvertFileSize(status.Uploaded(), 2),
+			converter.ConvertFileSize(status.TotalSize(), 2),
+			converter.ConvertFileSize(status.SpeedsPerSecond(), 2),
+			status.TimeElapsed(),
+		)
+	})
+
+	opt.bgTask.attach(1, su)
+	checksumList, err := su.Execute()
+	opt.bgTask.detach(1)
+	fmt.Fprintf(opt.Out, "\n")
+	switch {
+	case errors.Is(err, context.Canceled):
+		fmt.Fprintf(opt.Out, "上传已取消\n")
+		emitProgress(opt.Progress, event(transfer.ProgressCanceled))
+		return
+	case errors.Is(err, uploader.ErrStreamTooLarge):
+		failed("上传文件失败", fmt.Errorf("%s, 当前分块大小 %s, 最多 %d 个分块", err, converter.ConvertFileSize(blockSize, 2), baidupcs.MaxUploadBlockNum))
+		return
+	case err != nil:
+		failed("上传文件失败", err)
+		return
+	}
+
+	sum := su.Checksum()
+	// 每次加密的结果都不同, 不使用秒传
+	rapidUploaded, pcsError := pcsupload.CreateStreamFile(pcs, savePath, opt.OnDup, sum, checksumList, !opt.NotRapidUpload && !opt.Encrypt)
+	if baidupcs.IsOnDupSkipped(pcsError) {
+		fmt.Fprintf(opt.Out, "目标文件, %s, 已存在, 跳过...\n", savePath)
+		e := event(transfer.ProgressSkipped)
+		e.Message = "目标文件已存在"
+		emitProgress(opt.Progress, e)
+		return
+	}
+	if pcsError != nil {
+		failed("保存文件失败", pcsError)
+		return
+	}
+
+	if rapidUploaded {
+		fmt.Fprintf(opt.Out, "秒传成功, 保存到网盘路径: %s\n", savePath)
+	} else {
+		fmt.Fprintf(opt.Out, "上传文件成功, 保存到网盘路径: %s\n", savePath)
+	}
+	fmt.Fprintf(opt.Out, "总大小: %s, md5: %x\n", converter.ConvertFileSize(sum.Length, 2), sum.MD5)
+	e := event(transfer.ProgressFinished)
+	e.Size, e.Bytes = sum.Length, sum.Length
+	emitProgress(opt.Progress, e)
+}
@@ -0,0 +1,148 @@
+package pcscommand
+
+import (
+	"fmt"
+	"github.com/Erope/BaiduPCS-Go/internal/pcsfunctions/pcssync"
+	"github.com/Erope/BaiduPCS-Go/internal/pcsfunctions/pcswatch"
+	"github.com/Erope/BaiduPCS-Go/requester/transfer"
+	"path"
+	"path/filepath"
+	"time"
+)
+
+const (
+	// DefaultWatchStable 默认文件保持不变多久后上传
+	DefaultWatchStable = 5 * time.Second
+)
+
+type (
+	// WatchOptions 监视目录上传可选项
+	WatchOptions struct {
+		Stable  time.Duration // 文件保持不变多久后上传
+		Delete  bool          // 本地删除的文件或目录, 同时删除网盘中的, 移动到回收站
+		Include []string      // 只上传匹配的文件
+		Exclude []string      // 排除匹配的文件和目录
+		Upload  *UploadOptions
+	}
+)
+
+// RunUploadWatch 监视本地目录 localDir, 新建或修改的文件保持不变后上传到网盘目录 savePath,
+// 直到收到中断信号或所属的后台任务被取消. 启动前已存在的文件不会上传
+func RunUploadWatch(localDir, savePath string, opt *WatchOptions) {
+	if opt == nil {
+		opt = &WatchOptions{}
+	}
+	if opt.Stable <= 0 {
+		opt.Stable = DefaultWatchStable
+	}
+	opt.Upload = checkUploadOptions(opt.Upload)
+	out := opt.Upload.Out
+
+	err := matchPathByShellPatternOnce(&savePath)
+	if err != nil {
+		fmt.Fprintf(out, "获取网盘路径 %s 错误, %s\n", savePath, err)
+		return
+	}
+
+	err = opt.Upload.loadEncryptKey()
+	if err != nil {
+		fmt.Fprintf(out, "%s\n", err)
+		return
+	}
+
+	filter, err := pcssync.NewFilter(opt.Include, opt.Exclude)
+	if err != nil {
+		fmt.Fprintf(out, "%s\n", err)
+		return
+	}
+
+	localDir, err = filepath.Abs(localDir)
+	if err != nil {
+		fmt.Fprintf(out, "%s\n", err)
+		return
+	}
+
+	w, err := pcswatch.NewWatcher(localDir, opt.Stable, filter)
+	if err != nil {
+		fmt.Fprintf(out, "监视目录 %s 错误, %s\n", localDir, err)
+		return
+	}
+	defer w.Close()
+	w.OnError(func(err error) {
+		fmt.Fprintf(out, "监视目录错误, %s\n", err)
+	})
+
+	// 前台运行, 收到中断信号时停止
+	if opt.Upload.bgTask == nil {
+		opt.Upload.bgTask = newBgTask(transfer.DirectionUpload, []string{localDir}, nil)
+		defer handleInterrupt(out, opt.Upload.bgTask)()
+	}
+	// 每次上传的文件都不同, 不记录到传输日志
+	opt.Upload.noJournal = true
+
+	fmt.Fprintf(out, "正在监视 %s, 文件保持 %s 不变后上传到网盘目录 %s\n", localDir, opt.Stable, savePath)
+	w.Run(opt.Upload.bgTask.done(), func(events []*pcswatch.Event) {
+		var (
+			files   []*uploadFile
+			deletes []*pcssync.Action
+		)
+		for _, event := range events {
+			relPath := event.RelPath
+			if opt.Upload.EncryptName {
+				relPath, err = encryptRelPath(opt.Upload.encKey, relPath)
+				if err != nil {
+					fmt.Fprintf(out, "加密文件名错误: %s\n", err)
+					continue
+				}
+			}
+			remotePath := path.Join(savePath, relPath)
+			switch event.Type {
+			case pcswatch.EventChanged:
+				files = append(files, &uploadFile{
+					localPath: event.Path,
+					savePath:  remotePath,
+				})
+			case pcswatch.EventRemoved:
+				if !opt.Delete {
+					fmt.Fprintf(out, "本地已删除: %s, 网盘中保留\n", event.RelPath)
+					continue
+				}
+				deletes = append(deletes, &pcssync.Action{
+					Type:       pcssync.ActionDelete,
+					Reason:     "本地已删除",
+					RelPath:    event.RelPath,
+					RemotePath: remotePath,
+					Isdir:      event.Isdir,
+				})
+			}
+		}
+
+		if len(deletes) > 0 {
+			runSyncDelete(out, deletes, false)
+		}
+		if len(files) > 0 {
+			runUpload([]string{localDir}, savePath, files, opt.Upload)
+		}
+	})
+	fmt.Fprintf(out, "已停止监视 %s\n", localDir)
+}
+
+// RunBgUploadWatch 在后台监视目录上传, 返回的后台任务可用于停止监视, 如在网页或守护进程中
+func RunBgUploadWatch(localDir, savePath string, opt *WatchOptions) *BgTask {
+	if opt == nil {
+		opt = &WatchOptions{}
+	}
+	if opt.Upload == nil {
+		opt.Upload = &UploadOptions{}
+	}
+
+	task := BgMap.newTask(transfer.DirectionUpload, []string{localDir}, opt.Upload.Progress)
+	opt.Upload.Out = task.output
+	opt.Upload.Progress = task
+	opt.Upload.bgTask = task
+
+	runBgTask(task, func() {
+		RunUploadWatch(localDir, savePath, opt)
+	})
+	return task
+}
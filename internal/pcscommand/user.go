@@ -0,0 +1,39 @@
+package pcscommand
+
+import (
+	"fmt"
+	"github.com/Erope/BaiduPCS-Go/internal/pcsconfig"
+	"github.com/Erope/BaiduPCS-Go/pcscore"
+)
+
+// newUserRecord 将百度帐号转换为 pcscore.UserRecord
+func newUserRecord(baidu *pcsconfig.Baidu) *pcscore.UserRecord {
+	return &pcscore.UserRecord{
+		UID:     baidu.UID,
+		Name:    baidu.Name,
+		Sex:     baidu.Sex,
+		Age:     baidu.Age,
+		Workdir: baidu.Workdir,
+		Active:  baidu.UID == pcsconfig.Config.BaiduActiveUID,
+	}
+}
+
+// RunWho 输出当前帐号的信息
+func RunWho() {
+	activeUser := GetActiveUser()
+	renderObject(newUserRecord(activeUser), func() {
+		fmt.Printf("当前帐号 uid: %d, 用户名: %s, 性别: %s, 年龄: %.1f\n", activeUser.UID, activeUser.Name, activeUser.Sex, activeUser.Age)
+	})
+}
+
+// RunLoglist 输出所有已登录的百度帐号
+func RunLoglist() {
+	userList := pcsconfig.Config.BaiduUserList
+	records := make([]*pcscore.UserRecord, 0, len(userList))
+	for _, baidu := range userList {
+		records = append(records, newUserRecord(baidu))
+	}
+	renderList(records, func() {
+		fmt.Println(userList.String())
+	})
+}
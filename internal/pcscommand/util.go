@@ -1,15 +1,19 @@
 package pcscommand
 
 import (
-	"errors"
 	"fmt"
+	"github.com/Erope/BaiduPCS-Go/baidupcs"
+	"github.com/Erope/BaiduPCS-Go/pcscore"
+	"github.com/Erope/BaiduPCS-Go/pcstable"
+	"os"
+	"strconv"
 )
 
 var (
 	// ErrShellPatternMultiRes 多条通配符匹配结果
-	ErrShellPatternMultiRes = errors.New("多条通配符匹配结果")
+	ErrShellPatternMultiRes = pcscore.ErrShellPatternMultiRes
 	// ErrShellPatternNoHit 未匹配到路径
-	ErrShellPatternNoHit = errors.New("未匹配到路径, 请检测通配符")
+	ErrShellPatternNoHit = pcscore.ErrShellPatternNoHit
 )
 
 // ListTask 队列状态 (基类)
@@ -34,31 +38,86 @@ func RunTestShellPattern(pattern string) {
 }
 
 func matchPathByShellPatternOnce(pattern *string) error {
-	paths, err := GetBaiduPCS().MatchPathByShellPattern(GetActiveUser().PathJoin(*pattern))
+	pcspath, err := GetSession().MatchPathOnce(*pattern)
 	if err != nil {
 		return err
 	}
-	switch len(paths) {
-	case 0:
-		return ErrShellPatternNoHit
-	case 1:
-		*pattern = paths[0]
-	default:
-		return ErrShellPatternMultiRes
-	}
-
+	*pattern = pcspath
 	return nil
 }
 
 func matchPathByShellPattern(patterns ...string) (pcspaths []string, err error) {
-	acUser, pcs := GetActiveUser(), GetBaiduPCS()
-	for k := range patterns {
-		ps, err := pcs.MatchPathByShellPattern(acUser.PathJoin(patterns[k]))
-		if err != nil {
-			return nil, err
+	return GetSession().MatchPath(patterns...)
+}
+
+// printBatchResults 输出批量操作的结果, isCpMv 为 true 时输出原路径和目标路径, 否则输出路径
+func printBatchResults(results baidupcs.BatchResultList, isCpMv bool) {
+	var (
+		tb         = pcstable.NewTable(os.Stdout)
+		withReason = len(results.Succeeded()) < len(results)
+		header     []string
+	)
+	if isCpMv {
+		header = []string{"#", "原路径", "目标路径"}
+	} else {
+		header = []string{"#", "文件/目录"}
+	}
+	if withReason {
+		header = append(header, "原因")
+	}
+	tb.SetHeader(header)
+
+	for k, br := range results {
+		var row []string
+		if isCpMv {
+			row = []string{strconv.Itoa(k), br.From, br.To}
+		} else {
+			row = []string{strconv.Itoa(k), br.Path}
 		}
+		if withReason {
+			reason := br.Status.String()
+			if br.Status == baidupcs.BatchStatusFailed && br.PCSError != nil {
+				reason = br.PCSError.Error()
+			}
+			row = append(row, reason)
+		}
+		tb.Append(row)
+	}
+	tb.Render()
+}
+
+// printBatchSummary 输出批量操作的汇总, opName 为操作名称, 如 "删除".
+// 存在失败或跳过的项时输出汇总并返回 true, 否则不输出并返回 false
+func printBatchSummary(results baidupcs.BatchResultList, opName string, isCpMv bool) bool {
+	var (
+		succeeded = results.Succeeded()
+		skipped   = results.Skipped()
+		failed    = results.Failed()
+	)
+	switch {
+	case len(failed) == 0 && len(skipped) == 0:
+		return false
+	case len(failed) == len(results):
+		fmt.Printf("操作失败, 共 %d 项, 全部%s失败: \n", len(results), opName)
+		printBatchResults(failed, isCpMv)
+		return true
+	case len(failed) > 0:
+		fmt.Printf("部分操作失败, 共 %d 项, %s成功 %d 项, 跳过 %d 项, 失败 %d 项\n", len(results), opName, len(succeeded), len(skipped), len(failed))
+	default:
+		fmt.Printf("操作完成, 共 %d 项, %s成功 %d 项, 跳过 %d 项\n", len(results), opName, len(succeeded), len(skipped))
+	}
 
-		pcspaths = append(pcspaths, ps...)
+	if len(succeeded) > 0 {
+		fmt.Printf("以下文件/目录%s成功: \n", opName)
+		printBatchResults(succeeded, isCpMv)
+	}
+	if len(skipped) > 0 {
+		fmt.Printf("以下文件/目录已跳过: \n")
+		printBatchResults(skipped, isCpMv)
+	}
+	if len(failed) > 0 {
+		fmt.Printf("以下文件/目录%s失败: \n", opName)
+		printBatchResults(failed, isCpMv)
 	}
-	return pcspaths, nil
+	return true
 }
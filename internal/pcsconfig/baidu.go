@@ -4,6 +4,7 @@ import (
 	"errors"
 	"fmt"
 	"github.com/Erope/BaiduPCS-Go/baidupcs"
+	"github.com/Erope/BaiduPCS-Go/baidupcs/diskcache"
 	"github.com/Erope/BaiduPCS-Go/pcstable"
 	"github.com/Erope/BaiduPCS-Go/pcsutil/converter"
 	"github.com/Erope/baidu-tools/tieba"
@@ -48,6 +49,30 @@ func (baidu *Baidu) BaiduPCS() *baidupcs.BaiduPCS {
 	pcs.SetPCSUserAgent(Config.PCSUA)
 	pcs.SetPanUserAgent(Config.PanUA)
 	pcs.SetUID(baidu.UID)
+	pcs.SetRetryPolicy(Config.RetryPolicy())
+
+	rl, err := baidupcs.ParseRateLimit(Config.APIRateLimit)
+	if err != nil {
+		pcsConfigVerbose.Warnf("api_rate_limit 设置错误: %s\n", err)
+	} else {
+		pcs.SetRateLimit(rl)
+	}
+
+	ttl, err := Config.MetaCacheTTLDuration()
+	if err != nil {
+		pcsConfigVerbose.Warnf("meta_cache_ttl 设置错误: %s\n", err)
+	} else if ttl > 0 {
+		pcs.SetDiskCache(diskcache.New(MetaCacheDir(baidu.UID), ttl))
+	}
+
+	err = pcs.SetPCSAddr(Config.PCSAddr)
+	if err != nil {
+		pcsConfigVerbose.Warnf("pcs_addr 设置错误: %s\n", err)
+	}
+	err = pcs.SetPanAddr(Config.PanAddr)
+	if err != nil {
+		pcsConfigVerbose.Warnf("pan_addr 设置错误: %s\n", err)
+	}
 	return pcs
 }
 
@@ -15,4 +15,6 @@ var (
 	ErrConfigFileNoPermission = errors.New("config file permission denied")
 	//ErrConfigContentsParseError 解析Config数据错误
 	ErrConfigContentsParseError = errors.New("config contents parse error")
+	//ErrEncryptKeyNotSet 未设置客户端加密的密钥
+	ErrEncryptKeyNotSet = errors.New("encryption key not set, use config set -enc_key or env " + EnvEncryptKey)
 )
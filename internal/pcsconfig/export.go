@@ -6,6 +6,7 @@ import (
 	"github.com/Erope/BaiduPCS-Go/baidupcs/dlinkclient"
 	"github.com/Erope/BaiduPCS-Go/pcstable"
 	"github.com/Erope/BaiduPCS-Go/pcsutil/converter"
+	"github.com/Erope/BaiduPCS-Go/pcsutil/streamcrypto"
 	"github.com/Erope/BaiduPCS-Go/requester"
 	"github.com/olekukonko/tablewriter"
 	"os"
@@ -44,14 +45,34 @@ func (c *PCSConfig) ActiveUser() *Baidu {
 	return c.activeUser
 }
 
-// ActiveUserBaiduPCS 获取当前登录的用户的baidupcs.BaiduPCS
-func (c *PCSConfig) ActiveUserBaiduPCS() *baidupcs.BaiduPCS {
+// ActiveUserBaiduPCS 获取当前登录的用户的网盘客户端
+func (c *PCSConfig) ActiveUserBaiduPCS() baidupcs.Client {
 	if c.pcs == nil {
-		c.pcs = c.ActiveUser().BaiduPCS()
+		c.pcs = c.newClient(c.ActiveUser())
 	}
 	return c.pcs
 }
 
+// SetClientFactory 设置创建网盘客户端的方法, 用于替换为其他实现 (如测试用的内存实现),
+// 为空则使用 Baidu.BaiduPCS. 当前登录的用户的网盘客户端将重新创建
+func (c *PCSConfig) SetClientFactory(factory ClientFactory) {
+	c.clientFactory = factory
+	c.pcs = nil
+}
+
+func (c *PCSConfig) newClient(baidu *Baidu) baidupcs.Client {
+	if c.clientFactory != nil {
+		return c.clientFactory(baidu)
+	}
+	return baidu.BaiduPCS()
+}
+
+// baiduPCS 当前登录的用户的网盘客户端为 *baidupcs.BaiduPCS 时返回, 用于同步修改的配置
+func (c *PCSConfig) baiduPCS() *baidupcs.BaiduPCS {
+	pcs, _ := c.pcs.(*baidupcs.BaiduPCS)
+	return pcs
+}
+
 func (c *PCSConfig) httpClientWithUA(ua string) *requester.HTTPClient {
 	client := requester.NewHTTPClient()
 	client.SetHTTPSecure(c.EnableHTTPS)
@@ -106,6 +127,51 @@ func (c *PCSConfig) AverageParallel() int {
 	return AverageParallel(c.MaxParallel, c.MaxDownloadLoad)
 }
 
+// WalkOptions 返回遍历网盘目录的可选项
+func (c *PCSConfig) WalkOptions() *baidupcs.WalkOptions {
+	return &baidupcs.WalkOptions{
+		OrderOptions: baidupcs.DefaultOrderOptions,
+		Parallel:     c.WalkParallel,
+	}
+}
+
+// MetaCacheTTLDuration 返回本地元信息缓存有效期, 0 代表不使用
+func (c *PCSConfig) MetaCacheTTLDuration() (time.Duration, error) {
+	return parseMetaCacheTTL(c.MetaCacheTTL)
+}
+
+// RetryPolicy 返回 api 请求的重试策略
+func (c *PCSConfig) RetryPolicy() *baidupcs.RetryPolicy {
+	rp := baidupcs.NewDefaultRetryPolicy()
+	rp.MaxAttempts = c.APIMaxRetry + 1
+	return rp
+}
+
+// EncryptionKey 返回客户端加密的主密钥, 环境变量 BAIDUPCS_GO_ENC_KEY 优先于配置 enc_key,
+// 64 位十六进制字符串作为原始密钥, 其他作为密码, 与密码盐 enc_salt 一起派生密钥
+func (c *PCSConfig) EncryptionKey() ([]byte, error) {
+	source, ok := os.LookupEnv(EnvEncryptKey)
+	if !ok {
+		source = c.EncryptKey
+	}
+	if source == "" {
+		return nil, ErrEncryptKeyNotSet
+	}
+
+	c.encKeyMu.Lock()
+	defer c.encKeyMu.Unlock()
+	cacheKey := source + "\x00" + c.EncryptSalt
+	if c.encKey != nil && c.encKeySource == cacheKey {
+		return c.encKey, nil
+	}
+	key, err := streamcrypto.ParseKey(source, c.EncryptSalt)
+	if err != nil {
+		return nil, err
+	}
+	c.encKeySource, c.encKey = cacheKey, key
+	return key, nil
+}
+
 // PrintTable 输出表格
 func (c *PCSConfig) PrintTable() {
 	tb := pcstable.NewTable(os.Stdout)
@@ -118,8 +184,12 @@ func (c *PCSConfig) PrintTable() {
 		[]string{"max_parallel", strconv.Itoa(c.MaxParallel), "50 ~ 500", "下载最大并发量"},
 		[]string{"max_upload_parallel", strconv.Itoa(c.MaxUploadParallel), "1 ~ 100", "上传最大并发量"},
 		[]string{"max_download_load", strconv.Itoa(c.MaxDownloadLoad), "1 ~ 5", "同时进行下载文件的最大数量"},
+		[]string{"walk_parallel", strconv.Itoa(c.WalkParallel), "1 ~ 10", "遍历网盘目录的并发量"},
 		[]string{"max_download_rate", showMaxRate(c.MaxDownloadRate), "", "限制最大下载速度, 0代表不限制"},
 		[]string{"max_upload_rate", showMaxRate(c.MaxUploadRate), "", "限制最大上传速度, 0代表不限制"},
+		[]string{"api_max_retry", strconv.Itoa(c.APIMaxRetry), "0 ~ 5", "api 请求失败的最大重试次数, 0代表不重试"},
+		[]string{"api_rate_limit", c.APIRateLimit, "", "api 请求频率限制 (次/秒), 可设置 pcs, pan, read, write, upload, download, 为空则不限制, 推荐 " + baidupcs.NewDefaultRateLimit().String()},
+		[]string{"meta_cache_ttl", c.MetaCacheTTL, "", "本地元信息缓存有效期, 如 10m, 用于加速列目录等操作, 为空或0代表不使用"},
 		[]string{"savedir", c.SaveDir, "", "下载文件的储存目录"},
 		[]string{"enable_https", fmt.Sprint(c.EnableHTTPS), "true", "启用 https"},
 		[]string{"user_agent", c.UserAgent, requester.DefaultUserAgent, "浏览器标识"},
@@ -127,6 +197,10 @@ func (c *PCSConfig) PrintTable() {
 		[]string{"pan_ua", c.PanUA, baidupcs.NetdiskUA, "Pan 浏览器标识"},
 		[]string{"proxy", c.Proxy, "", "设置代理, 支持 http/socks5 代理"},
 		[]string{"local_addrs", c.LocalAddrs, "", "设置本地网卡地址, 多个地址用逗号隔开"},
+		[]string{"pcs_addr", c.PCSAddr, "", "自定义 PCS api 地址, 为空则使用 " + baidupcs.PCSBaiduCom},
+		[]string{"pan_addr", c.PanAddr, "", "自定义网盘首页 api 地址, 为空则使用 " + baidupcs.PanBaiduCom},
+		[]string{"enc_key", showSecret(c.EncryptKey), "", "客户端加密的密钥或密码, 环境变量 " + EnvEncryptKey + " 优先"},
+		[]string{"enc_salt", c.EncryptSalt, "", "由密码派生密钥的随机盐, 设置密码时自动生成, 在其他设备解密时需设置相同的值"},
 	})
 	tb.Render()
 }
@@ -1,7 +1,10 @@
 package pcsconfig
 
 import (
+	"github.com/Erope/BaiduPCS-Go/baidupcs"
+	"github.com/Erope/BaiduPCS-Go/baidupcs/diskcache"
 	"github.com/Erope/BaiduPCS-Go/pcsutil/converter"
+	"github.com/Erope/BaiduPCS-Go/pcsutil/streamcrypto"
 	"github.com/Erope/BaiduPCS-Go/requester"
 	"strings"
 )
@@ -88,7 +91,7 @@ func (c *PCSConfig) setupNewUser(user *Baidu) {
 	}
 	c.BaiduActiveUID = user.UID
 	c.activeUser = user
-	c.pcs = user.BaiduPCS()
+	c.pcs = c.newClient(user)
 }
 
 // SwitchUser 切换用户, 返回切换成功的用户
@@ -136,8 +139,8 @@ func (c *PCSConfig) SetupUserByBDUSS(bduss, ptoken, stoken string) (baidu *Baidu
 // SetAppID 设置app_id
 func (c *PCSConfig) SetAppID(appID int) {
 	c.AppID = appID
-	if c.pcs != nil {
-		c.pcs.SetAPPID(appID)
+	if pcs := c.baiduPCS(); pcs != nil {
+		pcs.SetAPPID(appID)
 	}
 }
 
@@ -180,16 +183,16 @@ func (c *PCSConfig) SetUserAgent(userAgent string) {
 // SetPCSUA 设置 PCS User-Agent
 func (c *PCSConfig) SetPCSUA(pcsUA string) {
 	c.PCSUA = pcsUA
-	if c.pcs != nil {
-		c.pcs.SetPCSUserAgent(pcsUA)
+	if pcs := c.baiduPCS(); pcs != nil {
+		pcs.SetPCSUserAgent(pcsUA)
 	}
 }
 
 // SetPanUA 设置 Pan User-Agent
 func (c *PCSConfig) SetPanUA(panUA string) {
 	c.PanUA = panUA
-	if c.pcs != nil {
-		c.pcs.SetPanUserAgent(panUA)
+	if pcs := c.baiduPCS(); pcs != nil {
+		pcs.SetPanUserAgent(panUA)
 	}
 	if c.dc != nil {
 		c.dc.SetClient(c.PanHTTPClient())
@@ -199,14 +202,76 @@ func (c *PCSConfig) SetPanUA(panUA string) {
 // SetEnableHTTPS 设置是否启用https
 func (c *PCSConfig) SetEnableHTTPS(https bool) {
 	c.EnableHTTPS = https
-	if c.pcs != nil {
-		c.pcs.SetHTTPS(https)
+	if pcs := c.baiduPCS(); pcs != nil {
+		pcs.SetHTTPS(https)
 	}
 	if c.dc != nil {
 		c.dc.SetClient(c.PanHTTPClient())
 	}
 }
 
+// SetPCSAddr 设置自定义 PCS api 地址, 为空则使用默认地址
+func (c *PCSConfig) SetPCSAddr(addr string) error {
+	if pcs := c.baiduPCS(); pcs != nil {
+		err := pcs.SetPCSAddr(addr)
+		if err != nil {
+			return err
+		}
+	}
+	c.PCSAddr = addr
+	return nil
+}
+
+// SetPanAddr 设置自定义网盘首页 api 地址, 为空则使用默认地址
+func (c *PCSConfig) SetPanAddr(addr string) error {
+	if pcs := c.baiduPCS(); pcs != nil {
+		err := pcs.SetPanAddr(addr)
+		if err != nil {
+			return err
+		}
+	}
+	c.PanAddr = addr
+	return nil
+}
+
+// SetAPIMaxRetry 设置 api 请求失败的最大重试次数, 0 代表不重试
+func (c *PCSConfig) SetAPIMaxRetry(maxRetry int) {
+	c.APIMaxRetry = maxRetry
+	if pcs := c.baiduPCS(); pcs != nil {
+		pcs.SetRetryPolicy(c.RetryPolicy())
+	}
+}
+
+// SetAPIRateLimit 设置 api 请求频率限制, 格式如 pcs=10,pan=5,write=2, 为空则不限制
+func (c *PCSConfig) SetAPIRateLimit(s string) error {
+	rl, err := baidupcs.ParseRateLimit(s)
+	if err != nil {
+		return err
+	}
+	if pcs := c.baiduPCS(); pcs != nil {
+		pcs.SetRateLimit(rl)
+	}
+	c.APIRateLimit = rl.String()
+	return nil
+}
+
+// SetMetaCacheTTL 设置本地元信息缓存有效期, 如 10m, 为空或0代表不使用
+func (c *PCSConfig) SetMetaCacheTTL(s string) error {
+	ttl, err := parseMetaCacheTTL(s)
+	if err != nil {
+		return err
+	}
+	if pcs := c.baiduPCS(); pcs != nil {
+		if ttl > 0 {
+			pcs.SetDiskCache(diskcache.New(MetaCacheDir(c.ActiveUser().UID), ttl))
+		} else {
+			pcs.SetDiskCache(nil)
+		}
+	}
+	c.MetaCacheTTL = s
+	return nil
+}
+
 // SetProxy 设置代理
 func (c *PCSConfig) SetProxy(proxy string) {
 	c.Proxy = proxy
@@ -219,6 +284,37 @@ func (c *PCSConfig) SetLocalAddrs(localAddrs string) {
 	requester.SetLocalTCPAddrList(strings.Split(localAddrs, ",")...)
 }
 
+// SetEncryptKey 设置客户端加密的密钥或密码, 设置为密码时生成新的随机密码盐
+func (c *PCSConfig) SetEncryptKey(key string) error {
+	if key != "" && !streamcrypto.IsRawKey(key) {
+		salt, err := streamcrypto.NewSalt()
+		if err != nil {
+			return err
+		}
+		c.EncryptSalt = salt
+	}
+	c.EncryptKey = key
+	return nil
+}
+
+// SetEncryptSalt 设置由密码派生密钥的十六进制密码盐, new 为生成新的随机密码盐, 为空则使用旧版本的固定盐
+func (c *PCSConfig) SetEncryptSalt(salt string) (err error) {
+	switch salt {
+	case "":
+	case "new":
+		salt, err = streamcrypto.NewSalt()
+		if err != nil {
+			return err
+		}
+	default:
+		if _, err = streamcrypto.ParseSalt(salt); err != nil {
+			return err
+		}
+	}
+	c.EncryptSalt = salt
+	return nil
+}
+
 // SetAccessPass 设置下载选项
 func (c *PCSConfig) SetDownloadOpts(opt CDownloadOptions) {
 	c.downloadOpts = opt
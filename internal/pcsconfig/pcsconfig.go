@@ -20,6 +20,8 @@ import (
 const (
 	// EnvConfigDir 配置路径环境变量
 	EnvConfigDir = "BAIDUPCS_GO_CONFIG_DIR"
+	// EnvEncryptKey 客户端加密的密钥或密码环境变量, 优先于配置 enc_key
+	EnvEncryptKey = "BAIDUPCS_GO_ENC_KEY"
 	// ConfigName 配置文件名
 	ConfigName = "pcs_config.json"
 )
@@ -31,6 +33,9 @@ type SessionData struct {
 
 type SessionMapType map[string]*SessionData
 
+// ClientFactory 为百度帐号创建网盘客户端
+type ClientFactory func(baidu *Baidu) baidupcs.Client
+
 var (
 	pcsConfigVerbose = pcsverbose.New("PCSCONFIG")
 	configFilePath   = filepath.Join(GetConfigDir(), ConfigName)
@@ -62,10 +67,15 @@ type PCSConfig struct {
 	MaxParallel       int `json:"max_parallel"`        // 最大下载并发量
 	MaxUploadParallel int `json:"max_upload_parallel"` // 最大上传并发量
 	MaxDownloadLoad   int `json:"max_download_load"`   // 同时进行下载文件的最大数量
+	WalkParallel      int `json:"walk_parallel"`       // 遍历网盘目录的并发量
 
 	MaxDownloadRate int64 `json:"max_download_rate"` // 限制最大下载速度
 	MaxUploadRate   int64 `json:"max_upload_rate"`   // 限制最大上传速度
 
+	APIMaxRetry  int    `json:"api_max_retry"`  // api 请求失败的最大重试次数
+	APIRateLimit string `json:"api_rate_limit"` // api 请求频率限制
+	MetaCacheTTL string `json:"meta_cache_ttl"` // 本地元信息缓存有效期
+
 	UserAgent   string `json:"user_agent"`   // 浏览器标识
 	PCSUA       string `json:"pcs_ua"`       // PCS浏览器标识
 	PanUA       string `json:"pan_ua"`       // PAN浏览器标识
@@ -73,6 +83,10 @@ type PCSConfig struct {
 	EnableHTTPS bool   `json:"enable_https"` // 启用https
 	Proxy       string `json:"proxy"`        // 代理
 	LocalAddrs  string `json:"local_addrs"`  // 本地网卡地址
+	PCSAddr     string `json:"pcs_addr"`     // 自定义 PCS api 地址
+	PanAddr     string `json:"pan_addr"`     // 自定义网盘首页 api 地址
+	EncryptKey  string `json:"enc_key"`      // 客户端加密的密钥或密码
+	EncryptSalt string `json:"enc_salt"`     // 由密码派生密钥的随机盐, 十六进制
 
 	downloadOpts   CDownloadOptions
 	sessions       SessionMapType
@@ -81,8 +95,13 @@ type PCSConfig struct {
 	configFile     *os.File
 	fileMu         sync.Mutex
 	activeUser     *Baidu
-	pcs            *baidupcs.BaiduPCS
+	pcs            baidupcs.Client
+	clientFactory  ClientFactory
 	dc             *dlinkclient.DlinkClient
+
+	encKeyMu     sync.Mutex
+	encKeySource string // 已派生的主密钥对应的密钥或密码和密码盐
+	encKey       []byte
 }
 
 // NewConfig 返回 PCSConfig 指针对象
@@ -176,7 +195,7 @@ func (c *PCSConfig) init() error {
 	if err != nil {
 		return err
 	}
-	c.pcs = c.activeUser.BaiduPCS()
+	c.pcs = c.newClient(c.activeUser)
 
 	// 设置全局User-Agent
 	requester.UserAgent = c.UserAgent
@@ -250,6 +269,9 @@ func (c *PCSConfig) initDefaultConfig() {
 	c.MaxParallel = 8
 	c.MaxUploadParallel = 8
 	c.MaxDownloadLoad = 1
+	c.WalkParallel = baidupcs.DefaultWalkParallel
+	c.APIMaxRetry = baidupcs.DefaultMaxAttempts - 1
+	c.APIRateLimit = ""
 	c.UserAgent = requester.UserAgent
 	c.PCSUA = ""
 	c.PanUA = baidupcs.NetdiskUA
@@ -332,4 +354,10 @@ func (c *PCSConfig) fix() {
 	if c.MaxDownloadLoad < 1 {
 		c.MaxDownloadLoad = 1
 	}
+	if c.WalkParallel < 1 {
+		c.WalkParallel = 1
+	}
+	if c.APIMaxRetry < 0 {
+		c.APIMaxRetry = 0
+	}
 }
@@ -2,7 +2,10 @@ package pcsconfig
 
 import (
 	"github.com/Erope/BaiduPCS-Go/pcsutil/converter"
+	"path/filepath"
+	"strconv"
 	"strings"
+	"time"
 )
 
 // AverageParallel 返回平均的下载最大并发量
@@ -32,3 +35,23 @@ func showMaxRate(size int64) string {
 	}
 	return converter.ConvertFileSize(size, 2) + "/s"
 }
+
+// showSecret 不显示密钥的内容, 只显示是否已设置
+func showSecret(secret string) string {
+	if secret == "" {
+		return ""
+	}
+	return "******"
+}
+
+// MetaCacheDir 返回百度帐号 uid 的本地元信息缓存目录
+func MetaCacheDir(uid uint64) string {
+	return filepath.Join(GetConfigDir(), "cache", strconv.FormatUint(uid, 10))
+}
+
+func parseMetaCacheTTL(s string) (time.Duration, error) {
+	if s == "" || s == "0" {
+		return 0, nil
+	}
+	return time.ParseDuration(s)
+}
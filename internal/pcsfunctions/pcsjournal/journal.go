@@ -0,0 +1,228 @@
+// Package pcsjournal 传输日志, 记录未完成的下载和上传任务, 进程退出后可恢复
+package pcsjournal
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/Erope/BaiduPCS-Go/internal/pcsconfig"
+	"github.com/Erope/BaiduPCS-Go/pcsutil"
+	"github.com/Erope/BaiduPCS-Go/pcsutil/filelock"
+	"github.com/Erope/BaiduPCS-Go/pcsutil/jsonhelper"
+	"github.com/Erope/BaiduPCS-Go/pcsverbose"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	// JournalFileName 传输日志的文件名
+	JournalFileName = "pcs_transfer_journal.json"
+)
+
+var (
+	// ErrJobNotFound 传输任务不存在
+	ErrJobNotFound = errors.New("传输任务不存在")
+
+	pcsJournalVerbose = pcsverbose.New("PCSJOURNAL")
+)
+
+type (
+	// Job 一次下载或上传的任务
+	Job struct {
+		ID         int64           `json:"id"`
+		Direction  string          `json:"direction"`           // download 或 upload
+		UID        uint64          `json:"uid"`                 // 所属帐号
+		Paths      []string        `json:"paths"`               // 源路径
+		SavePath   string          `json:"save_path,omitempty"` // 上传的网盘目录
+		Options    json.RawMessage `json:"options,omitempty"`   // 下载或上传的选项
+		Done       []string        `json:"done,omitempty"`      // 已完成的文件
+		CreateTime int64           `json:"create_time"`
+		UpdateTime int64           `json:"update_time"`
+	}
+
+	// Journal 传输日志, 每次修改都会重新读取并写回文件, 可并发调用, 多个进程通过文件锁互斥
+	Journal struct {
+		path string
+		mu   sync.Mutex
+	}
+
+	journalData struct {
+		LastID int64  `json:"last_id"`
+		Jobs   []*Job `json:"jobs"`
+	}
+)
+
+// NewJournal 打开配置目录下的传输日志
+func NewJournal() *Journal {
+	return NewJournalWithPath(filepath.Join(pcsconfig.GetConfigDir(), JournalFileName))
+}
+
+// NewJournalWithPath 打开路径为 path 的传输日志
+func NewJournalWithPath(path string) *Journal {
+	return &Journal{
+		path: path,
+	}
+}
+
+// lock 加锁, 同一进程内用 mu 互斥, 多个进程用文件锁互斥, 返回的函数用于解锁
+func (j *Journal) lock() (unlock func(), err error) {
+	j.mu.Lock()
+	unlockFile, err := filelock.Lock(j.path + ".lock")
+	if err != nil {
+		j.mu.Unlock()
+		return nil, err
+	}
+	return func() {
+		err := unlockFile()
+		if err != nil {
+			pcsJournalVerbose.Warnf("解锁传输日志错误: %s\n", err)
+		}
+		j.mu.Unlock()
+	}, nil
+}
+
+// load 读取传输日志, 文件损坏时备份为 .broken 文件并返回错误, 下次读取时使用空的传输日志
+func (j *Journal) load() (*journalData, error) {
+	data := &journalData{}
+	f, err := os.Open(j.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return data, nil
+		}
+		return nil, err
+	}
+
+	err = jsonhelper.UnmarshalData(f, data)
+	f.Close()
+	if err != nil {
+		backup := fmt.Sprintf("%s.%d.broken", j.path, time.Now().Unix())
+		renameErr := os.Rename(j.path, backup)
+		if renameErr != nil {
+			return nil, fmt.Errorf("解析传输日志错误, %s, 备份失败, %s", err, renameErr)
+		}
+		return nil, fmt.Errorf("解析传输日志错误, %s, 已备份到 %s", err, backup)
+	}
+	return data, nil
+}
+
+// save 先写入临时文件再重命名, 防止写入中途退出导致文件损坏
+func (j *Journal) save(data *journalData) error {
+	buf := &bytes.Buffer{}
+	err := jsonhelper.MarshalData(buf, data)
+	if err != nil {
+		return err
+	}
+	return pcsutil.WriteFileAtomic(j.path, buf.Bytes(), 0600)
+}
+
+// update 读取传输日志, 执行 f, 再写回文件
+func (j *Journal) update(f func(data *journalData) error) error {
+	unlock, err := j.lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	data, err := j.load()
+	if err != nil {
+		return err
+	}
+	err = f(data)
+	if err != nil {
+		return err
+	}
+	return j.save(data)
+}
+
+func (data *journalData) find(id int64) *Job {
+	for _, job := range data.Jobs {
+		if job.ID == id {
+			return job
+		}
+	}
+	return nil
+}
+
+// Add 添加传输任务, 并设置任务的 ID 和时间
+func (j *Journal) Add(job *Job) error {
+	return j.update(func(data *journalData) error {
+		data.LastID++
+		job.ID = data.LastID
+		job.CreateTime = time.Now().Unix()
+		job.UpdateTime = job.CreateTime
+		data.Jobs = append(data.Jobs, job)
+		return nil
+	})
+}
+
+// MarkDone 标记任务中的文件 path 已完成
+func (j *Journal) MarkDone(id int64, path string) error {
+	return j.update(func(data *journalData) error {
+		job := data.find(id)
+		if job == nil {
+			return ErrJobNotFound
+		}
+		for _, p := range job.Done {
+			if p == path {
+				return nil
+			}
+		}
+		job.Done = append(job.Done, path)
+		job.UpdateTime = time.Now().Unix()
+		return nil
+	})
+}
+
+// Remove 移除传输任务
+func (j *Journal) Remove(id int64) error {
+	return j.update(func(data *journalData) error {
+		for k, job := range data.Jobs {
+			if job.ID == id {
+				data.Jobs = append(data.Jobs[:k], data.Jobs[k+1:]...)
+				return nil
+			}
+		}
+		return ErrJobNotFound
+	})
+}
+
+// Get 获取传输任务
+func (j *Journal) Get(id int64) (*Job, error) {
+	unlock, err := j.lock()
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	data, err := j.load()
+	if err != nil {
+		return nil, err
+	}
+	job := data.find(id)
+	if job == nil {
+		return nil, ErrJobNotFound
+	}
+	return job, nil
+}
+
+// List 列出所有传输任务, 按 ID 排序
+func (j *Journal) List() ([]*Job, error) {
+	unlock, err := j.lock()
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	data, err := j.load()
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(data.Jobs, func(i, k int) bool {
+		return data.Jobs[i].ID < data.Jobs[k].ID
+	})
+	return data.Jobs, nil
+}
@@ -0,0 +1,126 @@
+package pcsjournal
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+func TestJournal(t *testing.T) {
+	dir, err := ioutil.TempDir("", "pcsjournal")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	j := NewJournalWithPath(filepath.Join(dir, JournalFileName))
+	jobs, err := j.List()
+	if err != nil || len(jobs) != 0 {
+		t.Fatalf("expect empty journal, got %v, %v", jobs, err)
+	}
+
+	job1, job2 := &Job{Direction: "download", Paths: []string{"/a"}}, &Job{Direction: "upload", Paths: []string{"/b"}, SavePath: "/c"}
+	if err = j.Add(job1); err != nil {
+		t.Fatal(err)
+	}
+	if err = j.Add(job2); err != nil {
+		t.Fatal(err)
+	}
+	if job1.ID != 1 || job2.ID != 2 {
+		t.Fatalf("unexpected id: %d, %d", job1.ID, job2.ID)
+	}
+
+	j.MarkDone(1, "/a/1")
+	j.MarkDone(1, "/a/1")
+	j.MarkDone(1, "/a/2")
+	if err = j.MarkDone(3, "/a/3"); err != ErrJobNotFound {
+		t.Fatalf("expect ErrJobNotFound, got %v", err)
+	}
+
+	// 重新打开
+	j = NewJournalWithPath(filepath.Join(dir, JournalFileName))
+	job, err := j.Get(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(job.Done) != 2 || job.Done[0] != "/a/1" || job.Done[1] != "/a/2" {
+		t.Fatalf("unexpected done: %v", job.Done)
+	}
+
+	if err = j.Remove(1); err != nil {
+		t.Fatal(err)
+	}
+	jobs, err = j.List()
+	if err != nil || len(jobs) != 1 || jobs[0].SavePath != "/c" {
+		t.Fatalf("unexpected jobs: %v, %v", jobs, err)
+	}
+
+	// 移除后 id 不重复使用
+	job3 := &Job{}
+	j.Add(job3)
+	if job3.ID != 3 {
+		t.Fatalf("unexpected id: %d", job3.ID)
+	}
+}
+
+func TestJournalConcurrent(t *testing.T) {
+	dir, err := ioutil.TempDir("", "pcsjournal")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// 两个 Journal 模拟两个进程, 通过文件锁互斥, 不丢失修改
+	var (
+		path = filepath.Join(dir, JournalFileName)
+		wg   sync.WaitGroup
+	)
+	for _, j := range []*Journal{NewJournalWithPath(path), NewJournalWithPath(path)} {
+		wg.Add(1)
+		go func(j *Journal) {
+			defer wg.Done()
+			for i := 0; i < 20; i++ {
+				if err := j.Add(&Job{}); err != nil {
+					t.Error(err)
+				}
+			}
+		}(j)
+	}
+	wg.Wait()
+
+	jobs, err := NewJournalWithPath(path).List()
+	if err != nil || len(jobs) != 40 {
+		t.Fatalf("expect 40 jobs, got %d, %v", len(jobs), err)
+	}
+}
+
+func TestJournalBroken(t *testing.T) {
+	dir, err := ioutil.TempDir("", "pcsjournal")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, JournalFileName)
+	ioutil.WriteFile(path, []byte(`{"last_id":1,"jobs":[`), 0600)
+
+	// 文件损坏时返回错误, 并保留原文件的备份
+	j := NewJournalWithPath(path)
+	if _, err = j.List(); err == nil {
+		t.Fatal("expect parse error")
+	}
+	backups, _ := filepath.Glob(path + ".*.broken")
+	if len(backups) != 1 {
+		t.Fatalf("expect backup, got %v", backups)
+	}
+	if data, _ := ioutil.ReadFile(backups[0]); string(data) != `{"last_id":1,"jobs":[` {
+		t.Fatalf("unexpected backup: %s", data)
+	}
+
+	jobs, err := j.List()
+	if err != nil || len(jobs) != 0 {
+		t.Fatalf("expect empty journal, got %v, %v", jobs, err)
+	}
+}
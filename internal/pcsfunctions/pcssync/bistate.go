@@ -0,0 +1,269 @@
+package pcssync
+
+import (
+	"bytes"
+	"github.com/Erope/BaiduPCS-Go/baidupcs"
+	"github.com/Erope/BaiduPCS-Go/internal/pcsconfig"
+	"github.com/Erope/BaiduPCS-Go/pcsutil"
+	"github.com/Erope/BaiduPCS-Go/pcsutil/checksum"
+	"github.com/Erope/BaiduPCS-Go/pcsutil/filelock"
+	"github.com/Erope/BaiduPCS-Go/pcsutil/jsonhelper"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+const (
+	// BiStateFileName 双向同步状态的文件名
+	BiStateFileName = "pcs_bisync_state.json"
+)
+
+type (
+	// BiRemoteMeta 网盘文件上次同步后的元信息
+	BiRemoteMeta struct {
+		FsID  int64  `json:"fs_id"`
+		MD5   string `json:"md5"`
+		Size  int64  `json:"size"`
+		Mtime int64  `json:"mtime"`
+	}
+
+	// BiEntry 文件上次同步后的状态, 此时本地和网盘的文件相同
+	BiEntry struct {
+		Local  *checksum.LocalFileMeta `json:"local"`
+		Remote *BiRemoteMeta           `json:"remote"`
+	}
+
+	// BiState 一对本地目录和网盘目录的同步状态, 键为相对路径
+	BiState struct {
+		UID       uint64              `json:"uid"`
+		LocalDir  string              `json:"local_dir"`
+		RemoteDir string              `json:"remote_dir"`
+		SyncTime  int64               `json:"sync_time"`
+		Entries   map[string]*BiEntry `json:"entries"`
+	}
+
+	// BiFiles 同一时刻获取的本地文件和网盘文件
+	BiFiles struct {
+		Local  *Local
+		Remote map[string]*baidupcs.FileDirectory
+	}
+
+	// BiStateDB 双向同步状态的数据库, 每次读取和保存都会访问文件, 可并发调用, 多个进程通过文件锁互斥
+	BiStateDB struct {
+		path string
+		mu   sync.Mutex
+	}
+
+	biStateData struct {
+		States []*BiState `json:"states"`
+	}
+)
+
+// NewBiEntry 由本地文件和网盘文件生成同步状态
+func NewBiEntry(meta *checksum.LocalFileMeta, fd *baidupcs.FileDirectory) *BiEntry {
+	return &BiEntry{
+		Local: &checksum.LocalFileMeta{
+			Path:    meta.Path,
+			Length:  meta.Length,
+			ModTime: meta.ModTime,
+			MD5:     meta.MD5,
+		},
+		Remote: &BiRemoteMeta{
+			FsID:  fd.FsID,
+			MD5:   fd.MD5,
+			Size:  fd.Size,
+			Mtime: fd.Mtime,
+		},
+	}
+}
+
+// Update 同步结束后更新同步状态. before 为生成同步计划 plan 时的两边的文件, after 为执行同步计划后重新获取的.
+// 两边都存在, 且与同步计划预期一致的文件记录为已同步. 同步失败, 或同步期间又被修改的文件保留原来的状态,
+// 下次同步时重新比较. cachedMD5 获取本地文件已知的 md5, 可为 nil
+func (state *BiState) Update(plan *BiPlan, before, after *BiFiles, filter *Filter, failed map[string]bool, cachedMD5 func(meta *checksum.LocalFileMeta) []byte) {
+	var (
+		prevFiles = filterRemote(before.Remote, filter)
+		files     = filterRemote(after.Remote, filter)
+		actions   = make(map[string]*Action, len(plan.Actions))
+		entries   = make(map[string]*BiEntry, len(after.Local.Files))
+	)
+	for _, action := range plan.Actions {
+		actions[action.RelPath] = action
+		if action.From != "" {
+			actions[action.From] = action
+		}
+	}
+
+	for rel, meta := range after.Local.Files {
+		fd := files[rel]
+		if fd == nil || failed[rel] || !biExpected(actions[rel], rel, meta, fd, before.Local, prevFiles) {
+			continue
+		}
+		entry := NewBiEntry(meta, fd)
+		if cachedMD5 != nil {
+			entry.Local.MD5 = cachedMD5(meta)
+		}
+		entries[rel] = entry
+	}
+
+	// 保留失败的, 和未按计划变化的文件原来的状态, 如同步期间一边被删除或修改的文件
+	for rel, entry := range state.Entries {
+		if entries[rel] != nil {
+			continue
+		}
+		if after.Local.Files[rel] == nil && files[rel] == nil {
+			continue // 两边都已不存在
+		}
+		if failed[rel] || actions[rel] == nil || actions[rel].RelPath == rel {
+			entries[rel] = entry
+		}
+	}
+	state.Entries = entries
+}
+
+// biExpected 判断执行同步计划后的本地文件 meta 和网盘文件 fd 是否与计划预期的一致.
+// 未被同步计划修改的一边应与生成计划时相同, 被传输覆盖的一边应与另一边大小相同
+func biExpected(action *Action, rel string, meta *checksum.LocalFileMeta, fd *baidupcs.FileDirectory, prevLocal *Local, prevFiles map[string]*baidupcs.FileDirectory) bool {
+	var (
+		localFrom, remoteFrom   = rel, rel
+		checkLocal, checkRemote = true, true
+	)
+	if action != nil {
+		switch action.Type {
+		case ActionUpload:
+			checkRemote = false
+		case ActionDownload, ActionConflict: // 冲突时网盘文件下载到原路径
+			checkLocal = false
+		case ActionMove:
+			remoteFrom = action.From
+		case ActionMoveLocal:
+			localFrom = action.From
+		case ActionSkip:
+			return false
+		}
+	}
+
+	if checkLocal {
+		prev := prevLocal.Files[localFrom]
+		if prev == nil || prev.Length != meta.Length || prev.ModTime != meta.ModTime {
+			return false
+		}
+	}
+	if checkRemote {
+		prev := prevFiles[remoteFrom]
+		if prev == nil || prev.FsID != fd.FsID || prev.MD5 != fd.MD5 {
+			return false
+		}
+	}
+	return checkLocal && checkRemote || meta.Length == fd.Size
+}
+
+// NewBiStateDB 打开配置目录下的双向同步状态
+func NewBiStateDB() *BiStateDB {
+	return NewBiStateDBWithPath(filepath.Join(pcsconfig.GetConfigDir(), BiStateFileName))
+}
+
+// NewBiStateDBWithPath 打开路径为 path 的双向同步状态
+func NewBiStateDBWithPath(path string) *BiStateDB {
+	return &BiStateDB{
+		path: path,
+	}
+}
+
+// lock 加锁, 同一进程内用 mu 互斥, 多个进程用文件锁互斥, 返回的函数用于解锁
+func (db *BiStateDB) lock() (unlock func(), err error) {
+	db.mu.Lock()
+	unlockFile, err := filelock.Lock(db.path + ".lock")
+	if err != nil {
+		db.mu.Unlock()
+		return nil, err
+	}
+	return func() {
+		unlockFile()
+		db.mu.Unlock()
+	}, nil
+}
+
+func (db *BiStateDB) load() (*biStateData, error) {
+	data := &biStateData{}
+	f, err := os.Open(db.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return data, nil
+		}
+		return nil, err
+	}
+	defer f.Close()
+
+	err = jsonhelper.UnmarshalData(f, data)
+	if err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+func (data *biStateData) find(uid uint64, localDir, remoteDir string) int {
+	for k, state := range data.States {
+		if state.UID == uid && state.LocalDir == localDir && state.RemoteDir == remoteDir {
+			return k
+		}
+	}
+	return -1
+}
+
+// Get 获取同步状态, 从未同步过时返回空的状态.
+// 状态文件损坏时返回错误, 避免把所有文件都当作新文件
+func (db *BiStateDB) Get(uid uint64, localDir, remoteDir string) (*BiState, error) {
+	unlock, err := db.lock()
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	data, err := db.load()
+	if err != nil {
+		return nil, err
+	}
+	if k := data.find(uid, localDir, remoteDir); k >= 0 {
+		state := data.States[k]
+		if state.Entries == nil {
+			state.Entries = map[string]*BiEntry{}
+		}
+		return state, nil
+	}
+	return &BiState{
+		UID:       uid,
+		LocalDir:  localDir,
+		RemoteDir: remoteDir,
+		Entries:   map[string]*BiEntry{},
+	}, nil
+}
+
+// Put 保存同步状态, 并设置同步时间, 先写入临时文件再重命名
+func (db *BiStateDB) Put(state *BiState) error {
+	unlock, err := db.lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	data, err := db.load()
+	if err != nil {
+		return err
+	}
+	state.SyncTime = time.Now().Unix()
+	if k := data.find(state.UID, state.LocalDir, state.RemoteDir); k >= 0 {
+		data.States[k] = state
+	} else {
+		data.States = append(data.States, state)
+	}
+
+	buf := &bytes.Buffer{}
+	err = jsonhelper.MarshalData(buf, data)
+	if err != nil {
+		return err
+	}
+
+	return pcsutil.WriteFileAtomic(db.path, buf.Bytes(), 0600)
+}
@@ -0,0 +1,364 @@
+package pcssync
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/Erope/BaiduPCS-Go/baidupcs"
+	"github.com/Erope/BaiduPCS-Go/pcsutil/checksum"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	// ActionDownload 下载网盘文件
+	ActionDownload ActionType = "download"
+	// ActionDeleteLocal 删除本地文件
+	ActionDeleteLocal ActionType = "delete_local"
+	// ActionMove 移动网盘文件, 即本地文件的重命名
+	ActionMove ActionType = "move"
+	// ActionMoveLocal 移动本地文件, 即网盘文件的重命名
+	ActionMoveLocal ActionType = "move_local"
+	// ActionSkip 本地和网盘的类型不同 (文件和目录), 跳过
+	ActionSkip ActionType = "skip"
+
+	// BiTrashDirName 本地目录下的回收目录, 删除的本地文件移动到这里, 不参与同步
+	BiTrashDirName = ".pcs_bisync_trash"
+	// DefaultMaxDeleteRatio 默认最多删除上次同步的文件数量的比例
+	DefaultMaxDeleteRatio = 0.5
+
+	// biDeleteGuardMin 删除的文件不超过这个数量时, 不检查删除的比例
+	biDeleteGuardMin = 10
+)
+
+var (
+	// ErrBiUnsafe 同步计划可能误删文件
+	ErrBiUnsafe = errors.New("同步计划可能误删文件, 已停止同步")
+)
+
+const (
+	changeNone changeType = iota
+	changeCreated
+	changeModified
+	changeDeleted
+)
+
+type (
+	changeType int
+
+	// BiPlan 双向同步计划
+	BiPlan struct {
+		Actions   []*Action
+		Unchanged int // 未变化的文件数量
+		Excluded  int // 本地被排除的文件数量
+	}
+)
+
+// filterRemote 只保留 filter 匹配的网盘文件, 不包括目录和被排除的目录中的文件
+func filterRemote(remote map[string]*baidupcs.FileDirectory, filter *Filter) map[string]*baidupcs.FileDirectory {
+	rels := make([]string, 0, len(remote))
+	for rel := range remote {
+		rels = append(rels, rel)
+	}
+	sort.Strings(rels)
+
+	var (
+		files        = make(map[string]*baidupcs.FileDirectory, len(remote))
+		skipPrefixes []string
+	)
+	for _, rel := range rels {
+		fd := remote[rel]
+		if hasAnyPrefix(rel, skipPrefixes) {
+			continue
+		}
+		if !filter.Match(rel, fd.Isdir) {
+			if fd.Isdir {
+				skipPrefixes = append(skipPrefixes, rel+"/")
+			}
+			continue
+		}
+		if !fd.Isdir {
+			files[rel] = fd
+		}
+	}
+	return files
+}
+
+// isMD5Reliable 网盘记录的 md5 是否可信, 分片上传的文件可能不正确
+func isMD5Reliable(fd *baidupcs.FileDirectory) bool {
+	return fd.MD5 != "" && len(fd.BlockList) <= 1
+}
+
+// sameContent 判断本地文件和网盘文件的内容是否相同, 无法判断时返回 false
+func sameContent(meta *checksum.LocalFileMeta, fd *baidupcs.FileDirectory, sumMD5 SumMD5Func) bool {
+	if meta.Length != fd.Size || !isMD5Reliable(fd) {
+		return false
+	}
+	sum, err := sumMD5(meta)
+	if err != nil {
+		return false
+	}
+	remoteMD5, _ := hex.DecodeString(fd.MD5)
+	return bytes.Equal(sum, remoteMD5)
+}
+
+func localChange(meta *checksum.LocalFileMeta, entry *BiEntry, sumMD5 SumMD5Func) changeType {
+	switch {
+	case entry == nil:
+		if meta != nil {
+			return changeCreated
+		}
+		return changeNone
+	case meta == nil:
+		return changeDeleted
+	case meta.Length != entry.Local.Length:
+		return changeModified
+	case meta.ModTime == entry.Local.ModTime:
+		return changeNone
+	case len(entry.Local.MD5) > 0:
+		// 只修改了修改时间
+		sum, err := sumMD5(meta)
+		if err == nil && bytes.Equal(sum, entry.Local.MD5) {
+			return changeNone
+		}
+	}
+	return changeModified
+}
+
+func remoteChange(fd *baidupcs.FileDirectory, entry *BiEntry) changeType {
+	switch {
+	case entry == nil:
+		if fd != nil {
+			return changeCreated
+		}
+		return changeNone
+	case fd == nil:
+		return changeDeleted
+	case fd.FsID != entry.Remote.FsID || fd.Size != entry.Remote.Size || fd.MD5 != entry.Remote.MD5:
+		return changeModified
+	}
+	return changeNone
+}
+
+// BiCompare 比较本地文件, 网盘文件和上次同步后的状态, 生成双向同步计划.
+// 只有一边变化时, 将变化同步到另一边, 包括新建, 修改, 删除和重命名.
+// 重命名的判断: 本地文件的大小和修改时间不变, 网盘文件的 fs_id 不变或 md5 和大小不变.
+// 两边都变化且内容不同时为冲突, 保留两边的文件.
+// 只有一边删除而另一边修改时, 保留修改的文件
+func BiCompare(local *Local, remote map[string]*baidupcs.FileDirectory, remoteDir string, state *BiState, filter *Filter, sumMD5 SumMD5Func) *BiPlan {
+	var (
+		plan  = &BiPlan{Excluded: local.Excluded}
+		files = filterRemote(remote, filter)
+		rels  = make([]string, 0, len(local.Files)+len(files))
+		seen  = make(map[string]bool, cap(rels))
+	)
+	for rel := range local.Files {
+		rels, seen[rel] = append(rels, rel), true
+	}
+	for rel := range files {
+		if !seen[rel] {
+			rels, seen[rel] = append(rels, rel), true
+		}
+	}
+	for rel := range state.Entries {
+		if !seen[rel] && filter.Match(rel, false) {
+			rels, seen[rel] = append(rels, rel), true
+		}
+	}
+	sort.Strings(rels)
+
+	newAction := func(actionType ActionType, reason, rel string, size int64) *Action {
+		action := &Action{
+			Type:       actionType,
+			Reason:     reason,
+			RelPath:    rel,
+			LocalPath:  filepath.Join(local.Dir, filepath.FromSlash(rel)),
+			RemotePath: path.Join(remoteDir, rel),
+			Size:       size,
+		}
+		if meta := local.Files[rel]; meta != nil {
+			action.LocalPath = meta.Path
+		}
+		if fd := files[rel]; fd != nil {
+			action.RemotePath, action.FsID = fd.Path, fd.FsID
+		}
+		return action
+	}
+
+	var (
+		lcs = make(map[string]changeType, len(rels))
+		rcs = make(map[string]changeType, len(rels))
+	)
+	for _, rel := range rels {
+		lcs[rel] = localChange(local.Files[rel], state.Entries[rel], sumMD5)
+		rcs[rel] = remoteChange(files[rel], state.Entries[rel])
+	}
+
+	// 检测重命名, 一边删除了旧文件并新建了相同的文件, 另一边没有变化
+	handled := map[string]bool{}
+	for _, to := range rels {
+		var (
+			meta = local.Files[to]
+			fd   = files[to]
+		)
+		switch {
+		case lcs[to] == changeCreated && fd == nil:
+			for _, from := range rels {
+				entry := state.Entries[from]
+				if handled[from] || lcs[from] != changeDeleted || rcs[from] != changeNone || entry == nil {
+					continue
+				}
+				if entry.Local.Length == meta.Length && entry.Local.ModTime == meta.ModTime {
+					action := newAction(ActionMove, "本地文件重命名", to, meta.Length)
+					action.From = from
+					plan.Actions = append(plan.Actions, action)
+					handled[from], handled[to] = true, true
+					break
+				}
+			}
+		case rcs[to] == changeCreated && meta == nil:
+			for _, from := range rels {
+				entry := state.Entries[from]
+				if handled[from] || rcs[from] != changeDeleted || lcs[from] != changeNone || entry == nil {
+					continue
+				}
+				if entry.Remote.FsID == fd.FsID || (isMD5Reliable(fd) && entry.Remote.MD5 == fd.MD5 && entry.Remote.Size == fd.Size) {
+					action := newAction(ActionMoveLocal, "网盘文件重命名", to, fd.Size)
+					action.From = from
+					plan.Actions = append(plan.Actions, action)
+					handled[from], handled[to] = true, true
+					break
+				}
+			}
+		}
+	}
+
+	for _, rel := range rels {
+		if handled[rel] {
+			continue
+		}
+
+		var (
+			meta   = local.Files[rel]
+			fd     = files[rel]
+			lc, rc = lcs[rel], rcs[rel]
+		)
+		if fd == nil && remote[rel] != nil && meta != nil {
+			// 网盘中存在同名的目录, 或者网盘文件被排除
+			if remote[rel].Isdir {
+				plan.Actions = append(plan.Actions, newAction(ActionSkip, "网盘中存在同名的目录", rel, meta.Length))
+			}
+			continue
+		}
+		if meta == nil && fd != nil && local.Dirs[rel] {
+			plan.Actions = append(plan.Actions, newAction(ActionSkip, "本地存在同名的目录", rel, fd.Size))
+			continue
+		}
+
+		switch {
+		case lc == changeNone && rc == changeNone:
+			if meta != nil && fd != nil {
+				plan.Unchanged++
+			}
+		case rc == changeNone, rc == changeDeleted && lc == changeModified:
+			// 只有本地变化, 或网盘删除而本地修改
+			switch {
+			case meta != nil:
+				plan.Actions = append(plan.Actions, newAction(ActionUpload, localReason(lc, rc), rel, meta.Length))
+			case fd != nil:
+				plan.Actions = append(plan.Actions, newAction(ActionDelete, "本地已删除", rel, fd.Size))
+			}
+		case lc == changeNone, lc == changeDeleted && rc == changeModified:
+			// 只有网盘变化, 或本地删除而网盘修改
+			switch {
+			case fd != nil:
+				plan.Actions = append(plan.Actions, newAction(ActionDownload, remoteReason(lc, rc), rel, fd.Size))
+			case meta != nil:
+				plan.Actions = append(plan.Actions, newAction(ActionDeleteLocal, "网盘已删除", rel, meta.Length))
+			}
+		case lc == changeDeleted && rc == changeDeleted:
+			// 两边都已删除
+		default:
+			// 两边都新建或修改
+			if sameContent(meta, fd, sumMD5) {
+				plan.Unchanged++
+				continue
+			}
+			plan.Actions = append(plan.Actions, newAction(ActionConflict, "两边都有修改", rel, meta.Length))
+		}
+	}
+	return plan
+}
+
+func localReason(lc, rc changeType) string {
+	switch {
+	case rc == changeDeleted:
+		return "本地已修改, 网盘已删除"
+	case lc == changeCreated:
+		return "本地新文件"
+	}
+	return "本地已修改"
+}
+
+func remoteReason(lc, rc changeType) string {
+	switch {
+	case lc == changeDeleted:
+		return "网盘已修改, 本地已删除"
+	case rc == changeCreated:
+		return "网盘新文件"
+	}
+	return "网盘已修改"
+}
+
+// ConflictPath 冲突时保留本地文件的相对路径, 在文件名的扩展名之前加上冲突的时间
+func ConflictPath(rel string, t time.Time) string {
+	var (
+		dir, name = path.Split(rel)
+		ext       = path.Ext(name)
+	)
+	// 隐藏文件如 .bashrc 没有扩展名
+	if ext == name {
+		ext = ""
+	}
+	return dir + strings.TrimSuffix(name, ext) + ".conflict-" + t.Format("20060102-150405") + ext
+}
+
+// TrashPath 删除本地文件时, 文件移动到的位置: 本地目录下的回收目录中, 按删除的时间分开保存
+func TrashPath(localDir, rel string, t time.Time) string {
+	return filepath.Join(localDir, BiTrashDirName, t.Format("20060102-150405"), filepath.FromSlash(rel))
+}
+
+// CheckBiPlan 执行同步计划前检查是否安全, 防止目录未挂载, 路径错误等导致删除另一边的大量文件.
+// 上次同步过的一边现在没有文件, 或删除的文件超过上次同步的文件数量的 maxDeleteRatio 时返回错误
+func CheckBiPlan(plan *BiPlan, local *Local, remote map[string]*baidupcs.FileDirectory, state *BiState, filter *Filter, maxDeleteRatio float64) error {
+	synced := len(state.Entries)
+	if synced == 0 {
+		return nil
+	}
+	if len(local.Files) == 0 {
+		return fmt.Errorf("%w, 本地目录 %s 没有文件, 上次同步时有 %d 个文件", ErrBiUnsafe, state.LocalDir, synced)
+	}
+	if len(filterRemote(remote, filter)) == 0 {
+		return fmt.Errorf("%w, 网盘目录 %s 不存在或没有文件, 上次同步时有 %d 个文件", ErrBiUnsafe, state.RemoteDir, synced)
+	}
+
+	deletes := plan.Count(ActionDelete) + plan.Count(ActionDeleteLocal)
+	if deletes > biDeleteGuardMin && float64(deletes) > float64(synced)*maxDeleteRatio {
+		return fmt.Errorf("%w, 将删除 %d 个文件, 超过上次同步的 %d 个文件的 %.0f%%", ErrBiUnsafe, deletes, synced, maxDeleteRatio*100)
+	}
+	return nil
+}
+
+// Count 统计各类操作的数量
+func (plan *BiPlan) Count(actionType ActionType) (n int) {
+	for _, action := range plan.Actions {
+		if action.Type == actionType {
+			n++
+		}
+	}
+	return
+}
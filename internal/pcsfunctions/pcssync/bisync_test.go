@@ -0,0 +1,300 @@
+package pcssync
+
+import (
+	"errors"
+	"fmt"
+	"github.com/Erope/BaiduPCS-Go/baidupcs"
+	"github.com/Erope/BaiduPCS-Go/pcsutil/checksum"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestBiCompare(t *testing.T) {
+	lf := func(length, modTime int64) *checksum.LocalFileMeta {
+		return &checksum.LocalFileMeta{Length: length, ModTime: modTime}
+	}
+	rf := func(fsID, size int64, md5 string) *baidupcs.FileDirectory {
+		return &baidupcs.FileDirectory{FsID: fsID, Size: size, MD5: md5}
+	}
+	entry := func(length, modTime, fsID int64, md5 string) *BiEntry {
+		return &BiEntry{
+			Local:  lf(length, modTime),
+			Remote: &BiRemoteMeta{FsID: fsID, Size: length, MD5: md5},
+		}
+	}
+
+	local := &Local{
+		Dir: "/l",
+		Files: map[string]*checksum.LocalFileMeta{
+			"same":      lf(1, 100),
+			"lmod":      lf(20, 200),
+			"rmod":      lf(3, 100),
+			"rdel":      lf(5, 100),
+			"both":      lf(60, 200),
+			"bothsame":  lf(70, 200),
+			"new":       lf(8, 100),
+			"rold":      lf(9, 100),
+			"lnew":      lf(11, 100),
+			"firstsame": lf(13, 100),
+			"firstdiff": lf(14, 100),
+		},
+	}
+	remote := map[string]*baidupcs.FileDirectory{
+		"same":      rf(1, 1, "01"),
+		"lmod":      rf(2, 2, "02"),
+		"rmod":      rf(33, 30, "33"),
+		"ldel":      rf(4, 4, "04"),
+		"both":      rf(66, 61, "66"),
+		"bothsame":  rf(77, 70, "0707"),
+		"old":       rf(8, 8, "08"),
+		"rnew":      rf(9, 9, "09"),
+		"delmod":    rf(100, 100, "10"),
+		"rnew2":     rf(12, 12, "12"),
+		"firstsame": rf(13, 13, "0d0d"),
+		"firstdiff": rf(14, 14, "0e0e"),
+	}
+	state := &BiState{
+		Entries: map[string]*BiEntry{
+			"same":     entry(1, 100, 1, "01"),
+			"lmod":     entry(2, 100, 2, "02"),
+			"rmod":     entry(3, 100, 3, "03"),
+			"ldel":     entry(4, 100, 4, "04"),
+			"rdel":     entry(5, 100, 5, "05"),
+			"both":     entry(6, 100, 6, "06"),
+			"bothsame": entry(7, 100, 7, "07"),
+			"old":      entry(8, 100, 8, "08"),
+			"rold":     entry(9, 100, 9, "09"),
+			"delmod":   entry(10, 100, 10, "10"),
+			"gone":     entry(15, 100, 15, "15"),
+		},
+	}
+
+	plan := BiCompare(local, remote, "/r", state, nil, func(meta *checksum.LocalFileMeta) ([]byte, error) {
+		switch meta.Length {
+		case 70:
+			return []byte{7, 7}, nil
+		case 13:
+			return []byte{13, 13}, nil
+		}
+		return []byte{0}, nil
+	})
+
+	expect := map[string]ActionType{
+		"lmod":      ActionUpload,
+		"rmod":      ActionDownload,
+		"ldel":      ActionDelete,
+		"rdel":      ActionDeleteLocal,
+		"both":      ActionConflict,
+		"new":       ActionMove,
+		"rnew":      ActionMoveLocal,
+		"delmod":    ActionDownload,
+		"lnew":      ActionUpload,
+		"rnew2":     ActionDownload,
+		"firstdiff": ActionConflict,
+	}
+	if len(plan.Actions) != len(expect) {
+		for _, action := range plan.Actions {
+			t.Logf("%s %s %s", action.Type, action.RelPath, action.Reason)
+		}
+		t.Fatalf("expect %d actions, got %d", len(expect), len(plan.Actions))
+	}
+	for _, action := range plan.Actions {
+		if expect[action.RelPath] != action.Type {
+			t.Fatalf("%s: expect %s, got %s", action.RelPath, expect[action.RelPath], action.Type)
+		}
+		switch action.RelPath {
+		case "new":
+			if action.From != "old" || action.RemotePath != "/r/new" {
+				t.Fatalf("unexpected move: %+v", action)
+			}
+		case "rnew":
+			if action.From != "rold" || action.LocalPath != filepath.Join("/l", "rnew") {
+				t.Fatalf("unexpected local move: %+v", action)
+			}
+		}
+	}
+	if plan.Unchanged != 3 {
+		t.Fatalf("expect 3 unchanged, got %d", plan.Unchanged)
+	}
+}
+
+func TestConflictPath(t *testing.T) {
+	tm := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	for rel, expect := range map[string]string{
+		"a/b.txt":  "a/b.conflict-20200102-030405.txt",
+		"b":        "b.conflict-20200102-030405",
+		".bashrc":  ".bashrc.conflict-20200102-030405",
+		"a.tar.gz": "a.tar.conflict-20200102-030405.gz",
+	} {
+		if p := ConflictPath(rel, tm); p != expect {
+			t.Fatalf("%s: expect %s, got %s", rel, expect, p)
+		}
+	}
+}
+
+func TestCheckBiPlan(t *testing.T) {
+	var (
+		local  = &Local{Files: map[string]*checksum.LocalFileMeta{}}
+		remote = map[string]*baidupcs.FileDirectory{}
+		state  = &BiState{LocalDir: "/l", RemoteDir: "/r", Entries: map[string]*BiEntry{}}
+		plan   = &BiPlan{}
+	)
+	for i := 0; i < 30; i++ {
+		rel := fmt.Sprint(i)
+		local.Files[rel] = &checksum.LocalFileMeta{}
+		state.Entries[rel] = &BiEntry{}
+	}
+
+	// 网盘目录不存在或为空, 而上次同步过
+	if err := CheckBiPlan(plan, local, remote, state, nil, DefaultMaxDeleteRatio); !errors.Is(err, ErrBiUnsafe) {
+		t.Fatal("expect unsafe for empty remote")
+	}
+	remote["0"] = &baidupcs.FileDirectory{}
+	if err := CheckBiPlan(plan, local, remote, state, nil, DefaultMaxDeleteRatio); err != nil {
+		t.Fatal(err)
+	}
+
+	// 删除超过一半的文件
+	for i := 0; i < 16; i++ {
+		plan.Actions = append(plan.Actions, &Action{Type: ActionDeleteLocal})
+	}
+	if err := CheckBiPlan(plan, local, remote, state, nil, DefaultMaxDeleteRatio); err == nil {
+		t.Fatal("expect unsafe for too many deletes")
+	}
+	if err := CheckBiPlan(plan, local, remote, state, nil, 1); err != nil {
+		t.Fatal(err)
+	}
+
+	// 本地目录为空
+	if err := CheckBiPlan(&BiPlan{}, &Local{}, remote, state, nil, 1); err == nil {
+		t.Fatal("expect unsafe for empty local")
+	}
+
+	// 从未同步过
+	if err := CheckBiPlan(plan, &Local{}, nil, &BiState{}, nil, DefaultMaxDeleteRatio); err != nil {
+		t.Fatal(err)
+	}
+
+	if p := TrashPath("/l", "a/b", time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)); p != filepath.Join("/l", BiTrashDirName, "20200102-030405", "a", "b") {
+		t.Fatalf("unexpected trash path: %s", p)
+	}
+}
+
+func TestBiStateDB(t *testing.T) {
+	dir, err := ioutil.TempDir("", "pcssync")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	db := NewBiStateDBWithPath(filepath.Join(dir, BiStateFileName))
+	state, err := db.Get(1, "/l", "/r")
+	if err != nil || len(state.Entries) != 0 {
+		t.Fatalf("expect empty state, got %v, %v", state, err)
+	}
+
+	state.Entries["a"] = NewBiEntry(&checksum.LocalFileMeta{Length: 1}, &baidupcs.FileDirectory{FsID: 2, Size: 1})
+	if err = db.Put(state); err != nil {
+		t.Fatal(err)
+	}
+	if err = db.Put(&BiState{UID: 1, LocalDir: "/l2", RemoteDir: "/r"}); err != nil {
+		t.Fatal(err)
+	}
+
+	db = NewBiStateDBWithPath(filepath.Join(dir, BiStateFileName))
+	state, err = db.Get(1, "/l", "/r")
+	if err != nil || state.SyncTime == 0 || state.Entries["a"] == nil || state.Entries["a"].Remote.FsID != 2 {
+		t.Fatalf("unexpected state: %+v, %v", state, err)
+	}
+	state, err = db.Get(2, "/l", "/r")
+	if err != nil || state.SyncTime != 0 {
+		t.Fatalf("expect new state for other uid, got %+v, %v", state, err)
+	}
+}
+
+func TestBiStateUpdate(t *testing.T) {
+	lf := func(length, modTime int64) *checksum.LocalFileMeta {
+		return &checksum.LocalFileMeta{Length: length, ModTime: modTime}
+	}
+	rf := func(fsID, size int64, md5 string) *baidupcs.FileDirectory {
+		return &baidupcs.FileDirectory{FsID: fsID, Size: size, MD5: md5}
+	}
+	old := func(fsID int64) *BiEntry {
+		return &BiEntry{Local: lf(1, 1), Remote: &BiRemoteMeta{FsID: fsID}}
+	}
+
+	before := &BiFiles{
+		Local: &Local{Files: map[string]*checksum.LocalFileMeta{
+			"same":   lf(1, 100),
+			"lmod":   lf(2, 100),
+			"up":     lf(3, 200),
+			"upmod":  lf(4, 200),
+			"failed": lf(5, 200),
+		}},
+		Remote: map[string]*baidupcs.FileDirectory{
+			"same":   rf(1, 1, "01"),
+			"lmod":   rf(2, 2, "02"),
+			"up":     rf(3, 30, "03"),
+			"upmod":  rf(4, 40, "04"),
+			"failed": rf(5, 50, "05"),
+			"down":   rf(6, 6, "06"),
+		},
+	}
+	after := &BiFiles{
+		Local: &Local{Files: map[string]*checksum.LocalFileMeta{
+			"same":   lf(1, 100),
+			"lmod":   lf(2, 300), // 同步期间被修改
+			"up":     lf(3, 200),
+			"upmod":  lf(44, 300), // 上传后又被修改
+			"failed": lf(5, 200),
+			"down":   lf(6, 300),
+		}},
+		Remote: map[string]*baidupcs.FileDirectory{
+			"same":   rf(1, 1, "01"),
+			"lmod":   rf(2, 2, "02"),
+			"up":     rf(33, 3, "33"),
+			"upmod":  rf(44, 4, "44"),
+			"failed": rf(5, 50, "05"),
+			"down":   rf(6, 6, "06"),
+		},
+	}
+	plan := &BiPlan{Actions: []*Action{
+		{Type: ActionUpload, RelPath: "up"},
+		{Type: ActionUpload, RelPath: "upmod"},
+		{Type: ActionUpload, RelPath: "failed"},
+		{Type: ActionDownload, RelPath: "down"},
+		{Type: ActionDeleteLocal, RelPath: "gone"},
+	}}
+	state := &BiState{Entries: map[string]*BiEntry{
+		"lmod":   old(2),
+		"upmod":  old(4),
+		"failed": old(5),
+		"gone":   old(7),
+	}}
+
+	state.Update(plan, before, after, nil, map[string]bool{"failed": true}, nil)
+	for rel, fsID := range map[string]int64{
+		"same":   1,
+		"lmod":   2,
+		"up":     33,
+		"upmod":  4,
+		"failed": 5,
+		"down":   6,
+	} {
+		entry := state.Entries[rel]
+		if entry == nil || entry.Remote.FsID != fsID {
+			t.Fatalf("%s: expect fs_id %d, got %+v", rel, fsID, entry)
+		}
+	}
+	for _, rel := range []string{"lmod", "upmod", "failed"} {
+		if state.Entries[rel].Local.ModTime != 1 {
+			t.Fatalf("%s: expect old entry kept, got %+v", rel, state.Entries[rel].Local)
+		}
+	}
+	if len(state.Entries) != 6 {
+		t.Fatalf("expect 6 entries, got %d", len(state.Entries))
+	}
+}
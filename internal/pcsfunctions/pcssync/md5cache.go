@@ -0,0 +1,161 @@
+package pcssync
+
+import (
+	"bytes"
+	"github.com/Erope/BaiduPCS-Go/internal/pcsconfig"
+	"github.com/Erope/BaiduPCS-Go/pcsutil"
+	"github.com/Erope/BaiduPCS-Go/pcsutil/checksum"
+	"github.com/Erope/BaiduPCS-Go/pcsutil/filelock"
+	"github.com/Erope/BaiduPCS-Go/pcsutil/jsonhelper"
+	"github.com/Erope/BaiduPCS-Go/pcsverbose"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+const (
+	// MD5CacheFileName 本地文件 md5 缓存的文件名
+	MD5CacheFileName = "pcs_sync_md5_cache.json"
+)
+
+var (
+	pcsSyncVerbose = pcsverbose.New("PCSSYNC")
+)
+
+type (
+	// MD5Cache 本地文件 md5 的缓存, 文件的大小和修改时间不变时使用缓存的 md5, 可并发调用
+	MD5Cache struct {
+		path    string
+		entries map[string]*checksum.LocalFileMeta // 键为本地文件的绝对路径
+		changed bool
+		mu      sync.Mutex
+	}
+)
+
+// NewMD5Cache 读取配置目录下的 md5 缓存
+func NewMD5Cache() *MD5Cache {
+	return NewMD5CacheWithPath(filepath.Join(pcsconfig.GetConfigDir(), MD5CacheFileName))
+}
+
+// NewMD5CacheWithPath 读取路径为 path 的 md5 缓存, 文件不存在或已损坏时为空
+func NewMD5CacheWithPath(path string) *MD5Cache {
+	return &MD5Cache{
+		path:    path,
+		entries: readMD5Cache(path),
+	}
+}
+
+// readMD5Cache 读取 md5 缓存文件, 文件不存在或已损坏时返回空
+func readMD5Cache(path string) map[string]*checksum.LocalFileMeta {
+	entries := map[string]*checksum.LocalFileMeta{}
+	f, err := os.Open(path)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			pcsSyncVerbose.Warnf("读取 md5 缓存错误: %s\n", err)
+		}
+		return entries
+	}
+	defer f.Close()
+
+	var list []*checksum.LocalFileMeta
+	err = jsonhelper.UnmarshalData(f, &list)
+	if err != nil {
+		pcsSyncVerbose.Warnf("解析 md5 缓存错误: %s\n", err)
+		return entries
+	}
+	for _, meta := range list {
+		if meta != nil {
+			entries[meta.Path] = meta
+		}
+	}
+	return entries
+}
+
+// Cached 获取缓存的本地文件的 md5, 文件的大小或修改时间已改变时返回空
+func (c *MD5Cache) Cached(meta *checksum.LocalFileMeta) []byte {
+	absPath, err := filepath.Abs(meta.Path)
+	if err != nil {
+		absPath = meta.Path
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	cached := c.entries[absPath]
+	if cached == nil || cached.Length != meta.Length || cached.ModTime != meta.ModTime {
+		return nil
+	}
+	return cached.MD5
+}
+
+// Sum 获取本地文件的 md5, 优先使用缓存, 实现 SumMD5Func
+func (c *MD5Cache) Sum(meta *checksum.LocalFileMeta) ([]byte, error) {
+	absPath, err := filepath.Abs(meta.Path)
+	if err != nil {
+		absPath = meta.Path
+	}
+
+	if sum := c.Cached(meta); len(sum) > 0 {
+		return sum, nil
+	}
+
+	lfc, err := checksum.GetFileSum(meta.Path, checksum.CHECKSUM_MD5)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	c.entries[absPath] = &checksum.LocalFileMeta{
+		Path:    absPath,
+		Length:  lfc.Length,
+		ModTime: lfc.ModTime,
+		MD5:     lfc.MD5,
+	}
+	c.changed = true
+	c.mu.Unlock()
+	return lfc.MD5, nil
+}
+
+// Save 合并其他进程已保存的缓存, 移除已不存在的文件, 保存缓存.
+// 多个进程通过文件锁互斥, 先写入临时文件再重命名
+func (c *MD5Cache) Save() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	unlock, err := filelock.Lock(c.path + ".lock")
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	// 同一文件保留修改时间较新的记录
+	for p, meta := range readMD5Cache(c.path) {
+		if cached := c.entries[p]; cached == nil || meta.ModTime > cached.ModTime {
+			c.entries[p] = meta
+		}
+	}
+
+	list := make([]*checksum.LocalFileMeta, 0, len(c.entries))
+	for p, meta := range c.entries {
+		if _, err := os.Stat(p); err != nil {
+			delete(c.entries, p)
+			c.changed = true
+			continue
+		}
+		list = append(list, meta)
+	}
+	if !c.changed {
+		return nil
+	}
+
+	buf := &bytes.Buffer{}
+	err = jsonhelper.MarshalData(buf, list)
+	if err != nil {
+		return err
+	}
+	err = pcsutil.WriteFileAtomic(c.path, buf.Bytes(), 0600)
+	if err != nil {
+		return err
+	}
+	c.changed = false
+	return nil
+}
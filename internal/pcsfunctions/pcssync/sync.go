@@ -0,0 +1,363 @@
+// Package pcssync 本地目录和网盘目录的单向和双向同步, 比较两边的文件, 生成同步计划
+package pcssync
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/Erope/BaiduPCS-Go/baidupcs"
+	"github.com/Erope/BaiduPCS-Go/baidupcs/pcserror"
+	"github.com/Erope/BaiduPCS-Go/pcsutil/checksum"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+const (
+	// ActionUpload 上传本地文件
+	ActionUpload ActionType = "upload"
+	// ActionDelete 删除网盘中的文件或目录
+	ActionDelete ActionType = "delete"
+	// ActionConflict 冲突, 单向同步时为网盘中存在同名的目录, 双向同步时为两边都有修改
+	ActionConflict ActionType = "conflict"
+)
+
+type (
+	// ActionType 同步操作的类型
+	ActionType string
+
+	// Action 同步操作
+	Action struct {
+		Type       ActionType
+		Reason     string // 原因
+		RelPath    string // 相对于同步目录的路径, 以 / 分隔
+		LocalPath  string // 本地路径, 删除时为空
+		RemotePath string // 网盘路径
+		Size       int64
+		FsID       int64 // 删除的网盘文件或目录的 fs_id
+		Isdir      bool
+		From       string // 重命名的原相对路径
+	}
+
+	// Plan 同步计划
+	Plan struct {
+		Uploads   []*Action
+		Deletes   []*Action // 网盘中多余的文件或目录, 目录中的文件不再单独列出
+		Conflicts []*Action
+		Unchanged int // 未变化的文件数量
+		Excluded  int // 本地被排除的文件数量
+	}
+
+	// Local 本地目录中的文件, 键为相对路径, 以 / 分隔
+	Local struct {
+		Dir      string
+		Files    map[string]*checksum.LocalFileMeta
+		Dirs     map[string]bool
+		Excluded int
+	}
+
+	// Filter 包含和排除的文件, 通配符匹配相对路径或文件名
+	Filter struct {
+		Include []string // 只同步匹配的文件, 为空则同步所有文件, 对目录无效
+		Exclude []string // 排除匹配的文件和目录
+	}
+
+	// SumMD5Func 获取本地文件的 md5
+	SumMD5Func func(meta *checksum.LocalFileMeta) ([]byte, error)
+)
+
+// NewFilter 检查通配符, 返回 Filter
+func NewFilter(include, exclude []string) (*Filter, error) {
+	for _, pattern := range append(append([]string{}, include...), exclude...) {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("通配符不合法: %s, %s", pattern, err)
+		}
+	}
+	return &Filter{
+		Include: include,
+		Exclude: exclude,
+	}, nil
+}
+
+func matchAny(patterns []string, relPath string) bool {
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(pattern, relPath); ok {
+			return true
+		}
+		if ok, _ := path.Match(pattern, path.Base(relPath)); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// Match 判断相对路径为 relPath 的文件或目录是否同步, filter 为 nil 时同步所有文件
+func (filter *Filter) Match(relPath string, isdir bool) bool {
+	if filter == nil {
+		return true
+	}
+	if matchAny(filter.Exclude, relPath) {
+		return false
+	}
+	if isdir || len(filter.Include) == 0 {
+		return true
+	}
+	return matchAny(filter.Include, relPath)
+}
+
+// WalkLocal 遍历本地目录 dir, 跳过 filter 排除的文件和目录
+func WalkLocal(dir string, filter *Filter) (*Local, error) {
+	info, err := os.Stat(dir)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("%s 不是目录", dir)
+	}
+
+	local := &Local{
+		Dir:   dir,
+		Files: map[string]*checksum.LocalFileMeta{},
+		Dirs:  map[string]bool{},
+	}
+	err = filepath.Walk(dir, func(localPath string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if localPath == dir {
+			return nil
+		}
+
+		rel, err := filepath.Rel(dir, localPath)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+
+		// 链接到文件时读取文件, 不进入链接的目录
+		if info.Mode()&os.ModeSymlink != 0 {
+			info, err = os.Stat(localPath)
+			if err != nil || info.IsDir() {
+				return nil
+			}
+		}
+
+		if !filter.Match(rel, info.IsDir()) {
+			if info.IsDir() {
+				return filepath.SkipDir
+			}
+			local.Excluded++
+			return nil
+		}
+
+		if info.IsDir() {
+			local.Dirs[rel] = true
+			return nil
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+		local.Files[rel] = &checksum.LocalFileMeta{
+			Path:    localPath,
+			Length:  info.Size(),
+			ModTime: info.ModTime().Unix(),
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return local, nil
+}
+
+// ListRemote 递归获取网盘目录 dir 中的文件和目录, 键为相对路径, 目录不存在时返回空
+func ListRemote(pcs baidupcs.Client, dir string) (remote map[string]*baidupcs.FileDirectory, err error) {
+	remote = map[string]*baidupcs.FileDirectory{}
+	prefix := strings.TrimSuffix(dir, baidupcs.PathSeparator) + baidupcs.PathSeparator
+	pcs.FilesDirectoriesRecurseList(dir, baidupcs.DefaultOrderOptions, func(depth int, fdPath string, fd *baidupcs.FileDirectory, pcsError pcserror.Error) bool {
+		if pcsError != nil {
+			if depth == 0 && errors.Is(pcsError, pcserror.ErrNotFound) {
+				return false
+			}
+			err = pcsError
+			return false
+		}
+		if depth == 0 {
+			err = fmt.Errorf("网盘路径 %s 不是目录", dir)
+			return false
+		}
+		remote[strings.TrimPrefix(fd.Path, prefix)] = fd
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	return remote, nil
+}
+
+// Compare 比较本地文件和网盘文件, 生成同步计划.
+// 大小不同的文件需要上传, 大小相同时, 本地文件的修改时间不晚于网盘文件的视为未变化,
+// 否则比较 md5, 网盘的 md5 可能不正确 (分片上传的文件) 时直接上传, 秒传可避免重复上传相同的内容.
+// 网盘中不符合 filter 的文件不会被删除
+func Compare(local *Local, remote map[string]*baidupcs.FileDirectory, remoteDir string, filter *Filter, sumMD5 SumMD5Func) *Plan {
+	plan := &Plan{
+		Excluded: local.Excluded,
+	}
+
+	localPaths := make([]string, 0, len(local.Files))
+	for rel := range local.Files {
+		localPaths = append(localPaths, rel)
+	}
+	sort.Strings(localPaths)
+
+	for _, rel := range localPaths {
+		meta := local.Files[rel]
+		action := &Action{
+			Type:       ActionUpload,
+			RelPath:    rel,
+			LocalPath:  meta.Path,
+			RemotePath: path.Join(remoteDir, rel),
+			Size:       meta.Length,
+		}
+
+		fd := remote[rel]
+		switch {
+		case fd == nil:
+			action.Reason = "新文件"
+		case fd.Isdir:
+			action.Type, action.Reason = ActionConflict, "网盘中存在同名的目录"
+			plan.Conflicts = append(plan.Conflicts, action)
+			continue
+		case fd.Size != meta.Length:
+			action.Reason = "文件大小不同"
+		case meta.ModTime <= fd.Mtime:
+			plan.Unchanged++
+			continue
+		case fd.MD5 == "" || len(fd.BlockList) > 1:
+			action.Reason = "本地文件较新"
+		default:
+			sum, err := sumMD5(meta)
+			if err != nil {
+				action.Reason = "本地文件较新"
+				break
+			}
+			remoteMD5, _ := hex.DecodeString(fd.MD5)
+			if bytes.Equal(sum, remoteMD5) {
+				plan.Unchanged++
+				continue
+			}
+			action.Reason = "文件内容不同"
+		}
+		plan.Uploads = append(plan.Uploads, action)
+	}
+
+	remotePaths := make([]string, 0, len(remote))
+	for rel := range remote {
+		remotePaths = append(remotePaths, rel)
+	}
+	sort.Strings(remotePaths)
+
+	// 含有不同步的文件的目录, 不能整个删除
+	keep := map[string]bool{}
+	for _, rel := range remotePaths {
+		if filter.Match(rel, remote[rel].Isdir) {
+			continue
+		}
+		for dir := path.Dir(rel); dir != "."; dir = path.Dir(dir) {
+			keep[dir] = true
+		}
+	}
+
+	var skipPrefixes []string // 已删除或被排除的目录
+	for _, rel := range remotePaths {
+		if hasAnyPrefix(rel, skipPrefixes) {
+			continue
+		}
+
+		fd := remote[rel]
+		if !filter.Match(rel, fd.Isdir) {
+			if fd.Isdir {
+				skipPrefixes = append(skipPrefixes, rel+"/")
+			}
+			continue
+		}
+
+		if fd.Isdir {
+			if local.Dirs[rel] || keep[rel] {
+				continue
+			}
+			if _, ok := local.Files[rel]; ok { // 已记录为冲突
+				skipPrefixes = append(skipPrefixes, rel+"/")
+				continue
+			}
+			skipPrefixes = append(skipPrefixes, rel+"/")
+		} else if _, ok := local.Files[rel]; ok {
+			continue
+		}
+
+		plan.Deletes = append(plan.Deletes, &Action{
+			Type:       ActionDelete,
+			Reason:     "本地不存在",
+			RelPath:    rel,
+			RemotePath: fd.Path,
+			Size:       fd.Size,
+			FsID:       fd.FsID,
+			Isdir:      fd.Isdir,
+		})
+	}
+	return plan
+}
+
+func hasAnyPrefix(s string, prefixes []string) bool {
+	for _, prefix := range prefixes {
+		if strings.HasPrefix(s, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// UploadSize 需要上传的总大小
+func (plan *Plan) UploadSize() (size int64) {
+	for _, action := range plan.Uploads {
+		size += action.Size
+	}
+	return
+}
+
+// CheckPlan 单向同步删除网盘文件前检查是否安全, 防止本地目录未挂载, 路径错误等导致删除网盘中的大量文件.
+// 本地目录没有文件而网盘中有, 或删除的文件超过网盘文件数量的 maxDeleteRatio 时返回 ErrBiUnsafe
+func CheckPlan(plan *Plan, local *Local, remote map[string]*baidupcs.FileDirectory, filter *Filter, maxDeleteRatio float64) error {
+	if len(plan.Deletes) == 0 {
+		return nil
+	}
+	files := filterRemote(remote, filter)
+	if len(files) == 0 {
+		return nil
+	}
+	if len(local.Files) == 0 {
+		return fmt.Errorf("%w, 本地目录没有文件, 将删除网盘中的 %d 个文件", ErrBiUnsafe, len(files))
+	}
+
+	// 统计删除的文件, 包括删除的目录中的文件
+	deletes := make(map[string]bool, len(plan.Deletes))
+	for _, action := range plan.Deletes {
+		deletes[action.RelPath] = true
+	}
+	var deleted int
+	for rel := range files {
+		for p := rel; p != "."; p = path.Dir(p) {
+			if deletes[p] {
+				deleted++
+				break
+			}
+		}
+	}
+	if deleted > biDeleteGuardMin && float64(deleted) > float64(len(files))*maxDeleteRatio {
+		return fmt.Errorf("%w, 将删除 %d 个文件, 超过网盘中 %d 个文件的 %.0f%%", ErrBiUnsafe, deleted, len(files), maxDeleteRatio*100)
+	}
+	return nil
+}
@@ -0,0 +1,202 @@
+package pcssync
+
+import (
+	"errors"
+	"fmt"
+	"github.com/Erope/BaiduPCS-Go/baidupcs"
+	"github.com/Erope/BaiduPCS-Go/pcsutil/checksum"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCompare(t *testing.T) {
+	local := &Local{
+		Files: map[string]*checksum.LocalFileMeta{
+			"new.txt":       {Path: "/l/new.txt", Length: 1, ModTime: 100},
+			"size.txt":      {Path: "/l/size.txt", Length: 2, ModTime: 100},
+			"old.txt":       {Path: "/l/old.txt", Length: 3, ModTime: 100},
+			"same.txt":      {Path: "/l/same.txt", Length: 4, ModTime: 300},
+			"changed.txt":   {Path: "/l/changed.txt", Length: 5, ModTime: 300},
+			"split.bin":     {Path: "/l/split.bin", Length: 6, ModTime: 300},
+			"conflict":      {Path: "/l/conflict", Length: 7, ModTime: 100},
+			"dir/a.txt":     {Path: "/l/dir/a.txt", Length: 8, ModTime: 100},
+			"dir/sub/b.txt": {Path: "/l/dir/sub/b.txt", Length: 9, ModTime: 100},
+		},
+		Dirs: map[string]bool{"dir": true, "dir/sub": true},
+	}
+	fd := func(p string, size, mtime int64, md5 string, isdir bool) *baidupcs.FileDirectory {
+		return &baidupcs.FileDirectory{Path: "/r/" + p, Size: size, Mtime: mtime, MD5: md5, Isdir: isdir}
+	}
+	split := fd("split.bin", 6, 200, "00", false)
+	split.BlockList = []string{"1", "2"}
+	remote := map[string]*baidupcs.FileDirectory{
+		"size.txt":      fd("size.txt", 20, 200, "", false),
+		"old.txt":       fd("old.txt", 3, 200, "", false),
+		"same.txt":      fd("same.txt", 4, 200, "0102", false),
+		"changed.txt":   fd("changed.txt", 5, 200, "0102", false),
+		"split.bin":     split,
+		"conflict":      fd("conflict", 0, 200, "", true),
+		"conflict/x":    fd("conflict/x", 1, 200, "", false),
+		"extra.txt":     fd("extra.txt", 1, 200, "", false),
+		"keep.log":      fd("keep.log", 1, 200, "", false),
+		"dir":           fd("dir", 0, 200, "", true),
+		"dir/c.txt":     fd("dir/c.txt", 1, 200, "", false),
+		"gone":          fd("gone", 0, 200, "", true),
+		"gone/d.txt":    fd("gone/d.txt", 1, 200, "", false),
+		"mixed":         fd("mixed", 0, 200, "", true),
+		"mixed/e.txt":   fd("mixed/e.txt", 1, 200, "", false),
+		"mixed/f.log":   fd("mixed/f.log", 1, 200, "", false),
+		"dir/sub/b.txt": fd("dir/sub/b.txt", 9, 200, "", false),
+	}
+
+	filter, err := NewFilter(nil, []string{"*.log"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sums := 0
+	plan := Compare(local, remote, "/r", filter, func(meta *checksum.LocalFileMeta) ([]byte, error) {
+		sums++
+		if meta.Path == "/l/same.txt" {
+			return []byte{1, 2}, nil
+		}
+		return []byte{3, 4}, nil
+	})
+
+	expectActions(t, "upload", plan.Uploads, "changed.txt", "dir/a.txt", "new.txt", "size.txt", "split.bin")
+	expectActions(t, "delete", plan.Deletes, "dir/c.txt", "extra.txt", "gone", "mixed/e.txt")
+	expectActions(t, "conflict", plan.Conflicts, "conflict")
+	if plan.Unchanged != 3 {
+		t.Fatalf("expect 3 unchanged, got %d", plan.Unchanged)
+	}
+	if sums != 2 {
+		t.Fatalf("expect 2 md5 sums, got %d", sums)
+	}
+	if plan.Uploads[0].RemotePath != "/r/changed.txt" || plan.Uploads[0].Reason != "文件内容不同" {
+		t.Fatalf("unexpected action: %+v", plan.Uploads[0])
+	}
+}
+
+func expectActions(t *testing.T, name string, actions []*Action, relPaths ...string) {
+	if len(actions) != len(relPaths) {
+		t.Fatalf("%s: expect %v, got %d actions", name, relPaths, len(actions))
+	}
+	for k := range actions {
+		if actions[k].RelPath != relPaths[k] {
+			t.Fatalf("%s: expect %s at %d, got %s", name, relPaths[k], k, actions[k].RelPath)
+		}
+	}
+}
+
+func TestFilter(t *testing.T) {
+	filter, err := NewFilter([]string{"*.mp4", "docs/*"}, []string{"tmp", "*.part"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cases := []struct {
+		relPath string
+		isdir   bool
+		match   bool
+	}{
+		{"a.mp4", false, true},
+		{"video/a.mp4", false, true},
+		{"docs/readme", false, true},
+		{"a.txt", false, false},
+		{"tmp", true, false},
+		{"video", true, true},
+		{"b.mp4.part", false, false},
+	}
+	for _, c := range cases {
+		if filter.Match(c.relPath, c.isdir) != c.match {
+			t.Fatalf("%s: expect %v", c.relPath, c.match)
+		}
+	}
+
+	if _, err = NewFilter([]string{"[a"}, nil); err == nil {
+		t.Fatal("expect error")
+	}
+}
+
+func TestMD5CacheMerge(t *testing.T) {
+	dir, err := ioutil.TempDir("", "pcssync")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	var metas []*checksum.LocalFileMeta
+	for _, name := range []string{"a.txt", "b.txt"} {
+		p := filepath.Join(dir, name)
+		if err = ioutil.WriteFile(p, []byte(name), 0644); err != nil {
+			t.Fatal(err)
+		}
+		info, _ := os.Stat(p)
+		metas = append(metas, &checksum.LocalFileMeta{Path: p, Length: info.Size(), ModTime: info.ModTime().Unix()})
+	}
+
+	// 两个进程分别计算不同文件的 md5 后保存, 不会覆盖对方的记录
+	cachePath := filepath.Join(dir, MD5CacheFileName)
+	c1, c2 := NewMD5CacheWithPath(cachePath), NewMD5CacheWithPath(cachePath)
+	for k, c := range []*MD5Cache{c1, c2} {
+		if _, err = c.Sum(metas[k]); err != nil {
+			t.Fatal(err)
+		}
+		if err = c.Save(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	c3 := NewMD5CacheWithPath(cachePath)
+	for _, meta := range metas {
+		if len(c3.Cached(meta)) == 0 {
+			t.Errorf("%s: md5 not cached", meta.Path)
+		}
+	}
+}
+
+func TestCheckPlan(t *testing.T) {
+	var (
+		local  = &Local{Files: map[string]*checksum.LocalFileMeta{}, Dirs: map[string]bool{}}
+		remote = map[string]*baidupcs.FileDirectory{
+			"d": {Path: "/r/d", Isdir: true},
+		}
+	)
+	for i := 0; i < 10; i++ {
+		rel := fmt.Sprint(i)
+		local.Files[rel] = &checksum.LocalFileMeta{Path: "/l/" + rel}
+		remote[rel] = &baidupcs.FileDirectory{Path: "/r/" + rel}
+	}
+	for i := 0; i < 20; i++ {
+		rel := fmt.Sprintf("d/%d", i)
+		remote[rel] = &baidupcs.FileDirectory{Path: "/r/" + rel}
+	}
+
+	// 删除目录 d 中的 20 个文件, 超过网盘中 30 个文件的一半
+	plan := Compare(local, remote, "/r", nil, nil)
+	if len(plan.Deletes) != 1 {
+		t.Fatalf("unexpected deletes: %v", plan.Deletes)
+	}
+	if err := CheckPlan(plan, local, remote, nil, DefaultMaxDeleteRatio); !errors.Is(err, ErrBiUnsafe) {
+		t.Fatalf("expect unsafe for too many deletes, got %v", err)
+	}
+	if err := CheckPlan(plan, local, remote, nil, 1); err != nil {
+		t.Fatal(err)
+	}
+
+	// 本地目录为空, 如未挂载
+	empty := &Local{}
+	if err := CheckPlan(Compare(empty, remote, "/r", nil, nil), empty, remote, nil, 1); !errors.Is(err, ErrBiUnsafe) {
+		t.Fatalf("expect unsafe for empty local, got %v", err)
+	}
+
+	// 没有删除
+	local.Dirs["d"] = true
+	for i := 0; i < 20; i++ {
+		rel := fmt.Sprintf("d/%d", i)
+		local.Files[rel] = &checksum.LocalFileMeta{Path: "/l/" + rel}
+	}
+	if err := CheckPlan(Compare(local, remote, "/r", nil, nil), local, remote, nil, DefaultMaxDeleteRatio); err != nil {
+		t.Fatal(err)
+	}
+}
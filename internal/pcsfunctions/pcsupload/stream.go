@@ -0,0 +1,61 @@
+package pcsupload
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"github.com/Erope/BaiduPCS-Go/baidupcs"
+	"github.com/Erope/BaiduPCS-Go/baidupcs/pcserror"
+	"github.com/Erope/BaiduPCS-Go/internal/pcsconfig"
+	"github.com/Erope/BaiduPCS-Go/requester/multipartreader"
+	"github.com/Erope/BaiduPCS-Go/requester/transfer"
+	"github.com/Erope/BaiduPCS-Go/requester/uploader"
+	"net/http"
+)
+
+// CreateStreamFile 将已上传的数据流分块 checksumList 保存到网盘路径 targetPath, sum 为整个数据流的校验信息.
+// rapid 为 true 时, 先使用数据流的 md5 尝试秒传, 秒传成功则不合并分块, 返回 rapidUploaded 为 true.
+// 分块多于一个时, 合并后再使用数据流的 md5 秒传覆盖, 使服务器记录正确的 md5, 失败不影响文件的完整性.
+// 数据流为空时, 直接上传空文件
+func CreateStreamFile(pcs baidupcs.Client, targetPath string, ondup baidupcs.OnDup, sum *uploader.StreamChecksum, checksumList []string, rapid bool) (rapidUploaded bool, pcsError pcserror.Error) {
+	if len(checksumList) == 0 {
+		pcsError = pcs.UploadWithOnDup(targetPath, ondup, func(uploadURL string, jar http.CookieJar) (*http.Response, error) {
+			client := pcsconfig.Config.PCSHTTPClient()
+			client.SetCookiejar(jar)
+
+			mr := multipartreader.NewMultipartReader()
+			mr.AddFormFile("uploadedfile", "", uploader.NewBufioSplitUnit(bytes.NewReader(nil), transfer.Range{}, nil, nil))
+			mr.CloseMultipart()
+			return client.ReqWithContext(pcs.Context(), http.MethodPost, uploadURL, mr, nil)
+		})
+		return false, pcsError
+	}
+
+	var (
+		contentMD5 = hex.EncodeToString(sum.MD5)
+		sliceMD5   = hex.EncodeToString(sum.SliceMD5)
+		crc32      = fmt.Sprint(sum.CRC32)
+		canRapid   = sum.Length <= baidupcs.MaxRapidUploadSize
+	)
+	if rapid && canRapid {
+		pcsError = pcs.RapidUploadWithOnDup(targetPath, ondup, contentMD5, sliceMD5, crc32, sum.Length)
+		if pcsError == nil || baidupcs.IsOnDupSkipped(pcsError) {
+			return pcsError == nil, pcsError
+		}
+		pcsUploadVerbose.Infof("rapid upload stream failed: %s, create superfile\n", pcsError)
+	}
+
+	pcsError = pcs.UploadCreateSuperFileWithOnDup(targetPath, ondup, checksumList...)
+	if pcsError != nil {
+		return false, pcsError
+	}
+
+	// 生成副本时不知道保存的路径, 不修复
+	if len(checksumList) > 1 && canRapid && ondup != baidupcs.OnDupNewCopy {
+		fixErr := pcs.RapidUploadWithOnDup(targetPath, baidupcs.OnDupOverwrite, contentMD5, sliceMD5, crc32, sum.Length)
+		if fixErr != nil {
+			pcsUploadVerbose.Warnf("fix stream md5 failed: %s, path: %s\n", fixErr, targetPath)
+		}
+	}
+	return false, nil
+}
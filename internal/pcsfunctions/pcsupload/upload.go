@@ -12,15 +12,18 @@ import (
 
 type (
 	PCSUpload struct {
-		pcs        *baidupcs.BaiduPCS
+		pcs        baidupcs.Client
 		targetPath string
+		ondup      baidupcs.OnDup
 	}
 )
 
-func NewPCSUpload(pcs *baidupcs.BaiduPCS, targetPath string) uploader.MultiUpload {
+// NewPCSUpload 上传文件到网盘路径 targetPath, ondup 为目标文件已存在时的处理方式
+func NewPCSUpload(pcs baidupcs.Client, targetPath string, ondup baidupcs.OnDup) uploader.MultiUpload {
 	return &PCSUpload{
 		pcs:        pcs,
 		targetPath: targetPath,
+		ondup:      ondup,
 	}
 }
 
@@ -50,7 +53,7 @@ func (pu *PCSUpload) TmpFile(ctx context.Context, partseq int, partOffset int64,
 
 		doneChan := make(chan struct{}, 1)
 		go func() {
-			resp, err = client.Req(http.MethodPost, uploadURL, mr, nil)
+			resp, err = client.ReqWithContext(ctx, http.MethodPost, uploadURL, mr, nil)
 			doneChan <- struct{}{}
 
 			if resp != nil {
@@ -83,5 +86,5 @@ func (pu *PCSUpload) TmpFile(ctx context.Context, partseq int, partOffset int64,
 
 func (pu *PCSUpload) CreateSuperFile(checksumList ...string) (err error) {
 	pu.lazyInit()
-	return pu.pcs.UploadCreateSuperFile(pu.targetPath, checksumList...)
+	return pu.pcs.UploadCreateSuperFileWithOnDup(pu.targetPath, pu.ondup, checksumList...)
 }
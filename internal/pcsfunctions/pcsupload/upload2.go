@@ -9,13 +9,13 @@ type (
 	// PCSUpload2 新的上传方式
 	// TODO
 	PCSUpload2 struct {
-		pcs        *baidupcs.BaiduPCS
+		pcs        baidupcs.Client
 		targetPath string
 		uploadid   string
 	}
 )
 
-func NewPCSUpload2(pcs *baidupcs.BaiduPCS, targetPath string) uploader.MultiUpload {
+func NewPCSUpload2(pcs baidupcs.Client, targetPath string) uploader.MultiUpload {
 	return &PCSUpload{
 		pcs:        pcs,
 		targetPath: targetPath,
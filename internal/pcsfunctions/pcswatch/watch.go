@@ -0,0 +1,379 @@
+// Package pcswatch 监视本地目录中文件的变化, 文件在一段时间内保持不变后才通知, 用于自动上传
+package pcswatch
+
+import (
+	"errors"
+	"github.com/Erope/BaiduPCS-Go/internal/pcsfunctions/pcssync"
+	"github.com/fsnotify/fsnotify"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// EventChanged 文件新建或修改, 且已保持不变
+	EventChanged EventType = "changed"
+	// EventRemoved 文件或目录被删除或移出监视的目录
+	EventRemoved EventType = "removed"
+
+	// minCheckInterval 检查文件是否稳定的最小间隔
+	minCheckInterval = 100 * time.Millisecond
+)
+
+type (
+	// EventType 变化的类型
+	EventType string
+
+	// Event 文件或目录的变化
+	Event struct {
+		Type    EventType
+		Path    string // 本地路径
+		RelPath string // 相对于监视目录的路径, 以 / 分隔
+		Isdir   bool   // 被删除的是目录
+	}
+
+	// fileState 文件的状态, 用于判断文件是否仍在写入
+	fileState struct {
+		exists  bool
+		isdir   bool
+		size    int64
+		modTime time.Time
+	}
+
+	// pendingPath 有变化, 等待稳定的路径
+	pendingPath struct {
+		last  time.Time // 最近一次变化的时间
+		state fileState
+	}
+
+	// debouncer 记录有变化的路径, 路径在 stable 时间内没有新的变化,
+	// 且状态和变化时一致时才通知
+	debouncer struct {
+		stable  time.Duration
+		stat    func(name string) fileState
+		pending map[string]*pendingPath
+		known   map[string]bool      // 已知的文件和目录, 值为是否为目录, 只通知已知路径的删除
+		seen    map[string]fileState // 已知的文件的状态, 重新扫描时用于判断文件是否有变化
+	}
+
+	// Watcher 监视目录 dir 及其子目录
+	Watcher struct {
+		dir     string
+		filter  *pcssync.Filter
+		fsw     *fsnotify.Watcher
+		onError func(err error)
+
+		mu  sync.Mutex
+		deb *debouncer
+	}
+)
+
+func statPath(name string) fileState {
+	info, err := os.Lstat(name)
+	if err != nil {
+		return fileState{}
+	}
+	return fileState{
+		exists:  true,
+		isdir:   info.IsDir(),
+		size:    info.Size(),
+		modTime: info.ModTime(),
+	}
+}
+
+func newDebouncer(stable time.Duration, stat func(name string) fileState) *debouncer {
+	return &debouncer{
+		stable:  stable,
+		stat:    stat,
+		pending: map[string]*pendingPath{},
+		known:   map[string]bool{},
+		seen:    map[string]fileState{},
+	}
+}
+
+// touch 记录 name 在 now 时有变化
+func (deb *debouncer) touch(name string, now time.Time) {
+	deb.pending[name] = &pendingPath{
+		last:  now,
+		state: deb.stat(name),
+	}
+}
+
+// due 返回在 now 时已稳定的路径的变化, 按路径排序.
+// 目录被删除时, 目录中已知的文件不再单独通知
+func (deb *debouncer) due(now time.Time) []*Event {
+	names := make([]string, 0)
+	for name, p := range deb.pending {
+		if now.Sub(p.last) < deb.stable {
+			continue
+		}
+		state := deb.stat(name)
+		if state != p.state {
+			// 上次变化之后仍在写入, 重新计时
+			p.state, p.last = state, now
+			continue
+		}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	events := make([]*Event, 0, len(names))
+	for _, name := range names {
+		state := deb.pending[name].state
+		delete(deb.pending, name)
+		switch {
+		case !state.exists:
+			isdir, ok := deb.known[name]
+			if !ok {
+				continue
+			}
+			deb.forget(name)
+			events = append(events, &Event{
+				Type:  EventRemoved,
+				Path:  name,
+				Isdir: isdir,
+			})
+		case state.isdir:
+			deb.known[name] = true
+		default:
+			deb.known[name] = false
+			deb.seen[name] = state
+			events = append(events, &Event{
+				Type: EventChanged,
+				Path: name,
+			})
+		}
+	}
+	return events
+}
+
+// forget 移除已知的路径 name, 以及目录 name 中的所有路径
+func (deb *debouncer) forget(name string) {
+	delete(deb.known, name)
+	delete(deb.seen, name)
+	prefix := name + string(os.PathSeparator)
+	for known := range deb.known {
+		if strings.HasPrefix(known, prefix) {
+			delete(deb.known, known)
+			delete(deb.seen, known)
+		}
+	}
+}
+
+// resync 与重新扫描得到的路径 current (值为是否为目录) 比较, 记录新建, 修改和删除的路径,
+// 用于系统的事件队列溢出, 丢失了部分变化之后
+func (deb *debouncer) resync(current map[string]bool, now time.Time) {
+	for name, isdir := range current {
+		known, ok := deb.known[name]
+		if !ok || known != isdir || (!isdir && deb.stat(name) != deb.seen[name]) {
+			deb.touch(name, now)
+		}
+	}
+	for name := range deb.known {
+		if _, ok := current[name]; !ok {
+			deb.touch(name, now)
+		}
+	}
+}
+
+// NewWatcher 开始监视目录 dir, 目录中已有的文件和目录视为已知, 不会通知,
+// 文件保持 stable 时间不变后通知, filter 排除的文件和目录不监视
+func NewWatcher(dir string, stable time.Duration, filter *pcssync.Filter) (*Watcher, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	local, err := pcssync.WalkLocal(dir, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	fsw, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, err
+	}
+
+	w := &Watcher{
+		dir:    dir,
+		filter: filter,
+		fsw:    fsw,
+		deb:    newDebouncer(stable, statPath),
+	}
+
+	err = fsw.Add(dir)
+	if err != nil {
+		fsw.Close()
+		return nil, err
+	}
+	for relPath := range local.Dirs {
+		name := filepath.Join(dir, filepath.FromSlash(relPath))
+		err = fsw.Add(name)
+		if err != nil {
+			fsw.Close()
+			return nil, err
+		}
+		w.deb.known[name] = true
+	}
+	for relPath := range local.Files {
+		name := filepath.Join(dir, filepath.FromSlash(relPath))
+		w.deb.known[name] = false
+		w.deb.seen[name] = w.deb.stat(name)
+	}
+	return w, nil
+}
+
+// OnError 设置监视出错时的处理, 如系统的事件队列溢出, 溢出后会重新扫描目录
+func (w *Watcher) OnError(f func(err error)) {
+	w.onError = f
+}
+
+func (w *Watcher) handleError(err error) {
+	if w.onError != nil {
+		w.onError(err)
+	}
+}
+
+// relPath 返回 name 相对于监视目录的路径, 以 / 分隔
+func (w *Watcher) relPath(name string) string {
+	relPath, err := filepath.Rel(w.dir, name)
+	if err != nil {
+		return filepath.ToSlash(name)
+	}
+	return filepath.ToSlash(relPath)
+}
+
+// addDir 监视新建或移入的目录 name, 其中已有的文件视为有变化
+func (w *Watcher) addDir(name string, now time.Time) {
+	filepath.Walk(name, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return nil
+		}
+		if !w.filter.Match(w.relPath(p), info.IsDir()) {
+			if info.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if info.IsDir() {
+			err = w.fsw.Add(p)
+			if err != nil {
+				w.handleError(err)
+			}
+		}
+		w.deb.touch(p, now)
+		return nil
+	})
+}
+
+// rescan 重新扫描监视的目录, 记录与已知状态不同的路径, 并监视新的目录
+func (w *Watcher) rescan() {
+	local, err := pcssync.WalkLocal(w.dir, w.filter)
+	if err != nil {
+		w.handleError(err)
+		return
+	}
+
+	current := make(map[string]bool, len(local.Dirs)+len(local.Files))
+	for relPath := range local.Dirs {
+		name := filepath.Join(w.dir, filepath.FromSlash(relPath))
+		err = w.fsw.Add(name)
+		if err != nil {
+			w.handleError(err)
+		}
+		current[name] = true
+	}
+	for relPath := range local.Files {
+		current[filepath.Join(w.dir, filepath.FromSlash(relPath))] = false
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.deb.resync(current, time.Now())
+}
+
+func (w *Watcher) handleEvent(event fsnotify.Event) {
+	// 只修改权限, 内容不变
+	if event.Op == fsnotify.Chmod {
+		return
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	now := time.Now()
+	state := w.deb.stat(event.Name)
+	isdir := state.isdir
+	if !state.exists {
+		isdir = w.deb.known[event.Name]
+	}
+	if !w.filter.Match(w.relPath(event.Name), isdir) {
+		return
+	}
+	if event.Op&fsnotify.Create != 0 && state.isdir {
+		w.addDir(event.Name, now)
+		return
+	}
+	w.deb.touch(event.Name, now)
+}
+
+// Run 监视目录, 直到 stop 被关闭. 每次检查时, 以已稳定的文件和已删除的路径调用 handle,
+// handle 返回之后才进行下一次检查, 其间的变化会被记录
+func (w *Watcher) Run(stop <-chan struct{}, handle func(events []*Event)) {
+	quit := make(chan struct{})
+	defer close(quit)
+	go func() {
+		for {
+			select {
+			case event, ok := <-w.fsw.Events:
+				if !ok {
+					return
+				}
+				w.handleEvent(event)
+			case err, ok := <-w.fsw.Errors:
+				if !ok {
+					return
+				}
+				w.handleError(err)
+				if errors.Is(err, fsnotify.ErrEventOverflow) {
+					// 丢失了部分变化, 重新扫描
+					w.rescan()
+				}
+			case <-quit:
+				return
+			}
+		}
+	}()
+
+	interval := w.deb.stable / 4
+	if interval < minCheckInterval {
+		interval = minCheckInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case now := <-ticker.C:
+			w.mu.Lock()
+			events := w.deb.due(now)
+			w.mu.Unlock()
+			if len(events) == 0 {
+				continue
+			}
+			for _, event := range events {
+				event.RelPath = w.relPath(event.Path)
+			}
+			handle(events)
+		}
+	}
+}
+
+// Close 停止监视
+func (w *Watcher) Close() error {
+	return w.fsw.Close()
+}
@@ -0,0 +1,170 @@
+package pcswatch
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestDebouncer(t *testing.T) {
+	var (
+		states = map[string]fileState{}
+		start  = time.Unix(1000, 0)
+		deb    = newDebouncer(5*time.Second, func(name string) fileState {
+			return states[name]
+		})
+	)
+	deb.known["/d/old.txt"] = false
+	deb.known["/d/dir"] = true
+	deb.known["/d/dir/a.txt"] = false
+
+	states["/d/new.txt"] = fileState{exists: true, size: 1}
+	deb.touch("/d/new.txt", start)
+	deb.touch("/d/old.txt", start)
+	deb.touch("/d/tmp.txt", start)
+	deb.touch("/d/dir", start)
+	deb.touch("/d/dir/a.txt", start)
+
+	if events := deb.due(start.Add(4 * time.Second)); len(events) != 0 {
+		t.Fatalf("events before stable: %d", len(events))
+	}
+
+	// 仍在写入, 重新计时
+	states["/d/new.txt"] = fileState{exists: true, size: 2}
+	events := deb.due(start.Add(5 * time.Second))
+	if len(events) != 2 {
+		t.Fatalf("events: %d, want 2", len(events))
+	}
+	if e := events[0]; e.Type != EventRemoved || e.Path != "/d/dir" || !e.Isdir {
+		t.Errorf("event 0: %+v", e)
+	}
+	if e := events[1]; e.Type != EventRemoved || e.Path != "/d/old.txt" || e.Isdir {
+		t.Errorf("event 1: %+v", e)
+	}
+	if _, ok := deb.known["/d/dir/a.txt"]; ok {
+		t.Errorf("file in removed dir still known")
+	}
+
+	if events := deb.due(start.Add(9 * time.Second)); len(events) != 0 {
+		t.Fatalf("events while writing: %d", len(events))
+	}
+	events = deb.due(start.Add(10 * time.Second))
+	if len(events) != 1 || events[0].Type != EventChanged || events[0].Path != "/d/new.txt" {
+		t.Fatalf("events: %+v", events)
+	}
+	if isdir, ok := deb.known["/d/new.txt"]; !ok || isdir {
+		t.Errorf("new file not known")
+	}
+	if len(deb.pending) != 0 {
+		t.Errorf("pending: %d", len(deb.pending))
+	}
+}
+
+func TestDebouncerResync(t *testing.T) {
+	var (
+		states = map[string]fileState{
+			"/d/same.txt": {exists: true, size: 1},
+			"/d/mod.txt":  {exists: true, size: 2},
+			"/d/dir":      {exists: true, isdir: true},
+		}
+		start = time.Unix(1000, 0)
+		deb   = newDebouncer(5*time.Second, func(name string) fileState {
+			return states[name]
+		})
+	)
+	deb.known["/d/same.txt"], deb.seen["/d/same.txt"] = false, states["/d/same.txt"]
+	deb.known["/d/mod.txt"], deb.seen["/d/mod.txt"] = false, states["/d/mod.txt"]
+	deb.known["/d/del.txt"], deb.seen["/d/del.txt"] = false, fileState{exists: true, size: 3}
+	deb.known["/d/dir"] = true
+
+	// 事件队列溢出期间, 修改, 新建和删除了文件
+	states["/d/mod.txt"] = fileState{exists: true, size: 4}
+	states["/d/new.txt"] = fileState{exists: true, size: 5}
+	deb.resync(map[string]bool{
+		"/d/same.txt": false,
+		"/d/mod.txt":  false,
+		"/d/new.txt":  false,
+		"/d/dir":      true,
+	}, start)
+
+	events := deb.due(start.Add(5 * time.Second))
+	got := map[string]EventType{}
+	for _, e := range events {
+		got[e.Path] = e.Type
+	}
+	want := map[string]EventType{
+		"/d/mod.txt": EventChanged,
+		"/d/new.txt": EventChanged,
+		"/d/del.txt": EventRemoved,
+	}
+	if len(got) != len(want) {
+		t.Fatalf("events: %v", got)
+	}
+	for name, typ := range want {
+		if got[name] != typ {
+			t.Errorf("%s: got %s, want %s", name, got[name], typ)
+		}
+	}
+}
+
+func TestWatcher(t *testing.T) {
+	dir := t.TempDir()
+	err := os.WriteFile(filepath.Join(dir, "old.txt"), []byte("old"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	w, err := NewWatcher(dir, 200*time.Millisecond, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+
+	var (
+		stop   = make(chan struct{})
+		done   = make(chan struct{})
+		got    = map[string]EventType{}
+		events = make(chan *Event, 16)
+	)
+	go func() {
+		w.Run(stop, func(batch []*Event) {
+			for _, event := range batch {
+				events <- event
+			}
+		})
+		close(done)
+	}()
+
+	err = os.Mkdir(filepath.Join(dir, "sub"), 0755)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = os.WriteFile(filepath.Join(dir, "sub", "new.txt"), []byte("new"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = os.Remove(filepath.Join(dir, "old.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	timeout := time.After(5 * time.Second)
+	for len(got) < 2 {
+		select {
+		case event := <-events:
+			got[event.RelPath] = event.Type
+		case <-timeout:
+			t.Fatalf("timeout, events: %v", got)
+		}
+	}
+	close(stop)
+	<-done
+
+	if got["sub/new.txt"] != EventChanged {
+		t.Errorf("sub/new.txt: %s", got["sub/new.txt"])
+	}
+	if got["old.txt"] != EventRemoved {
+		t.Errorf("old.txt: %s", got["old.txt"])
+	}
+}
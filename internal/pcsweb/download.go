@@ -880,7 +880,7 @@ func getLocateDownloadLinks(pcspath string) (dlinks []*url.URL, err error) {
 	return urls, nil
 }
 
-func getLocatePanLink(pcs *baidupcs.BaiduPCS, fsID int64) (dlink string, err error) {
+func getLocatePanLink(pcs baidupcs.Client, fsID int64) (dlink string, err error) {
 	list, err := pcs.LocatePanAPIDownload(fsID)
 	if err != nil {
 		return
@@ -14,8 +14,10 @@ import (
 	"strings"
 
 	"github.com/Erope/BaiduPCS-Go/baidupcs"
+	"github.com/Erope/BaiduPCS-Go/baidupcs/pcserror"
 	"github.com/Erope/BaiduPCS-Go/internal/pcscommand"
 	"github.com/Erope/BaiduPCS-Go/internal/pcsconfig"
+	"github.com/Erope/BaiduPCS-Go/pcscore"
 	"github.com/Erope/BaiduPCS-Go/pcsutil/converter"
 	"github.com/Erope/BaiduPCS-Go/pcsverbose"
 )
@@ -175,12 +177,16 @@ func UserHandle(w http.ResponseWriter, r *http.Request) {
 }
 
 func QuotaHandle(w http.ResponseWriter, r *http.Request) {
-	quota, used, _ := pcsconfig.Config.ActiveUserBaiduPCS().QuotaInfo()
+	quotaInfo, err := pcscommand.GetSession().Quota()
+	if err != nil {
+		sendHttpErrorResponse(w, -1, err.Error())
+		return
+	}
 	quotaMsg := fmt.Sprintf("{\"quota\": \"%s\", \"used\": \"%s\", \"un_used\": \"%s\", \"percent\": %.2f}",
-		converter.ConvertFileSize(quota, 2),
-		converter.ConvertFileSize(used, 2),
-		converter.ConvertFileSize(quota-used, 2),
-		100*float64(used)/float64(quota))
+		converter.ConvertFileSize(quotaInfo.Quota, 2),
+		converter.ConvertFileSize(quotaInfo.Used, 2),
+		converter.ConvertFileSize(quotaInfo.Free(), 2),
+		quotaInfo.UsedPercent())
 	pcsCommandVerbose.Info(quotaMsg)
 	sendHttpResponse(w, "", quotaMsg)
 }
@@ -221,7 +227,7 @@ func OfflineDownloadHandle(w http.ResponseWriter, r *http.Request) {
 
 	switch method {
 	case "list":
-		cl, err := pcsconfig.Config.ActiveUserBaiduPCS().CloudDlListTask()
+		cl, err := pcscommand.GetSession().CloudDlListTask()
 		if err != nil {
 			sendHttpErrorResponse(w, -1, err.Error())
 			return
@@ -261,7 +267,9 @@ func SearchHandle(w http.ResponseWriter, r *http.Request) {
 	keyword := r.Form.Get("keyword")
 	pcsCommandVerbose.Info("搜索:" + tpath + " " + keyword)
 
-	files, err := pcsconfig.Config.ActiveUserBaiduPCS().Search(tpath, keyword, true)
+	files, err := pcscommand.GetSession().Search(tpath, keyword, &pcscore.SearchOptions{
+		Recurse: true,
+	})
 	if err != nil {
 		sendHttpErrorResponse(w, -1, err.Error())
 		return
@@ -275,7 +283,7 @@ func RecycleHandle(w http.ResponseWriter, r *http.Request) {
 	pcsCommandVerbose.Info(rmethod)
 
 	if rmethod == "list" {
-		recycle, err := pcsconfig.Config.ActiveUserBaiduPCS().RecycleList(1)
+		recycle, err := pcscommand.GetSession().RecycleList(1)
 		if err != nil {
 			sendHttpErrorResponse(w, -1, err.Error())
 			return
@@ -318,7 +326,7 @@ func ShareHandle(w http.ResponseWriter, r *http.Request) {
 	pcsCommandVerbose.Info(rmethod)
 
 	if rmethod == "list" {
-		records, err := pcsconfig.Config.ActiveUserBaiduPCS().ShareList(1)
+		records, err := pcscommand.GetSession().ShareList(1)
 		if err != nil {
 			sendHttpErrorResponse(w, -1, err.Error())
 			return
@@ -700,9 +708,9 @@ func FileOperationHandle(w http.ResponseWriter, r *http.Request) {
 	paths := strings.Split(rpaths, "|")
 	var err error
 	if rmethod == "copy" {
-		err = pcscommand.RunCopy(paths...)
+		err = pcscommand.RunCopy(baidupcs.OnDupDefault, paths...)
 	} else if rmethod == "move" {
-		err = pcscommand.RunMove(paths...)
+		err = pcscommand.RunMove(baidupcs.OnDupDefault, paths...)
 	} else if rmethod == "remove" {
 		err = pcscommand.RunRemove(paths...)
 	} else {
@@ -757,7 +765,21 @@ func fileList(w http.ResponseWriter, r *http.Request) {
 		orderOptions.By = baidupcs.OrderByName
 	}
 
-	dataReadCloser, err := pcsconfig.Config.ActiveUserBaiduPCS().PrepareFilesDirectoriesList(fpath, orderOptions)
+	var (
+		dataReadCloser io.ReadCloser
+		err            pcserror.Error
+	)
+	// 可选的分页参数, 避免目录过大
+	limit, _ := strconv.Atoi(r.Form.Get("limit"))
+	if limit > 0 {
+		start, _ := strconv.Atoi(r.Form.Get("start"))
+		if start < 0 {
+			start = 0
+		}
+		dataReadCloser, err = pcsconfig.Config.ActiveUserBaiduPCS().PrepareFilesDirectoriesListPage(fpath, orderOptions, start, limit)
+	} else {
+		dataReadCloser, err = pcsconfig.Config.ActiveUserBaiduPCS().PrepareFilesDirectoriesList(fpath, orderOptions)
+	}
 
 	w.Header().Set("content-type", "application/json")
 
@@ -6,6 +6,7 @@ import (
 	"html/template"
 	"net/http"
 
+	"github.com/Erope/BaiduPCS-Go/internal/pcscommand"
 	rice "github.com/GeertJohan/go.rice"
 	"golang.org/x/net/websocket"
 )
@@ -54,6 +55,12 @@ func StartServer(port uint, access bool) error {
 	http.HandleFunc("/bd/", bdHandle)
 
 	http.Handle("/ws", websocket.Handler(WSHandler))
+
+	// 恢复传输日志中未完成的任务, 在后台进行
+	if n := pcscommand.RestoreTransferJobs(true); n > 0 {
+		fmt.Printf("已恢复 %d 个未完成的传输任务\n", n)
+	}
+
 	if access {
 		return http.ListenAndServe(fmt.Sprintf(":%d", port), nil)
 	}
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"container/list"
 	"encoding/hex"
+	"errors"
 	"fmt"
 	"os"
 	"path"
@@ -65,7 +66,7 @@ func RunRapidUpload(targetPath, contentMD5, sliceMD5, crc32 string, length int64
 		fmt.Printf("警告: %s, 获取网盘路径 %s 错误, %s\n", baidupcs.OperationRapidUpload, targetPath, err)
 	}
 
-	err = pcscommand.GetBaiduPCS().RapidUpload(targetPath, contentMD5, sliceMD5, crc32, length)
+	err = pcscommand.GetBaiduPCS().RapidUploadWithOnDup(targetPath, baidupcs.OnDupDefault, contentMD5, sliceMD5, crc32, length)
 	if err != nil {
 		fmt.Printf("%s失败, 消息: %s\n", baidupcs.OperationRapidUpload, err)
 		return
@@ -82,7 +83,7 @@ func RunCreateSuperFile(targetPath string, blockList ...string) {
 		fmt.Printf("警告: %s, 获取网盘路径 %s 错误, %s\n", baidupcs.OperationUploadCreateSuperFile, targetPath, err)
 	}
 
-	err = pcscommand.GetBaiduPCS().UploadCreateSuperFile(targetPath, blockList...)
+	err = pcscommand.GetBaiduPCS().UploadCreateSuperFileWithOnDup(targetPath, baidupcs.OnDupDefault, blockList...)
 	if err != nil {
 		fmt.Printf("%s失败, 消息: %s\n", baidupcs.OperationUploadCreateSuperFile, err)
 		return
@@ -316,7 +317,7 @@ func RunUpload(conn *websocket.Conn, localPaths []string, savePath string, opt *
 					}
 				}
 
-				pcsError = pcs.RapidUpload(task.savePath, hex.EncodeToString(task.localFileChecksum.MD5), hex.EncodeToString(task.localFileChecksum.SliceMD5), fmt.Sprint(task.localFileChecksum.CRC32), task.localFileChecksum.Length)
+				pcsError = pcs.RapidUploadWithOnDup(task.savePath, baidupcs.OnDupDefault, hex.EncodeToString(task.localFileChecksum.MD5), hex.EncodeToString(task.localFileChecksum.SliceMD5), fmt.Sprint(task.localFileChecksum.CRC32), task.localFileChecksum.Length)
 				if pcsError == nil {
 					fmt.Printf("[%d] 秒传成功, 保存到网盘路径: %s\n\n", task.ID, task.savePath)
 					MsgBody = fmt.Sprintf("{\"LastID\": %d, \"savePath\": \"%s\"}", task.ID, task.savePath)
@@ -326,14 +327,9 @@ func RunUpload(conn *websocket.Conn, localPaths []string, savePath string, opt *
 				}
 
 				// 判断配额是否已满
-				switch pcsError.GetErrType() {
-				// 远程服务器错误
-				case pcserror.ErrTypeRemoteError:
-					switch pcsError.GetRemoteErrCode() {
-					case 31112: //exceed quota
-						fmt.Printf("[%d] 秒传失败, 超出配额, 网盘容量已满\n\n", task.ID)
-						return
-					}
+				if errors.Is(pcsError, pcserror.ErrQuotaExceeded) {
+					fmt.Printf("[%d] 秒传失败, 超出配额, 网盘容量已满\n\n", task.ID)
+					return
 				}
 			}
 
@@ -354,7 +350,7 @@ func RunUpload(conn *websocket.Conn, localPaths []string, savePath string, opt *
 					blockSize = getBlockSize(task.localFileChecksum.Length)
 				}
 
-				muer := uploader.NewMultiUploader(pcsupload.NewPCSUpload(pcs, task.savePath), rio.NewFileReaderAtLen64(task.localFileChecksum.GetFile()), &uploader.MultiUploaderConfig{
+				muer := uploader.NewMultiUploader(pcsupload.NewPCSUpload(pcs, task.savePath, baidupcs.OnDupDefault), rio.NewFileReaderAtLen64(task.localFileChecksum.GetFile()), &uploader.MultiUploaderConfig{
 					Parallel:  opt.Parallel,
 					BlockSize: blockSize,
 					MaxRate:   pcsconfig.Config.MaxUploadRate,
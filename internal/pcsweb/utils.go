@@ -44,33 +44,16 @@ func (fds FileDescs) Swap(i, j int) {
 
 
 func matchPathByShellPatternOnce(pattern *string) error {
-	paths, err := pcscommand.GetBaiduPCS().MatchPathByShellPattern(pcscommand.GetActiveUser().PathJoin(*pattern))
+	pcspath, err := pcscommand.GetSession().MatchPathOnce(*pattern)
 	if err != nil {
 		return err
 	}
-	switch len(paths) {
-	case 0:
-		return pcscommand.ErrShellPatternNoHit
-	case 1:
-		*pattern = paths[0]
-	default:
-		return pcscommand.ErrShellPatternMultiRes
-	}
-
+	*pattern = pcspath
 	return nil
 }
 
 func matchPathByShellPattern(patterns ...string) (pcspaths []string, err error) {
-	acUser, pcs := pcscommand.GetActiveUser(), pcscommand.GetBaiduPCS()
-	for k := range patterns {
-		ps, err := pcs.MatchPathByShellPattern(acUser.PathJoin(patterns[k]))
-		if err != nil {
-			return nil, err
-		}
-
-		pcspaths = append(pcspaths, ps...)
-	}
-	return pcspaths, nil
+	return pcscommand.GetSession().MatchPath(patterns...)
 }
 
 // boxTmplParse ricebox 载入文件内容, 并进行模板解析
@@ -1,24 +1,29 @@
 package main
 
 import (
+	"context"
 	"encoding/hex"
 	"fmt"
 	"os"
 	"os/exec"
+	"os/signal"
 	"path/filepath"
 	"runtime"
 	"strconv"
+	"time"
 
 	"github.com/Erope/BaiduPCS-Go/baidupcs"
 	"github.com/Erope/BaiduPCS-Go/internal/pcscommand"
 	"github.com/Erope/BaiduPCS-Go/internal/pcsconfig"
 	_ "github.com/Erope/BaiduPCS-Go/internal/pcsinit"
+	"github.com/Erope/BaiduPCS-Go/internal/pcsupdate"
 	"github.com/Erope/BaiduPCS-Go/internal/pcsweb"
 	"github.com/Erope/BaiduPCS-Go/pcstable"
 	"github.com/Erope/BaiduPCS-Go/pcsutil"
 	"github.com/Erope/BaiduPCS-Go/pcsutil/checksum"
 	"github.com/Erope/BaiduPCS-Go/pcsutil/converter"
 	"github.com/Erope/BaiduPCS-Go/pcsverbose"
+	"github.com/Erope/BaiduPCS-Go/requester/transfer"
 	"github.com/olekukonko/tablewriter"
 	"github.com/urfave/cli"
 )
@@ -61,6 +66,109 @@ var (
 		}
 		return nil
 	}
+	// parseOnDup 解析 -ondup 选项, 解析失败时输出错误
+	parseOnDup = func(c *cli.Context) (ondup baidupcs.OnDup, ok bool) {
+		ondup, err := baidupcs.ParseOnDup(c.String("ondup"))
+		if err != nil {
+			fmt.Println(err)
+			return baidupcs.OnDupDefault, false
+		}
+		return ondup, true
+	}
+	// parseProgress 解析 -progress 和 -progress-fd 选项, 解析失败时输出错误
+	parseProgress = func(c *cli.Context) (handler transfer.ProgressHandler, ok bool) {
+		handler, err := pcscommand.NewProgressHandler(c.String("progress"), c.Int("progress-fd"))
+		if err != nil {
+			fmt.Println(err)
+			return nil, false
+		}
+		return handler, true
+	}
+	progressFlags = []cli.Flag{
+		cli.StringFlag{
+			Name:  "progress",
+			Usage: "传输进度的输出格式, 可选: text, ndjson. ndjson 为每行一个 json 传输事件, 供脚本解析",
+			Value: pcscommand.ProgressText,
+		},
+		cli.IntFlag{
+			Name:  "progress-fd",
+			Usage: "ndjson 传输事件写入的文件描述符, 默认为标准错误",
+			Value: 2,
+		},
+	}
+	// parseBgTaskID 解析后台任务 id, 解析失败时输出错误
+	parseBgTaskID = func(c *cli.Context) (id int64, ok bool) {
+		if c.NArg() != 1 {
+			cli.ShowCommandHelp(c, c.Command.Name)
+			return 0, false
+		}
+		id, err := strconv.ParseInt(c.Args().Get(0), 10, 64)
+		if err != nil {
+			fmt.Printf("后台任务 id 不合法: %s\n", c.Args().Get(0))
+			return 0, false
+		}
+		return id, true
+	}
+	// newBgTaskCommand 后台任务的子命令, op 见 pcscommand.RunBgTaskCommand
+	newBgTaskCommand = func(op, usage string) cli.Command {
+		return cli.Command{
+			Name:      op,
+			Usage:     usage,
+			UsageText: "BaiduPCS-Go bg " + op + " <task_id>",
+			Action: func(c *cli.Context) error {
+				id, ok := parseBgTaskID(c)
+				if !ok {
+					return nil
+				}
+				pcscommand.RunBgTaskCommand(op, id)
+				return nil
+			},
+		}
+	}
+	// parseCloudDlTaskIDs 解析离线下载任务ID, 解析失败时输出错误
+	parseCloudDlTaskIDs = func(c *cli.Context) (taskIDs []int64, ok bool) {
+		if c.NArg() < 1 {
+			cli.ShowCommandHelp(c, c.Command.Name)
+			return nil, false
+		}
+		taskIDs = make([]int64, 0, c.NArg())
+		for _, arg := range c.Args() {
+			id, err := strconv.ParseInt(arg, 10, 64)
+			if err != nil {
+				fmt.Printf("任务ID不合法: %s\n", arg)
+				return nil, false
+			}
+			taskIDs = append(taskIDs, id)
+		}
+		return taskIDs, true
+	}
+	cloudDlWaitFlag = cli.BoolFlag{
+		Name:  "wait",
+		Usage: "等待离线下载任务全部结束, 并输出结果",
+	}
+	cloudDlIntervalFlag = cli.DurationFlag{
+		Name:  "interval",
+		Usage: "等待时查询任务状态的间隔",
+		Value: 5 * time.Second,
+	}
+	cloudDlTimeoutFlag = cli.DurationFlag{
+		Name:  "timeout",
+		Usage: "最长等待时间, 超时后停止等待, 任务仍会继续, 默认不限制, 也可按 Ctrl+C 停止等待",
+	}
+	// checkFsIDs 检查 fs_id 是否合法, 不合法时输出错误
+	checkFsIDs = func(c *cli.Context) bool {
+		if c.NArg() < 1 {
+			cli.ShowCommandHelp(c, c.Command.Name)
+			return false
+		}
+		for _, arg := range c.Args() {
+			if _, err := strconv.ParseInt(arg, 10, 64); err != nil {
+				fmt.Printf("fs_id 不合法: %s\n", arg)
+				return false
+			}
+		}
+		return true
+	}
 	isCli bool
 )
 
@@ -137,8 +245,42 @@ func main() {
 			Value:       "",
 			Destination: &pcsweb.PD_Url,
 		},
+		cli.StringFlag{
+			Name:   "output",
+			Usage:  "命令结果的输出格式, 可选: table, json, ndjson, csv, 支持 ls, search, meta, tree, quota, share list, who, loglist 等命令",
+			Value:  string(pcscommand.OutputTable),
+			EnvVar: pcscommand.EnvOutput,
+		},
+		cli.BoolFlag{
+			Name:  "json",
+			Usage: "同 --output=json",
+		},
+		cli.BoolFlag{
+			Name:  "csv",
+			Usage: "同 --output=csv",
+		},
+	}
+	app.Before = func(c *cli.Context) error {
+		output := c.GlobalString("output")
+		switch {
+		case c.GlobalBool("json"):
+			output = string(pcscommand.OutputJSON)
+		case c.GlobalBool("csv"):
+			output = string(pcscommand.OutputCSV)
+		}
+		err := pcscommand.SetOutputFormat(output)
+		if err != nil {
+			fmt.Println(err)
+			return err
+		}
+		return nil
 	}
 	app.Action = func(c *cli.Context) {
+		if c.NArg() != 0 {
+			fmt.Printf("未找到命令: %s\n运行命令 %s help 获取帮助\n", c.Args().Get(0), app.Name)
+			return
+		}
+
 		if pcsweb.Aria2 {
 			fmt.Printf("已经启用Aria2下载，停用默认下载，下载列表会为空，仍在开发中，可能不稳定\n")
 		}
@@ -185,13 +327,76 @@ func main() {
 				},
 			},
 		},
+		{
+			Name:      "shell",
+			Aliases:   []string{"sh"},
+			Usage:     "进入交互模式",
+			UsageText: app.Name + " shell",
+			Description: `
+	进入交互式命令行, 在交互模式中可直接输入命令, 无需输入程序名.
+	支持历史命令, 历史命令保存在配置目录下的 pcs_command_history.txt.
+	Tab 键可自动补全命令, 子命令, 参数和网盘路径, 网盘的相对路径基于当前工作目录.
+	交互模式中, 可使用 download -bg 在后台下载, 后台任务在退出交互模式前会一直进行.
+	输入 exit 或按 Ctrl+C, Ctrl+D 退出, 输入 clear 清空屏幕.
+
+	例子:
+		BaiduPCS-Go shell
+`,
+			Category: "其他",
+			Before:   reloadFn,
+			Action: func(c *cli.Context) error {
+				if isCli {
+					fmt.Printf("已在交互模式中\n")
+					return nil
+				}
+				runShell(c)
+				return nil
+			},
+		},
+		{
+			Name:      "emulate",
+			Usage:     "启动本地网盘模拟器, 用于离线测试",
+			UsageText: app.Name + " emulate [arguments...]",
+			Description: `
+	将本地目录模拟为网盘, 提供 PCS/网盘首页 api,
+	支持 列目录, 元信息, 创建目录, 删除, 拷贝/移动, 上传, 秒传, 下载, 分享, 回收站.
+	模拟器的内部数据储存在根目录下的 .pcsemu 目录.
+
+	例子:
+		BaiduPCS-Go emulate -root /tmp/pcsemu
+		BaiduPCS-Go config set -pcs_addr=http://127.0.0.1:8085 -pan_addr=http://127.0.0.1:8085 -enable_https=false
+`,
+			Category: "其他",
+			Hidden:   true,
+			Action: func(c *cli.Context) error {
+				err := pcscommand.RunEmulate(c.String("addr"), c.String("root"))
+				if err != nil {
+					fmt.Println(err)
+				}
+				return nil
+			},
+			Flags: []cli.Flag{
+				cli.StringFlag{
+					Name:  "addr",
+					Usage: "监听地址",
+					Value: "127.0.0.1:8085",
+				},
+				cli.StringFlag{
+					Name:  "root",
+					Usage: "模拟的网盘根目录",
+					Value: "pcsemu",
+				},
+			},
+		},
 		{
 			Name:     "env",
 			Usage:    "显示程序环境变量",
 			Category: "其他",
 			Description: `
 				BAIDUPCS_GO_CONFIG_DIR: 配置文件路径,
-				BAIDUPCS_GO_VERBOSE: 是否启用调试.
+				BAIDUPCS_GO_VERBOSE: 是否启用调试,
+				BAIDUPCS_GO_OUTPUT: 命令结果的输出格式,
+				BAIDUPCS_GO_ENC_KEY: 客户端加密的密钥或密码, 优先于配置 enc_key.
 			`,
 			Action: func(c *cli.Context) error {
 				envStr := "%s=\"%s\"\n"
@@ -209,6 +414,20 @@ func main() {
 					fmt.Printf(envStr, pcsconfig.EnvConfigDir, pcsconfig.GetConfigDir())
 				}
 
+				envVar, ok = os.LookupEnv(pcscommand.EnvOutput)
+				if ok {
+					fmt.Printf(envStr, pcscommand.EnvOutput, envVar)
+				} else {
+					fmt.Printf(envStr, pcscommand.EnvOutput, pcscommand.OutputTable)
+				}
+
+				// 不显示密钥的内容
+				if _, ok = os.LookupEnv(pcsconfig.EnvEncryptKey); ok {
+					fmt.Printf(envStr, pcsconfig.EnvEncryptKey, "******")
+				} else {
+					fmt.Printf(envStr, pcsconfig.EnvEncryptKey, "")
+				}
+
 				return nil
 			},
 		},
@@ -403,7 +622,7 @@ func main() {
 			Category:    "百度帐号",
 			Before:      reloadFn,
 			Action: func(c *cli.Context) error {
-				fmt.Println(pcsconfig.Config.BaiduUserList.String())
+				pcscommand.RunLoglist()
 				return nil
 			},
 		},
@@ -414,8 +633,7 @@ func main() {
 			Category:    "百度帐号",
 			Before:      reloadFn,
 			Action: func(c *cli.Context) error {
-				activeUser := pcsconfig.Config.ActiveUser()
-				fmt.Printf("当前帐号 uid: %d, 用户名: %s, 性别: %s, 年龄: %.1f\n", activeUser.UID, activeUser.Name, activeUser.Sex, activeUser.Age)
+				pcscommand.RunWho()
 				return nil
 			},
 		},
@@ -558,6 +776,9 @@ func main() {
 				BaiduPCS-Go search 关键字
 				递归搜索当前工作目录的文件
 				BaiduPCS-Go search -r 关键字
+
+				遍历目录搜索, 可以查找目录, 关键字支持通配符, 不受服务器搜索结果数量的限制
+				BaiduPCS-Go search -walk -r "*.mp4"
 			`,
 			Category: "百度网盘",
 			Before:   reloadFn,
@@ -570,6 +791,7 @@ func main() {
 				pcscommand.RunSearch(c.String("path"), c.Args().Get(0), &pcscommand.SearchOptions{
 					Total:   c.Bool("l"),
 					Recurse: c.Bool("r"),
+					Walk:    c.Bool("walk"),
 				})
 
 				return nil
@@ -583,6 +805,10 @@ func main() {
 					Name:  "r",
 					Usage: "递归搜索",
 				},
+				cli.BoolFlag{
+					Name:  "walk",
+					Usage: "遍历目录进行搜索, 按 walk_parallel 并发",
+				},
 				cli.StringFlag{
 					Name:  "path",
 					Usage: "需要检索的目录",
@@ -640,7 +866,7 @@ func main() {
 			Usage:     "删除文件/目录",
 			UsageText: app.Name + " rm <文件/目录的路径1> <文件/目录2> <文件/目录3> ...",
 			Description: `
-				注意: 删除多个文件和目录时, 请确保每一个文件和目录都存在, 否则删除操作会失败.
+				删除多个文件和目录时, 不存在的文件或目录会删除失败, 不影响其他文件和目录.
 				被删除的文件或目录可在网盘文件回收站找回.
 				示例:
 				删除 /我的资源/1.mp4
@@ -686,12 +912,15 @@ func main() {
 			UsageText: `BaiduPCS-Go cp <文件/目录> <目标文件/目录>
 				BaiduPCS-Go cp <文件/目录1> <文件/目录2> <文件/目录3> ... <目标目录>`,
 			Description: `
-				注意: 拷贝多个文件和目录时, 请确保每一个文件和目录都存在, 否则拷贝操作会失败.
+				拷贝多个文件和目录时, 不存在的文件或目录会拷贝失败, 不影响其他文件和目录.
+				目标已存在时默认拷贝失败, 可通过 -ondup 指定处理方式.
 				示例:
 				将 /我的资源/1.mp4 复制到 根目录 /
 				BaiduPCS-Go cp /我的资源/1.mp4 /
 				将 /我的资源/1.mp4 和 /我的资源/2.mp4 复制到 根目录 /
 				BaiduPCS-Go cp /我的资源/1.mp4 /我的资源/2.mp4 /
+				将 /我的资源 内的所有文件和目录复制到 /备份, 跳过 /备份 中已存在的
+				BaiduPCS-Go cp -ondup=skip /我的资源/* /备份
 			`,
 			Category: "百度网盘",
 			Before:   reloadFn,
@@ -701,9 +930,21 @@ func main() {
 					return nil
 				}
 
-				pcscommand.RunCopy(c.Args()...)
+				ondup, ok := parseOnDup(c)
+				if !ok {
+					return nil
+				}
+
+				pcscommand.RunCopy(ondup, c.Args()...)
 				return nil
 			},
+			Flags: []cli.Flag{
+				cli.StringFlag{
+					Name:  "ondup",
+					Usage: "目标已存在时的处理方式, 可选: overwrite 覆盖, skip 跳过, newcopy 生成副本并重命名, fail 失败",
+					Value: "fail",
+				},
+			},
 		},
 		{
 			Name:  "mv",
@@ -713,12 +954,15 @@ func main() {
 				重命名:
 				BaiduPCS-Go mv <文件/目录> <重命名的文件/目录>`,
 			Description: `
-				注意: 移动多个文件和目录时, 请确保每一个文件和目录都存在, 否则移动操作会失败.
+				移动多个文件和目录时, 不存在的文件或目录会移动失败, 不影响其他文件和目录.
+				目标已存在时默认移动失败, 可通过 -ondup 指定处理方式.
 				示例:
 				将 /我的资源/1.mp4 移动到 根目录 /
 				BaiduPCS-Go mv /我的资源/1.mp4 /
 				将 /我的资源/1.mp4 重命名为 /我的资源/3.mp4
 				BaiduPCS-Go mv /我的资源/1.mp4 /我的资源/3.mp4
+				将 /我的资源/1.mp4 移动到 根目录 /, 覆盖已存在的 /1.mp4
+				BaiduPCS-Go mv -ondup=overwrite /我的资源/1.mp4 /
 			`,
 			Category: "百度网盘",
 			Before:   reloadFn,
@@ -728,9 +972,21 @@ func main() {
 					return nil
 				}
 
-				pcscommand.RunMove(c.Args()...)
+				ondup, ok := parseOnDup(c)
+				if !ok {
+					return nil
+				}
+
+				pcscommand.RunMove(ondup, c.Args()...)
 				return nil
 			},
+			Flags: []cli.Flag{
+				cli.StringFlag{
+					Name:  "ondup",
+					Usage: "目标已存在时的处理方式, 可选: overwrite 覆盖, skip 跳过, newcopy 生成副本并重命名, fail 失败",
+					Value: "fail",
+				},
+			},
 		},
 		{
 			Name:      "download",
@@ -757,6 +1013,8 @@ func main() {
 				下载网盘内的全部文件!!
 				BaiduPCS-Go d /
 				BaiduPCS-Go d *
+				下载并解密使用 upload -encrypt 上传的 /backup 目录
+				BaiduPCS-Go d -decrypt /backup
 			`,
 			Category: "百度网盘",
 			Before:   reloadFn,
@@ -766,6 +1024,11 @@ func main() {
 					return nil
 				}
 
+				progress, ok := parseProgress(c)
+				if !ok {
+					return nil
+				}
+
 				var (
 					saveTo string
 				)
@@ -790,6 +1053,8 @@ func main() {
 					Load:                   c.Int("l"),
 					MaxRetry:               c.Int("retry"),
 					NoCheck:                c.Bool("nocheck"),
+					Decrypt:                c.Bool("decrypt"),
+					Progress:               progress,
 				}
 
 				if c.Bool("bg") && isCli {
@@ -800,7 +1065,7 @@ func main() {
 
 				return nil
 			},
-			Flags: []cli.Flag{
+			Flags: append([]cli.Flag{
 				cli.BoolFlag{
 					Name:  "test",
 					Usage: "测试下载, 此操作不会保存文件到本地",
@@ -858,36 +1123,249 @@ func main() {
 					Name:  "nocheck",
 					Usage: "下载文件完成后不校验文件",
 				},
+				cli.BoolFlag{
+					Name:  "decrypt",
+					Usage: "解密使用 upload -encrypt 上传的文件和文件名, 未加密的文件按原样下载, 不支持断点续传",
+				},
 				cli.BoolFlag{
 					Name:  "bg",
-					Usage: "加入后台下载",
+					Usage: "加入后台下载, 仅在交互模式中有效, 使用 bg 命令管理",
+				},
+			}, progressFlags...),
+		},
+		{
+			Name:      "cat",
+			Usage:     "输出文件的内容到标准输出",
+			UsageText: app.Name + " cat [-range=<start-end>] [-decrypt] <文件路径>",
+			Description: `
+	多线程下载文件, 按顺序输出到标准输出, 不保存到本地, 可通过管道交给其他程序处理.
+	提示信息和错误输出到标准错误.
+
+	示例:
+
+	恢复数据库备份
+	BaiduPCS-Go cat /backups/db.sql.gz | gunzip | psql
+
+	查看文件开头的 1KB
+	BaiduPCS-Go cat -range=0-1023 /我的资源/1.txt
+
+	查看文件最后的 1KB
+	BaiduPCS-Go cat -range=-1024 /我的资源/1.txt
+
+	输出从 1MB 开始的全部内容
+	BaiduPCS-Go cat -range=1048576- /我的资源/1.mp4
+
+	解密 upload -encrypt 上传的文件, 范围为解密后的范围, 只下载范围所在的加密分块
+	BaiduPCS-Go cat -decrypt -range=0-1023 /backup/secret.txt
+`,
+			Category: "百度网盘",
+			Before:   reloadFn,
+			Action: func(c *cli.Context) error {
+				if c.NArg() != 1 {
+					cli.ShowCommandHelp(c, c.Command.Name)
+					return nil
+				}
+
+				// 收到中断信号时中断输出
+				ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+				defer stop()
+
+				err := pcscommand.RunCat(c.Args().Get(0), &pcscommand.CatOptions{
+					Range:    c.String("range"),
+					Decrypt:  c.Bool("decrypt"),
+					Parallel: c.Int("p"),
+					MaxRetry: c.Int("retry"),
+					Context:  ctx,
+				})
+				if err != nil {
+					// 非交互模式返回非零的退出状态, 使管道中的其他程序可以察觉
+					if isCli {
+						fmt.Fprintln(os.Stderr, err)
+						return nil
+					}
+					return cli.NewExitError(err, 1)
+				}
+				return nil
+			},
+			Flags: []cli.Flag{
+				cli.StringFlag{
+					Name:  "range",
+					Usage: "输出的范围, 结束位置包含在内, 如 0-1023, 1024-, -1024",
+				},
+				cli.BoolFlag{
+					Name:  "decrypt",
+					Usage: "解密客户端加密的文件, 密钥通过 config set -enc_key 或环境变量 BAIDUPCS_GO_ENC_KEY 设置",
+				},
+				cli.IntFlag{
+					Name:  "p",
+					Usage: "指定下载线程数",
+				},
+				cli.IntFlag{
+					Name:  "retry",
+					Usage: "下载失败最大重试次数",
+					Value: pcscommand.DefaultDownloadMaxRetry,
 				},
 			},
 		},
 		{
-			Name:  "bg",
-			Usage: "管理后台任务",
+			Name:      "bg",
+			Usage:     "管理后台任务",
+			UsageText: app.Name + " bg <子命令> <task_id>",
 			Description: `
-				默认关闭下载中任何向终端的输出
-				再后台进行文件下载，不会影响用户继续在客户端操作
-				可以同时进行多个任务
-				示例:
-				显示所有后台任务
-				BaiduPCS-Go bg
-			`,
+	在交互模式中, 使用 download -bg 或 upload -bg 加入后台任务,
+	后台任务不会向终端输出, 可以同时进行多个任务, 不影响用户继续在客户端操作.
+	暂停时, 正在上传的分片会继续上传完成. 取消后, 已下载或上传的部分可断点续传.
+
+	示例:
+
+	显示所有后台任务
+	BaiduPCS-Go bg
+
+	查看后台任务 1 的每个文件的进度
+	BaiduPCS-Go bg status 1
+
+	暂停, 恢复, 取消后台任务 1
+	BaiduPCS-Go bg pause 1
+	BaiduPCS-Go bg resume 1
+	BaiduPCS-Go bg cancel 1
+
+	查看后台任务 1 的输出
+	BaiduPCS-Go bg logs 1
+
+	将后台任务 1 的输出附加到终端, 按 Ctrl+C 返回, 任务继续在后台进行
+	BaiduPCS-Go bg fg 1
+`,
+			Category: "其他",
+			Before:   reloadFn,
+			Action: func(c *cli.Context) error {
+				if c.NArg() != 0 {
+					fmt.Printf("未找到子命令: %s\n", c.Args().Get(0))
+					return nil
+				}
+				pcscommand.BgMap.PrintAllBgTask()
+				return nil
+			},
+			Subcommands: []cli.Command{
+				{
+					Name:  "list",
+					Usage: "显示所有后台任务",
+					Action: func(c *cli.Context) error {
+						pcscommand.BgMap.PrintAllBgTask()
+						return nil
+					},
+				},
+				newBgTaskCommand("status", "查看后台任务中每个文件的进度"),
+				newBgTaskCommand("pause", "暂停后台任务"),
+				newBgTaskCommand("resume", "恢复后台任务"),
+				newBgTaskCommand("cancel", "取消后台任务"),
+				newBgTaskCommand("logs", "查看后台任务的输出"),
+				{
+					Name:      "fg",
+					Usage:     "将后台任务的输出附加到终端, 按 Ctrl+C 返回",
+					UsageText: app.Name + " bg fg <task_id>",
+					Action: func(c *cli.Context) error {
+						id, ok := parseBgTaskID(c)
+						if !ok {
+							return nil
+						}
+						pcscommand.RunBgFg(id)
+						return nil
+					},
+				},
+				{
+					Name:  "clean",
+					Usage: "删除已结束和已取消的后台任务",
+					Action: func(c *cli.Context) error {
+						fmt.Printf("已删除 %d 个后台任务\n", pcscommand.BgMap.Clean())
+						return nil
+					},
+				},
+			},
+		},
+		{
+			Name:      "fg",
+			Usage:     "将后台任务的输出附加到终端",
+			UsageText: app.Name + " fg <task_id>",
+			Description: `
+	同 bg fg <task_id>, 按 Ctrl+C 返回, 任务继续在后台进行.
+`,
+			Category: "其他",
+			Before:   reloadFn,
+			Action: func(c *cli.Context) error {
+				id, ok := parseBgTaskID(c)
+				if !ok {
+					return nil
+				}
+				pcscommand.RunBgFg(id)
+				return nil
+			},
+		},
+		{
+			Name:      "resume",
+			Usage:     "恢复未完成的下载和上传任务",
+			UsageText: app.Name + " resume [-bg] [-d] <id1> <id2> ...",
+			Description: `
+	下载和上传任务会记录到配置目录下的传输日志中, 全部文件完成后才会移除.
+	进程被结束, 或按下 Ctrl+C 中断后, 可使用此命令恢复任务, 已完成的文件会被跳过, 未完成的文件会断点续传.
+	启动 web 服务时, 会自动在后台恢复当前帐号未完成的任务.
+
+	示例:
+
+	列出未完成的任务
+	BaiduPCS-Go resume
+
+	恢复任务 1 和 2
+	BaiduPCS-Go resume 1 2
+
+	恢复当前帐号所有未完成的任务
+	BaiduPCS-Go resume -all
+
+	删除任务 1, 不再恢复
+	BaiduPCS-Go resume -d 1
+`,
 			Category: "其他",
 			Before:   reloadFn,
 			Action: func(c *cli.Context) error {
+				if c.Bool("all") {
+					n := pcscommand.RestoreTransferJobs(c.Bool("bg") && isCli)
+					fmt.Printf("已恢复 %d 个未完成的传输任务\n", n)
+					return nil
+				}
+
 				if c.NArg() == 0 {
-					pcscommand.BgMap.PrintAllBgTask()
+					pcscommand.RunResumeList()
+					return nil
+				}
+
+				ids := make([]int64, 0, c.NArg())
+				for _, arg := range c.Args() {
+					id, err := strconv.ParseInt(arg, 10, 64)
+					if err != nil {
+						fmt.Printf("传输任务 id 不合法: %s\n", arg)
+						return nil
+					}
+					ids = append(ids, id)
+				}
+
+				if c.Bool("d") {
+					pcscommand.RunResumeDelete(ids)
 					return nil
 				}
+				pcscommand.RunResume(ids, c.Bool("bg") && isCli)
 				return nil
 			},
 			Flags: []cli.Flag{
 				cli.BoolFlag{
-					Name:  "test",
-					Usage: "测试下载, 此操作不会保存文件到本地",
+					Name:  "all",
+					Usage: "恢复当前帐号所有未完成的任务",
+				},
+				cli.BoolFlag{
+					Name:  "bg",
+					Usage: "加入后台任务, 仅在交互模式中有效, 使用 bg 命令管理",
+				},
+				cli.BoolFlag{
+					Name:  "d",
+					Usage: "从传输日志中删除任务, 不再恢复",
 				},
 			},
 		},
@@ -898,7 +1376,7 @@ func main() {
 			UsageText: app.Name + " upload <本地文件/目录的路径1> <文件/目录2> <文件/目录3> ... <目标目录>",
 			Description: `
 				上传默认采用分片上传的方式, 上传的文件将会保存到, <目标目录>.
-				遇到同名文件将会自动覆盖!!
+				遇到同名文件默认自动覆盖!! 可通过 -ondup 指定处理方式, 如 -ondup=skip 跳过已存在的文件.
 				当上传的文件名和网盘的目录名称相同时, 不会覆盖目录, 防止丢失数据.
 				注意: 
 				分片上传之后, 服务器可能会记录到错误的文件md5, 可使用 fixmd5 命令尝试修复文件的MD5值, 修复md5不一定能成功, 但文件的完整性是没问题的.
@@ -916,6 +1394,19 @@ func main() {
 				BaiduPCS-Go upload C:/Users/Administrator/Desktop /视频
 				4. 使用相对路径
 				BaiduPCS-Go upload 1.mp4 /视频
+				5. 监视本地目录 /data/artifacts, 新建或修改的文件保持 10 秒不变后自动上传到网盘 /artifacts 目录, 本地删除的文件同时移动到网盘回收站
+				BaiduPCS-Go upload -watch -stable 10s -delete /data/artifacts /artifacts
+				监视目录时, 启动前已存在的文件不会上传, 可先使用 sync 命令同步.
+				6. 从标准输入上传, 保存为网盘文件 /backup/etc.tar.gz, 本地路径为 "-" 时, 目标路径需为文件路径
+				tar cz /etc | BaiduPCS-Go upload - /backup/etc.tar.gz
+				从标准输入上传时, 数据按 -stream-blocksize 分块读入内存并上传, 不保存到临时文件, 内存占用约为 (线程数+1) * 分块大小,
+				分块最多 1024 个, 默认分块大小 32MB 可上传约 32GB 的数据. 不支持断点续传.
+				7. 在本地加密后上传, 并加密文件名, 使用 download -decrypt 下载并解密
+				BaiduPCS-Go config set -enc_key "<密钥或密码>"
+				BaiduPCS-Go upload -encrypt -encrypt-name /data/secret /backup
+				密钥也可以通过环境变量 BAIDUPCS_GO_ENC_KEY 指定, 64 位十六进制字符串作为原始密钥, 其他作为密码.
+				使用密码时, 与配置中的随机密码盐 enc_salt 一起派生密钥, 在其他设备解密时需设置相同的 enc_salt.
+				加密上传不使用秒传和断点续传, 请妥善保管密钥, 丢失后无法解密.
 			`,
 			Category: "百度网盘",
 			Before:   reloadFn,
@@ -925,16 +1416,86 @@ func main() {
 					return nil
 				}
 
-				subArgs := c.Args()
-				pcscommand.RunUpload(subArgs[:c.NArg()-1], subArgs[c.NArg()-1], &pcscommand.UploadOptions{
-					Parallel:       c.Int("p"),
-					MaxRetry:       c.Int("retry"),
-					NotRapidUpload: c.Bool("norapid"),
-					NotSplitFile:   c.Bool("nosplit"),
-				})
+				ondup, ok := parseOnDup(c)
+				if !ok {
+					return nil
+				}
+				progress, ok := parseProgress(c)
+				if !ok {
+					return nil
+				}
+
+				var (
+					subArgs = c.Args()
+					opt     = &pcscommand.UploadOptions{
+						Parallel:       c.Int("p"),
+						MaxRetry:       c.Int("retry"),
+						NotRapidUpload: c.Bool("norapid"),
+						NotSplitFile:   c.Bool("nosplit"),
+						OnDup:          ondup,
+						Encrypt:        c.Bool("encrypt"),
+						EncryptName:    c.Bool("encrypt-name"),
+						Progress:       progress,
+					}
+				)
+
+				if c.Args().Get(0) == pcscommand.StdinPath {
+					if c.NArg() != 2 {
+						fmt.Println("从标准输入上传时, 只能指定一个目标文件路径")
+						return nil
+					}
+					if isCli {
+						fmt.Println("交互模式中不支持从标准输入上传")
+						return nil
+					}
+					if s := c.String("stream-blocksize"); s != "" {
+						blockSize, err := converter.ParseFileSizeStr(s)
+						if err != nil || blockSize < baidupcs.MinUploadBlockSize || blockSize > baidupcs.MaxUploadBlockSize {
+							fmt.Printf("分块大小不合法: %s, 范围: %s ~ %s\n", s, converter.ConvertFileSize(baidupcs.MinUploadBlockSize), converter.ConvertFileSize(baidupcs.MaxUploadBlockSize))
+							return nil
+						}
+						opt.StreamBlockSize = blockSize
+					}
+				}
+
+				if c.Bool("watch") {
+					if c.NArg() != 2 {
+						fmt.Println("监视目录上传时, 只能指定一个本地目录和一个目标目录")
+						return nil
+					}
+					watchOpt := &pcscommand.WatchOptions{
+						Stable:  c.Duration("stable"),
+						Delete:  c.Bool("delete"),
+						Include: c.StringSlice("include"),
+						Exclude: c.StringSlice("exclude"),
+						Upload:  opt,
+					}
+					if c.Bool("bg") && isCli {
+						pcscommand.RunBgUploadWatch(subArgs[0], subArgs[1], watchOpt)
+					} else {
+						pcscommand.RunUploadWatch(subArgs[0], subArgs[1], watchOpt)
+					}
+					return nil
+				}
+				if c.Bool("delete") {
+					fmt.Println("-delete 需要和 -watch 一起使用")
+					return nil
+				}
+
+				if c.Bool("bg") && isCli {
+					pcscommand.RunBgUpload(subArgs[:c.NArg()-1], subArgs[c.NArg()-1], opt)
+				} else {
+					pcscommand.RunUpload(subArgs[:c.NArg()-1], subArgs[c.NArg()-1], opt)
+				}
 				return nil
 			},
-			Flags: []cli.Flag{
+			Flags: append([]cli.Flag{
+				cli.StringFlag{
+					Name:  "ondup",
+					Usage: "目标已存在时的处理方式, 可选: overwrite 覆盖, skip 跳过, newcopy 生成副本并重命名, fail 失败",
+					Value: "overwrite",
+				},
+
 				cli.IntFlag{
 					Name:  "p",
 					Usage: "指定单个文件上传的最大线程数",
@@ -952,9 +1513,232 @@ func main() {
 					Name:  "nosplit",
 					Usage: "禁用分片上传",
 				},
-			},
-		},
-		{
+				cli.BoolFlag{
+					Name:  "bg",
+					Usage: "加入后台上传, 仅在交互模式中有效, 使用 bg 命令管理",
+				},
+				cli.StringFlag{
+					Name:  "stream-blocksize",
+					Usage: "从标准输入上传时的分块大小, 如 64MB",
+				},
+				cli.BoolFlag{
+					Name:  "encrypt",
+					Usage: "在本地加密文件内容后上传, 密钥通过 config set -enc_key 或环境变量 BAIDUPCS_GO_ENC_KEY 设置",
+				},
+				cli.BoolFlag{
+					Name:  "encrypt-name",
+					Usage: "同时加密文件名和目录名, 包含 -encrypt",
+				},
+				cli.BoolFlag{
+					Name:  "watch",
+					Usage: "持续监视本地目录, 自动上传新建或修改的文件",
+				},
+				cli.DurationFlag{
+					Name:  "stable",
+					Usage: "监视目录时, 文件保持不变多久后上传",
+					Value: pcscommand.DefaultWatchStable,
+				},
+				cli.BoolFlag{
+					Name:  "delete",
+					Usage: "监视目录时, 本地删除的文件或目录同时在网盘中删除, 移动到回收站",
+				},
+				cli.StringSliceFlag{
+					Name:  "include",
+					Usage: "监视目录时, 只上传匹配的文件, 可指定多次",
+				},
+				cli.StringSliceFlag{
+					Name:  "exclude",
+					Usage: "监视目录时, 排除匹配的文件和目录, 可指定多次",
+				},
+			}, progressFlags...),
+		},
+		{
+			Name:      "sync",
+			Usage:     "单向同步本地目录到网盘",
+			UsageText: app.Name + " sync [-dry-run] [-delete] [-force] <本地目录> <网盘目录>",
+			Description: `
+	比较本地目录和网盘目录, 只上传新的和有变化的文件, 上传时优先秒传.
+	文件大小不同时上传, 大小相同而本地文件较新时, 比较文件的md5, 本地文件的md5会缓存, 文件不变时不会重复计算.
+	网盘中多余的文件或目录默认保留, 可通过 -delete 删除 (移动到回收站), 加上 -permanent 则彻底删除.
+	本地目录没有文件, 或将删除网盘中超过一半的文件时, 为防止误删, 停止同步, 确认无误后可使用 -force 强制同步.
+	-include 和 -exclude 的通配符匹配相对于同步目录的路径或文件名, 可指定多次, 被排除的网盘文件不会被删除.
+
+	示例:
+
+	1. 查看同步计划, 不执行
+	BaiduPCS-Go sync -dry-run C:/Users/Administrator/Desktop/photos /photos
+
+	2. 同步本地目录到网盘, 删除网盘中多余的文件
+	BaiduPCS-Go sync -delete ~/photos /photos
+
+	3. 只同步 jpg 文件, 排除 .cache 目录
+	BaiduPCS-Go sync -include "*.jpg" -exclude .cache ~/photos /photos
+`,
+			Category: "百度网盘",
+			Before:   reloadFn,
+			Action: func(c *cli.Context) error {
+				if c.NArg() != 2 {
+					cli.ShowCommandHelp(c, c.Command.Name)
+					return nil
+				}
+				if c.Bool("permanent") && !c.Bool("delete") {
+					fmt.Println("-permanent 需要和 -delete 一起使用")
+					return nil
+				}
+
+				progress, ok := parseProgress(c)
+				if !ok {
+					return nil
+				}
+
+				pcscommand.RunSync(c.Args().Get(0), c.Args().Get(1), &pcscommand.SyncOptions{
+					DryRun:    c.Bool("dry-run"),
+					Delete:    c.Bool("delete"),
+					Permanent: c.Bool("permanent"),
+					Force:     c.Bool("force"),
+					Include:   c.StringSlice("include"),
+					Exclude:   c.StringSlice("exclude"),
+					Upload: &pcscommand.UploadOptions{
+						Parallel:       c.Int("p"),
+						MaxRetry:       c.Int("retry"),
+						NotRapidUpload: c.Bool("norapid"),
+						NotSplitFile:   c.Bool("nosplit"),
+						OnDup:          baidupcs.OnDupOverwrite,
+						Progress:       progress,
+					},
+				})
+				return nil
+			},
+			Flags: append([]cli.Flag{
+				cli.BoolFlag{
+					Name:  "dry-run",
+					Usage: "只输出同步计划, 不上传和删除",
+				},
+				cli.BoolFlag{
+					Name:  "delete",
+					Usage: "删除网盘中多余的文件或目录, 移动到回收站",
+				},
+				cli.BoolFlag{
+					Name:  "permanent",
+					Usage: "彻底删除网盘中多余的文件或目录, 不保留在回收站, 需要和 -delete 一起使用",
+				},
+				cli.BoolFlag{
+					Name:  "force",
+					Usage: "跳过误删文件的安全检查",
+				},
+				cli.StringSliceFlag{
+					Name:  "include",
+					Usage: "只同步匹配的文件, 可指定多次",
+				},
+				cli.StringSliceFlag{
+					Name:  "exclude",
+					Usage: "排除匹配的文件和目录, 可指定多次",
+				},
+				cli.IntFlag{
+					Name:  "p",
+					Usage: "指定单个文件上传的最大线程数",
+				},
+				cli.IntFlag{
+					Name:  "retry",
+					Usage: "上传失败最大重试次数",
+					Value: pcscommand.DefaultUploadMaxRetry,
+				},
+				cli.BoolFlag{
+					Name:  "norapid",
+					Usage: "不检测秒传",
+				},
+				cli.BoolFlag{
+					Name:  "nosplit",
+					Usage: "禁用分片上传",
+				},
+			}, progressFlags...),
+		},
+		{
+			Name:      "bisync",
+			Usage:     "双向同步本地目录和网盘目录",
+			UsageText: app.Name + " bisync [-dry-run] [-force] <本地目录> <网盘目录>",
+			Description: `
+	双向同步本地目录和网盘目录, 两边的新建, 修改, 删除和重命名都会同步到另一边.
+	每次同步后, 会在配置目录记录所有文件的状态, 下次同步时据此判断哪一边有变化.
+	两边都修改了同一个文件时为冲突, 本地的文件重命名为 文件名.conflict-时间.扩展名 并上传, 再下载网盘的文件, 两边都会保留两个文件.
+	一边删除而另一边修改了同一个文件时, 保留修改的文件.
+	删除的网盘文件可在网盘文件回收站找回, 删除的本地文件会移动到本地目录下的 .pcs_bisync_trash 目录, 建议先使用 -dry-run 查看同步计划.
+	为防止目录未挂载或路径错误导致误删, 一边没有文件而上次同步时有文件, 或删除的文件超过上次同步的一半时, 会停止同步, 确认无误后可使用 -force 强制同步.
+	-include 和 -exclude 的通配符匹配相对于同步目录的路径或文件名, 可指定多次.
+
+	示例:
+
+	1. 查看同步计划, 不执行
+	BaiduPCS-Go bisync -dry-run ~/notes /notes
+
+	2. 双向同步, 排除 .git 目录
+	BaiduPCS-Go bisync -exclude .git ~/notes /notes
+`,
+			Category: "百度网盘",
+			Before:   reloadFn,
+			Action: func(c *cli.Context) error {
+				if c.NArg() != 2 {
+					cli.ShowCommandHelp(c, c.Command.Name)
+					return nil
+				}
+
+				progress, ok := parseProgress(c)
+				if !ok {
+					return nil
+				}
+
+				pcscommand.RunBisync(c.Args().Get(0), c.Args().Get(1), &pcscommand.BisyncOptions{
+					DryRun:  c.Bool("dry-run"),
+					Force:   c.Bool("force"),
+					Include: c.StringSlice("include"),
+					Exclude: c.StringSlice("exclude"),
+					Upload: &pcscommand.UploadOptions{
+						Parallel:       c.Int("p"),
+						MaxRetry:       c.Int("retry"),
+						NotRapidUpload: c.Bool("norapid"),
+						Progress:       progress,
+					},
+					Download: &pcscommand.DownloadOptions{
+						Parallel: c.Int("p"),
+						MaxRetry: c.Int("retry"),
+						Progress: progress,
+					},
+				})
+				return nil
+			},
+			Flags: append([]cli.Flag{
+				cli.BoolFlag{
+					Name:  "dry-run",
+					Usage: "只输出同步计划, 不执行",
+				},
+				cli.BoolFlag{
+					Name:  "force",
+					Usage: "跳过误删文件的安全检查",
+				},
+				cli.StringSliceFlag{
+					Name:  "include",
+					Usage: "只同步匹配的文件, 可指定多次",
+				},
+				cli.StringSliceFlag{
+					Name:  "exclude",
+					Usage: "排除匹配的文件和目录, 可指定多次",
+				},
+				cli.IntFlag{
+					Name:  "p",
+					Usage: "指定单个文件上传和下载的最大线程数",
+				},
+				cli.IntFlag{
+					Name:  "retry",
+					Usage: "上传和下载失败最大重试次数",
+					Value: pcscommand.DefaultUploadMaxRetry,
+				},
+				cli.BoolFlag{
+					Name:  "norapid",
+					Usage: "上传时不检测秒传",
+				},
+			}, progressFlags...),
+		},
+		{
 			Name:      "locate",
 			Aliases:   []string{"lt"},
 			Usage:     "获取下载直链",
@@ -995,7 +1779,7 @@ func main() {
 			Description: `
 				使用此功能秒传文件, 前提是知道文件的大小, md5, 前256KB切片的 md5 (可选), crc32 (可选), 且百度网盘中存在一模一样的文件.
 				上传的文件将会保存到网盘的目标目录.
-				遇到同名文件将会自动覆盖! 
+				遇到同名文件默认自动覆盖! 可通过 -ondup 指定处理方式.
 				可能无法秒传 20GB 以上的文件!!
 				示例:
 				1. 如果秒传成功, 则保存到网盘路径 /test
@@ -1009,10 +1793,21 @@ func main() {
 					return nil
 				}
 
-				pcscommand.RunRapidUpload(c.Args().Get(0), c.String("md5"), c.String("slicemd5"), c.String("crc32"), c.Int64("length"))
+				ondup, ok := parseOnDup(c)
+				if !ok {
+					return nil
+				}
+
+				pcscommand.RunRapidUpload(c.Args().Get(0), ondup, c.String("md5"), c.String("slicemd5"), c.String("crc32"), c.Int64("length"))
 				return nil
 			},
 			Flags: []cli.Flag{
+				cli.StringFlag{
+					Name:  "ondup",
+					Usage: "目标已存在时的处理方式, 可选: overwrite 覆盖, skip 跳过, newcopy 生成副本并重命名, fail 失败",
+					Value: "overwrite",
+				},
+
 				cli.StringFlag{
 					Name:  "md5",
 					Usage: "文件的 md5 值",
@@ -1039,7 +1834,7 @@ func main() {
 			Description: `
 				block1, block2 ... 为文件分片的md5值
 				上传的文件将会保存到网盘的目标目录.
-				遇到同名文件将会自动覆盖! 
+				遇到同名文件默认自动覆盖! 可通过 -ondup 指定处理方式.
 				示例:
 				BaiduPCS-Go createsuperfile -path=1.mp4 ec87a838931d4d5d2e94a04644788a55 ec87a838931d4d5d2e94a04644788a55
 			`,
@@ -1051,10 +1846,21 @@ func main() {
 					return nil
 				}
 
-				pcscommand.RunCreateSuperFile(c.String("path"), c.Args()...)
+				ondup, ok := parseOnDup(c)
+				if !ok {
+					return nil
+				}
+
+				pcscommand.RunCreateSuperFile(c.String("path"), ondup, c.Args()...)
 				return nil
 			},
 			Flags: []cli.Flag{
+				cli.StringFlag{
+					Name:  "ondup",
+					Usage: "目标已存在时的处理方式, 可选: overwrite 覆盖, skip 跳过, newcopy 生成副本并重命名, fail 失败",
+					Value: "overwrite",
+				},
+
 				cli.StringFlag{
 					Name:  "path",
 					Usage: "保存的网盘路径",
@@ -1197,6 +2003,312 @@ func main() {
 				},
 			},
 		},
+		{
+			Name:      "export",
+			Aliases:   []string{"ep"},
+			Usage:     "导出文件/目录",
+			UsageText: app.Name + " export <文件/目录1> <文件/目录2> ...",
+			Description: `
+	导出网盘内的文件或目录, 原理为秒传文件, 此操作会生成导出文件或目录的命令.
+	导出的信息保存在 -out 指定的文件中, 在其他帐号中运行文件中的命令即可导入.
+	大于20GB的文件无法导出, 空目录会导出为 mkdir 命令.
+
+	示例:
+
+	导出当前工作目录:
+	BaiduPCS-Go export
+
+	递归导出 /我的资源 到 /test.txt, 导入时保存到 /资源 目录下:
+	BaiduPCS-Go export -r -out /test.txt -root /资源 /我的资源
+`,
+			Category: "百度网盘",
+			Before:   reloadFn,
+			Action: func(c *cli.Context) error {
+				pcspaths := c.Args()
+				if len(pcspaths) == 0 {
+					pcspaths = []string{"."}
+				}
+
+				pcscommand.RunExport(pcspaths, &pcscommand.ExportOptions{
+					RootPath:  c.String("root"),
+					SavePath:  c.String("out"),
+					MaxRerty:  c.Int("retry"),
+					Recursive: c.Bool("r"),
+				})
+				return nil
+			},
+			Flags: []cli.Flag{
+				cli.StringFlag{
+					Name:  "out",
+					Usage: "导出信息保存的本地文件, 默认为 " + pcscommand.GetExportFilename(),
+				},
+				cli.StringFlag{
+					Name:  "root",
+					Usage: "导入时保存到的网盘根目录, 默认为原目录",
+				},
+				cli.IntFlag{
+					Name:  "retry",
+					Usage: "导出失败的重试次数",
+					Value: 3,
+				},
+				cli.BoolFlag{
+					Name:  "r",
+					Usage: "递归导出子目录",
+				},
+			},
+		},
+		{
+			Name:      "offlinedl",
+			Aliases:   []string{"clouddl", "od"},
+			Usage:     "离线下载",
+			UsageText: app.Name + " offlinedl <子命令>",
+			Description: `
+	支持http/https/ftp/电驴/磁力链协议
+	离线下载同时进行的任务数量有限, 超出限制的部分将无法添加.
+
+	示例:
+
+	1. 将百度和腾讯主页, 离线下载到根目录 /
+	BaiduPCS-Go offlinedl add -path=/ http://baidu.com http://qq.com
+
+	2. 添加磁力链接任务, 并等待任务结束
+	BaiduPCS-Go offlinedl add -wait magnet:?xt=urn:btih:xxx
+
+	3. 查询任务ID为 12345 的离线下载任务状态
+	BaiduPCS-Go offlinedl query 12345
+
+	4. 取消任务ID为 12345 和 23456 的离线下载任务
+	BaiduPCS-Go offlinedl cancel 12345 23456
+
+	5. 清空离线下载任务记录
+	BaiduPCS-Go offlinedl delete -all
+
+	6. 列出磁力链接中的文件, 不添加任务
+	BaiduPCS-Go offlinedl add -list magnet:?xt=urn:btih:xxx
+
+	7. 上传本地的种子文件, 只下载序号为 1 到 3, 或扩展名为 .mkv 且大于 100MB 的文件
+	BaiduPCS-Go offlinedl add -path=/video -select="1-3,*.mkv,>100MB" ./movie.torrent
+`,
+			Category: "百度网盘",
+			Before:   reloadFn,
+			Action: func(c *cli.Context) error {
+				cli.ShowCommandHelp(c, c.Command.Name)
+				return nil
+			},
+			Subcommands: []cli.Command{
+				{
+					Name:      "add",
+					Aliases:   []string{"a"},
+					Usage:     "添加离线下载任务",
+					UsageText: app.Name + " offlinedl add -path=<离线下载文件保存的路径> [-wait] [-timeout=<最长等待时间>] [-select=<选择BT资源中的文件>] [-list] 资源地址1 地址2 ...",
+					Action: func(c *cli.Context) error {
+						if c.NArg() < 1 {
+							cli.ShowCommandHelp(c, c.Command.Name)
+							return nil
+						}
+
+						taskIDs := pcscommand.RunCloudDlAddTask(c.Args(), c.String("path"), &pcscommand.CloudDlAddOptions{
+							Select:   c.String("select"),
+							ListOnly: c.Bool("list"),
+						})
+						if c.Bool("wait") {
+							pcscommand.RunCloudDlWaitTask(taskIDs, c.Duration("interval"), c.Duration("timeout"))
+						}
+						return nil
+					},
+					Flags: []cli.Flag{
+						cli.StringFlag{
+							Name:  "path",
+							Usage: "离线下载文件保存的路径, 默认为工作目录",
+						},
+						cli.StringFlag{
+							Name:  "select",
+							Usage: "选择磁力链接或种子文件中要下载的文件, 多个条件以逗号分隔, 支持序号 (3), 序号范围 (1-5), 通配符 (*.mkv), 文件大小 (>100MB), 默认全部下载",
+						},
+						cli.BoolFlag{
+							Name:  "list",
+							Usage: "只列出磁力链接或种子文件中的文件, 不添加任务",
+						},
+						cloudDlWaitFlag,
+						cloudDlIntervalFlag,
+						cloudDlTimeoutFlag,
+					},
+				},
+				{
+					Name:      "query",
+					Aliases:   []string{"q"},
+					Usage:     "精确查询离线下载任务",
+					UsageText: app.Name + " offlinedl query [-wait] [-timeout=<最长等待时间>] 任务ID1 任务ID2 ...",
+					Action: func(c *cli.Context) error {
+						taskIDs, ok := parseCloudDlTaskIDs(c)
+						if !ok {
+							return nil
+						}
+
+						if c.Bool("wait") {
+							pcscommand.RunCloudDlWaitTask(taskIDs, c.Duration("interval"), c.Duration("timeout"))
+							return nil
+						}
+						pcscommand.RunCloudDlQueryTask(taskIDs)
+						return nil
+					},
+					Flags: []cli.Flag{
+						cloudDlWaitFlag,
+						cloudDlIntervalFlag,
+						cloudDlTimeoutFlag,
+					},
+				},
+				{
+					Name:      "list",
+					Aliases:   []string{"ls", "l"},
+					Usage:     "查询离线下载任务列表",
+					UsageText: app.Name + " offlinedl list",
+					Action: func(c *cli.Context) error {
+						pcscommand.RunCloudDlListTask()
+						return nil
+					},
+				},
+				{
+					Name:      "cancel",
+					Aliases:   []string{"c"},
+					Usage:     "取消离线下载任务",
+					UsageText: app.Name + " offlinedl cancel 任务ID1 任务ID2 ...",
+					Action: func(c *cli.Context) error {
+						taskIDs, ok := parseCloudDlTaskIDs(c)
+						if !ok {
+							return nil
+						}
+						pcscommand.RunCloudDlCancelTask(taskIDs)
+						return nil
+					},
+				},
+				{
+					Name:      "delete",
+					Aliases:   []string{"del", "d"},
+					Usage:     "删除离线下载任务",
+					UsageText: app.Name + " offlinedl delete 任务ID1 任务ID2 ...",
+					Action: func(c *cli.Context) error {
+						if c.Bool("all") {
+							pcscommand.RunCloudDlClearTask()
+							return nil
+						}
+
+						taskIDs, ok := parseCloudDlTaskIDs(c)
+						if !ok {
+							return nil
+						}
+						pcscommand.RunCloudDlDeleteTask(taskIDs)
+						return nil
+					},
+					Flags: []cli.Flag{
+						cli.BoolFlag{
+							Name:  "all",
+							Usage: "清空离线下载任务记录, 程序不会进行二次确认, 谨慎操作!!!",
+						},
+					},
+				},
+			},
+		},
+		{
+			Name:      "recycle",
+			Usage:     "回收站",
+			UsageText: app.Name + " recycle <子命令>",
+			Description: `
+	回收站操作.
+
+	示例:
+
+	1. 列出回收站第1页的文件, 列出所有页的文件
+	BaiduPCS-Go recycle list
+	BaiduPCS-Go recycle list -all
+
+	2. 从回收站还原两个文件, 其中的两个文件的 fs_id 分别为 1013792297798440 和 643596340463870
+	BaiduPCS-Go recycle restore 1013792297798440 643596340463870
+
+	3. 从回收站删除两个文件, 其中的两个文件的 fs_id 分别为 1013792297798440 和 643596340463870
+	BaiduPCS-Go recycle delete 1013792297798440 643596340463870
+
+	4. 清空回收站, 程序不会进行二次确认, 谨慎操作!!!
+	BaiduPCS-Go recycle delete -all
+`,
+			Category: "百度网盘",
+			Before:   reloadFn,
+			Action: func(c *cli.Context) error {
+				cli.ShowCommandHelp(c, c.Command.Name)
+				return nil
+			},
+			Subcommands: []cli.Command{
+				{
+					Name:      "list",
+					Aliases:   []string{"ls", "l"},
+					Usage:     "列出回收站文件列表",
+					UsageText: app.Name + " recycle list [-page <页数>] [-all]",
+					Action: func(c *cli.Context) error {
+						if c.Bool("all") {
+							pcscommand.RunRecycleList(0)
+							return nil
+						}
+						if c.Int("page") < 1 {
+							fmt.Printf("页数不合法: %d\n", c.Int("page"))
+							return nil
+						}
+						pcscommand.RunRecycleList(c.Int("page"))
+						return nil
+					},
+					Flags: []cli.Flag{
+						cli.IntFlag{
+							Name:  "page",
+							Usage: "回收站文件列表页数",
+							Value: 1,
+						},
+						cli.BoolFlag{
+							Name:  "all",
+							Usage: "列出所有页",
+						},
+					},
+				},
+				{
+					Name:        "restore",
+					Aliases:     []string{"r"},
+					Usage:       "还原回收站文件或目录",
+					UsageText:   app.Name + " recycle restore <fs_id 1> <fs_id 2> <fs_id 3> ...",
+					Description: `根据文件/目录的 fs_id, 还原回收站指定的文件或目录`,
+					Action: func(c *cli.Context) error {
+						if !checkFsIDs(c) {
+							return nil
+						}
+						pcscommand.RunRecycleRestore(c.Args()...)
+						return nil
+					},
+				},
+				{
+					Name:        "delete",
+					Aliases:     []string{"d"},
+					Usage:       "删除回收站文件或目录 / 清空回收站",
+					UsageText:   app.Name + " recycle delete [-all] <fs_id 1> <fs_id 2> <fs_id 3> ...",
+					Description: `根据文件/目录的 fs_id 或 -all 参数, 删除回收站指定的文件或目录或清空回收站`,
+					Action: func(c *cli.Context) error {
+						if c.Bool("all") {
+							// 清空回收站
+							pcscommand.RunRecycleClear()
+							return nil
+						}
+
+						if !checkFsIDs(c) {
+							return nil
+						}
+						pcscommand.RunRecycleDelete(c.Args()...)
+						return nil
+					},
+					Flags: []cli.Flag{
+						cli.BoolFlag{
+							Name:  "all",
+							Usage: "清空回收站, 程序不会进行二次确认, 谨慎操作!!!",
+						},
+					},
+				},
+			},
+		},
 		{
 			Name:        "config",
 			Usage:       "显示和修改程序配置项",
@@ -1265,6 +2377,9 @@ func main() {
 						if c.IsSet("max_download_load") {
 							pcsconfig.Config.MaxDownloadLoad = c.Int("max_download_load")
 						}
+						if c.IsSet("walk_parallel") {
+							pcsconfig.Config.WalkParallel = c.Int("walk_parallel")
+						}
 						if c.IsSet("max_download_rate") {
 							err := pcsconfig.Config.SetMaxDownloadRateByStr(c.String("max_download_rate"))
 							if err != nil {
@@ -1279,15 +2394,60 @@ func main() {
 								return nil
 							}
 						}
+						if c.IsSet("api_max_retry") {
+							pcsconfig.Config.SetAPIMaxRetry(c.Int("api_max_retry"))
+						}
+						if c.IsSet("api_rate_limit") {
+							err := pcsconfig.Config.SetAPIRateLimit(c.String("api_rate_limit"))
+							if err != nil {
+								fmt.Printf("设置 api_rate_limit 错误: %s\n", err)
+								return nil
+							}
+						}
+						if c.IsSet("meta_cache_ttl") {
+							err := pcsconfig.Config.SetMetaCacheTTL(c.String("meta_cache_ttl"))
+							if err != nil {
+								fmt.Printf("设置 meta_cache_ttl 错误: %s\n", err)
+								return nil
+							}
+						}
 						if c.IsSet("savedir") {
 							pcsconfig.Config.SaveDir = c.String("savedir")
 						}
+						if c.IsSet("enc_key") {
+							err := pcsconfig.Config.SetEncryptKey(c.String("enc_key"))
+							if err != nil {
+								fmt.Printf("设置 enc_key 错误: %s\n", err)
+								return nil
+							}
+						}
+						if c.IsSet("enc_salt") {
+							err := pcsconfig.Config.SetEncryptSalt(c.String("enc_salt"))
+							if err != nil {
+								fmt.Printf("设置 enc_salt 错误: %s\n", err)
+								return nil
+							}
+						}
 						if c.IsSet("proxy") {
 							pcsconfig.Config.SetProxy(c.String("proxy"))
 						}
 						if c.IsSet("local_addrs") {
 							pcsconfig.Config.SetLocalAddrs(c.String("local_addrs"))
 						}
+						if c.IsSet("pcs_addr") {
+							err := pcsconfig.Config.SetPCSAddr(c.String("pcs_addr"))
+							if err != nil {
+								fmt.Printf("设置 pcs_addr 错误: %s\n", err)
+								return nil
+							}
+						}
+						if c.IsSet("pan_addr") {
+							err := pcsconfig.Config.SetPanAddr(c.String("pan_addr"))
+							if err != nil {
+								fmt.Printf("设置 pan_addr 错误: %s\n", err)
+								return nil
+							}
+						}
 
 						err := pcsconfig.Config.Save()
 						if err != nil {
@@ -1321,6 +2481,10 @@ func main() {
 							Name:  "max_download_load",
 							Usage: "同时进行下载文件的最大数量",
 						},
+						cli.IntFlag{
+							Name:  "walk_parallel",
+							Usage: "遍历网盘目录的并发量",
+						},
 						cli.StringFlag{
 							Name:  "max_download_rate",
 							Usage: "限制最大下载速度, 0代表不限制",
@@ -1329,10 +2493,30 @@ func main() {
 							Name:  "max_upload_rate",
 							Usage: "限制最大上传速度, 0代表不限制",
 						},
+						cli.IntFlag{
+							Name:  "api_max_retry",
+							Usage: "api 请求失败的最大重试次数, 0代表不重试",
+						},
+						cli.StringFlag{
+							Name:  "api_rate_limit",
+							Usage: "api 请求频率限制 (次/秒), 如 pcs=10,pan=5,write=2, 为空则不限制",
+						},
+						cli.StringFlag{
+							Name:  "meta_cache_ttl",
+							Usage: "本地元信息缓存有效期, 如 10m, 为空或0代表不使用",
+						},
 						cli.StringFlag{
 							Name:  "savedir",
 							Usage: "下载文件的储存目录",
 						},
+						cli.StringFlag{
+							Name:  "enc_key",
+							Usage: "客户端加密的密钥或密码, 用于 upload -encrypt 和 download -decrypt, 设置密码时生成新的随机密码盐 enc_salt",
+						},
+						cli.StringFlag{
+							Name:  "enc_salt",
+							Usage: "由密码派生密钥的十六进制随机盐, 在其他设备解密时设置为相同的值, new 为重新生成, 为空则使用旧版本的固定盐",
+						},
 						cli.BoolFlag{
 							Name:  "enable_https",
 							Usage: "启用 https",
@@ -1357,11 +2541,91 @@ func main() {
 							Name:  "local_addrs",
 							Usage: "设置本地网卡地址, 多个地址用逗号隔开",
 						},
+						cli.StringFlag{
+							Name:  "pcs_addr",
+							Usage: "自定义 PCS api 地址, 为空则使用默认地址",
+						},
+						cli.StringFlag{
+							Name:  "pan_addr",
+							Usage: "自定义网盘首页 api 地址, 为空则使用默认地址",
+						},
+					},
+				},
+			},
+		},
+		{
+			Name:  "cache",
+			Usage: "管理本地元信息缓存",
+			Description: `
+	本地元信息缓存用于缓存网盘的文件列表和元信息, 加速列目录, 切换工作目录等操作,
+	通过 config set -meta_cache_ttl 设置有效期后启用, 缓存按帐号分别储存.
+	通过本程序修改网盘文件时, 相关的缓存会自动失效;
+	在其他地方修改网盘文件后, 可运行 cache clear 清空缓存.
+
+	示例:
+
+	启用缓存, 有效期为10分钟
+	BaiduPCS-Go config set -meta_cache_ttl 10m
+
+	统计缓存
+	BaiduPCS-Go cache stats
+
+	清空缓存
+	BaiduPCS-Go cache clear
+`,
+			Category: "配置",
+			Before:   reloadFn,
+			Action: func(c *cli.Context) error {
+				cli.ShowCommandHelp(c, c.Command.Name)
+				return nil
+			},
+			Subcommands: []cli.Command{
+				{
+					Name:  "stats",
+					Usage: "统计缓存",
+					Action: func(c *cli.Context) error {
+						pcscommand.RunCacheStats()
+						return nil
 					},
 				},
+				{
+					Name:  "clear",
+					Usage: "清空缓存",
+					Action: func(c *cli.Context) error {
+						pcscommand.RunCacheClear()
+						return nil
+					},
+				},
+			},
+		},
+		{
+			Name:  "update",
+			Usage: "检测程序更新",
+			Description: `
+	检测程序更新, 有新版本时下载并替换当前程序, 需要程序所在目录可写.
+
+	示例:
+
+	检测更新, 不询问直接更新
+	BaiduPCS-Go update -y
+`,
+			Category: "其他",
+			Action: func(c *cli.Context) error {
+				pcsupdate.CheckUpdate(app.Version, c.Bool("y"))
+				return nil
+			},
+			Flags: []cli.Flag{
+				cli.BoolFlag{
+					Name:  "y",
+					Usage: "确认更新",
+				},
 			},
 		},
 	}
 
 	app.Run(os.Args)
+	if code := pcscommand.ExitCode(); code != 0 {
+		pcsconfig.Config.Close()
+		os.Exit(code)
+	}
 }
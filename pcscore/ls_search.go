@@ -0,0 +1,152 @@
+package pcscore
+
+import (
+	"errors"
+	"fmt"
+	"github.com/Erope/BaiduPCS-Go/baidupcs"
+	"github.com/Erope/BaiduPCS-Go/baidupcs/pcserror"
+	"path"
+	"strings"
+)
+
+type (
+	// SearchOptions 搜索可选项
+	SearchOptions struct {
+		Recurse bool // 递归搜索
+		Walk    bool // 遍历目录进行搜索, 而不是使用服务器的搜索接口
+	}
+)
+
+var (
+	// ErrSearchPathNotDir 搜索路径不是目录
+	ErrSearchPathNotDir = errors.New("搜索路径不是目录")
+)
+
+// Ls 获取目录 dir 下的文件和目录, 启用了本地元信息缓存时优先读取缓存
+func (s *Session) Ls(dir string, orderOptions *baidupcs.OrderOptions) (files baidupcs.FileDirectoryList, err error) {
+	var pcsError pcserror.Error
+	dir = s.PathJoin(dir)
+	if s.Client.DiskCache() != nil { // 启用了本地元信息缓存
+		files, pcsError = s.Client.CacheFilesDirectoriesList(dir, orderOptions)
+	} else {
+		files, pcsError = s.Client.ListIter(dir, &baidupcs.ListIterOptions{
+			OrderOptions: orderOptions,
+		}).All()
+	}
+	if pcsError != nil {
+		return nil, pcsError
+	}
+	return files, nil
+}
+
+// LsPages 分页获取目录 dir 下的文件和目录, 每获取一页调用一次 fn, fn 返回错误时停止并返回该错误.
+// 启用了本地元信息缓存时, 整个目录作为一页
+func (s *Session) LsPages(dir string, orderOptions *baidupcs.OrderOptions, fn func(page baidupcs.FileDirectoryList) error) error {
+	dir = s.PathJoin(dir)
+	if s.Client.DiskCache() != nil {
+		files, pcsError := s.Client.CacheFilesDirectoriesList(dir, orderOptions)
+		if pcsError != nil {
+			return pcsError
+		}
+		return fn(files)
+	}
+
+	iter := s.Client.ListIter(dir, &baidupcs.ListIterOptions{
+		OrderOptions: orderOptions,
+	})
+	for iter.NextPage() {
+		err := fn(iter.Page())
+		if err != nil {
+			return err
+		}
+	}
+	if iter.Err() != nil {
+		return iter.Err()
+	}
+	return nil
+}
+
+// Meta 获取文件/目录的元信息, 出错时返回已获取的部分
+func (s *Session) Meta(pcspaths ...string) (files baidupcs.FileDirectoryList, err error) {
+	files = make(baidupcs.FileDirectoryList, 0, len(pcspaths))
+	for _, pcspath := range pcspaths {
+		fd, pcsError := s.Client.FilesDirectoriesMeta(s.PathJoin(pcspath))
+		if pcsError != nil {
+			return files, pcsError
+		}
+		files = append(files, fd)
+	}
+	return files, nil
+}
+
+// Search 在目录 targetPath 下按文件名搜索文件和目录
+func (s *Session) Search(targetPath, keyword string, opt *SearchOptions) (files baidupcs.FileDirectoryList, err error) {
+	if opt == nil {
+		opt = &SearchOptions{}
+	}
+
+	targetPath = s.PathJoin(targetPath)
+	if opt.Walk {
+		return s.walkSearch(targetPath, keyword, opt.Recurse)
+	}
+
+	files, pcsError := s.Client.Search(targetPath, keyword, opt.Recurse)
+	if pcsError != nil {
+		return nil, pcsError
+	}
+	return files, nil
+}
+
+// walkSearch 遍历目录, 按文件名搜索文件和目录,
+// keyword 含有通配符时按通配符匹配, 否则按关键字匹配 (不区分大小写)
+func (s *Session) walkSearch(targetPath, keyword string, recurse bool) (files baidupcs.FileDirectoryList, err error) {
+	var (
+		isPattern    = strings.ContainsAny(keyword, "*?[")
+		lowerKeyword = strings.ToLower(keyword)
+		rootPath     string
+	)
+	if isPattern {
+		// 检查通配符是否合法
+		_, err = path.Match(keyword, "")
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	files = baidupcs.FileDirectoryList{}
+	err = s.Client.Walk(targetPath, s.WalkOptions, func(fdPath string, fd *baidupcs.FileDirectory, pcsError pcserror.Error) error {
+		if pcsError != nil {
+			if rootPath == "" {
+				return pcsError
+			}
+			pcsCoreVerbose.Warnf("%s\n", pcsError)
+			return nil
+		}
+		if rootPath == "" { // 最先回调的是 targetPath 本身
+			rootPath = fd.Path
+			if !fd.Isdir {
+				return fmt.Errorf("%s, path: %s", ErrSearchPathNotDir, fd.Path)
+			}
+			return nil
+		}
+
+		var matched bool
+		if isPattern {
+			matched, _ = path.Match(keyword, fd.Filename)
+		} else {
+			matched = strings.Contains(strings.ToLower(fd.Filename), lowerKeyword)
+		}
+		if matched {
+			files = append(files, fd)
+		}
+
+		if fd.Isdir && !recurse {
+			return baidupcs.SkipDir
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return files, nil
+}
@@ -0,0 +1,76 @@
+// Package pcscore 命令的核心逻辑, 只返回结构化的结果, 不输出任何内容,
+// 可供命令行, pcsweb 以及第三方程序复用
+package pcscore
+
+import (
+	"errors"
+	"github.com/Erope/BaiduPCS-Go/baidupcs"
+	"github.com/Erope/BaiduPCS-Go/pcsverbose"
+	"path"
+)
+
+var (
+	pcsCoreVerbose = pcsverbose.New("PCSCORE")
+
+	// ErrShellPatternMultiRes 多条通配符匹配结果
+	ErrShellPatternMultiRes = errors.New("多条通配符匹配结果")
+	// ErrShellPatternNoHit 未匹配到路径
+	ErrShellPatternNoHit = errors.New("未匹配到路径, 请检测通配符")
+)
+
+type (
+	// Session 执行命令的会话, 包括网盘客户端和工作目录
+	Session struct {
+		Client      baidupcs.Client
+		Workdir     string                // 工作目录, 相对路径基于此目录
+		WalkOptions *baidupcs.WalkOptions // 遍历目录的可选项, 为空则使用默认值
+	}
+)
+
+// NewSession 返回 Session 指针对象
+func NewSession(client baidupcs.Client, workdir string) *Session {
+	return &Session{
+		Client:  client,
+		Workdir: workdir,
+	}
+}
+
+// PathJoin 合并工作目录和相对路径, p 为绝对路径则直接返回
+func (s *Session) PathJoin(p string) string {
+	if path.IsAbs(p) {
+		return p
+	}
+	if s.Workdir == "" {
+		return path.Join(baidupcs.PathSeparator, p)
+	}
+	return path.Join(s.Workdir, p)
+}
+
+// MatchPath 按通配符匹配网盘路径, 返回所有匹配到的路径
+func (s *Session) MatchPath(patterns ...string) (pcspaths []string, err error) {
+	for k := range patterns {
+		ps, pcsError := s.Client.MatchPathByShellPattern(s.PathJoin(patterns[k]))
+		if pcsError != nil {
+			return nil, pcsError
+		}
+
+		pcspaths = append(pcspaths, ps...)
+	}
+	return pcspaths, nil
+}
+
+// MatchPathOnce 按通配符匹配网盘路径, 必须只匹配到一条路径
+func (s *Session) MatchPathOnce(pattern string) (pcspath string, err error) {
+	paths, pcsError := s.Client.MatchPathByShellPattern(s.PathJoin(pattern))
+	if pcsError != nil {
+		return "", pcsError
+	}
+	switch len(paths) {
+	case 0:
+		return "", ErrShellPatternNoHit
+	case 1:
+		return paths[0], nil
+	default:
+		return "", ErrShellPatternMultiRes
+	}
+}
@@ -0,0 +1,103 @@
+package pcscore_test
+
+import (
+	"github.com/Erope/BaiduPCS-Go/baidupcs"
+	"github.com/Erope/BaiduPCS-Go/baidupcs/diskcache"
+	"github.com/Erope/BaiduPCS-Go/baidupcs/pcserror"
+	"github.com/Erope/BaiduPCS-Go/pcscore"
+	"path"
+	"testing"
+)
+
+// fakeClient 内存中的网盘, 只实现测试用到的方法
+type fakeClient struct {
+	baidupcs.Client
+	files baidupcs.FileDirectoryList
+}
+
+func (fc *fakeClient) MatchPathByShellPattern(pattern string) (pcspaths []string, pcsError pcserror.Error) {
+	for _, fd := range fc.files {
+		if ok, _ := path.Match(pattern, fd.Path); ok {
+			pcspaths = append(pcspaths, fd.Path)
+		}
+	}
+	return pcspaths, nil
+}
+
+func (fc *fakeClient) DiskCache() *diskcache.Cache {
+	return nil
+}
+
+func (fc *fakeClient) ListIter(p string, opts *baidupcs.ListIterOptions) *baidupcs.ListIter {
+	return baidupcs.NewListIter(fc, p, opts)
+}
+
+func (fc *fakeClient) FilesDirectoriesListPage(p string, options *baidupcs.OrderOptions, start, limit int) (data baidupcs.FileDirectoryList, pcsError pcserror.Error) {
+	for _, fd := range fc.files {
+		if path.Dir(fd.Path) == p {
+			data = append(data, fd)
+		}
+	}
+	if start >= len(data) {
+		return nil, nil
+	}
+	return data[start:], nil
+}
+
+func (fc *fakeClient) QuotaInfo() (quota, used int64, pcsError pcserror.Error) {
+	return 100, 25, nil
+}
+
+func TestSession(t *testing.T) {
+	session := pcscore.NewSession(&fakeClient{
+		files: baidupcs.FileDirectoryList{
+			{Path: "/a", Isdir: true},
+			{Path: "/a/1.txt"},
+			{Path: "/a/2.txt"},
+			{Path: "/b.txt"},
+		},
+	}, "/a")
+
+	_, err := session.MatchPathOnce("*.txt")
+	if err != pcscore.ErrShellPatternMultiRes {
+		t.Fatalf("expect ErrShellPatternMultiRes, got %v", err)
+	}
+	pcspath, err := session.MatchPathOnce("1.*")
+	if err != nil || pcspath != "/a/1.txt" {
+		t.Fatalf("unexpected path: %s, %v", pcspath, err)
+	}
+
+	// 相对路径基于工作目录
+	files, err := session.Ls(".", nil)
+	if err != nil || len(files) != 2 {
+		t.Fatalf("unexpected files: %v, %v", files, err)
+	}
+
+	quotaInfo, err := session.Quota()
+	if err != nil || quotaInfo.Free() != 75 || quotaInfo.UsedPercent() != 25 {
+		t.Fatalf("unexpected quota: %+v, %v", quotaInfo, err)
+	}
+}
+
+// recycleClient 回收站有两页
+type recycleClient struct {
+	baidupcs.Client
+}
+
+func (rc *recycleClient) RecycleList(page int) (fdl baidupcs.RecycleFDInfoList, pcsError pcserror.Error) {
+	switch page {
+	case 1:
+		return baidupcs.RecycleFDInfoList{{FsID: 1}, {FsID: 2}}, nil
+	case 2:
+		return baidupcs.RecycleFDInfoList{{FsID: 3}}, nil
+	}
+	return nil, nil
+}
+
+func TestRecycleListAll(t *testing.T) {
+	session := pcscore.NewSession(&recycleClient{}, "/")
+	fdl, err := session.RecycleListAll()
+	if err != nil || len(fdl) != 3 || fdl[2].FsID != 3 {
+		t.Fatalf("unexpected recycle list: %v, %v", fdl, err)
+	}
+}
@@ -0,0 +1,34 @@
+package pcscore
+
+type (
+	// QuotaInfo 空间配额信息
+	QuotaInfo struct {
+		Quota int64 `json:"quota"` // 总空间
+		Used  int64 `json:"used"`  // 已用空间
+	}
+)
+
+// Free 返回剩余空间
+func (qi *QuotaInfo) Free() int64 {
+	return qi.Quota - qi.Used
+}
+
+// UsedPercent 返回已用空间的百分比
+func (qi *QuotaInfo) UsedPercent() float64 {
+	if qi.Quota <= 0 {
+		return 0
+	}
+	return 100 * float64(qi.Used) / float64(qi.Quota)
+}
+
+// Quota 获取空间配额信息
+func (s *Session) Quota() (*QuotaInfo, error) {
+	quota, used, pcsError := s.Client.QuotaInfo()
+	if pcsError != nil {
+		return nil, pcsError
+	}
+	return &QuotaInfo{
+		Quota: quota,
+		Used:  used,
+	}, nil
+}
@@ -0,0 +1,212 @@
+package pcscore
+
+import (
+	"github.com/Erope/BaiduPCS-Go/baidupcs"
+)
+
+// 以下为命令结构化输出 (json, ndjson, csv) 的记录格式, 字段名即 json 的键名和 csv 的列名,
+// 时间均为 unix 时间戳, 大小均以字节为单位. 字段只会增加, 不会修改或删除.
+type (
+	// FileRecord 文件/目录, 用于 ls, search, meta, tree
+	FileRecord struct {
+		FsID     int64  `json:"fs_id"`
+		AppID    int64  `json:"app_id"`
+		Path     string `json:"path"`     // 完整路径
+		Filename string `json:"filename"` // 文件名 或 目录名
+		Isdir    bool   `json:"isdir"`    // 是否为目录
+		Size     int64  `json:"size"`     // 文件大小, 目录为0
+		Ctime    int64  `json:"ctime"`    // 创建日期
+		Mtime    int64  `json:"mtime"`    // 修改日期
+		MD5      string `json:"md5"`      // md5 值, 分片上传的文件可能不正确
+	}
+
+	// ShareRecord 分享记录, 用于 share list
+	ShareRecord struct {
+		ShareID         int64   `json:"share_id"`
+		FsIDs           []int64 `json:"fs_ids"`           // 分享的文件/目录的 fs_id
+		Link            string  `json:"link"`             // 分享链接
+		Passwd          string  `json:"passwd"`           // 提取密码
+		Status          int     `json:"status"`           // 分享状态, 0为正常
+		TypicalCategory int     `json:"typical_category"` // 特征文件类型, -1为目录
+		TypicalPath     string  `json:"typical_path"`     // 特征路径
+	}
+
+	// CloudDlTaskRecord 离线下载任务, 用于 offlinedl list, offlinedl query
+	CloudDlTaskRecord struct {
+		TaskID       int64  `json:"task_id"`
+		TaskName     string `json:"task_name"`     // 任务名称, 一般为文件名
+		Status       int    `json:"status"`        // 0下载成功, 1下载进行中, 2系统错误, 3资源不存在, 4下载超时, 5资源存在但下载失败, 6存储空间不足, 7任务取消
+		StatusText   string `json:"status_text"`   // 状态描述
+		FileSize     int64  `json:"file_size"`     // 文件大小
+		FinishedSize int64  `json:"finished_size"` // 已下载大小
+		CreateTime   int64  `json:"create_time"`   // 创建时间
+		StartTime    int64  `json:"start_time"`    // 开始时间
+		FinishTime   int64  `json:"finish_time"`   // 结束时间
+		SavePath     string `json:"save_path"`     // 保存的路径
+		SourceURL    string `json:"source_url"`    // 资源地址
+
+		Files []*CloudDlFileRecord `json:"files,omitempty"` // BT任务中每个文件的进度
+	}
+
+	// CloudDlFileRecord 离线下载任务中的文件, csv 格式为 文件名:文件大小:已下载大小
+	CloudDlFileRecord struct {
+		FileName     string `json:"file_name"`
+		FileSize     int64  `json:"file_size"`
+		FinishedSize int64  `json:"finished_size"`
+	}
+
+	// CloudDlBtFileRecord BT资源中的文件, 用于 offlinedl add -list
+	CloudDlBtFileRecord struct {
+		Index    int    `json:"index"` // 文件序号, 从1开始
+		FileName string `json:"file_name"`
+		Size     int64  `json:"size"`
+		Selected bool   `json:"selected"` // 是否被 -select 选择
+	}
+
+	// RecycleRecord 回收站中的文件/目录, 用于 recycle list
+	RecycleRecord struct {
+		FsID     int64  `json:"fs_id"`
+		Path     string `json:"path"`      // 原路径
+		Filename string `json:"filename"`  // 文件名 或 目录名
+		Isdir    bool   `json:"isdir"`     // 是否为目录
+		Size     int64  `json:"size"`      // 文件大小, 目录为0
+		Ctime    int64  `json:"ctime"`     // 创建日期
+		Mtime    int64  `json:"mtime"`     // 修改日期
+		MD5      string `json:"md5"`       // md5 值
+		LeftTime int    `json:"left_time"` // 剩余保留天数
+	}
+
+	// SyncActionRecord 同步操作, 用于 sync, bisync
+	SyncActionRecord struct {
+		Action     string `json:"action"`      // upload 上传, download 下载, delete 删除网盘文件, delete_local 删除本地文件, move 移动网盘文件, move_local 移动本地文件, conflict 冲突, skip 跳过
+		Reason     string `json:"reason"`      // 原因
+		Path       string `json:"path"`        // 相对于同步目录的路径
+		From       string `json:"from"`        // 重命名的原相对路径, 用于 bisync
+		LocalPath  string `json:"local_path"`  // 本地路径, sync 删除网盘文件时为空
+		RemotePath string `json:"remote_path"` // 网盘路径
+		Isdir      bool   `json:"isdir"`       // 是否为目录
+		Size       int64  `json:"size"`        // 文件大小, 目录为0
+	}
+
+	// UserRecord 百度帐号, 用于 who, loglist, 不包含登录凭据
+	UserRecord struct {
+		UID     uint64  `json:"uid"`
+		Name    string  `json:"name"`    // 用户名
+		Sex     string  `json:"sex"`     // 性别
+		Age     float64 `json:"age"`     // 帐号年龄
+		Workdir string  `json:"workdir"` // 工作目录
+		Active  bool    `json:"active"`  // 是否为当前帐号
+	}
+)
+
+// NewFileRecord 将 baidupcs.FileDirectory 转换为 FileRecord
+func NewFileRecord(fd *baidupcs.FileDirectory) *FileRecord {
+	return &FileRecord{
+		FsID:     fd.FsID,
+		AppID:    fd.AppID,
+		Path:     fd.Path,
+		Filename: fd.Filename,
+		Isdir:    fd.Isdir,
+		Size:     fd.Size,
+		Ctime:    fd.Ctime,
+		Mtime:    fd.Mtime,
+		MD5:      fd.MD5,
+	}
+}
+
+// NewFileRecords 将 baidupcs.FileDirectoryList 转换为 FileRecord 列表
+func NewFileRecords(fdl baidupcs.FileDirectoryList) []*FileRecord {
+	records := make([]*FileRecord, 0, len(fdl))
+	for _, fd := range fdl {
+		if fd == nil {
+			continue
+		}
+		records = append(records, NewFileRecord(fd))
+	}
+	return records
+}
+
+// NewShareRecords 将 baidupcs.ShareRecordInfoList 转换为 ShareRecord 列表
+func NewShareRecords(list baidupcs.ShareRecordInfoList) []*ShareRecord {
+	records := make([]*ShareRecord, 0, len(list))
+	for _, info := range list {
+		if info == nil {
+			continue
+		}
+		records = append(records, &ShareRecord{
+			ShareID:         info.ShareID,
+			FsIDs:           info.FsIds,
+			Link:            info.Shortlink,
+			Passwd:          info.Passwd,
+			Status:          info.Status,
+			TypicalCategory: info.TypicalCategory,
+			TypicalPath:     info.TypicalPath,
+		})
+	}
+	return records
+}
+
+// NewCloudDlTaskRecords 将 baidupcs.CloudDlTaskList 转换为 CloudDlTaskRecord 列表
+func NewCloudDlTaskRecords(cl baidupcs.CloudDlTaskList) []*CloudDlTaskRecord {
+	records := make([]*CloudDlTaskRecord, 0, len(cl))
+	for _, task := range cl {
+		if task == nil {
+			continue
+		}
+		records = append(records, &CloudDlTaskRecord{
+			TaskID:       task.TaskID,
+			TaskName:     task.TaskName,
+			Status:       task.Status,
+			StatusText:   task.StatusText,
+			FileSize:     task.FileSize,
+			FinishedSize: task.FinishedSize,
+			CreateTime:   task.CreateTime,
+			StartTime:    task.StartTime,
+			FinishTime:   task.FinishTime,
+			SavePath:     task.SavePath,
+			SourceURL:    task.SourceURL,
+			Files:        newCloudDlFileRecords(task.FileList),
+		})
+	}
+	return records
+}
+
+func newCloudDlFileRecords(files []*baidupcs.CloudDlFileInfo) []*CloudDlFileRecord {
+	if len(files) == 0 {
+		return nil
+	}
+	records := make([]*CloudDlFileRecord, 0, len(files))
+	for _, f := range files {
+		if f == nil {
+			continue
+		}
+		records = append(records, &CloudDlFileRecord{
+			FileName:     f.FileName,
+			FileSize:     f.FileSize,
+			FinishedSize: f.FinishedSize,
+		})
+	}
+	return records
+}
+
+// NewRecycleRecords 将 baidupcs.RecycleFDInfoList 转换为 RecycleRecord 列表
+func NewRecycleRecords(fdl baidupcs.RecycleFDInfoList) []*RecycleRecord {
+	records := make([]*RecycleRecord, 0, len(fdl))
+	for _, fd := range fdl {
+		if fd == nil {
+			continue
+		}
+		records = append(records, &RecycleRecord{
+			FsID:     fd.FsID,
+			Path:     fd.Path,
+			Filename: fd.Filename,
+			Isdir:    fd.Isdir == 1,
+			Size:     fd.Size,
+			Ctime:    fd.Ctime,
+			Mtime:    fd.Mtime,
+			MD5:      fd.MD5,
+			LeftTime: fd.LeftTime,
+		})
+	}
+	return records
+}
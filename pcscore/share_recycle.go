@@ -0,0 +1,61 @@
+package pcscore
+
+import (
+	"github.com/Erope/BaiduPCS-Go/baidupcs"
+)
+
+// ShareList 获取已分享列表的第 page 页, page 从1开始
+func (s *Session) ShareList(page int) (records baidupcs.ShareRecordInfoList, err error) {
+	if page < 1 {
+		page = 1
+	}
+	records, pcsError := s.Client.ShareList(page)
+	if pcsError != nil {
+		return nil, pcsError
+	}
+	return records, nil
+}
+
+// RecycleList 获取回收站列表的第 page 页, page 从1开始
+func (s *Session) RecycleList(page int) (fdl baidupcs.RecycleFDInfoList, err error) {
+	if page < 1 {
+		page = 1
+	}
+	fdl, pcsError := s.Client.RecycleList(page)
+	if pcsError != nil {
+		return nil, pcsError
+	}
+	return fdl, nil
+}
+
+// RecycleListAll 获取回收站所有页的列表
+func (s *Session) RecycleListAll() (fdl baidupcs.RecycleFDInfoList, err error) {
+	for page := 1; ; page++ {
+		pageFdl, pcsError := s.Client.RecycleList(page)
+		if pcsError != nil {
+			return nil, pcsError
+		}
+		if len(pageFdl) == 0 {
+			return fdl, nil
+		}
+		fdl = append(fdl, pageFdl...)
+	}
+}
+
+// CloudDlListTask 获取离线下载任务列表
+func (s *Session) CloudDlListTask() (cl baidupcs.CloudDlTaskList, err error) {
+	cl, pcsError := s.Client.CloudDlListTask()
+	if pcsError != nil {
+		return nil, pcsError
+	}
+	return cl, nil
+}
+
+// CloudDlQueryTask 精确查询离线下载任务
+func (s *Session) CloudDlQueryTask(taskIDs []int64) (cl baidupcs.CloudDlTaskList, err error) {
+	cl, pcsError := s.Client.CloudDlQueryTask(taskIDs)
+	if pcsError != nil {
+		return nil, pcsError
+	}
+	return cl, nil
+}
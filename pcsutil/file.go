@@ -3,6 +3,7 @@ package pcsutil
 import (
 	"github.com/Erope/BaiduPCS-Go/pcsverbose"
 	"github.com/kardianos/osext"
+	"io/ioutil"
 	"os"
 	"path"
 	"path/filepath"
@@ -110,3 +111,32 @@ func WalkDir(dirPth, suffix string) (files []string, err error) {
 func ConvertToUnixPathSeparator(p string) string {
 	return strings.Replace(p, "\\", "/", -1)
 }
+
+// WriteFileAtomic 先将 data 写入同一目录下的临时文件, 再重命名为 filename,
+// 防止写入中途退出导致文件损坏, 多个进程同时写入时也不会使用同一个临时文件
+func WriteFileAtomic(filename string, data []byte, perm os.FileMode) error {
+	dir, base := filepath.Split(filename)
+	if dir == "" {
+		dir = "."
+	}
+	tmp, err := ioutil.TempFile(dir, base+".*.tmp")
+	if err != nil {
+		return err
+	}
+	tmpName := tmp.Name()
+
+	_, err = tmp.Write(data)
+	if err == nil {
+		err = tmp.Chmod(perm)
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmpName, filename)
+	}
+	if err != nil {
+		os.Remove(tmpName)
+	}
+	return err
+}
@@ -0,0 +1,28 @@
+// Package filelock 文件锁, 用于多个进程读写同一个文件
+package filelock
+
+import (
+	"os"
+)
+
+// Lock 打开或创建锁文件 path, 并加上排他锁, 其他进程持有锁时阻塞等待.
+// 返回的函数用于解锁
+func Lock(path string) (unlock func() error, err error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR, 0600)
+	if err != nil {
+		return nil, err
+	}
+
+	err = lockFile(f)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	return func() error {
+		err := unlockFile(f)
+		if closeErr := f.Close(); err == nil {
+			err = closeErr
+		}
+		return err
+	}, nil
+}
@@ -0,0 +1,188 @@
+package streamcrypto
+
+import (
+	"errors"
+	"io"
+	"sync"
+)
+
+var (
+	// ErrIncomplete 加密文件未完整写入
+	ErrIncomplete = errors.New("加密文件的数据不完整")
+)
+
+type (
+	// DecryptWriterAt 接收乱序写入的加密文件数据, 如多线程下载, 分块完整后解密, 明文写入 dst 对应的位置.
+	// 未完整的分块缓存在内存中, 因此不支持断点续传
+	DecryptWriterAt struct {
+		fc        *fileCipher
+		dst       io.WriterAt
+		size      int64
+		plainSize int64
+		numChunks int64
+
+		mu        sync.Mutex
+		pending   map[int64]*partialChunk
+		done      []uint64 // 已解密的分块
+		decrypted int64
+	}
+
+	partialChunk struct {
+		data   []byte
+		filled []span // 已写入的部分, 按位置排序, 不重叠
+	}
+
+	span struct {
+		begin, end int
+	}
+)
+
+// NewDecryptWriterAt 解密大小为 encryptedSize 的加密文件, header 为加密文件开头的 HeaderSize 字节
+func NewDecryptWriterAt(key, header []byte, dst io.WriterAt, encryptedSize int64) (*DecryptWriterAt, error) {
+	h, err := ParseHeader(header)
+	if err != nil {
+		return nil, err
+	}
+	fc, err := newFileCipher(key, h)
+	if err != nil {
+		return nil, err
+	}
+	plainSize, err := h.PlainSize(encryptedSize)
+	if err != nil {
+		return nil, err
+	}
+	numChunks := h.numChunks(plainSize)
+	return &DecryptWriterAt{
+		fc:        fc,
+		dst:       dst,
+		size:      encryptedSize,
+		plainSize: plainSize,
+		numChunks: numChunks,
+		pending:   map[int64]*partialChunk{},
+		done:      make([]uint64, (numChunks+63)/64),
+	}, nil
+}
+
+// PlainSize 返回明文的大小
+func (dw *DecryptWriterAt) PlainSize() int64 {
+	return dw.plainSize
+}
+
+// WriteAt 写入加密文件位置 off 的数据
+func (dw *DecryptWriterAt) WriteAt(p []byte, off int64) (n int, err error) {
+	dw.mu.Lock()
+	defer dw.mu.Unlock()
+
+	for len(p) > 0 {
+		if off >= dw.size {
+			return n, ErrInvalidSize
+		}
+
+		var take int
+		if off < HeaderSize {
+			// 头部已单独读取, 只检查是否一致
+			take = int(HeaderSize - off)
+			if take > len(p) {
+				take = len(p)
+			}
+			for i := 0; i < take; i++ {
+				if p[i] != dw.fc.raw[off+int64(i)] {
+					return n, ErrAuthFailed
+				}
+			}
+		} else {
+			take, err = dw.writeChunk(p, off)
+			if err != nil {
+				return n, err
+			}
+		}
+		n += take
+		off += int64(take)
+		p = p[take:]
+	}
+	return n, nil
+}
+
+// writeChunk 写入位置 off 所在的分块, 返回写入的数据量
+func (dw *DecryptWriterAt) writeChunk(p []byte, off int64) (int, error) {
+	var (
+		index      = (off - HeaderSize) / int64(dw.fc.header.ChunkSize+TagSize)
+		chunkBegin = dw.fc.chunkOffset(index)
+		chunkLen   = int64(dw.fc.header.ChunkSize + TagSize)
+		pos        = int(off - chunkBegin)
+	)
+	if chunkBegin+chunkLen > dw.size {
+		chunkLen = dw.size - chunkBegin
+	}
+	take := int(chunkLen) - pos
+	if take > len(p) {
+		take = len(p)
+	}
+	if dw.isDone(index) { // 重复写入
+		return take, nil
+	}
+
+	pc := dw.pending[index]
+	if pc == nil {
+		pc = &partialChunk{
+			data: make([]byte, chunkLen),
+		}
+		dw.pending[index] = pc
+	}
+	copy(pc.data[pos:], p[:take])
+	if !pc.fill(pos, pos+take) {
+		return take, nil
+	}
+
+	// 分块已完整, 解密
+	delete(dw.pending, index)
+	plain, err := dw.fc.open(pc.data[:0], pc.data, index, index == dw.numChunks-1)
+	if err != nil {
+		return 0, err
+	}
+	_, err = dw.dst.WriteAt(plain, index*int64(dw.fc.header.ChunkSize))
+	if err != nil {
+		return 0, err
+	}
+	dw.done[index/64] |= 1 << uint(index%64)
+	dw.decrypted++
+	return take, nil
+}
+
+func (dw *DecryptWriterAt) isDone(index int64) bool {
+	return dw.done[index/64]&(1<<uint(index%64)) != 0
+}
+
+// Finish 检查所有的分块是否都已解密
+func (dw *DecryptWriterAt) Finish() error {
+	dw.mu.Lock()
+	defer dw.mu.Unlock()
+	if dw.decrypted != dw.numChunks {
+		return ErrIncomplete
+	}
+	return nil
+}
+
+// fill 记录已写入 [begin, end), 返回分块是否已完整
+func (pc *partialChunk) fill(begin, end int) bool {
+	merged := make([]span, 0, len(pc.filled)+1)
+	for _, s := range pc.filled {
+		switch {
+		case s.end < begin:
+			merged = append(merged, s)
+		case end < s.begin:
+			merged = append(merged, span{begin, end})
+			begin, end = s.begin, s.end
+		default: // 相交或相邻, 合并
+			if s.begin < begin {
+				begin = s.begin
+			}
+			if s.end > end {
+				end = s.end
+			}
+		}
+	}
+	merged = append(merged, span{begin, end})
+	pc.filled = merged
+	return len(merged) == 1 && merged[0].begin == 0 && merged[0].end == len(pc.data)
+}
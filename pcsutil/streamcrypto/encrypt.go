@@ -0,0 +1,199 @@
+package streamcrypto
+
+import (
+	"io"
+	"sync"
+)
+
+type (
+	// EncryptReaderAt 将明文 src 作为加密文件读取, 可以并发读取任意位置, 用于多线程分块上传.
+	// 读取时才加密涉及的分块, 同一个 EncryptReaderAt 读取的结果总是相同的
+	EncryptReaderAt struct {
+		fc        *fileCipher
+		src       io.ReaderAt
+		plainSize int64
+		size      int64
+
+		mu    sync.Mutex
+		cache [4]*sealedChunk // 最近加密的分块, 避免分多次读取同一个分块时重复加密
+		next  int
+	}
+
+	sealedChunk struct {
+		index int64
+		data  []byte
+	}
+
+	// EncryptReader 按顺序加密长度未知的数据流, 如标准输入
+	EncryptReader struct {
+		fc    *fileCipher
+		r     io.Reader
+		index int64
+		buf   []byte // 待输出的加密数据
+		plain []byte
+		peek  []byte // 预读的一个字节, 用于判断是否为最后一个分块
+		done  bool
+		err   error
+	}
+)
+
+// NewEncryptReaderAt 加密大小为 plainSize 的明文 src, 使用新生成的头部
+func NewEncryptReaderAt(key []byte, src io.ReaderAt, plainSize int64) (*EncryptReaderAt, error) {
+	h, err := NewHeader(0)
+	if err != nil {
+		return nil, err
+	}
+	h.KeyID = keyID(key)
+	fc, err := newFileCipher(key, h)
+	if err != nil {
+		return nil, err
+	}
+	return &EncryptReaderAt{
+		fc:        fc,
+		src:       src,
+		plainSize: plainSize,
+		size:      h.EncryptedSize(plainSize),
+	}, nil
+}
+
+// Len 返回加密文件的大小
+func (er *EncryptReaderAt) Len() int64 {
+	return er.size
+}
+
+// ReadAt 读取加密文件位置 off 的数据
+func (er *EncryptReaderAt) ReadAt(p []byte, off int64) (n int, err error) {
+	if off < 0 {
+		return 0, io.ErrUnexpectedEOF
+	}
+	for len(p) > 0 && off < er.size {
+		var data []byte
+		if off < HeaderSize {
+			data = er.fc.raw[off:]
+		} else {
+			index := (off - HeaderSize) / int64(er.fc.header.ChunkSize+TagSize)
+			data, err = er.chunk(index)
+			if err != nil {
+				return n, err
+			}
+			data = data[off-er.fc.chunkOffset(index):]
+		}
+		nn := copy(p, data)
+		n += nn
+		off += int64(nn)
+		p = p[nn:]
+	}
+	if len(p) > 0 {
+		return n, io.EOF
+	}
+	return n, nil
+}
+
+// chunk 返回加密后的分块
+func (er *EncryptReaderAt) chunk(index int64) ([]byte, error) {
+	er.mu.Lock()
+	for _, c := range er.cache {
+		if c != nil && c.index == index {
+			er.mu.Unlock()
+			return c.data, nil
+		}
+	}
+	er.mu.Unlock()
+
+	var (
+		chunkSize = int64(er.fc.header.ChunkSize)
+		begin     = index * chunkSize
+		end       = begin + chunkSize
+	)
+	if end > er.plainSize {
+		end = er.plainSize
+	}
+	plain := make([]byte, end-begin, chunkSize+TagSize)
+	nn, err := er.src.ReadAt(plain, begin)
+	if nn < len(plain) {
+		if err == nil || err == io.EOF {
+			err = io.ErrUnexpectedEOF // 文件在上传时被截断
+		}
+		return nil, err
+	}
+	data := er.fc.seal(plain[:0], plain, index, end == er.plainSize)
+
+	er.mu.Lock()
+	er.cache[er.next] = &sealedChunk{
+		index: index,
+		data:  data,
+	}
+	er.next = (er.next + 1) % len(er.cache)
+	er.mu.Unlock()
+	return data, nil
+}
+
+// NewEncryptReader 加密数据流 r, 使用新生成的头部
+func NewEncryptReader(key []byte, r io.Reader) (*EncryptReader, error) {
+	h, err := NewHeader(0)
+	if err != nil {
+		return nil, err
+	}
+	h.KeyID = keyID(key)
+	fc, err := newFileCipher(key, h)
+	if err != nil {
+		return nil, err
+	}
+	return &EncryptReader{
+		fc:    fc,
+		r:     r,
+		buf:   append([]byte(nil), fc.raw...),
+		plain: make([]byte, 0, h.ChunkSize+TagSize),
+	}, nil
+}
+
+func (er *EncryptReader) Read(p []byte) (n int, err error) {
+	for len(er.buf) == 0 {
+		if er.err != nil {
+			return 0, er.err
+		}
+		if er.done {
+			return 0, io.EOF
+		}
+		er.err = er.fill()
+	}
+	n = copy(p, er.buf)
+	er.buf = er.buf[n:]
+	return n, nil
+}
+
+// fill 读取并加密下一个分块
+func (er *EncryptReader) fill() error {
+	chunkSize := er.fc.header.ChunkSize
+	plain := append(er.plain[:0], er.peek...)
+	plain = plain[:chunkSize]
+	n, err := io.ReadFull(er.r, plain[len(er.peek):])
+	plain = plain[:len(er.peek)+n]
+	switch err {
+	case nil:
+	case io.EOF, io.ErrUnexpectedEOF:
+		er.done = true
+	default:
+		return err
+	}
+
+	// 分块已满时预读一个字节, 判断是否还有数据
+	if !er.done {
+		var b [1]byte
+		_, err = io.ReadFull(er.r, b[:])
+		switch err {
+		case nil:
+			er.peek = append(er.peek[:0], b[0])
+		case io.EOF:
+			er.done = true
+		default:
+			return err
+		}
+	} else {
+		er.peek = er.peek[:0]
+	}
+
+	er.buf = er.fc.seal(plain[:0], plain, er.index, er.done)
+	er.index++
+	return nil
+}
@@ -0,0 +1,74 @@
+package streamcrypto
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+)
+
+const (
+	// MaxNameSize 网盘文件名的最大长度 (字节)
+	MaxNameSize = 255
+)
+
+var (
+	// ErrNameTooLong 加密后的文件名超过网盘的长度限制
+	ErrNameTooLong = errors.New("加密后的文件名过长")
+)
+
+// deriveKey 由主密钥派生用途为 label 的密钥
+func deriveKey(key []byte, label string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(label))
+	return mac.Sum(nil)
+}
+
+// nameCipher 返回加密文件名的 AEAD, 密钥由主密钥派生, 与生成 nonce 的密钥不同
+func nameCipher(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(deriveKey(key, "name-enc"))
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// EncryptName 加密文件名, 相同的文件名总是得到相同的结果, 以便查找和覆盖已上传的文件.
+// nonce 由文件名的 HMAC 生成, 结果为 base64url 编码的 nonce 和密文, 约为原长度的 4/3 加 38 字节,
+// 超过 MaxNameSize 返回 ErrNameTooLong
+func EncryptName(key []byte, name string) (string, error) {
+	aead, err := nameCipher(key)
+	if err != nil {
+		return "", err
+	}
+	mac := hmac.New(sha256.New, deriveKey(key, "name-iv"))
+	mac.Write([]byte(name))
+	nonce := mac.Sum(nil)[:aead.NonceSize()]
+
+	data := aead.Seal(nonce, nonce, []byte(name), nil)
+	encrypted := base64.RawURLEncoding.EncodeToString(data)
+	if len(encrypted) > MaxNameSize {
+		return "", fmt.Errorf("%w: %s, 加密后为 %d 字节, 最大 %d 字节", ErrNameTooLong, name, len(encrypted), MaxNameSize)
+	}
+	return encrypted, nil
+}
+
+// DecryptName 解密文件名, 不是使用 key 加密的文件名返回 ErrAuthFailed
+func DecryptName(key []byte, name string) (string, error) {
+	aead, err := nameCipher(key)
+	if err != nil {
+		return "", err
+	}
+	data, err := base64.RawURLEncoding.DecodeString(name)
+	if err != nil || len(data) < aead.NonceSize()+TagSize {
+		return "", ErrAuthFailed
+	}
+	plain, err := aead.Open(nil, data[:aead.NonceSize()], data[aead.NonceSize():], nil)
+	if err != nil {
+		return "", ErrAuthFailed
+	}
+	return string(plain), nil
+}
@@ -0,0 +1,110 @@
+package streamcrypto
+
+import (
+	"io"
+)
+
+type (
+	// DecryptRangeReader 解密明文范围 [begin, end) 所在的分块, 只输出该范围的明文, 用于输出或下载文件的一部分.
+	// src 为加密文件从 EncryptedRange 起始位置开始的数据
+	DecryptRangeReader struct {
+		fc         *fileCipher
+		src        io.Reader
+		plainSize  int64
+		numChunks  int64
+		begin, end int64 // 明文范围
+		index      int64 // 下一个解密的分块
+		chunk      []byte
+		buf        []byte // 待输出的明文
+	}
+)
+
+// NewDecryptRangeReader 解密大小为 encryptedSize 的加密文件中明文范围 [begin, end) 的数据,
+// header 为加密文件开头的 HeaderSize 字节, 超出明文大小的部分会被忽略
+func NewDecryptRangeReader(key, header []byte, encryptedSize, begin, end int64, src io.Reader) (*DecryptRangeReader, error) {
+	h, err := ParseHeader(header)
+	if err != nil {
+		return nil, err
+	}
+	fc, err := newFileCipher(key, h)
+	if err != nil {
+		return nil, err
+	}
+	plainSize, err := h.PlainSize(encryptedSize)
+	if err != nil {
+		return nil, err
+	}
+	if end > plainSize {
+		end = plainSize
+	}
+	if end < 0 {
+		end = 0
+	}
+	if begin < 0 || begin > end {
+		begin = end
+	}
+	return &DecryptRangeReader{
+		fc:        fc,
+		src:       src,
+		plainSize: plainSize,
+		numChunks: h.numChunks(plainSize),
+		begin:     begin,
+		end:       end,
+		index:     begin / int64(h.ChunkSize),
+		chunk:     make([]byte, h.ChunkSize+TagSize),
+	}, nil
+}
+
+// EncryptedRange 返回需要读取的加密文件范围 [begin, end), 为明文范围所在的完整分块
+func (dr *DecryptRangeReader) EncryptedRange() (begin, end int64) {
+	if dr.begin >= dr.end {
+		return HeaderSize, HeaderSize
+	}
+	last := (dr.end - 1) / int64(dr.fc.header.ChunkSize)
+	return dr.fc.chunkOffset(dr.index), dr.fc.chunkOffset(last) + dr.chunkLen(last)
+}
+
+// chunkLen 返回分块在加密文件中的长度, 最后一个分块可能不完整
+func (dr *DecryptRangeReader) chunkLen(index int64) int64 {
+	if index < dr.numChunks-1 {
+		return int64(dr.fc.header.ChunkSize + TagSize)
+	}
+	return dr.plainSize - index*int64(dr.fc.header.ChunkSize) + TagSize
+}
+
+// Read 读取解密后的明文, 加密数据不完整返回 io.ErrUnexpectedEOF, 认证失败返回 ErrAuthFailed
+func (dr *DecryptRangeReader) Read(p []byte) (n int, err error) {
+	for len(dr.buf) == 0 {
+		chunkBegin := dr.index * int64(dr.fc.header.ChunkSize)
+		if dr.begin >= dr.end || chunkBegin >= dr.end {
+			return 0, io.EOF
+		}
+
+		data := dr.chunk[:dr.chunkLen(dr.index)]
+		_, err = io.ReadFull(dr.src, data)
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		if err != nil {
+			return 0, err
+		}
+		plain, err := dr.fc.open(data[:0], data, dr.index, dr.index == dr.numChunks-1)
+		if err != nil {
+			return 0, err
+		}
+		dr.index++
+
+		// 截取范围内的部分
+		if chunkBegin+int64(len(plain)) > dr.end {
+			plain = plain[:dr.end-chunkBegin]
+		}
+		if dr.begin > chunkBegin {
+			plain = plain[dr.begin-chunkBegin:]
+		}
+		dr.buf = plain
+	}
+
+	n = copy(p, dr.buf)
+	dr.buf = dr.buf[n:]
+	return n, nil
+}
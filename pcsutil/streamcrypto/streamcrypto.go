@@ -0,0 +1,271 @@
+// Package streamcrypto 客户端加密的分块流格式.
+//
+// 加密文件由头部和若干分块组成, 头部记录格式版本, 分块大小和随机盐,
+// 每个文件使用由主密钥和盐派生的密钥, 每个分块使用 AES-256-GCM 单独加密和认证,
+// nonce 由分块序号和是否为最后一个分块组成, 头部作为附加数据参与认证,
+// 因此可以按任意顺序加密和解密分块, 支持多线程分块上传和多线程, 部分下载,
+// 同时可以发现分块被篡改, 重排或截断.
+//
+// 头部格式 (HeaderSize 字节):
+//
+//	magic "BPCS-ENC" (8) | 版本 (1) | 密钥 ID (3) | 分块大小, 大端序 (4) | 盐 (16)
+//
+// 密钥 ID 由主密钥派生, 用于在解密前发现密钥或密码盐不正确, 旧版本的文件为 0, 不检查
+package streamcrypto
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"golang.org/x/crypto/scrypt"
+	"io"
+)
+
+const (
+	// Version1 格式版本 1
+	Version1 = 1
+	// HeaderSize 头部的大小
+	HeaderSize = 32
+	// TagSize 每个分块的认证标签大小
+	TagSize = 16
+	// KeySize 主密钥的大小
+	KeySize = 32
+	// DefaultChunkSize 默认的分块大小
+	DefaultChunkSize = 64 * 1024
+	// MaxChunkSize 分块大小的最大值
+	MaxChunkSize = 16 * 1024 * 1024
+
+	magic     = "BPCS-ENC"
+	saltSize  = 16
+	keyIDSize = 3
+)
+
+var (
+	// ErrNotEncrypted 不是加密文件
+	ErrNotEncrypted = errors.New("不是加密文件")
+	// ErrUnsupportedVersion 不支持的格式版本
+	ErrUnsupportedVersion = errors.New("不支持的加密格式版本")
+	// ErrAuthFailed 认证失败, 密钥错误或数据被篡改
+	ErrAuthFailed = errors.New("解密失败, 密钥错误或数据已损坏")
+	// ErrInvalidSize 加密文件的大小不正确, 可能被截断
+	ErrInvalidSize = errors.New("加密文件的大小不正确")
+	// ErrKeyMismatch 加密文件的密钥 ID 与主密钥不一致
+	ErrKeyMismatch = errors.New("密钥不正确, 请检查密钥或密码盐 enc_salt")
+
+	// legacyPassphraseSalt 旧版本由密码派生主密钥时使用的固定盐, 未设置密码盐时使用, 以便解密旧文件
+	legacyPassphraseSalt = []byte("BaiduPCS-Go streamcrypto passphrase")
+)
+
+type (
+	// Header 加密文件的头部
+	Header struct {
+		Version   byte
+		KeyID     [keyIDSize]byte
+		ChunkSize int
+		Salt      [saltSize]byte
+	}
+
+	// fileCipher 单个文件的加密和解密
+	fileCipher struct {
+		header *Header
+		raw    []byte // 头部的原始数据, 作为附加数据
+		aead   cipher.AEAD
+	}
+)
+
+// IsRawKey 判断 s 是否为 64 位十六进制字符串的原始密钥
+func IsRawKey(s string) bool {
+	if len(s) != KeySize*2 {
+		return false
+	}
+	_, err := hex.DecodeString(s)
+	return err == nil
+}
+
+// NewSalt 生成随机的密码盐, 返回十六进制字符串, 需与密码一起保存
+func NewSalt() (string, error) {
+	salt := make([]byte, saltSize)
+	_, err := io.ReadFull(rand.Reader, salt)
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(salt), nil
+}
+
+// ParseKey 解析主密钥, 64 位十六进制字符串作为原始密钥, 其他作为密码, 使用 scrypt 和十六进制的密码盐 salt 派生.
+// salt 为空时使用旧版本的固定盐
+func ParseKey(s, salt string) ([]byte, error) {
+	if s == "" {
+		return nil, errors.New("密钥为空")
+	}
+	if IsRawKey(s) {
+		key, _ := hex.DecodeString(s)
+		return key, nil
+	}
+
+	saltBytes, err := ParseSalt(salt)
+	if err != nil {
+		return nil, err
+	}
+	return scrypt.Key([]byte(s), saltBytes, 1<<15, 8, 1, KeySize)
+}
+
+// ParseSalt 解析十六进制的密码盐, 为空时返回旧版本的固定盐
+func ParseSalt(salt string) ([]byte, error) {
+	if salt == "" {
+		return legacyPassphraseSalt, nil
+	}
+	saltBytes, err := hex.DecodeString(salt)
+	if err != nil || len(saltBytes) < 8 {
+		return nil, fmt.Errorf("密码盐格式错误: %s", salt)
+	}
+	return saltBytes, nil
+}
+
+// keyID 返回主密钥的 ID, 记录在加密文件的头部
+func keyID(key []byte) (id [keyIDSize]byte) {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte("key-id"))
+	copy(id[:], mac.Sum(nil))
+	return
+}
+
+// NewHeader 生成新的头部, 使用随机的盐, chunkSize 为 0 则使用默认值
+func NewHeader(chunkSize int) (*Header, error) {
+	if chunkSize <= 0 {
+		chunkSize = DefaultChunkSize
+	}
+	if chunkSize > MaxChunkSize {
+		return nil, fmt.Errorf("分块大小不能超过 %d", MaxChunkSize)
+	}
+	h := &Header{
+		Version:   Version1,
+		ChunkSize: chunkSize,
+	}
+	_, err := io.ReadFull(rand.Reader, h.Salt[:])
+	if err != nil {
+		return nil, err
+	}
+	return h, nil
+}
+
+// ParseHeader 解析头部, data 至少为 HeaderSize 字节
+func ParseHeader(data []byte) (*Header, error) {
+	if len(data) < HeaderSize || !bytes.Equal(data[:len(magic)], []byte(magic)) {
+		return nil, ErrNotEncrypted
+	}
+	h := &Header{
+		Version:   data[8],
+		ChunkSize: int(binary.BigEndian.Uint32(data[12:16])),
+	}
+	if h.Version != Version1 {
+		return nil, ErrUnsupportedVersion
+	}
+	if h.ChunkSize <= 0 || h.ChunkSize > MaxChunkSize {
+		return nil, ErrNotEncrypted
+	}
+	copy(h.KeyID[:], data[9:12])
+	copy(h.Salt[:], data[16:HeaderSize])
+	return h, nil
+}
+
+// MarshalBinary 返回头部的原始数据
+func (h *Header) MarshalBinary() ([]byte, error) {
+	data := make([]byte, HeaderSize)
+	copy(data, magic)
+	data[8] = h.Version
+	copy(data[9:12], h.KeyID[:])
+	binary.BigEndian.PutUint32(data[12:16], uint32(h.ChunkSize))
+	copy(data[16:], h.Salt[:])
+	return data, nil
+}
+
+// numChunks 明文大小为 plainSize 时的分块数量, 空文件也有一个分块
+func (h *Header) numChunks(plainSize int64) int64 {
+	n := (plainSize + int64(h.ChunkSize) - 1) / int64(h.ChunkSize)
+	if n == 0 {
+		n = 1
+	}
+	return n
+}
+
+// EncryptedSize 返回明文大小为 plainSize 时, 加密文件的大小
+func (h *Header) EncryptedSize(plainSize int64) int64 {
+	return HeaderSize + plainSize + h.numChunks(plainSize)*TagSize
+}
+
+// PlainSize 返回加密文件大小为 encryptedSize 时, 明文的大小
+func (h *Header) PlainSize(encryptedSize int64) (int64, error) {
+	size := encryptedSize - HeaderSize
+	if size < TagSize {
+		return 0, ErrInvalidSize
+	}
+	n := (size + int64(h.ChunkSize) + TagSize - 1) / (int64(h.ChunkSize) + TagSize)
+	plainSize := size - n*TagSize
+	if plainSize < 0 || h.EncryptedSize(plainSize) != encryptedSize {
+		return 0, ErrInvalidSize
+	}
+	return plainSize, nil
+}
+
+func newFileCipher(key []byte, h *Header) (*fileCipher, error) {
+	if len(key) != KeySize {
+		return nil, fmt.Errorf("密钥长度应为 %d 字节", KeySize)
+	}
+	if h.KeyID != ([keyIDSize]byte{}) && h.KeyID != keyID(key) {
+		return nil, ErrKeyMismatch
+	}
+	raw, _ := h.MarshalBinary()
+
+	// 文件密钥 = HMAC-SHA256(主密钥, "content" | 盐)
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte("content"))
+	mac.Write(h.Salt[:])
+	block, err := aes.NewCipher(mac.Sum(nil))
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return &fileCipher{
+		header: h,
+		raw:    raw,
+		aead:   aead,
+	}, nil
+}
+
+// nonce 分块序号 (8) | 保留 (3) | 是否为最后一个分块 (1)
+func (fc *fileCipher) nonce(index int64, final bool) []byte {
+	nonce := make([]byte, fc.aead.NonceSize())
+	binary.BigEndian.PutUint64(nonce, uint64(index))
+	if final {
+		nonce[len(nonce)-1] = 1
+	}
+	return nonce
+}
+
+// chunkOffset 返回分块在加密文件中的位置
+func (fc *fileCipher) chunkOffset(index int64) int64 {
+	return HeaderSize + index*int64(fc.header.ChunkSize+TagSize)
+}
+
+func (fc *fileCipher) seal(dst, plain []byte, index int64, final bool) []byte {
+	return fc.aead.Seal(dst, fc.nonce(index, final), plain, fc.raw)
+}
+
+func (fc *fileCipher) open(dst, data []byte, index int64, final bool) ([]byte, error) {
+	plain, err := fc.aead.Open(dst, fc.nonce(index, final), data, fc.raw)
+	if err != nil {
+		return nil, ErrAuthFailed
+	}
+	return plain, nil
+}
@@ -0,0 +1,293 @@
+package streamcrypto
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"strings"
+	"sync"
+	"testing"
+)
+
+var testKey = bytes.Repeat([]byte{0x42}, KeySize)
+
+// memWriterAt 内存中的 io.WriterAt
+type memWriterAt struct {
+	mu   sync.Mutex
+	data []byte
+}
+
+func (mw *memWriterAt) WriteAt(p []byte, off int64) (int, error) {
+	mw.mu.Lock()
+	defer mw.mu.Unlock()
+	if end := int(off) + len(p); end > len(mw.data) {
+		mw.data = append(mw.data, make([]byte, end-len(mw.data))...)
+	}
+	copy(mw.data[off:], p)
+	return len(p), nil
+}
+
+// decryptShuffled 将加密文件分成长度不一的片段, 乱序并发写入 DecryptWriterAt
+func decryptShuffled(t *testing.T, encrypted []byte) []byte {
+	header := encrypted[:HeaderSize]
+	dst := &memWriterAt{}
+	dw, err := NewDecryptWriterAt(testKey, header, dst, int64(len(encrypted)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var pieces [][2]int
+	for off := 0; off < len(encrypted); {
+		end := off + 1 + rand.Intn(100000)
+		if end > len(encrypted) {
+			end = len(encrypted)
+		}
+		pieces = append(pieces, [2]int{off, end})
+		off = end
+	}
+	var wg sync.WaitGroup
+	for _, i := range rand.Perm(len(pieces)) {
+		wg.Add(1)
+		go func(piece [2]int) {
+			defer wg.Done()
+			_, err := dw.WriteAt(encrypted[piece[0]:piece[1]], int64(piece[0]))
+			if err != nil {
+				t.Error(err)
+			}
+		}(pieces[i])
+	}
+	wg.Wait()
+
+	if err = dw.Finish(); err != nil {
+		t.Fatal(err)
+	}
+	if int64(len(dst.data)) != dw.PlainSize() {
+		t.Fatalf("plain size: %d, want %d", len(dst.data), dw.PlainSize())
+	}
+	return dst.data
+}
+
+func TestEncryptDecrypt(t *testing.T) {
+	for _, size := range []int{0, 1, DefaultChunkSize - 1, DefaultChunkSize, DefaultChunkSize + 1, 3*DefaultChunkSize + 123} {
+		plain := make([]byte, size)
+		rand.Read(plain)
+
+		era, err := NewEncryptReaderAt(testKey, bytes.NewReader(plain), int64(size))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if era.Len() != era.fc.header.EncryptedSize(int64(size)) {
+			t.Fatalf("size %d: len %d", size, era.Len())
+		}
+
+		// 以不同的长度分段读取, 结果应一致
+		encrypted, err := ioutil.ReadAll(io.NewSectionReader(era, 0, era.Len()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		again := make([]byte, len(encrypted))
+		for off := 0; off < len(again); off += 1000 {
+			end := off + 1000
+			if end > len(again) {
+				end = len(again)
+			}
+			_, err = era.ReadAt(again[off:end], int64(off))
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+		if !bytes.Equal(encrypted, again) {
+			t.Fatalf("size %d: ReadAt not stable", size)
+		}
+		if plainSize, err := era.fc.header.PlainSize(int64(len(encrypted))); err != nil || plainSize != int64(size) {
+			t.Fatalf("size %d: plain size %d, %v", size, plainSize, err)
+		}
+
+		if !bytes.Equal(decryptShuffled(t, encrypted), plain) {
+			t.Errorf("size %d: decrypted data not match", size)
+		}
+
+		// 数据流加密
+		er, err := NewEncryptReader(testKey, bytes.NewReader(plain))
+		if err != nil {
+			t.Fatal(err)
+		}
+		streamed, err := ioutil.ReadAll(er)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(streamed) != len(encrypted) {
+			t.Fatalf("size %d: stream length %d, want %d", size, len(streamed), len(encrypted))
+		}
+		if !bytes.Equal(decryptShuffled(t, streamed), plain) {
+			t.Errorf("size %d: decrypted stream not match", size)
+		}
+	}
+}
+
+func TestDecryptErrors(t *testing.T) {
+	plain := make([]byte, 3*DefaultChunkSize)
+	era, _ := NewEncryptReaderAt(testKey, bytes.NewReader(plain), int64(len(plain)))
+	encrypted, _ := ioutil.ReadAll(io.NewSectionReader(era, 0, era.Len()))
+
+	// 篡改数据
+	tampered := append([]byte(nil), encrypted...)
+	tampered[HeaderSize+10] ^= 1
+	dw, _ := NewDecryptWriterAt(testKey, tampered[:HeaderSize], &memWriterAt{}, int64(len(tampered)))
+	if _, err := dw.WriteAt(tampered, 0); err != ErrAuthFailed {
+		t.Errorf("tampered: %v, want ErrAuthFailed", err)
+	}
+
+	// 密钥错误, 由头部的密钥 ID 发现
+	wrongKey := bytes.Repeat([]byte{1}, KeySize)
+	if _, err := NewDecryptWriterAt(wrongKey, encrypted[:HeaderSize], &memWriterAt{}, int64(len(encrypted))); err != ErrKeyMismatch {
+		t.Errorf("wrong key: %v, want ErrKeyMismatch", err)
+	}
+
+	// 没有密钥 ID 的旧版本头部, 解密时认证失败
+	legacy := append([]byte(nil), encrypted...)
+	copy(legacy[9:12], []byte{0, 0, 0})
+	dw, _ = NewDecryptWriterAt(wrongKey, legacy[:HeaderSize], &memWriterAt{}, int64(len(legacy)))
+	if _, err := dw.WriteAt(legacy, 0); err != ErrAuthFailed {
+		t.Errorf("legacy header: %v, want ErrAuthFailed", err)
+	}
+
+	// 按分块截断, 最后一个分块的标记不匹配
+	truncated := encrypted[:HeaderSize+2*(DefaultChunkSize+TagSize)]
+	dw, err := NewDecryptWriterAt(testKey, truncated[:HeaderSize], &memWriterAt{}, int64(len(truncated)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = dw.WriteAt(truncated, 0); err != ErrAuthFailed {
+		t.Errorf("truncated: %v, want ErrAuthFailed", err)
+	}
+
+	// 未写入完整
+	dw, _ = NewDecryptWriterAt(testKey, encrypted[:HeaderSize], &memWriterAt{}, int64(len(encrypted)))
+	dw.WriteAt(encrypted[:len(encrypted)-1], 0)
+	if err = dw.Finish(); err != ErrIncomplete {
+		t.Errorf("incomplete: %v, want ErrIncomplete", err)
+	}
+
+	if _, err = ParseHeader(make([]byte, HeaderSize)); err != ErrNotEncrypted {
+		t.Errorf("plain header: %v, want ErrNotEncrypted", err)
+	}
+}
+
+func TestEncryptName(t *testing.T) {
+	for _, name := range []string{"a.txt", "备份 2024.sql.gz", ""} {
+		encrypted, err := EncryptName(testKey, name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		again, _ := EncryptName(testKey, name)
+		if encrypted != again {
+			t.Errorf("%q: name encryption not deterministic", name)
+		}
+		decrypted, err := DecryptName(testKey, encrypted)
+		if err != nil || decrypted != name {
+			t.Errorf("%q: decrypted %q, %v", name, decrypted, err)
+		}
+	}
+
+	if _, err := DecryptName(testKey, "a.txt"); err != ErrAuthFailed {
+		t.Errorf("plain name: %v, want ErrAuthFailed", err)
+	}
+
+	// 加密后超过网盘文件名的长度限制
+	if _, err := EncryptName(testKey, strings.Repeat("a", 180)); !errors.Is(err, ErrNameTooLong) {
+		t.Errorf("long name: %v, want ErrNameTooLong", err)
+	}
+}
+
+func TestParseKey(t *testing.T) {
+	hexKey := "000102030405060708090a0b0c0d0e0f101112131415161718191a1b1c1d1e1f"
+	key, err := ParseKey(hexKey, "")
+	if err != nil || len(key) != KeySize || key[31] != 0x1f {
+		t.Errorf("hex key: %x, %v", key, err)
+	}
+
+	salt, err := NewSalt()
+	if err != nil {
+		t.Fatal(err)
+	}
+	key1, err := ParseKey("correct horse battery staple", salt)
+	if err != nil || len(key1) != KeySize {
+		t.Fatalf("passphrase: %x, %v", key1, err)
+	}
+	key2, _ := ParseKey("correct horse battery staple", salt)
+	if !bytes.Equal(key1, key2) {
+		t.Errorf("passphrase key not stable")
+	}
+
+	// 不同的盐派生不同的密钥, 未设置盐时使用旧版本的固定盐
+	salt2, _ := NewSalt()
+	key3, _ := ParseKey("correct horse battery staple", salt2)
+	legacy, _ := ParseKey("correct horse battery staple", "")
+	if bytes.Equal(key1, key3) || bytes.Equal(key1, legacy) || len(legacy) != KeySize {
+		t.Errorf("salt not used")
+	}
+
+	if _, err = ParseKey("", ""); err == nil {
+		t.Errorf("want error for empty key")
+	}
+	if _, err = ParseKey("passphrase", "xyz"); err == nil {
+		t.Errorf("want error for invalid salt")
+	}
+}
+
+func TestDecryptRangeReader(t *testing.T) {
+	chunk := int64(DefaultChunkSize)
+	for _, size := range []int64{0, 1, chunk, 3*chunk + 123} {
+		plain := make([]byte, size)
+		rand.Read(plain)
+		era, _ := NewEncryptReaderAt(testKey, bytes.NewReader(plain), size)
+		encrypted, _ := ioutil.ReadAll(io.NewSectionReader(era, 0, era.Len()))
+
+		for _, r := range [][2]int64{{0, size}, {0, 1}, {1, size - 1}, {chunk - 1, chunk + 1}, {chunk, 2 * chunk}, {size - 10, size + 10}, {2*chunk + 5, 3*chunk + 100}} {
+			begin, end := r[0], r[1]
+			if begin < 0 {
+				begin = 0
+			}
+			dr, err := NewDecryptRangeReader(testKey, encrypted[:HeaderSize], int64(len(encrypted)), begin, end, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			encBegin, encEnd := dr.EncryptedRange()
+			if encBegin < HeaderSize || encEnd > int64(len(encrypted)) || encBegin > encEnd {
+				t.Fatalf("size %d, range %v: encrypted range [%d, %d)", size, r, encBegin, encEnd)
+			}
+			dr.src = bytes.NewReader(encrypted[encBegin:encEnd])
+			got, err := ioutil.ReadAll(dr)
+			if err != nil {
+				t.Fatalf("size %d, range %v: %s", size, r, err)
+			}
+
+			if end > size {
+				end = size
+			}
+			if end < 0 {
+				end = 0
+			}
+			if begin > end {
+				begin = end
+			}
+			if !bytes.Equal(got, plain[begin:end]) {
+				t.Errorf("size %d, range %v: got %d bytes, want %d", size, r, len(got), end-begin)
+			}
+		}
+	}
+
+	// 加密数据不完整
+	plain := make([]byte, 2*chunk)
+	era, _ := NewEncryptReaderAt(testKey, bytes.NewReader(plain), int64(len(plain)))
+	encrypted, _ := ioutil.ReadAll(io.NewSectionReader(era, 0, era.Len()))
+	dr, _ := NewDecryptRangeReader(testKey, encrypted[:HeaderSize], int64(len(encrypted)), 0, chunk+1, nil)
+	encBegin, encEnd := dr.EncryptedRange()
+	dr.src = bytes.NewReader(encrypted[encBegin : encEnd-1])
+	if _, err := ioutil.ReadAll(dr); err != io.ErrUnexpectedEOF {
+		t.Errorf("short data: %v, want io.ErrUnexpectedEOF", err)
+	}
+}
@@ -1,6 +1,7 @@
 package downloader
 
 import (
+	"context"
 	"github.com/Erope/BaiduPCS-Go/requester/transfer"
 )
 
@@ -25,6 +26,8 @@ type Config struct {
 	InstanceStatePath          string                     // 断点续传信息路径
 	IsTest                     bool                       // 是否测试下载
 	TryHTTP                    bool                       // 是否尝试使用 http 连接
+	Range                      *transfer.Range            // 只下载文件的这一部分, 为 nil 时下载整个文件, 按 RangeGenMode_BlockSize 分配
+	Context                    context.Context            // 下载绑定的 context, 取消或超时后中断下载, 为 nil 时使用 context.Background()
 }
 
 //NewConfig 返回默认配置
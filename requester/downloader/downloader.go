@@ -24,6 +24,13 @@ const (
 	DefaultAcceptRanges = "bytes"
 )
 
+var (
+	// ErrRangeNotSupported 服务器不支持多线程下载, 无法只下载部分
+	ErrRangeNotSupported = errors.New("服务器不支持 Range, 无法只下载文件的一部分")
+	// ErrInvalidRange 下载的范围超出文件大小
+	ErrInvalidRange = errors.New("下载的范围无效")
+)
+
 type (
 	// Downloader 下载
 	Downloader struct {
@@ -142,7 +149,16 @@ func (der *Downloader) SelectBlockSizeAndInitRangeGen(single bool, status *trans
 	}
 	gen := status.RangeListGen()
 	if gen == nil {
-		switch der.config.Mode {
+		var (
+			mode       = der.config.Mode
+			begin, end = int64(0), status.TotalSize()
+		)
+		if r := der.config.Range; r != nil {
+			// 只下载部分时, status 的总大小为该部分的大小
+			mode = transfer.RangeGenMode_BlockSize
+			begin, end = r.LoadBegin(), r.LoadEnd()
+		}
+		switch mode {
 		case transfer.RangeGenMode_Default:
 			gen = transfer.NewRangeListGenDefault(status.TotalSize(), 0, 0, parallel)
 			blockSize = gen.LoadBlockSize()
@@ -154,7 +170,7 @@ func (der *Downloader) SelectBlockSizeAndInitRangeGen(single bool, status *trans
 				blockSize = b2
 			}
 
-			gen = transfer.NewRangeListGenBlockSize(status.TotalSize(), 0, blockSize)
+			gen = transfer.NewRangeListGenBlockSize(end, begin, blockSize)
 		default:
 			initErr = transfer.ErrUnknownRangeGenMode
 			return
@@ -273,6 +289,14 @@ func (der *Downloader) checkLoadBalancers() *LoadBalancerResponseList {
 func (der *Downloader) Execute() error {
 	der.lazyInit()
 
+	parentCtx := der.config.Context
+	if parentCtx == nil {
+		parentCtx = context.Background()
+	}
+	if err := parentCtx.Err(); err != nil {
+		return err
+	}
+
 	var (
 		resp *http.Response
 	)
@@ -324,6 +348,14 @@ func (der *Downloader) Execute() error {
 		single                   = der.firstInfo.AcceptRanges == ""
 		bii                      *transfer.DownloadInstanceInfo
 	)
+	if der.config.Range != nil {
+		if single {
+			return ErrRangeNotSupported
+		}
+		if r := der.config.Range; r.LoadBegin() < 0 || r.LoadEnd() > der.firstInfo.ContentLength || r.Len() <= 0 {
+			return ErrInvalidRange
+		}
+	}
 
 	if !single {
 		//load breakpoint
@@ -350,6 +382,9 @@ func (der *Downloader) Execute() error {
 		// 新建状态
 		status = transfer.NewDownloadStatus()
 		status.SetTotalSize(der.firstInfo.ContentLength)
+		if r := der.config.Range; r != nil {
+			status.SetTotalSize(r.Len())
+		}
 	}
 
 	// 设置限速
@@ -376,7 +411,7 @@ func (der *Downloader) Execute() error {
 	var writer Writer
 	if !der.config.IsTest {
 		// 尝试修剪文件
-		if fder, ok := der.writer.(Fder); ok {
+		if fder, ok := der.writer.(Fder); ok && der.config.Range == nil {
 			err = prealloc.PreAlloc(fder.Fd(), status.TotalSize())
 			if err != nil {
 				pcsverbose.Verbosef("DEBUG: truncate file error: %s\n", err)
@@ -414,13 +449,16 @@ func (der *Downloader) Execute() error {
 
 		worker := NewWorker(k, loadBalancer.URL, writer)
 		worker.SetClient(der.client)
-		worker.SetWriteMutex(writeMu)
+		// 按顺序输出时, 写入会阻塞等待其他线程写入前面的数据, 不能加锁
+		if _, ok := der.writer.(*OrderedWriter); !ok {
+			worker.SetWriteMutex(writeMu)
+		}
 		worker.SetReferer(loadBalancer.Referer)
 		worker.SetTotalSize(der.firstInfo.ContentLength)
 
 		// 使用第一个连接
-		// 断点续传时不使用
-		if k == 0 && !isInstance {
+		// 断点续传, 或只下载部分时不使用
+		if k == 0 && !isInstance && der.config.Range == nil {
 			worker.firstResp = resp
 		}
 
@@ -434,7 +472,7 @@ func (der *Downloader) Execute() error {
 	// 服务器不支持断点续传, 或者单线程下载, 都不重载worker
 	der.monitor.SetReloadWorker(parallel > 1)
 
-	moniterCtx, moniterCancelFunc := context.WithCancel(context.Background())
+	moniterCtx, moniterCancelFunc := context.WithCancel(parentCtx)
 	der.monitorCancelFunc = moniterCancelFunc
 
 	der.monitor.SetInstanceState(der.instanceState)
@@ -447,6 +485,12 @@ func (der *Downloader) Execute() error {
 
 	// 检查错误
 	err = der.monitor.Err()
+	if err == nil && moniterCtx.Err() != nil { // 已取消
+		err = context.Canceled
+		if parentCtx.Err() != nil { // 超时
+			err = parentCtx.Err()
+		}
+	}
 	if err == nil { // 成功
 		pcsutil.Trigger(der.onSuccessEvent)
 		if !single {
@@ -498,7 +542,7 @@ func (der *Downloader) Resume() {
 	pcsutil.Trigger(der.onResumeEvent)
 }
 
-//Cancel 取消
+//Cancel 取消, Execute 返回 context.Canceled
 func (der *Downloader) Cancel() {
 	if der.monitor == nil {
 		return
@@ -8,6 +8,7 @@ import (
 	"github.com/Erope/BaiduPCS-Go/requester/transfer"
 	"sort"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -26,6 +27,7 @@ type (
 		err             error
 		resetController *ResetController
 		isReloadWorker  bool //是否重载worker, 单线程模式不重载
+		paused          int32
 
 		// 临时变量
 		lastAvaliableIndex int
@@ -174,7 +176,7 @@ func (mt *Monitor) registerAllCompleted() {
 			for _, worker := range mt.workers {
 				switch worker.GetStatus().StatusCode() {
 				case StatusCodeInternalError:
-					mt.err = fmt.Errorf("ERROR: fatal internal error: %s", worker.Err())
+					mt.err = fmt.Errorf("ERROR: fatal internal error: %w", worker.Err())
 					close(mt.completed)
 					return
 				case StatusCodeSuccessed, StatusCodeCanceled:
@@ -227,6 +229,7 @@ func (mt *Monitor) RangeWorker(f RangeWorkerFunc) {
 
 //Pause 暂停所有的下载
 func (mt *Monitor) Pause() {
+	atomic.StoreInt32(&mt.paused, 1)
 	for k := range mt.workers {
 		if mt.workers[k] == nil {
 			continue
@@ -238,6 +241,7 @@ func (mt *Monitor) Pause() {
 
 //Resume 恢复所有的下载
 func (mt *Monitor) Resume() {
+	atomic.StoreInt32(&mt.paused, 0)
 	for k := range mt.workers {
 		if mt.workers[k] == nil {
 			continue
@@ -287,7 +291,7 @@ func (mt *Monitor) DymanicSplitWorker(worker *Worker) {
 		return
 	}
 
-	switch worker.status.statusCode {
+	switch worker.status.StatusCode() {
 	case StatusCodeDownloading, StatusCodeFailed, StatusCodeNetError:
 	//pass
 	default:
@@ -311,7 +315,7 @@ func (mt *Monitor) DymanicSplitWorker(worker *Worker) {
 
 	// 折半
 	avaliableWorkerRange := avaliableWorker.GetRange()
-	avaliableWorkerRange.StoreBegin(middle + 1)
+	avaliableWorkerRange.StoreBegin(middle)
 	avaliableWorkerRange.StoreEnd(end)
 	avaliableWorker.CleanStatus()
 
@@ -388,6 +392,12 @@ func (mt *Monitor) Execute(cancelCtx context.Context) {
 		case <-mt.completed:
 			return
 		case <-ticker.C:
+			// 暂停时不重载worker, 不加入新range
+			if atomic.LoadInt32(&mt.paused) == 1 {
+				mt.status.UpdateSpeeds()
+				continue
+			}
+
 			// 初始化监控工作
 			mt.ResetFailedAndNetErrorWorkers()
 
@@ -0,0 +1,51 @@
+package downloader
+
+import (
+	"bytes"
+	"github.com/Erope/BaiduPCS-Go/requester"
+	"github.com/Erope/BaiduPCS-Go/requester/transfer"
+	"testing"
+)
+
+func TestDymanicSplitWorker(t *testing.T) {
+	var (
+		data      = append(bytes.Repeat([]byte("0123456789abcdef"), 1<<14), 'x') // 长度为奇数
+		maxActive int32
+		server    = newRangeTestServer(data, &maxActive)
+		out       = make(memWriterAt, len(data))
+	)
+	defer server.Close()
+
+	newWorker := func(id int, r *transfer.Range) *Worker {
+		wer := NewWorker(id, server.URL, out)
+		wer.SetClient(requester.NewHTTPClient())
+		wer.SetAcceptRange("bytes")
+		wer.SetRange(r)
+		wer.lazyInit()
+		return wer
+	}
+	var (
+		failed = newWorker(1, &transfer.Range{Begin: 0, End: int64(len(data))})
+		idle   = newWorker(2, &transfer.Range{Begin: int64(len(data)), End: int64(len(data))}) // 已完成
+	)
+	failed.status.SetStatusCode(StatusCodeFailed)
+	idle.status.SetStatusCode(StatusCodeSuccessed)
+
+	mt := NewMonitor()
+	mt.SetWorkers(WorkerList{failed, idle})
+	mt.lazyInit()
+	mt.DymanicSplitWorker(failed)
+
+	// 两个 worker 的范围相接, 中间的字节不会被遗漏
+	if failed.GetRange().LoadEnd() != idle.GetRange().LoadBegin() {
+		t.Fatalf("ranges not contiguous: %s, %s", failed.GetRange().ShowDetails(), idle.GetRange().ShowDetails())
+	}
+	waitCompleted(t, idle)
+
+	failed.CleanStatus()
+	go failed.Execute()
+	waitCompleted(t, failed)
+	if !bytes.Equal(out, data) {
+		t.Fatal("unexpected data")
+	}
+}
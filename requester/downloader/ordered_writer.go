@@ -0,0 +1,148 @@
+package downloader
+
+import (
+	"errors"
+	"io"
+	"sort"
+	"sync"
+)
+
+var (
+	// ErrOrderedWriterClosed 按顺序输出已关闭
+	ErrOrderedWriterClosed = errors.New("ordered writer closed")
+)
+
+type (
+	// OrderedWriter 将多线程下载乱序写入的数据按顺序输出到 io.Writer, 如标准输出.
+	// 暂时不能输出的数据缓存在内存中, 缓存超过 maxBuffer 时, 写入阻塞,
+	// 直到前面的数据写入, 只有正在等待的位置的数据可以立即写入
+	OrderedWriter struct {
+		w         io.Writer
+		offset    int64 // 下一个要输出的位置
+		maxBuffer int64
+		buffered  int64
+		pending   []*orderedChunk // 按 offset 排序
+		err       error
+		mu        sync.Mutex
+		cond      *sync.Cond
+	}
+
+	orderedChunk struct {
+		offset int64
+		data   []byte
+	}
+)
+
+// NewOrderedWriter 初始化按顺序输出, offset 为第一个字节的位置, maxBuffer 为最大的缓存大小
+func NewOrderedWriter(w io.Writer, offset, maxBuffer int64) *OrderedWriter {
+	ow := &OrderedWriter{
+		w:         w,
+		offset:    offset,
+		maxBuffer: maxBuffer,
+	}
+	ow.cond = sync.NewCond(&ow.mu)
+	return ow
+}
+
+// WriteAt 写入位置 off 的数据, 已输出过的部分会被忽略
+func (ow *OrderedWriter) WriteAt(p []byte, off int64) (n int, err error) {
+	ow.mu.Lock()
+	defer ow.mu.Unlock()
+
+	// 缓存为空时总是可以写入, 避免单次写入超过缓存大小时一直阻塞
+	for ow.err == nil && off > ow.offset && ow.buffered > 0 && ow.buffered+int64(len(p)) > ow.maxBuffer {
+		ow.cond.Wait()
+	}
+	if ow.err != nil {
+		return 0, ow.err
+	}
+
+	end := off + int64(len(p))
+	if end <= ow.offset {
+		return len(p), nil
+	}
+
+	if off > ow.offset {
+		data := make([]byte, len(p)) // p 会被调用者重用, 需拷贝
+		copy(data, p)
+		i := sort.Search(len(ow.pending), func(i int) bool {
+			return ow.pending[i].offset > off
+		})
+		ow.pending = append(ow.pending, nil)
+		copy(ow.pending[i+1:], ow.pending[i:])
+		ow.pending[i] = &orderedChunk{
+			offset: off,
+			data:   data,
+		}
+		ow.buffered += int64(len(data))
+		return len(p), nil
+	}
+
+	err = ow.output(p[ow.offset-off:], end)
+	if err == nil {
+		err = ow.flush()
+	}
+	ow.cond.Broadcast()
+	if err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// output 输出数据, 输出后的位置为 end
+func (ow *OrderedWriter) output(data []byte, end int64) error {
+	_, err := ow.w.Write(data)
+	if err != nil {
+		ow.err = err
+		return err
+	}
+	ow.offset = end
+	return nil
+}
+
+// flush 输出缓存中已连续的数据
+func (ow *OrderedWriter) flush() error {
+	for len(ow.pending) > 0 && ow.pending[0].offset <= ow.offset {
+		chunk := ow.pending[0]
+		ow.pending[0] = nil
+		ow.pending = ow.pending[1:]
+		ow.buffered -= int64(len(chunk.data))
+
+		end := chunk.offset + int64(len(chunk.data))
+		if end <= ow.offset {
+			continue
+		}
+		err := ow.output(chunk.data[ow.offset-chunk.offset:], end)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Offset 返回已输出的位置
+func (ow *OrderedWriter) Offset() int64 {
+	ow.mu.Lock()
+	defer ow.mu.Unlock()
+	return ow.offset
+}
+
+// Err 返回输出时发生的错误, 关闭后返回 ErrOrderedWriterClosed
+func (ow *OrderedWriter) Err() error {
+	ow.mu.Lock()
+	defer ow.mu.Unlock()
+	return ow.err
+}
+
+// Close 丢弃缓存的数据, 唤醒阻塞的写入, 之后的写入返回 ErrOrderedWriterClosed
+func (ow *OrderedWriter) Close() error {
+	ow.mu.Lock()
+	if ow.err == nil {
+		ow.err = ErrOrderedWriterClosed
+	}
+	ow.pending = nil
+	ow.buffered = 0
+	ow.mu.Unlock()
+	ow.cond.Broadcast()
+	return nil
+}
@@ -0,0 +1,71 @@
+package downloader
+
+import (
+	"bytes"
+	"math/rand"
+	"sync"
+	"testing"
+)
+
+func TestOrderedWriter(t *testing.T) {
+	data := make([]byte, 100000)
+	rand.Read(data)
+
+	const (
+		begin     = 1000
+		blockSize = 3000
+	)
+	buf := &bytes.Buffer{}
+	ow := NewOrderedWriter(buf, begin, 8000)
+
+	// 乱序并发写入, 每个分块分多次写入, 并重复写入部分已写入的数据
+	var (
+		wg     sync.WaitGroup
+		blocks = rand.Perm((len(data) - begin + blockSize - 1) / blockSize)
+	)
+	for _, i := range blocks {
+		wg.Add(1)
+		go func(off int) {
+			defer wg.Done()
+			end := off + blockSize
+			if end > len(data) {
+				end = len(data)
+			}
+			for pos := off; pos < end; pos += 512 {
+				chunkEnd := pos + 512
+				if chunkEnd > end {
+					chunkEnd = end
+				}
+				_, err := ow.WriteAt(data[pos:chunkEnd], int64(pos))
+				if err != nil {
+					t.Error(err)
+					return
+				}
+			}
+			ow.WriteAt(data[off:off+10], int64(off))
+		}(begin + i*blockSize)
+	}
+	wg.Wait()
+
+	if ow.Offset() != int64(len(data)) {
+		t.Fatalf("offset: %d, want %d", ow.Offset(), len(data))
+	}
+	if !bytes.Equal(buf.Bytes(), data[begin:]) {
+		t.Errorf("data not match")
+	}
+}
+
+func TestOrderedWriterClose(t *testing.T) {
+	ow := NewOrderedWriter(&bytes.Buffer{}, 0, 10)
+	ow.WriteAt(make([]byte, 10), 100)
+
+	done := make(chan error)
+	go func() {
+		_, err := ow.WriteAt(make([]byte, 10), 200) // 超出缓存, 阻塞
+		done <- err
+	}()
+	ow.Close()
+	if err := <-done; err != ErrOrderedWriterClosed {
+		t.Errorf("err: %v, want ErrOrderedWriterClosed", err)
+	}
+}
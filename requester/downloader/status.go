@@ -2,6 +2,7 @@ package downloader
 
 import (
 	"github.com/Erope/BaiduPCS-Go/requester/transfer"
+	"sync/atomic"
 )
 
 type (
@@ -16,7 +17,7 @@ type (
 
 	//WorkerStatus worker状态
 	WorkerStatus struct {
-		statusCode StatusCode
+		statusCode int32 // StatusCode, 原子读写, 下载和暂停, 监控可能并发访问
 	}
 
 	// DownloadStatusFunc 下载状态处理函数
@@ -85,21 +86,21 @@ func GetStatusText(sc StatusCode) string {
 //NewWorkerStatus 初始化WorkerStatus
 func NewWorkerStatus() *WorkerStatus {
 	return &WorkerStatus{
-		statusCode: StatusCodeInit,
+		statusCode: int32(StatusCodeInit),
 	}
 }
 
 //SetStatusCode 设置worker状态码
 func (ws *WorkerStatus) SetStatusCode(sc StatusCode) {
-	ws.statusCode = sc
+	atomic.StoreInt32(&ws.statusCode, int32(sc))
 }
 
 //StatusCode 返回状态码
 func (ws *WorkerStatus) StatusCode() StatusCode {
-	return ws.statusCode
+	return StatusCode(atomic.LoadInt32(&ws.statusCode))
 }
 
 //StatusText 返回状态信息
 func (ws *WorkerStatus) StatusText() string {
-	return GetStatusText(ws.statusCode)
+	return GetStatusText(ws.StatusCode())
 }
@@ -30,6 +30,10 @@ type (
 		writeMu      *sync.Mutex
 		execMu       sync.Mutex
 
+		ctrlMu                 sync.Mutex    // 保护 paused, stopped 和取消函数
+		pauseMu                sync.Mutex    // 串行执行 Pause 和 Resume
+		paused                 bool          // 是否已暂停
+		stopped                chan struct{} // 当前执行结束时关闭, 未在执行时为 nil
 		pauseChan              chan struct{}
 		workerCancelFunc       context.CancelFunc
 		resetFunc              context.CancelFunc
@@ -53,9 +57,10 @@ func (wl WorkerList) Duplicate() WorkerList {
 //NewWorker 初始化Worker
 func NewWorker(id int, durl string, writerAt io.WriterAt) *Worker {
 	return &Worker{
-		id:       id,
-		url:      durl,
-		writerAt: writerAt,
+		id:        id,
+		url:       durl,
+		writerAt:  writerAt,
+		pauseChan: make(chan struct{}, 1),
 	}
 }
 
@@ -69,7 +74,7 @@ func (wer *Worker) lazyInit() {
 		wer.client = requester.NewHTTPClient()
 	}
 	if wer.pauseChan == nil {
-		wer.pauseChan = make(chan struct{})
+		wer.pauseChan = make(chan struct{}, 1)
 	}
 	if wer.wrange == nil {
 		wer.wrange = &transfer.Range{}
@@ -140,7 +145,7 @@ func (wer *Worker) GetSpeedsPerSecond() int64 {
 	return wer.speedsStat.GetSpeeds()
 }
 
-//Pause 暂停下载
+//Pause 暂停下载, 等待正在下载的 worker 停止后返回
 func (wer *Worker) Pause() {
 	wer.lazyInit()
 	if wer.acceptRanges == "" {
@@ -148,42 +153,80 @@ func (wer *Worker) Pause() {
 		return
 	}
 
-	if wer.status.statusCode == StatusCodePaused {
+	wer.pauseMu.Lock()
+	defer wer.pauseMu.Unlock()
+
+	wer.ctrlMu.Lock()
+	if wer.paused || wer.Completed() {
+		wer.ctrlMu.Unlock()
 		return
 	}
-	wer.pauseChan <- struct{}{}
-	wer.status.statusCode = StatusCodePaused
+	wer.paused = true
+	stopped := wer.stopped
+	if stopped != nil {
+		select {
+		case wer.pauseChan <- struct{}{}:
+		default:
+		}
+	}
+	wer.ctrlMu.Unlock()
+
+	if stopped != nil {
+		<-stopped
+	}
+	if !wer.Completed() {
+		wer.status.SetStatusCode(StatusCodePaused)
+	}
 }
 
 //Resume 恢复下载
 func (wer *Worker) Resume() {
-	if wer.status.statusCode != StatusCodePaused {
+	wer.pauseMu.Lock()
+	defer wer.pauseMu.Unlock()
+
+	wer.ctrlMu.Lock()
+	defer wer.ctrlMu.Unlock()
+	if !wer.paused {
+		return
+	}
+	wer.paused = false
+	if wer.stopped != nil {
+		// 正在执行
 		return
 	}
+	wer.stopped = make(chan struct{})
 	go wer.Execute()
 }
 
 //Cancel 取消下载
 func (wer *Worker) Cancel() error {
-	if wer.workerCancelFunc == nil {
+	wer.ctrlMu.Lock()
+	workerCancelFunc, readRespBodyCancelFunc := wer.workerCancelFunc, wer.readRespBodyCancelFunc
+	wer.ctrlMu.Unlock()
+
+	if workerCancelFunc == nil {
 		return errors.New("cancelFunc not set")
 	}
-	wer.workerCancelFunc()
-	if wer.readRespBodyCancelFunc != nil {
-		wer.readRespBodyCancelFunc()
+	workerCancelFunc()
+	if readRespBodyCancelFunc != nil {
+		readRespBodyCancelFunc()
 	}
 	return nil
 }
 
 //Reset 重设连接
 func (wer *Worker) Reset() {
-	if wer.resetFunc == nil {
+	wer.ctrlMu.Lock()
+	resetFunc, readRespBodyCancelFunc := wer.resetFunc, wer.readRespBodyCancelFunc
+	wer.ctrlMu.Unlock()
+
+	if resetFunc == nil {
 		pcsverbose.Verbosef("DEBUG: worker: resetFunc not set")
 		return
 	}
-	wer.resetFunc()
-	if wer.readRespBodyCancelFunc != nil {
-		wer.readRespBodyCancelFunc()
+	resetFunc()
+	if readRespBodyCancelFunc != nil {
+		readRespBodyCancelFunc()
 	}
 	wer.CleanStatus()
 	go wer.Execute()
@@ -191,12 +234,12 @@ func (wer *Worker) Reset() {
 
 // Canceled 是否已经取消
 func (wer *Worker) Canceled() bool {
-	return wer.status.statusCode == StatusCodeCanceled
+	return wer.status.StatusCode() == StatusCodeCanceled
 }
 
 //Completed 是否已经完成
 func (wer *Worker) Completed() bool {
-	switch wer.status.statusCode {
+	switch wer.status.StatusCode() {
 	case StatusCodeSuccessed, StatusCodeCanceled:
 		return true
 	default:
@@ -206,7 +249,7 @@ func (wer *Worker) Completed() bool {
 
 //Failed 是否失败
 func (wer *Worker) Failed() bool {
-	switch wer.status.statusCode {
+	switch wer.status.StatusCode() {
 	case StatusCodeFailed, StatusCodeInternalError, StatusCodeTooManyConnections, StatusCodeNetError:
 		return true
 	default:
@@ -216,7 +259,7 @@ func (wer *Worker) Failed() bool {
 
 //CleanStatus 清空状态
 func (wer *Worker) CleanStatus() {
-	wer.status.statusCode = StatusCodeInit
+	wer.status.SetStatusCode(StatusCodeInit)
 }
 
 //Err 返回worker错误
@@ -231,13 +274,41 @@ func (wer *Worker) Execute() {
 	wer.execMu.Lock()
 	defer wer.execMu.Unlock()
 
-	wer.status.statusCode = StatusCodeInit
-	single := wer.acceptRanges == ""
+	wer.ctrlMu.Lock()
+	if wer.stopped == nil {
+		wer.stopped = make(chan struct{})
+	}
+	stopped := wer.stopped
+	defer func() {
+		wer.ctrlMu.Lock()
+		if wer.stopped == stopped {
+			wer.stopped = nil
+		}
+		close(stopped)
+		wer.ctrlMu.Unlock()
+	}()
 
 	// 如果已暂停, 退出
-	if wer.status.statusCode == StatusCodePaused {
+	if wer.paused {
+		wer.ctrlMu.Unlock()
+		wer.status.SetStatusCode(StatusCodePaused)
 		return
 	}
+	// 丢弃上次执行未处理的暂停信号
+	select {
+	case <-wer.pauseChan:
+	default:
+	}
+
+	workerCancelCtx, workerCancelFunc := context.WithCancel(context.Background())
+	wer.workerCancelFunc = workerCancelFunc
+	resetCtx, resetFunc := context.WithCancel(context.Background())
+	wer.resetFunc = resetFunc
+	wer.readRespBodyCancelFunc = nil
+	wer.ctrlMu.Unlock()
+
+	wer.status.SetStatusCode(StatusCodeInit)
+	single := wer.acceptRanges == ""
 
 	if !single {
 		// 已完成
@@ -245,16 +316,11 @@ func (wer *Worker) Execute() {
 			if rlen < 0 {
 				pcsverbose.Verbosef("DEBUG: RangeLen is negative at begin: %v, %d\n", wer.wrange, wer.wrange.Len())
 			}
-			wer.status.statusCode = StatusCodeSuccessed
+			wer.status.SetStatusCode(StatusCodeSuccessed)
 			return
 		}
 	}
 
-	workerCancelCtx, workerCancelFunc := context.WithCancel(context.Background())
-	wer.workerCancelFunc = workerCancelFunc
-	resetCtx, resetFunc := context.WithCancel(context.Background())
-	wer.resetFunc = resetFunc
-
 	header := map[string]string{}
 	if wer.referer != "" {
 		header["Referer"] = wer.referer
@@ -264,7 +330,7 @@ func (wer *Worker) Execute() {
 		header["Range"] = fmt.Sprintf("%s=%d-%d", wer.acceptRanges, wer.wrange.LoadBegin(), wer.wrange.LoadEnd()-1)
 	}
 
-	wer.status.statusCode = StatusCodePending
+	wer.status.SetStatusCode(StatusCodePending)
 
 	var resp *http.Response
 	if wer.firstResp != nil {
@@ -277,12 +343,14 @@ func (wer *Worker) Execute() {
 			resp.Body.Close()
 			wer.firstResp = nil // 去掉第一个连接
 		}()
+		wer.ctrlMu.Lock()
 		wer.readRespBodyCancelFunc = func() {
 			resp.Body.Close()
 		}
+		wer.ctrlMu.Unlock()
 	}
 	if wer.err != nil {
-		wer.status.statusCode = StatusCodeNetError
+		wer.status.SetStatusCode(StatusCodeNetError)
 		return
 	}
 
@@ -295,7 +363,7 @@ func (wer *Worker) Execute() {
 	case 403: // Forbidden
 		fallthrough
 	case 406: // Not Acceptable
-		wer.status.statusCode = StatusCodeNetError
+		wer.status.SetStatusCode(StatusCodeNetError)
 		wer.err = errors.New(resp.Status)
 		return
 	case 429, 509: // Too Many Requests
@@ -303,7 +371,7 @@ func (wer *Worker) Execute() {
 		wer.err = errors.New(resp.Status)
 		return
 	default:
-		wer.status.statusCode = StatusCodeNetError
+		wer.status.SetStatusCode(StatusCodeNetError)
 		wer.err = fmt.Errorf("unexpected http status code, %d, %s", resp.StatusCode, resp.Status)
 		return
 	}
@@ -316,7 +384,7 @@ func (wer *Worker) Execute() {
 	if !single {
 		// 检查请求长度
 		if contentLength != rangeLength && wer.firstResp == nil { // 跳过检查第一个连接
-			wer.status.statusCode = StatusCodeNetError
+			wer.status.SetStatusCode(StatusCodeNetError)
 			wer.err = fmt.Errorf("Content-Length is unexpected: %d, need %d", contentLength, rangeLength)
 			return
 		}
@@ -325,7 +393,7 @@ func (wer *Worker) Execute() {
 			total := ParseContentRange(resp.Header.Get("Content-Range"))
 			if total > 0 {
 				if total != wer.totalSize {
-					wer.status.statusCode = StatusCodeInternalError // 这里设置为内部错误, 强制停止下载
+					wer.status.SetStatusCode(StatusCodeInternalError) // 这里设置为内部错误, 强制停止下载
 					wer.err = fmt.Errorf("Content-Range total length is unexpected: %d, need %d", total, wer.totalSize)
 					return
 				}
@@ -343,15 +411,16 @@ func (wer *Worker) Execute() {
 	for {
 		select {
 		case <-workerCancelCtx.Done(): //取消
-			wer.status.statusCode = StatusCodeCanceled
+			wer.status.SetStatusCode(StatusCodeCanceled)
 			return
 		case <-resetCtx.Done(): //重设连接
-			wer.status.statusCode = StatusCodeReseted
+			wer.status.SetStatusCode(StatusCodeReseted)
 			return
 		case <-wer.pauseChan: //暂停
+			wer.status.SetStatusCode(StatusCodePaused)
 			return
 		default:
-			wer.status.statusCode = StatusCodeDownloading
+			wer.status.SetStatusCode(StatusCodeDownloading)
 
 			// 初始化数据
 			var readErr error
@@ -382,7 +451,7 @@ func (wer *Worker) Execute() {
 
 				// 已完成 (未雨绸缪)
 				if rangeLength <= 0 {
-					wer.status.statusCode = StatusCodeCanceled
+					wer.status.SetStatusCode(StatusCodeCanceled)
 					wer.err = errors.New("worker already complete")
 					return
 				}
@@ -397,7 +466,7 @@ func (wer *Worker) Execute() {
 
 			// 写入数据
 			if wer.writerAt != nil {
-				wer.status.statusCode = StatusCodeWaitToWrite
+				wer.status.SetStatusCode(StatusCodeWaitToWrite)
 				if wer.writeMu != nil {
 					wer.writeMu.Lock() // 加锁, 减轻硬盘的压力
 				}
@@ -406,14 +475,14 @@ func (wer *Worker) Execute() {
 					if wer.writeMu != nil {
 						wer.writeMu.Unlock() //解锁
 					}
-					wer.status.statusCode = StatusCodeInternalError
+					wer.status.SetStatusCode(StatusCodeInternalError)
 					return
 				}
 
 				if wer.writeMu != nil {
 					wer.writeMu.Unlock() //解锁
 				}
-				wer.status.statusCode = StatusCodeDownloading
+				wer.status.SetStatusCode(StatusCodeDownloading)
 			}
 
 			// 更新下载统计数据
@@ -436,14 +505,14 @@ func (wer *Worker) Execute() {
 				case rlen <= 0:
 					// 下载完成
 					// 小于0可能是因为 worker 被 duplicate
-					wer.status.statusCode = StatusCodeSuccessed
+					wer.status.SetStatusCode(StatusCodeSuccessed)
 					if rlen < 0 {
 						pcsverbose.Verbosef("DEBUG: RangeLen is negative at end: %v, %d\n", wer.wrange, wer.wrange.Len())
 					}
 					return
 				default:
 					// 其他错误, 返回
-					wer.status.statusCode = StatusCodeFailed
+					wer.status.SetStatusCode(StatusCodeFailed)
 					wer.err = readErr
 					return
 				}
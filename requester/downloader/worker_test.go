@@ -0,0 +1,89 @@
+package downloader
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/Erope/BaiduPCS-Go/requester"
+	"github.com/Erope/BaiduPCS-Go/requester/transfer"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type memWriterAt []byte
+
+func (m memWriterAt) WriteAt(p []byte, off int64) (int, error) {
+	return copy(m[off:], p), nil
+}
+
+// newRangeTestServer 返回支持 Range 请求的测试服务器, 数据分块缓慢发送, maxActive 记录同时连接的最大数量
+func newRangeTestServer(data []byte, maxActive *int32) *httptest.Server {
+	var active int32
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&active, 1)
+		defer atomic.AddInt32(&active, -1)
+		if max := atomic.LoadInt32(maxActive); n > max {
+			atomic.CompareAndSwapInt32(maxActive, max, n)
+		}
+
+		var begin, end int64
+		fmt.Sscanf(r.Header.Get("Range"), "bytes=%d-%d", &begin, &end)
+		w.Header().Set("Content-Length", fmt.Sprint(end-begin+1))
+		w.WriteHeader(http.StatusPartialContent)
+		for i := begin; i <= end; i += 4096 {
+			j := i + 4096
+			if j > end+1 {
+				j = end + 1
+			}
+			if _, err := w.Write(data[i:j]); err != nil {
+				return
+			}
+			w.(http.Flusher).Flush()
+			time.Sleep(time.Millisecond)
+		}
+	}))
+}
+
+// waitCompleted 等待 worker 完成
+func waitCompleted(t *testing.T, wer *Worker) {
+	deadline := time.Now().Add(10 * time.Second)
+	for !wer.Completed() {
+		if time.Now().After(deadline) {
+			t.Fatalf("worker not completed, status: %s, err: %v", wer.GetStatus().StatusText(), wer.Err())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestWorkerPauseResume(t *testing.T) {
+	var (
+		data      = bytes.Repeat([]byte("0123456789abcdef"), 1<<14)
+		maxActive int32
+		server    = newRangeTestServer(data, &maxActive)
+	)
+	defer server.Close()
+
+	out := make(memWriterAt, len(data))
+	wer := NewWorker(1, server.URL, out)
+	wer.SetClient(requester.NewHTTPClient())
+	wer.SetAcceptRange("bytes")
+	wer.SetRange(&transfer.Range{Begin: 0, End: int64(len(data))})
+	wer.lazyInit()
+	go wer.Execute()
+
+	// 快速地暂停和恢复, 同一时间只能有一个连接在下载
+	for i := 0; i < 50; i++ {
+		wer.Pause()
+		wer.Resume()
+	}
+
+	waitCompleted(t, wer)
+	if maxActive > 1 {
+		t.Fatalf("expect one connection at a time, got %d", maxActive)
+	}
+	if !bytes.Equal(out, data) {
+		t.Fatal("unexpected data")
+	}
+}
@@ -0,0 +1,38 @@
+package requester_test
+
+import (
+	"context"
+	"github.com/Erope/BaiduPCS-Go/requester"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestReqWithContext(t *testing.T) {
+	block := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-block:
+		case <-r.Context().Done():
+		}
+	}))
+	defer server.Close()
+	defer close(block)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	c := requester.NewHTTPClient()
+	start := time.Now()
+	_, err := c.FetchWithContext(ctx, http.MethodGet, server.URL, nil, nil)
+	if err == nil {
+		t.Fatalf("expect error")
+	}
+	if ctx.Err() != context.DeadlineExceeded {
+		t.Fatalf("expect deadline exceeded, got %s", ctx.Err())
+	}
+	if time.Since(start) > 5*time.Second {
+		t.Fatalf("request not canceled in time")
+	}
+}
@@ -0,0 +1,101 @@
+package transfer
+
+import (
+	"time"
+)
+
+const (
+	// ProgressQueued 任务加入队列
+	ProgressQueued ProgressEventType = "queued"
+	// ProgressStarted 任务开始传输
+	ProgressStarted ProgressEventType = "started"
+	// ProgressProgress 传输进度, 每秒一次
+	ProgressProgress ProgressEventType = "progress"
+	// ProgressRetry 任务失败, 即将重试
+	ProgressRetry ProgressEventType = "retry"
+	// ProgressChecksum 文件校验结果
+	ProgressChecksum ProgressEventType = "checksum"
+	// ProgressSkipped 任务被跳过, 如目标文件已存在
+	ProgressSkipped ProgressEventType = "skipped"
+	// ProgressFinished 任务完成
+	ProgressFinished ProgressEventType = "finished"
+	// ProgressFailed 任务失败, 不再重试
+	ProgressFailed ProgressEventType = "failed"
+	// ProgressCanceled 任务被取消
+	ProgressCanceled ProgressEventType = "canceled"
+
+	// DirectionDownload 下载
+	DirectionDownload = "download"
+	// DirectionUpload 上传
+	DirectionUpload = "upload"
+)
+
+type (
+	// ProgressEventType 传输事件类型
+	ProgressEventType string
+
+	// ProgressEvent 传输事件, 字段名即 json 的键名, 字段只会增加, 不会修改或删除
+	ProgressEvent struct {
+		Event     ProgressEventType `json:"event"`
+		Direction string            `json:"direction"`           // download 或 upload
+		TaskID    int               `json:"task_id"`             // 任务id, 与文本输出中的 [id] 一致
+		Time      int64             `json:"time"`                // 事件时间, unix 毫秒时间戳
+		Path      string            `json:"path,omitempty"`      // 源路径
+		SavePath  string            `json:"save_path,omitempty"` // 保存的路径
+		Isdir     bool              `json:"isdir,omitempty"`     // 是否为目录, 目录展开后即完成
+		Size      int64             `json:"size"`                // 总大小
+		Bytes     int64             `json:"bytes"`               // 已传输的数据量
+		Speed     int64             `json:"speed"`               // 每秒的速度
+		Elapsed   float64           `json:"elapsed"`             // 已花费的时间, 单位为秒
+		ETA       float64           `json:"eta"`                 // 预计剩余时间, 单位为秒, -1 代表未知
+		Retry     int               `json:"retry,omitempty"`     // 第几次重试
+		MaxRetry  int               `json:"max_retry,omitempty"` // 最大重试次数
+		Checksum  string            `json:"checksum,omitempty"`  // 校验结果, ok, mismatch, unsupported 或 error
+		Message   string            `json:"message,omitempty"`   // 附加信息
+		Error     string            `json:"error,omitempty"`     // 错误信息
+	}
+
+	// ProgressStatus 传输状态, 由 DownloadStatuser 和 uploader.Status 实现
+	ProgressStatus interface {
+		TotalSize() int64
+		SpeedsPerSecond() int64
+		TimeElapsed() time.Duration
+	}
+
+	// ProgressHandler 处理传输事件, 须可并发调用
+	ProgressHandler interface {
+		HandleProgress(event *ProgressEvent)
+	}
+
+	// ProgressHandlerFunc 将函数转换为 ProgressHandler
+	ProgressHandlerFunc func(event *ProgressEvent)
+)
+
+// HandleProgress 调用 f(event)
+func (f ProgressHandlerFunc) HandleProgress(event *ProgressEvent) {
+	f(event)
+}
+
+// NewProgressEvent 初始化传输事件, 时间为当前时间
+func NewProgressEvent(event ProgressEventType, direction string, taskID int) *ProgressEvent {
+	return &ProgressEvent{
+		Event:     event,
+		Direction: direction,
+		TaskID:    taskID,
+		Time:      time.Now().UnixNano() / 1e6,
+		ETA:       -1,
+	}
+}
+
+// SetStatus 从传输状态设置总大小, 速度, 花费时间和预计剩余时间, transferred 为已传输的数据量
+func (pe *ProgressEvent) SetStatus(status ProgressStatus, transferred int64) *ProgressEvent {
+	pe.Size = status.TotalSize()
+	pe.Bytes = transferred
+	pe.Speed = status.SpeedsPerSecond()
+	pe.Elapsed = status.TimeElapsed().Seconds()
+	pe.ETA = -1
+	if pe.Speed > 0 && pe.Size >= transferred {
+		pe.ETA = float64(pe.Size-transferred) / float64(pe.Speed)
+	}
+	return pe
+}
@@ -25,6 +25,8 @@ type (
 		onSuccessEvent      requester.Event        //成功上传事件
 		onFinishEvent       requester.Event        //结束上传事件
 		onCancelEvent       requester.Event        //取消上传事件
+		onPauseEvent        requester.Event        //暂停上传事件
+		onResumeEvent       requester.Event        //恢复上传事件
 		onErrorEvent        requester.EventOnError //上传出错事件
 		onUploadStatusEvent UploadStatusFunc       //上传状态事件
 
@@ -42,6 +44,9 @@ type (
 		canceled                chan struct{}
 		closeCanceledOnce       sync.Once
 		updateInstanceStateChan chan struct{}
+
+		paused    bool
+		pauseCond *sync.Cond
 	}
 
 	// MultiUploaderConfig 多线程上传配置
@@ -49,6 +54,9 @@ type (
 		Parallel  int   // 上传并发量
 		BlockSize int64 // 上传分块
 		MaxRate   int64 // 限制最大上传速度
+
+		// Context 上传绑定的 context, 取消或超时后中断上传, 为 nil 时使用 context.Background()
+		Context context.Context
 	}
 )
 
@@ -58,6 +66,8 @@ func NewMultiUploader(multiUpload MultiUpload, file rio.ReaderAtLen64, config *M
 		multiUpload: multiUpload,
 		file:        file,
 		config:      config,
+		canceled:    make(chan struct{}),
+		pauseCond:   sync.NewCond(&sync.Mutex{}),
 	}
 }
 
@@ -82,6 +92,9 @@ func (muer *MultiUploader) lazyInit() {
 	if muer.config.Parallel <= 0 {
 		muer.config.Parallel = 4
 	}
+	if muer.config.Context == nil {
+		muer.config.Context = context.Background()
+	}
 	if muer.config.BlockSize <= 0 {
 		muer.config.BlockSize = 1 * converter.GB
 	}
@@ -128,6 +141,19 @@ func (muer *MultiUploader) Execute() {
 
 	muer.uploadStatusEvent()
 
+	// context 取消时取消上传
+	if muer.config.Context.Done() != nil {
+		stop := make(chan struct{})
+		defer close(stop)
+		go func() {
+			select {
+			case <-muer.config.Context.Done():
+				muer.Cancel()
+			case <-stop:
+			}
+		}()
+	}
+
 	err := muer.upload()
 
 	// 完成
@@ -161,9 +187,43 @@ func (muer *MultiUploader) InstanceState() *InstanceState {
 	}
 }
 
-// Cancel 取消上传
+// Pause 暂停上传, 正在上传的分片会继续上传完成
+func (muer *MultiUploader) Pause() {
+	muer.pauseCond.L.Lock()
+	muer.paused = true
+	muer.pauseCond.L.Unlock()
+	pcsutil.Trigger(muer.onPauseEvent)
+}
+
+// Resume 恢复上传
+func (muer *MultiUploader) Resume() {
+	muer.pauseCond.L.Lock()
+	muer.paused = false
+	muer.pauseCond.L.Unlock()
+	muer.pauseCond.Broadcast()
+	pcsutil.Trigger(muer.onResumeEvent)
+}
+
+// Cancel 取消上传, 可重复调用
 func (muer *MultiUploader) Cancel() {
-	close(muer.canceled)
+	muer.closeCanceledOnce.Do(func() {
+		close(muer.canceled)
+	})
+	muer.pauseCond.Broadcast()
+}
+
+// waitResume 暂停时阻塞, 直到恢复或取消
+func (muer *MultiUploader) waitResume() {
+	muer.pauseCond.L.Lock()
+	defer muer.pauseCond.L.Unlock()
+	for muer.paused {
+		select {
+		case <-muer.canceled:
+			return
+		default:
+		}
+		muer.pauseCond.Wait()
+	}
 }
 
 //OnExecute 设置开始上传事件
@@ -181,6 +241,16 @@ func (muer *MultiUploader) OnFinish(onFinishEvent requester.Event) {
 	muer.onFinishEvent = onFinishEvent
 }
 
+//OnPause 设置暂停上传事件
+func (muer *MultiUploader) OnPause(onPauseEvent requester.Event) {
+	muer.onPauseEvent = onPauseEvent
+}
+
+//OnResume 设置恢复上传事件
+func (muer *MultiUploader) OnResume(onResumeEvent requester.Event) {
+	muer.onResumeEvent = onResumeEvent
+}
+
 //OnCancel 设置取消上传事件
 func (muer *MultiUploader) OnCancel(onCancelEvent requester.Event) {
 	muer.onCancelEvent = onCancelEvent
@@ -56,6 +56,7 @@ func (muer *MultiUploader) upload() (uperr error) {
 	for {
 		wg := waitgroup.NewWaitGroup(muer.config.Parallel)
 		for {
+			muer.waitResume()
 			e := uploadDeque.Shift()
 			if e == nil { // 任务为空
 				break
@@ -67,7 +68,7 @@ func (muer *MultiUploader) upload() (uperr error) {
 				defer wg.Done()
 
 				var (
-					ctx, cancel = context.WithCancel(context.Background())
+					ctx, cancel = context.WithCancel(muer.config.Context)
 					doneChan    = make(chan struct{})
 					checksum    string
 					terr        error
@@ -0,0 +1,381 @@
+package uploader
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"errors"
+	"github.com/Erope/BaiduPCS-Go/pcsutil/converter"
+	"github.com/Erope/BaiduPCS-Go/requester/rio/speeds"
+	"github.com/Erope/BaiduPCS-Go/requester/transfer"
+	"hash"
+	"hash/crc32"
+	"io"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// DefaultStreamBlockSize 默认的数据流分块大小
+	DefaultStreamBlockSize = 32 * converter.MB
+	// DefaultStreamMaxBlocks 默认的数据流分块最大数量
+	DefaultStreamMaxBlocks = 1024
+	// DefaultStreamMaxRetry 默认的单个分块上传失败最大重试次数
+	DefaultStreamMaxRetry = 3
+)
+
+var (
+	// ErrStreamTooLarge 数据流超出分块数量限制
+	ErrStreamTooLarge = errors.New("数据流超出分块数量限制, 请增大分块大小")
+)
+
+type (
+	// StreamUploader 上传长度未知的数据流, 如标准输入.
+	// 数据按分块读入内存, 分块读满即上传, 同时计算整个数据流的 md5, slice-md5 和 crc32,
+	// 内存中最多同时存在 Parallel+1 个分块, 数据流不会保存到临时文件
+	StreamUploader struct {
+		onUploadStatusEvent UploadStatusFunc //上传状态事件
+
+		multiUpload MultiUpload
+		r           io.Reader
+		config      *StreamUploaderConfig
+		speedsStat  *speeds.Speeds
+		rateLimit   *speeds.RateLimit
+
+		read     int64 // 已从数据流读取的数据量
+		uploaded int64 // 已上传完成的分块的数据量
+		checksum *StreamChecksum
+
+		executeTime       time.Time
+		finished          chan struct{}
+		canceled          chan struct{}
+		closeCanceledOnce sync.Once
+
+		paused    bool
+		pauseCond *sync.Cond
+	}
+
+	// StreamUploaderConfig 数据流上传配置
+	StreamUploaderConfig struct {
+		Parallel     int   // 上传并发量
+		BlockSize    int64 // 分块大小
+		MaxBlocks    int   // 分块的最大数量
+		MaxRetry     int   // 单个分块上传失败最大重试次数
+		MaxRate      int64 // 限制最大上传速度
+		SliceMD5Size int64 // 计算 slice-md5 所需的长度
+
+		// Context 上传绑定的 context, 取消或超时后中断上传, 为 nil 时使用 context.Background()
+		Context context.Context
+	}
+
+	// StreamChecksum 整个数据流的校验信息
+	StreamChecksum struct {
+		Length   int64
+		MD5      []byte
+		SliceMD5 []byte
+		CRC32    uint32
+	}
+
+	// streamSum 读取数据流时计算校验信息
+	streamSum struct {
+		length    int64
+		sliceSize int64
+		md5       hash.Hash
+		sliceMD5  hash.Hash
+		crc32     hash.Hash32
+	}
+)
+
+func newStreamSum(sliceSize int64) *streamSum {
+	return &streamSum{
+		sliceSize: sliceSize,
+		md5:       md5.New(),
+		sliceMD5:  md5.New(),
+		crc32:     crc32.NewIEEE(),
+	}
+}
+
+func (ss *streamSum) Write(p []byte) (n int, err error) {
+	if left := ss.sliceSize - ss.length; left > 0 {
+		if int64(len(p)) < left {
+			left = int64(len(p))
+		}
+		ss.sliceMD5.Write(p[:left])
+	}
+	ss.md5.Write(p)
+	ss.crc32.Write(p)
+	ss.length += int64(len(p))
+	return len(p), nil
+}
+
+func (ss *streamSum) checksum() *StreamChecksum {
+	return &StreamChecksum{
+		Length:   ss.length,
+		MD5:      ss.md5.Sum(nil),
+		SliceMD5: ss.sliceMD5.Sum(nil),
+		CRC32:    ss.crc32.Sum32(),
+	}
+}
+
+// NewStreamUploader 初始化数据流上传
+func NewStreamUploader(multiUpload MultiUpload, r io.Reader, config *StreamUploaderConfig) *StreamUploader {
+	return &StreamUploader{
+		multiUpload: multiUpload,
+		r:           r,
+		config:      config,
+		canceled:    make(chan struct{}),
+		pauseCond:   sync.NewCond(&sync.Mutex{}),
+	}
+}
+
+func (su *StreamUploader) lazyInit() {
+	if su.config == nil {
+		su.config = &StreamUploaderConfig{}
+	}
+	if su.config.Parallel <= 0 {
+		su.config.Parallel = 4
+	}
+	if su.config.BlockSize <= 0 {
+		su.config.BlockSize = DefaultStreamBlockSize
+	}
+	if su.config.MaxBlocks <= 0 {
+		su.config.MaxBlocks = DefaultStreamMaxBlocks
+	}
+	if su.config.MaxRetry < 0 {
+		su.config.MaxRetry = DefaultStreamMaxRetry
+	}
+	if su.config.Context == nil {
+		su.config.Context = context.Background()
+	}
+	if su.config.SliceMD5Size <= 0 {
+		su.config.SliceMD5Size = 256 * converter.KB
+	}
+	if su.speedsStat == nil {
+		su.speedsStat = &speeds.Speeds{}
+	}
+}
+
+// Execute 读取数据流并上传所有分块, 返回按顺序排列的分块 checksum, 由调用者合并分块.
+// 数据流为空时返回的 checksum 为空. 取消时返回 context.Canceled
+func (su *StreamUploader) Execute() (checksumList []string, err error) {
+	if su.multiUpload == nil {
+		panic("multiUpload is nil")
+	}
+	su.lazyInit()
+
+	// 初始化限速
+	if su.config.MaxRate > 0 {
+		su.rateLimit = speeds.NewRateLimit(su.config.MaxRate)
+		defer su.rateLimit.Stop()
+	}
+
+	err = su.multiUpload.Precreate()
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithCancel(su.config.Context)
+	defer cancel()
+	go func() {
+		select {
+		case <-su.canceled:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	su.executeTime = time.Now()
+	su.finished = make(chan struct{})
+	defer close(su.finished)
+	su.uploadStatusEvent()
+
+	var (
+		// 空闲的分块缓冲区, 首次使用时分配
+		bufs  = make(chan []byte, su.config.Parallel+1)
+		sum   = newStreamSum(su.config.SliceMD5Size)
+		wg    sync.WaitGroup
+		mu    sync.Mutex
+		uperr error
+	)
+	for i := 0; i < cap(bufs); i++ {
+		bufs <- nil
+	}
+	setErr := func(err error) {
+		mu.Lock()
+		if uperr == nil {
+			uperr = err
+		}
+		mu.Unlock()
+		cancel()
+	}
+
+	for id := 0; ; id++ {
+		su.waitResume()
+
+		var buf []byte
+		select {
+		case buf = <-bufs:
+		case <-ctx.Done():
+		}
+		if ctx.Err() != nil {
+			break
+		}
+		if buf == nil {
+			buf = make([]byte, su.config.BlockSize)
+		}
+
+		n, rerr := io.ReadFull(su.r, buf)
+		if rerr == io.EOF { // 数据流结束
+			break
+		}
+		if rerr != nil && rerr != io.ErrUnexpectedEOF {
+			setErr(rerr)
+			break
+		}
+		if id >= su.config.MaxBlocks {
+			setErr(ErrStreamTooLarge)
+			break
+		}
+		sum.Write(buf[:n])
+		atomic.AddInt64(&su.read, int64(n))
+
+		mu.Lock()
+		checksumList = append(checksumList, "")
+		mu.Unlock()
+
+		wg.Add(1)
+		go func(id int, data []byte) {
+			defer wg.Done()
+			checksum, err := su.uploadBlock(ctx, id, data)
+			if err != nil {
+				setErr(err)
+				return
+			}
+			mu.Lock()
+			checksumList[id] = checksum
+			mu.Unlock()
+			atomic.AddInt64(&su.uploaded, int64(len(data)))
+			bufs <- data[:cap(data)]
+		}(id, buf[:n])
+
+		if rerr == io.ErrUnexpectedEOF { // 最后一个分块
+			break
+		}
+	}
+	wg.Wait()
+
+	select {
+	case <-su.canceled:
+		return nil, context.Canceled
+	default:
+	}
+	if err = su.config.Context.Err(); err != nil {
+		return nil, err
+	}
+	if uperr != nil {
+		return nil, uperr
+	}
+
+	su.checksum = sum.checksum()
+	return checksumList, nil
+}
+
+// uploadBlock 上传分块, 失败时重试, 数据流无法重新读取, 超过重试次数则返回错误
+func (su *StreamUploader) uploadBlock(ctx context.Context, id int, data []byte) (checksum string, err error) {
+	for retry := 0; ; retry++ {
+		unit := NewBufioSplitUnit(bytes.NewReader(data), transfer.Range{End: int64(len(data))}, su.speedsStat, su.rateLimit)
+		checksum, err = su.multiUpload.TmpFile(ctx, id, int64(id)*su.config.BlockSize, unit)
+		if err == nil {
+			return checksum, nil
+		}
+		if ctx.Err() != nil {
+			return "", ctx.Err()
+		}
+		if me, ok := err.(*MultiError); ok && me.Terminated {
+			return "", me.Err
+		}
+		if retry >= su.config.MaxRetry {
+			return "", err
+		}
+
+		uploaderVerbose.Warnf("upload stream block err: %s, id: %d, retry: %d\n", err, id, retry+1)
+		select {
+		case <-time.After(time.Duration(retry+1) * time.Second):
+		case <-ctx.Done():
+			return "", ctx.Err()
+		}
+	}
+}
+
+// Checksum 返回整个数据流的校验信息, Execute 成功后有效
+func (su *StreamUploader) Checksum() *StreamChecksum {
+	return su.checksum
+}
+
+// Pause 暂停上传, 正在上传的分块会继续上传完成
+func (su *StreamUploader) Pause() {
+	su.pauseCond.L.Lock()
+	su.paused = true
+	su.pauseCond.L.Unlock()
+}
+
+// Resume 恢复上传
+func (su *StreamUploader) Resume() {
+	su.pauseCond.L.Lock()
+	su.paused = false
+	su.pauseCond.L.Unlock()
+	su.pauseCond.Broadcast()
+}
+
+// Cancel 取消上传, 可重复调用
+func (su *StreamUploader) Cancel() {
+	su.closeCanceledOnce.Do(func() {
+		close(su.canceled)
+	})
+	su.pauseCond.Broadcast()
+}
+
+// waitResume 暂停时阻塞, 直到恢复或取消
+func (su *StreamUploader) waitResume() {
+	su.pauseCond.L.Lock()
+	defer su.pauseCond.L.Unlock()
+	for su.paused {
+		select {
+		case <-su.canceled:
+			return
+		default:
+		}
+		su.pauseCond.Wait()
+	}
+}
+
+// OnUploadStatusEvent 设置上传状态事件, 总大小为已从数据流读取的数据量
+func (su *StreamUploader) OnUploadStatusEvent(f UploadStatusFunc) {
+	su.onUploadStatusEvent = f
+}
+
+func (su *StreamUploader) uploadStatusEvent() {
+	if su.onUploadStatusEvent == nil {
+		return
+	}
+
+	finished := su.finished
+	go func() {
+		ticker := time.NewTicker(1 * time.Second) // 每秒统计
+		defer ticker.Stop()
+		for {
+			select {
+			case <-finished:
+				return
+			case <-ticker.C:
+				su.onUploadStatusEvent(&UploadStatus{
+					totalSize:       atomic.LoadInt64(&su.read),
+					uploaded:        atomic.LoadInt64(&su.uploaded),
+					speedsPerSecond: su.speedsStat.GetSpeeds(),
+					timeElapsed:     time.Since(su.executeTime) / 1e8 * 1e8,
+				}, nil)
+			}
+		}
+	}()
+}
@@ -0,0 +1,122 @@
+package uploader
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"errors"
+	"github.com/Erope/BaiduPCS-Go/requester/rio"
+	"hash/crc32"
+	"io/ioutil"
+	"strings"
+	"sync"
+	"testing"
+)
+
+type memMultiUpload struct {
+	mu       sync.Mutex
+	blocks   map[string][]byte
+	failures int // 前几次上传失败
+}
+
+func (mu *memMultiUpload) Precreate() error {
+	return nil
+}
+
+func (mu *memMultiUpload) TmpFile(ctx context.Context, partseq int, partOffset int64, r rio.ReaderLen64) (string, error) {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return "", err
+	}
+	if int64(len(data)) != r.Len() {
+		return "", errors.New("length not match")
+	}
+
+	mu.mu.Lock()
+	defer mu.mu.Unlock()
+	if mu.failures > 0 {
+		mu.failures--
+		return "", errors.New("temporary error")
+	}
+	sum := md5.Sum(data)
+	checksum := hex.EncodeToString(sum[:])
+	mu.blocks[checksum] = data
+	return checksum, nil
+}
+
+func (mu *memMultiUpload) CreateSuperFile(checksumList ...string) error {
+	return nil
+}
+
+func TestStreamUploader(t *testing.T) {
+	data := []byte(strings.Repeat("0123456789abcdef", 1000)) // 16000 bytes
+	for _, size := range []int{0, 100, 1000, 4096, len(data)} {
+		mu := &memMultiUpload{
+			blocks:   map[string][]byte{},
+			failures: 1,
+		}
+		su := NewStreamUploader(mu, bytes.NewReader(data[:size]), &StreamUploaderConfig{
+			Parallel:     2,
+			BlockSize:    1000,
+			MaxRetry:     1,
+			SliceMD5Size: 256,
+		})
+		checksumList, err := su.Execute()
+		if err != nil {
+			t.Fatalf("size %d: %s", size, err)
+		}
+		if len(checksumList) != (size+999)/1000 {
+			t.Fatalf("size %d: blocks %d", size, len(checksumList))
+		}
+
+		var joined []byte
+		for _, checksum := range checksumList {
+			joined = append(joined, mu.blocks[checksum]...)
+		}
+		if !bytes.Equal(joined, data[:size]) {
+			t.Errorf("size %d: content not match", size)
+		}
+
+		sum := su.Checksum()
+		wantMD5 := md5.Sum(data[:size])
+		sliceEnd := size
+		if sliceEnd > 256 {
+			sliceEnd = 256
+		}
+		wantSliceMD5 := md5.Sum(data[:sliceEnd])
+		if sum.Length != int64(size) || !bytes.Equal(sum.MD5, wantMD5[:]) || !bytes.Equal(sum.SliceMD5, wantSliceMD5[:]) || sum.CRC32 != crc32.ChecksumIEEE(data[:size]) {
+			t.Errorf("size %d: checksum not match: %+v", size, sum)
+		}
+	}
+}
+
+func TestStreamUploaderErrors(t *testing.T) {
+	mu := &memMultiUpload{
+		blocks:   map[string][]byte{},
+		failures: 10,
+	}
+	su := NewStreamUploader(mu, bytes.NewReader(make([]byte, 3000)), &StreamUploaderConfig{
+		BlockSize: 1000,
+	})
+	_, err := su.Execute()
+	if err == nil {
+		t.Errorf("want error after max retry")
+	}
+
+	su = NewStreamUploader(&memMultiUpload{blocks: map[string][]byte{}}, bytes.NewReader(make([]byte, 3001)), &StreamUploaderConfig{
+		BlockSize: 1000,
+		MaxBlocks: 3,
+	})
+	_, err = su.Execute()
+	if err != ErrStreamTooLarge {
+		t.Errorf("err: %v, want ErrStreamTooLarge", err)
+	}
+
+	su = NewStreamUploader(&memMultiUpload{blocks: map[string][]byte{}}, bytes.NewReader(make([]byte, 3000)), nil)
+	su.Cancel()
+	_, err = su.Execute()
+	if err != context.Canceled {
+		t.Errorf("err: %v, want context.Canceled", err)
+	}
+}
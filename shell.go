@@ -0,0 +1,231 @@
+package main
+
+import (
+	"fmt"
+	"github.com/Erope/BaiduPCS-Go/baidupcs"
+	"github.com/Erope/BaiduPCS-Go/internal/pcsconfig"
+	"github.com/Erope/BaiduPCS-Go/pcsliner"
+	"github.com/Erope/BaiduPCS-Go/pcsliner/args"
+	"github.com/Erope/BaiduPCS-Go/pcsutil"
+	"github.com/Erope/BaiduPCS-Go/pcsutil/converter"
+	"github.com/Erope/BaiduPCS-Go/pcsutil/escaper"
+	"github.com/peterh/liner"
+	"github.com/urfave/cli"
+	"io"
+	"os"
+	"path"
+	"strings"
+	"unicode"
+)
+
+var (
+	// shellPathCommands 参数为网盘路径的命令, 交互模式下可自动补全网盘路径
+	shellPathCommands = []string{
+		"cd", "ls", "search", "tree", "meta", "rm", "mkdir", "cp", "mv", "download", "upload",
+		"locate", "rapidupload", "createsuperfile", "fixmd5", "share", "cache", "export", "sync", "bisync",
+	}
+)
+
+// runShell 运行交互式命令行, 直到输入 exit 或 Ctrl+C, Ctrl+D.
+// 后台任务在交互式命令行运行期间继续进行
+func runShell(c *cli.Context) {
+	isCli = true
+
+	var (
+		line = pcsliner.NewLiner()
+		err  error
+	)
+
+	line.History, err = pcsliner.NewLineHistory(historyFilePath)
+	if err != nil {
+		fmt.Printf("警告: 读取历史命令文件错误, %s\n", err)
+	} else {
+		line.ReadHistory()
+	}
+	defer func() {
+		if line.History != nil {
+			line.DoWriteHistory()
+		}
+		line.Close()
+	}()
+
+	line.State.SetCompleter(shellCompleter(c.App, func() string {
+		return pcsconfig.Config.ActiveUser().Workdir
+	}, func(dir string) (baidupcs.FileDirectoryList, error) {
+		fdl, pcsError := pcsconfig.Config.ActiveUserBaiduPCS().CacheFilesDirectoriesList(dir, baidupcs.DefaultOrderOptions)
+		if pcsError != nil {
+			return nil, pcsError
+		}
+		return fdl, nil
+	}))
+
+	fmt.Printf("提示: 方向键上下可切换历史命令.\n")
+	fmt.Printf("提示: Tab 键自动补全命令, 参数和网盘路径.\n")
+	fmt.Printf("提示: 输入 help 获取帮助, 输入 exit 退出.\n")
+
+	for {
+		var (
+			prompt     string
+			activeUser = pcsconfig.Config.ActiveUser()
+		)
+
+		if activeUser.Name != "" {
+			// 格式: BaiduPCS-Go:<工作目录> <百度ID>$
+			// 工作目录太长时, 会自动缩略
+			prompt = c.App.Name + ":" + converter.ShortDisplay(path.Base(activeUser.Workdir), NameShortDisplayNum) + " " + activeUser.Name + "$ "
+		} else {
+			// BaiduPCS-Go >
+			prompt = c.App.Name + " > "
+		}
+
+		commandLine, err := line.State.Prompt(prompt)
+		switch err {
+		case nil:
+		case liner.ErrPromptAborted, io.EOF:
+			return
+		default:
+			fmt.Println(err)
+			return
+		}
+
+		cmdArgs := args.Parse(commandLine)
+		if len(cmdArgs) == 0 {
+			continue
+		}
+
+		line.State.AppendHistory(commandLine)
+
+		switch cmdArgs[0] {
+		case "exit", "quit":
+			return
+		case "clear":
+			line.ClearScreen()
+			continue
+		case c.Command.Name:
+			fmt.Printf("已在交互模式中\n")
+			continue
+		}
+
+		// 恢复原始终端状态
+		// 防止运行命令时程序被结束, 终端出现异常
+		line.Pause()
+		c.App.Run(append([]string{os.Args[0]}, cmdArgs...))
+		line.Resume()
+	}
+}
+
+// shellCompleter 返回交互式命令行的 tab 补全函数,
+// 补全命令, 子命令, 参数, 以及 shellPathCommands 中的命令的网盘路径, 相对路径基于 workdir() 的工作目录
+func shellCompleter(app *cli.App, workdir func() string, list func(dir string) (baidupcs.FileDirectoryList, error)) liner.Completer {
+	// escapeFunc 需要转义的字符
+	escapeFunc := func(r rune) bool {
+		return args.IsQuote(r) || unicode.IsSpace(r)
+	}
+
+	return func(line string) (s []string) {
+		var (
+			lineArgs = args.Parse(line)
+			closed   = line == "" || strings.HasSuffix(line, " ")
+			current  string
+		)
+
+		if !closed && len(lineArgs) > 0 {
+			current = lineArgs[len(lineArgs)-1]
+			lineArgs = lineArgs[:len(lineArgs)-1]
+		}
+		escaper.EscapeStringsByRuneFunc(lineArgs, escapeFunc)
+
+		var (
+			prefix = strings.Join(lineArgs, " ")
+		)
+		if prefix != "" {
+			prefix += " "
+		}
+
+		// 补全命令
+		if len(lineArgs) == 0 {
+			for _, cmd := range app.Commands {
+				if cmd.Hidden {
+					continue
+				}
+				for _, name := range cmd.Names() {
+					if strings.HasPrefix(name, current) {
+						s = append(s, name+" ")
+					}
+				}
+			}
+			return
+		}
+
+		cmd := app.Command(lineArgs[0])
+		if cmd == nil {
+			return
+		}
+		cmdName := cmd.Name
+
+		// 补全子命令
+		if len(cmd.Subcommands) > 0 {
+			if len(lineArgs) == 1 && !strings.HasPrefix(current, "-") {
+				for _, sub := range cmd.Subcommands {
+					for _, name := range sub.Names() {
+						if strings.HasPrefix(name, current) {
+							s = append(s, prefix+name+" ")
+						}
+					}
+				}
+				return
+			}
+			for k := range cmd.Subcommands {
+				if cmd.Subcommands[k].HasName(lineArgs[1]) {
+					cmd = &cmd.Subcommands[k]
+					break
+				}
+			}
+		}
+
+		// 补全参数
+		if strings.HasPrefix(current, "-") {
+			for _, flag := range cmd.Flags {
+				for _, name := range strings.Split(flag.GetName(), ",") {
+					name = "-" + strings.TrimSpace(name)
+					if strings.HasPrefix(name, current) {
+						s = append(s, prefix+name+" ")
+					}
+				}
+			}
+			return
+		}
+
+		if !pcsutil.ContainsString(shellPathCommands, cmdName) {
+			return
+		}
+
+		// 补全网盘路径
+		var (
+			dir, base = path.Split(current)
+			targetDir = dir
+		)
+		if !path.IsAbs(targetDir) {
+			targetDir = path.Join("/", workdir(), targetDir)
+		}
+
+		fdl, err := list(path.Clean(targetDir))
+		if err != nil {
+			return
+		}
+
+		for _, fd := range fdl {
+			if fd == nil || !strings.HasPrefix(fd.Filename, base) {
+				continue
+			}
+
+			completed := prefix + escaper.EscapeByRuneFunc(dir+fd.Filename, escapeFunc)
+			if fd.Isdir {
+				s = append(s, completed+"/")
+				continue
+			}
+			s = append(s, completed+" ")
+		}
+		return
+	}
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"github.com/Erope/BaiduPCS-Go/baidupcs"
+	"github.com/Erope/BaiduPCS-Go/baidupcs/pcsemu"
+	"github.com/urfave/cli"
+	"io/ioutil"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func newTestShellApp() *cli.App {
+	app := cli.NewApp()
+	app.Commands = []cli.Command{
+		{Name: "ls", Aliases: []string{"l", "ll"}, Flags: []cli.Flag{
+			cli.BoolFlag{Name: "asc"},
+			cli.BoolFlag{Name: "desc"},
+		}},
+		{Name: "login"},
+		{Name: "logout"},
+		{Name: "cd"},
+		{Name: "debug", Hidden: true},
+		{Name: "config", Subcommands: []cli.Command{
+			{Name: "set", Flags: []cli.Flag{cli.IntFlag{Name: "max_parallel, p"}}},
+			{Name: "reset"},
+		}},
+	}
+	return app
+}
+
+func TestShellCompleterCommand(t *testing.T) {
+	completer := shellCompleter(newTestShellApp(), func() string { return "/" }, func(dir string) (baidupcs.FileDirectoryList, error) {
+		t.Fatalf("unexpected list: %s", dir)
+		return nil, nil
+	})
+
+	for _, c := range []struct {
+		line   string
+		expect []string
+	}{
+		{"lo", []string{"login ", "logout "}},
+		{"l", []string{"ls ", "l ", "ll ", "login ", "logout "}},
+		{"de", nil},
+		{"x", nil},
+		{"config ", []string{"config set ", "config reset "}},
+		{"config re", []string{"config reset "}},
+		{"config set -", []string{"config set -max_parallel ", "config set -p "}},
+		{"ls -d", []string{"ls -desc "}},
+		{"login ", nil},
+		{"unknown ", nil},
+	} {
+		if s := completer(c.line); !reflect.DeepEqual(s, c.expect) {
+			t.Errorf("%q: expect %q, got %q", c.line, c.expect, s)
+		}
+	}
+}
+
+func TestShellCompleterPath(t *testing.T) {
+	root, err := ioutil.TempDir("", "pcsemu")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+
+	for _, dir := range []string{"docs", "my dir", "docs/sub"} {
+		if err = os.MkdirAll(filepath.Join(root, filepath.FromSlash(dir)), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, file := range []string{"doc.txt", "docs/a.txt", "my dir/b.txt"} {
+		if err = ioutil.WriteFile(filepath.Join(root, filepath.FromSlash(file)), []byte(file), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	s, err := pcsemu.NewServer(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := httptest.NewServer(s)
+	defer server.Close()
+
+	pcs := baidupcs.NewPCS(266719, "")
+	pcs.SetUID(1)
+	pcs.SetHTTPS(false)
+	if err = pcs.SetPCSAddr(server.URL); err != nil {
+		t.Fatal(err)
+	}
+
+	workdir := "/"
+	completer := shellCompleter(newTestShellApp(), func() string { return workdir }, func(dir string) (baidupcs.FileDirectoryList, error) {
+		fdl, pcsError := pcs.FilesDirectoriesList(dir, baidupcs.DefaultOrderOptions)
+		if pcsError != nil {
+			return nil, pcsError
+		}
+		return fdl, nil
+	})
+
+	for _, c := range []struct {
+		workdir string
+		line    string
+		expect  []string
+	}{
+		{"/", "ls do", []string{"ls docs/", "ls doc.txt "}},
+		{"/", "ls docs/", []string{"ls docs/sub/", "ls docs/a.txt "}},
+		{"/", "ls /docs/s", []string{"ls /docs/sub/"}},
+		{"/", "cd my", []string{`cd my\ dir/`}},
+		{"/", `ls my\ dir/`, []string{`ls my\ dir/b.txt `}},
+		{"/", "ls -asc do", []string{"ls -asc docs/", "ls -asc doc.txt "}},
+		{"/", "ls x", nil},
+		{"/", "ls nodir/", nil},
+		{"/", "login do", nil},
+		{"/docs", "ls ", []string{"ls sub/", "ls a.txt "}},
+		{"/docs", "ls ../my", []string{`ls ../my\ dir/`}},
+	} {
+		workdir = c.workdir
+		if s := completer(c.line); !reflect.DeepEqual(s, c.expect) {
+			t.Errorf("%s: %q: expect %q, got %q", c.workdir, c.line, c.expect, s)
+		}
+	}
+}